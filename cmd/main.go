@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"ssh-scp/internal/config"
+	"ssh-scp/internal/layout"
 	sshclient "ssh-scp/internal/ssh"
 	"ssh-scp/internal/ui"
 
@@ -27,6 +32,10 @@ const (
 	statePasswordPrompt
 )
 
+// prog is the running program. Terminal sessions use it to deliver output
+// from their SSH goroutines.
+var prog *tea.Program
+
 // passwordResponse carries the user's reply back to the connection goroutine.
 type passwordResponse struct {
 	Password  string
@@ -60,13 +69,24 @@ type AppModel struct {
 	tabs           []ui.Tab
 	activeTab      int
 	clients        []*sshclient.Client
+	terminals      []*ui.TerminalModel
 	browsers       []ui.FileBrowserModel
+	conns          []config.Connection // connection settings per tab
+	layouts        []*layout.Layout    // pane layout per tab
 	pending        *pendingConnection
 	showHelp       bool
 	err            string
 	bridge         *passwordBridge
 	passwordDialog ui.PasswordDialogModel
 	editor         *ui.EditorModel
+	history        *ui.HistoryModel
+	syncView       *ui.SyncModel
+	copyTarget     *ui.CopyTargetModel
+	player         *ui.PlayerModel
+	broadcastView  *ui.BroadcastModel
+	fleet          *ui.FleetModel // command run on the hosts marked on the connection screen
+	broadcasting   bool           // keys typed into a member tab go to every tabs[i].Broadcast tab
+	sudoPrompt     bool           // passwordDialog is asking for the active tab's sudo password
 }
 
 func initialModel() AppModel {
@@ -103,8 +123,34 @@ type connectedMsg struct {
 	err    error
 }
 
+// terminalStartedMsg is sent when a tab's shell session has started.
+type terminalStartedMsg struct {
+	term *ui.TerminalModel
+	err  error
+}
+
 // acceptedHosts stores fingerprints of host keys the user has accepted.
-var acceptedHosts = map[string]string{}
+// Fleet commands connect to several hosts at once, so access goes through
+// acceptedHostsMu.
+var (
+	acceptedHosts   = map[string]string{}
+	acceptedHostsMu sync.Mutex
+)
+
+// hostKeyAccepted reports whether the key with fingerprint fp has been
+// accepted for hostname.
+func hostKeyAccepted(hostname, fp string) bool {
+	acceptedHostsMu.Lock()
+	defer acceptedHostsMu.Unlock()
+	return acceptedHosts[hostname] == fp
+}
+
+// acceptHostKey remembers the key with fingerprint fp for hostname.
+func acceptHostKey(hostname, fp string) {
+	acceptedHostsMu.Lock()
+	defer acceptedHostsMu.Unlock()
+	acceptedHosts[hostname] = fp
+}
 
 func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -118,32 +164,12 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			browser, _ := m.browsers[i].Update(msg)
 			m.browsers[i] = browser
 		}
+		m.resizeTerminals()
 		return m, cmd
 
 	case ui.ConnectMsg:
 		// Merge SSH config options for the target host if not already set.
-		conn := msg.Conn
-		if match := config.MatchSSHHost(m.sshHosts, conn.Host); match != nil {
-			log.Printf("[AppModel] matched SSH config host %q for %s", match.Alias, conn.Host)
-			if conn.HostKeyAlgorithms == "" {
-				conn.HostKeyAlgorithms = match.HostKeyAlgorithms
-			}
-			if conn.PubkeyAcceptedTypes == "" {
-				conn.PubkeyAcceptedTypes = match.PubkeyAcceptedTypes
-			}
-			if conn.StrictHostKeyChecking == "" {
-				conn.StrictHostKeyChecking = match.StrictHostKeyChecking
-			}
-			if conn.UserKnownHostsFile == "" {
-				conn.UserKnownHostsFile = match.UserKnownHostsFile
-			}
-			if conn.KeyPath == "" && match.IdentityFile != "" {
-				conn.KeyPath = match.IdentityFile
-			}
-			if conn.ProxyJump == "" && match.ProxyJump != "" {
-				conn.ProxyJump = match.ProxyJump
-			}
-		}
+		conn := mergeSSHConfig(msg.Conn, m.sshHosts)
 		// Create the bridge and start the async connection worker.
 		bridge := &passwordBridge{
 			msgCh:      make(chan tea.Msg, 1),
@@ -169,27 +195,116 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if err := config.Save(m.cfg); err != nil {
 			log.Printf("[AppModel] failed to save config: %v", err)
 		}
+		hostConn := msg.conn
+		hostConn.HostSettings = m.cfg.Host(msg.conn)
+		if kbps := m.cfg.RateLimitFor(hostConn); kbps > 0 {
+			log.Printf("[AppModel] limiting transfers to %d KB/s", kbps)
+			msg.client.SetRateLimit(int64(kbps) * 1024)
+		}
 		tabTitle := ui.TabTitle(msg.conn.Username, msg.conn.Host, len(m.tabs))
 		m.tabs = append(m.tabs, ui.Tab{Title: tabTitle, Connected: true})
 		m.clients = append(m.clients, msg.client)
 
-		homeDir := "~"
+		homeDir, hostname := "~", ""
 		if sess, err := msg.client.NewSession(); err == nil {
-			if out, err := sess.Output("echo $HOME"); err == nil {
-				homeDir = strings.TrimSpace(string(out))
+			if out, err := sess.Output("echo $HOME; uname -n"); err == nil {
+				lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+				homeDir = strings.TrimSpace(lines[0])
+				if len(lines) > 1 {
+					hostname = strings.TrimSpace(lines[1])
+				}
 			}
 			if err := sess.Close(); err != nil {
 				log.Printf("close home-dir session: %v", err)
 			}
 		}
 
-		localDir, _ := os.Getwd()
-		browser := ui.NewFileBrowserModel(msg.client, localDir, homeDir)
+		cwd, _ := os.Getwd()
+		localDir := hostConn.LocalStartDir(cwd)
+		remoteDir := hostConn.RemoteStartDir(homeDir)
+		browser := ui.NewFileBrowserModel(msg.client, localDir, remoteDir)
+		browser.SetHistoryHost(config.HostKey(hostConn))
+		browser.SetCompress(hostConn.Compress)
+		browser.SetPanelViews(m.cfg.LocalPanel, m.cfg.RemotePanel)
+		browser.SetBookmarks(hostConn.Bookmarks)
+		lay := layout.New(hostConn.Layout)
+		previewCmd := browser.SetPreviewPane(lay.Has(layout.Preview))
 		m.browsers = append(m.browsers, browser)
+		m.conns = append(m.conns, hostConn)
+		m.layouts = append(m.layouts, lay)
+		term := ui.NewTerminalModel(msg.client)
+		term.SetProgram(prog)
+		term.SetScrollback(m.cfg.Scrollback())
+		term.SetHostname(hostname)
+		term.SetShellIntegration(hostConn.ShellIntegration)
+		m.terminals = append(m.terminals, term)
 		m.activeTab = len(m.tabs) - 1
 		m.state = stateMain
+		m.resizeTerminals()
+		if hostConn.AutoRecord {
+			m.toggleRecording()
+		}
+
+		return m, tea.Batch(browser.Init(), startTerminalCmd(term), previewCmd)
+
+	case terminalStartedMsg:
+		if msg.err != nil {
+			log.Printf("[AppModel] terminal start error: %v", msg.err)
+			msg.term.SetError("Failed to start shell: " + msg.err.Error())
+		}
+		return m, nil
+
+	case ui.TerminalOutputMsg:
+		msg.Term.AppendOutput(msg.Data)
+		if dir, ok := msg.Term.TakeCwd(); ok {
+			return m, m.followShellDir(msg.Term, dir)
+		}
+		return m, nil
 
-		return m, browser.Init()
+	case ui.RunnerOutputMsg, ui.TailLinesMsg, ui.SearchBatchMsg, ui.RemoteCopyCheckedMsg, ui.RemoteCopyTickMsg, ui.RemoteSpaceMsg:
+		// Commands, followed files, searches, copies and df keep running in
+		// hidden tabs; each browser takes the messages of its own.
+		var cmds []tea.Cmd
+		for i := range m.browsers {
+			browser, cmd := m.browsers[i].Update(msg)
+			m.browsers[i] = browser
+			cmds = append(cmds, cmd)
+		}
+		return m, tea.Batch(cmds...)
+
+	case ui.CdTerminalMsg:
+		term := m.activeTerminal()
+		if term == nil {
+			m.err = "No terminal to change directory in"
+			return m, nil
+		}
+		if err := term.Cd(msg.Dir); err != nil {
+			m.err = "Cannot cd the terminal: " + err.Error()
+			return m, nil
+		}
+		m.err = ""
+		if l := m.activeLayout(); l != nil && l.FocusKind(layout.Terminal) {
+			return m, m.layoutChanged(false)
+		}
+		return m, nil
+
+	case ui.SnippetTerminalMsg:
+		term := m.activeTerminal()
+		if term == nil {
+			m.err = "No terminal to paste the snippet into"
+			return m, nil
+		}
+		// Typed at the prompt as a paste, so the user can check it and
+		// press Enter themselves.
+		if err := term.SendKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(msg.Command), Paste: true}); err != nil {
+			m.err = "Cannot paste the snippet: " + err.Error()
+			return m, nil
+		}
+		m.err = ""
+		if l := m.activeLayout(); l != nil && l.FocusKind(layout.Terminal) {
+			return m, m.layoutChanged(false)
+		}
+		return m, nil
 
 	case ui.PasswordRequestMsg:
 		log.Printf("[AppModel] password requested for %s@%s: %q", msg.Username, msg.Hostname, msg.Prompt)
@@ -197,8 +312,27 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = statePasswordPrompt
 		return m, nil
 
+	case ui.SudoPasswordNeededMsg:
+		if m.state != stateMain || m.activeTab >= len(m.conns) {
+			return m, nil
+		}
+		conn := m.conns[m.activeTab]
+		m.passwordDialog.Show(fmt.Sprintf("[sudo] password for %s@%s:", conn.Username, conn.Host))
+		m.sudoPrompt = true
+		return m, nil
+
 	case ui.PasswordResponseMsg:
 		m.passwordDialog.Hide()
+		if m.sudoPrompt {
+			m.sudoPrompt = false
+			if msg.Cancelled {
+				return m, func() tea.Msg { return ui.SudoDoneMsg{Err: errors.New("sudo password prompt cancelled")} }
+			}
+			if m.activeTab >= len(m.clients) || m.clients[m.activeTab] == nil {
+				return m, nil
+			}
+			return m, ui.SudoLoginCmd(m.clients[m.activeTab], msg.Password, m.cfg.SudoTimeout())
+		}
 		if m.bridge != nil {
 			m.bridge.responseCh <- passwordResponse{
 				Password:  msg.Password,
@@ -274,6 +408,9 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		log.Printf("[AppModel] editor loaded: %s (%d bytes)", msg.Path, len(msg.Content))
 		editor := ui.NewEditorModel(msg.Path, msg.IsRemote, msg.Content)
 		m.editor = &editor
+		if l := m.activeLayout(); l != nil {
+			l.FocusKind(layout.Editor)
+		}
 		return m, nil
 
 	case ui.EditorSaveMsg:
@@ -304,6 +441,202 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+	case ui.HistoryLoadedMsg:
+		if msg.Err != nil {
+			log.Printf("[AppModel] history load error: %v", msg.Err)
+			m.err = "Failed to load transfer history: " + msg.Err.Error()
+			return m, nil
+		}
+		history := ui.NewHistoryModel(msg.Host, msg.Records)
+		m.history = &history
+		return m, nil
+
+	case ui.HistoryCloseMsg:
+		m.history = nil
+		return m, nil
+
+	case ui.HistoryRerunMsg:
+		m.history = nil
+		if m.activeTab < len(m.browsers) {
+			browser, cmd := m.browsers[m.activeTab].RerunTransfer(msg.Record)
+			m.browsers[m.activeTab] = browser
+			return m, cmd
+		}
+		return m, nil
+
+	case ui.PanelViewChangedMsg:
+		// Remember sort and display settings for new tabs and sessions.
+		m.cfg.LocalPanel = msg.Local
+		m.cfg.RemotePanel = msg.Remote
+		if err := config.Save(m.cfg); err != nil {
+			log.Printf("[AppModel] failed to save config: %v", err)
+		}
+		return m, nil
+
+	case ui.BookmarksChangedMsg:
+		if m.activeTab < len(m.conns) {
+			conn := &m.conns[m.activeTab]
+			conn.Bookmarks = msg.Bookmarks
+			saved := m.cfg.Host(*conn)
+			saved.Bookmarks = msg.Bookmarks
+			m.cfg.SetHost(*conn, saved)
+			if err := config.Save(m.cfg); err != nil {
+				log.Printf("[AppModel] failed to save config: %v", err)
+			}
+		}
+		return m, nil
+
+	case ui.RateLimitChangedMsg:
+		if m.activeTab < len(m.conns) {
+			conn := &m.conns[m.activeTab]
+			conn.RateLimitKBps = msg.KBps
+			saved := m.cfg.Host(*conn)
+			saved.RateLimitKBps = msg.KBps
+			m.cfg.SetHost(*conn, saved)
+			if err := config.Save(m.cfg); err != nil {
+				log.Printf("[AppModel] failed to save config: %v", err)
+			}
+		}
+		return m, nil
+
+	case ui.OpenSyncMsg:
+		if m.activeTab < len(m.clients) {
+			sv := ui.NewSyncModel(m.clients[m.activeTab], msg.LocalDir, msg.RemoteDir)
+			m.syncView = &sv
+		}
+		return m, nil
+
+	case ui.SyncScanDoneMsg, ui.SyncApplyDoneMsg:
+		if m.syncView != nil {
+			sv, cmd := m.syncView.Update(msg)
+			m.syncView = &sv
+			return m, cmd
+		}
+		return m, nil
+
+	case ui.SyncCloseMsg:
+		m.syncView = nil
+		if m.activeTab < len(m.browsers) {
+			m.browsers[m.activeTab].RefreshLocal()
+			return m, m.browsers[m.activeTab].RefreshRemoteCmd()
+		}
+		return m, nil
+
+	case ui.RemoteCopyRequestMsg:
+		var targets []ui.CopyTarget
+		for i := range m.tabs {
+			if i == m.activeTab || i >= len(m.clients) || m.clients[i] == nil || i >= len(m.browsers) {
+				continue
+			}
+			targets = append(targets, ui.CopyTarget{Index: i, Title: m.tabs[i].Title, Dir: m.browsers[i].RemoteDir()})
+		}
+		if len(targets) == 0 {
+			m.err = "Open another connection tab to copy files between hosts"
+			return m, nil
+		}
+		m.err = ""
+		picker := ui.NewCopyTargetModel(msg, targets)
+		m.copyTarget = &picker
+		return m, nil
+
+	case ui.CopyTargetCloseMsg:
+		m.copyTarget = nil
+		return m, nil
+
+	case ui.CopyTargetPickedMsg:
+		m.copyTarget = nil
+		idx := msg.Target.Index
+		if idx >= len(m.clients) || m.clients[idx] == nil || m.activeTab >= len(m.browsers) {
+			return m, nil
+		}
+		dest := ui.RemoteCopyDest{
+			Client: m.clients[idx],
+			Label:  m.tabs[idx].Title,
+			Dir:    msg.Target.Dir,
+		}
+		if idx < len(m.conns) {
+			dest.User, dest.Host, dest.Port = m.conns[idx].Username, m.conns[idx].Host, m.conns[idx].Port
+		}
+		browser, cmd := m.browsers[m.activeTab].StartRemoteCopy(msg.Request, dest, msg.Direct)
+		m.browsers[m.activeTab] = browser
+		return m, cmd
+
+	case ui.RemoteCopyDoneMsg:
+		// The user may have switched tabs during the copy: the source tab
+		// takes the result, and hidden tabs refresh their listing when they
+		// are next shown.
+		var cmds []tea.Cmd
+		for i, c := range m.clients {
+			if i >= len(m.browsers) {
+				break
+			}
+			if c == msg.Source {
+				browser, cmd := m.browsers[i].Update(ui.TransferDoneMsg{Err: msg.Err, Detail: msg.Detail})
+				m.browsers[i] = browser
+				if i == m.activeTab {
+					cmds = append(cmds, cmd)
+				} else {
+					m.browsers[i].MarkRemoteStale()
+				}
+			}
+			if c == msg.Dest {
+				if i == m.activeTab {
+					cmds = append(cmds, m.browsers[i].RefreshRemoteCmd())
+				} else {
+					m.browsers[i].MarkRemoteStale()
+				}
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case ui.RecordingsLoadedMsg:
+		if msg.Err != nil {
+			log.Printf("[AppModel] recordings load error: %v", msg.Err)
+			m.err = "Failed to list recordings: " + msg.Err.Error()
+			return m, nil
+		}
+		player := ui.NewPlayerModel(msg.Dir, msg.Files)
+		m.player = &player
+		return m, nil
+
+	case ui.PlayerLoadedMsg, ui.PlayerTickMsg:
+		if m.player != nil {
+			player, cmd := m.player.Update(msg)
+			m.player = &player
+			return m, cmd
+		}
+		return m, nil
+
+	case ui.PlayerCloseMsg:
+		m.player = nil
+		return m, nil
+
+	case ui.FleetMsg:
+		fleet := ui.NewFleetModel(msg.Conns, runFleetCommand(m.sshHosts), m.cfg.FleetParallel())
+		m.fleet = &fleet
+		return m, fleet.Init()
+
+	case ui.FleetResultMsg:
+		if m.fleet != nil {
+			fleet, cmd := m.fleet.Update(msg)
+			m.fleet = &fleet
+			return m, cmd
+		}
+		return m, nil
+
+	case ui.FleetCloseMsg:
+		m.fleet = nil
+		return m, nil
+
+	case ui.BroadcastCloseMsg:
+		m.broadcastView = nil
+		return m, nil
+
+	case ui.BroadcastSetMsg:
+		m.broadcastView = nil
+		m.setBroadcast(msg.Members, msg.Enabled)
+		return m, nil
+
 	case ui.EditorCloseMsg:
 		log.Printf("[AppModel] EditorCloseMsg")
 		m.editor = nil
@@ -314,8 +647,17 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		log.Printf("[AppModel] key: type=%d string=%q runes=%v alt=%v state=%d",
-			msg.Type, msg.String(), msg.Runes, msg.Alt, m.state)
+		// Keys typed into a password dialog are not logged. Keys for the
+		// terminal may be passwords typed at a remote prompt, so only their
+		// type is.
+		switch {
+		case m.passwordDialog.Visible():
+		case m.terminalFocused():
+			log.Printf("[AppModel] terminal key: type=%d", msg.Type)
+		default:
+			log.Printf("[AppModel] key: type=%d string=%q runes=%v alt=%v state=%d",
+				msg.Type, msg.String(), msg.Runes, msg.Alt, m.state)
+		}
 
 		// Password dialog captures all keys when visible.
 		if m.state == statePasswordPrompt {
@@ -328,8 +670,36 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
-		// Editor captures all keys when active (except Ctrl+C).
-		if m.state == stateMain && m.editor != nil {
+		// The fleet view captures all keys while open; Ctrl+C cancels the
+		// command there.
+		if m.state == stateConnection && m.fleet != nil {
+			fleet, cmd := m.fleet.Update(msg)
+			m.fleet = &fleet
+			return m, cmd
+		}
+
+		// The sudo password dialog captures all keys when visible.
+		if m.state == stateMain && m.sudoPrompt {
+			if msg.Type == tea.KeyCtrlC {
+				m.cleanup()
+				return m, tea.Quit
+			}
+			dlg, cmd := m.passwordDialog.Update(msg)
+			m.passwordDialog = dlg
+			return m, cmd
+		}
+
+		// Layout keys move the focus between the panes of the active tab
+		// and change its splits while nothing covers the layout.
+		if m.layoutShown() {
+			if ok, cmd := m.handleLayoutKey(msg.String()); ok {
+				return m, cmd
+			}
+		}
+
+		// Editor captures all keys when active (except Ctrl+C), unless it
+		// sits in a layout pane that does not have the focus.
+		if m.state == stateMain && m.editor != nil && (!m.layoutHas(layout.Editor) || m.focusedKind() == layout.Editor) {
 			if msg.Type == tea.KeyCtrlC {
 				m.cleanup()
 				return m, tea.Quit
@@ -339,6 +709,94 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		// Transfer history captures all keys when open (except Ctrl+C).
+		if m.state == stateMain && m.history != nil {
+			if msg.Type == tea.KeyCtrlC {
+				m.cleanup()
+				return m, tea.Quit
+			}
+			history, cmd := m.history.Update(msg)
+			m.history = &history
+			return m, cmd
+		}
+
+		// Sync dialog captures all keys when open (except Ctrl+C).
+		if m.state == stateMain && m.syncView != nil {
+			if msg.Type == tea.KeyCtrlC {
+				m.cleanup()
+				return m, tea.Quit
+			}
+			sv, cmd := m.syncView.Update(msg)
+			m.syncView = &sv
+			return m, cmd
+		}
+
+		// Copy destination picker captures all keys when open (except Ctrl+C).
+		if m.state == stateMain && m.copyTarget != nil {
+			if msg.Type == tea.KeyCtrlC {
+				m.cleanup()
+				return m, tea.Quit
+			}
+			picker, cmd := m.copyTarget.Update(msg)
+			m.copyTarget = &picker
+			return m, cmd
+		}
+
+		// Broadcast dialog captures all keys when open (except Ctrl+C).
+		if m.state == stateMain && m.broadcastView != nil {
+			if msg.Type == tea.KeyCtrlC {
+				m.cleanup()
+				return m, tea.Quit
+			}
+			bv, cmd := m.broadcastView.Update(msg)
+			m.broadcastView = &bv
+			return m, cmd
+		}
+
+		// Recording player captures all keys when open (except Ctrl+C).
+		if m.state == stateMain && m.player != nil {
+			if msg.Type == tea.KeyCtrlC {
+				m.cleanup()
+				return m, tea.Quit
+			}
+			player, cmd := m.player.Update(msg)
+			m.player = &player
+			return m, cmd
+		}
+
+		// The focused terminal gets every key except the layout keys above,
+		// Ctrl+] (next tab), Alt+Shift+R (record), Alt+Shift+B (broadcast),
+		// Alt+Shift+F (follow the shell), Alt+Shift+I (snippets) and Alt+PgUp
+		// (copy mode). In copy mode the keys drive the copy view instead of
+		// the shell. While broadcasting, keys typed into a member tab go to
+		// every member. A browser dialog covering the view takes the keys
+		// instead.
+		if term := m.activeTerminal(); m.terminalFocused() {
+			switch {
+			case msg.String() == "ctrl+]", msg.String() == "alt+R", msg.String() == "alt+B", msg.String() == "alt+F", msg.String() == "alt+I":
+			case term.CopyMode():
+				return m, term.UpdateCopyMode(msg)
+			case msg.String() == "alt+pgup":
+				term.EnterCopyMode()
+				return m, nil
+			default:
+				if err := term.SendKey(msg); err != nil {
+					log.Printf("[AppModel] terminal write: %v", err)
+				}
+				if m.broadcasting && m.tabs[m.activeTab].Broadcast {
+					for i, t := range m.terminals {
+						if i == m.activeTab || i >= len(m.tabs) || !m.tabs[i].Broadcast {
+							continue
+						}
+						if err := t.SendKey(msg); err != nil {
+							log.Printf("[AppModel] broadcast to %s: %v", m.tabs[i].Title, err)
+						}
+					}
+				}
+				return m, nil
+			}
+		}
+
 		// File browser input dialog captures all keys when active (except Ctrl+C).
 		if m.state == stateMain && m.activeTab < len(m.browsers) && m.browsers[m.activeTab].InputActive() {
 			if msg.Type == tea.KeyCtrlC {
@@ -363,7 +821,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = stateConnection
 				// Accept the host key and tell the waiting goroutine.
 				fp := fingerprintSHA256(pending.hostKey)
-				acceptedHosts[pending.hostname] = fp
+				acceptHostKey(pending.hostname, fp)
 				if m.bridge != nil {
 					m.bridge.approvalCh <- true
 					return m, waitForBridgeMsg(m.bridge)
@@ -386,9 +844,56 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.connModel.Init()
 			}
 
+		case "ctrl+o":
+			if m.state == stateMain && m.activeTab < len(m.conns) {
+				return m, ui.LoadHistoryCmd(config.HostKey(m.conns[m.activeTab]))
+			}
+
+		case "alt+R":
+			if m.activeTerminal() != nil {
+				m.toggleRecording()
+				return m, nil
+			}
+
+		case "alt+F":
+			if m.state == stateMain && m.activeTab < len(m.browsers) {
+				return m, m.browsers[m.activeTab].ToggleFollowShell()
+			}
+
+		case "alt+I":
+			if m.state == stateMain && m.activeTab < len(m.browsers) {
+				m.browsers[m.activeTab].OpenSnippets()
+				return m, nil
+			}
+
+		case "alt+B":
+			if m.state == stateMain && len(m.terminals) > 0 {
+				if len(m.terminals) < 2 {
+					m.err = "Open another connection tab to broadcast to"
+					return m, nil
+				}
+				m.err = ""
+				var tabs []ui.BroadcastTab
+				for i := range m.terminals {
+					member := m.tabs[i].Broadcast || !m.broadcasting && i == m.activeTab
+					tabs = append(tabs, ui.BroadcastTab{Index: i, Title: m.tabs[i].Title, Member: member})
+				}
+				bv := ui.NewBroadcastModel(tabs, m.broadcasting)
+				m.broadcastView = &bv
+				return m, nil
+			}
+
+		case "ctrl+p":
+			if m.state == stateMain {
+				return m, ui.LoadRecordingsCmd(m.cfg.RecordingDirectory())
+			}
+
 		case "ctrl+]":
 			if m.state == stateMain && len(m.tabs) > 1 {
 				m.activeTab = (m.activeTab + 1) % len(m.tabs)
+				if m.activeTab < len(m.browsers) {
+					return m, m.browsers[m.activeTab].RefreshIfStale()
+				}
 				return m, nil
 			}
 
@@ -417,10 +922,14 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if m.state == stateMain && !m.showHelp {
+			// An editor pane with no file open takes no keys.
+			if m.focusedKind() == layout.Editor {
+				return m, nil
+			}
 			if m.activeTab < len(m.browsers) {
 				browser, cmd := m.browsers[m.activeTab].Update(msg)
 				m.browsers[m.activeTab] = browser
-				return m, cmd
+				return m, tea.Batch(cmd, m.followBrowserPanel())
 			}
 		}
 
@@ -458,6 +967,10 @@ func (m AppModel) View() string {
 
 	switch m.state {
 	case stateConnection:
+		if m.fleet != nil {
+			m.fleet.SetDimensions(m.width, m.height)
+			return m.fleet.View()
+		}
 		return m.connModel.View()
 	case stateHostKeyPrompt:
 		return m.renderHostKeyPrompt()
@@ -497,17 +1010,42 @@ func (m AppModel) renderMain() string {
 		return "Initializing..."
 	}
 
+	if m.sudoPrompt {
+		return m.passwordDialog.View(m.width, m.height)
+	}
+
 	tabBar := ui.RenderTabBar(m.tabs, m.activeTab, m.width)
 
 	var body string
-	if m.editor != nil {
+	if m.broadcastView != nil {
+		m.broadcastView.SetDimensions(m.width, m.height-4)
+		body = m.broadcastView.View()
+	} else if m.player != nil {
+		m.player.SetDimensions(m.width, m.height-4)
+		body = m.player.View()
+	} else if m.copyTarget != nil {
+		m.copyTarget.SetDimensions(m.width, m.height-4)
+		body = m.copyTarget.View()
+	} else if m.syncView != nil {
+		m.syncView.SetDimensions(m.width, m.height-4)
+		body = m.syncView.View()
+	} else if m.history != nil {
+		m.history.SetDimensions(m.width, m.height-4)
+		body = m.history.View()
+	} else if m.editor != nil && !m.layoutHas(layout.Editor) {
 		browserHeight := m.height - 4
 		m.editor.SetDimensions(m.width, browserHeight)
 		body = m.editor.View()
 	} else if m.activeTab < len(m.browsers) {
-		browserHeight := m.height - 4 // tab bar + status line
-		m.browsers[m.activeTab].SetDimensions(m.width, browserHeight)
-		body = m.browsers[m.activeTab].View()
+		browser := &m.browsers[m.activeTab]
+		browser.SetDimensions(m.width, m.height-4)
+		if l := m.activeLayout(); l != nil && !browser.OverlayActive() {
+			footer := browser.Footer()
+			body = lipgloss.JoinVertical(lipgloss.Left,
+				m.renderLayout(l, m.height-4-lipgloss.Height(footer)), footer)
+		} else {
+			body = browser.View()
+		}
 	}
 
 	var errLine string
@@ -515,14 +1053,365 @@ func (m AppModel) renderMain() string {
 		errLine = "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(m.err)
 	}
 
+	hints := " ^]: next tab • ^N: new tab • ^W: close tab • ^O: history • ^P: recordings • ?: help • ^C: quit"
+	if l := m.activeLayout(); l != nil {
+		if l.FocusedKind() == layout.Terminal {
+			hints = " F12: next pane • ^]: next tab • Alt+PgUp: copy mode • Alt+Shift+R: record"
+		} else {
+			hints += " • F12: next pane"
+		}
+	}
+	if m.broadcasting {
+		n := 0
+		for _, t := range m.tabs {
+			if t.Broadcast {
+				n++
+			}
+		}
+		hints = fmt.Sprintf(" BROADCAST to %d tabs • Alt+Shift+B: change •", n) + hints
+	}
 	statusLine := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#555555")).
-		Render(" ^]: next tab • ^N: new tab • ^W: close tab • ?: help • ^C: quit" + errLine + "\n")
+		Render(hints + errLine + "\n")
 
 	return lipgloss.JoinVertical(lipgloss.Left, statusLine, tabBar, body)
 }
 
+// activeTerminal returns the active tab's terminal, or nil.
+func (m AppModel) activeTerminal() *ui.TerminalModel {
+	if m.state != stateMain || m.activeTab >= len(m.terminals) {
+		return nil
+	}
+	return m.terminals[m.activeTab]
+}
+
+// terminalFocused reports whether keys go to the active tab's terminal.
+func (m AppModel) terminalFocused() bool {
+	return m.activeTerminal() != nil && m.focusedKind() == layout.Terminal && !m.showHelp && !m.browserOverlay()
+}
+
+// browserOverlay reports whether a dialog of the active tab's browser
+// covers the view.
+func (m AppModel) browserOverlay() bool {
+	return m.state == stateMain && m.activeTab < len(m.browsers) && m.browsers[m.activeTab].OverlayActive()
+}
+
+// followShellDir passes a working directory reported by term's shell to
+// the tab's browser. A hidden tab's remote panel is listed when the tab is
+// shown again.
+func (m AppModel) followShellDir(term *ui.TerminalModel, dir string) tea.Cmd {
+	for i, t := range m.terminals {
+		if t != term || i >= len(m.browsers) {
+			continue
+		}
+		cmd := m.browsers[i].FollowShellDir(dir)
+		if i == m.activeTab {
+			return cmd
+		}
+		if cmd != nil {
+			m.browsers[i].MarkRemoteStale()
+		}
+		return nil
+	}
+	return nil
+}
+
+// activeLayout returns the active tab's pane layout, or nil.
+func (m AppModel) activeLayout() *layout.Layout {
+	if m.state != stateMain || m.activeTab >= len(m.layouts) {
+		return nil
+	}
+	return m.layouts[m.activeTab]
+}
+
+// layoutHas reports whether a pane of the active tab shows k.
+func (m AppModel) layoutHas(k layout.Kind) bool {
+	l := m.activeLayout()
+	return l != nil && l.Has(k)
+}
+
+// focusedKind returns what the focused pane of the active tab shows.
+// Without a layout the file browser has the keys.
+func (m AppModel) focusedKind() layout.Kind {
+	if l := m.activeLayout(); l != nil {
+		return l.FocusedKind()
+	}
+	return layout.Local
+}
+
+// layoutHeight is the height of the pane layout: the window below the
+// status line and tab bar, less the file browser's footer line.
+func (m AppModel) layoutHeight() int {
+	return m.height - 5
+}
+
+// layoutShown reports whether the active tab's layout is on screen, with
+// no dialog or full-screen view covering it.
+func (m AppModel) layoutShown() bool {
+	if m.activeLayout() == nil || m.showHelp || m.sudoPrompt || m.history != nil || m.syncView != nil ||
+		m.copyTarget != nil || m.broadcastView != nil || m.player != nil {
+		return false
+	}
+	if m.editor != nil && !m.layoutHas(layout.Editor) {
+		return false
+	}
+	return m.activeTab >= len(m.browsers) || !m.browsers[m.activeTab].InputActive()
+}
+
+// renderLayout draws the panes of layout l in the full width and the
+// given height.
+func (m AppModel) renderLayout(l *layout.Layout, height int) string {
+	browser := &m.browsers[m.activeTab]
+	rects := l.Rects(m.width, height)
+	var local, remote int
+	if p := l.Find(layout.Local); p != nil {
+		local = rects[p].H
+	}
+	if p := l.Find(layout.Remote); p != nil {
+		remote = rects[p].H
+	}
+	browser.SetPaneHeights(local, remote)
+
+	focused := l.Focused()
+	pane := func(n *layout.Node, w, h int) string {
+		active := n == focused
+		var view string
+		switch n.Kind {
+		case layout.Terminal:
+			if term := m.activeTerminal(); term != nil {
+				view = term.RenderTerminal(active, w, h)
+			} else {
+				view = ui.RenderEmptyPane("Terminal", "No shell session", w, h, active)
+			}
+		case layout.Editor:
+			if m.editor != nil {
+				m.editor.SetDimensions(w, h)
+				view = m.editor.View()
+			} else {
+				view = ui.RenderEmptyPane("Editor", "Press Enter on a file to edit it here", w, h, active)
+			}
+		default:
+			view = browser.RenderPane(n.Kind, w, h, active)
+		}
+		// Clip or pad every pane to its exact size so the splits line up.
+		return lipgloss.NewStyle().Width(w).Height(h).MaxWidth(w).MaxHeight(h).Render(view)
+	}
+	join := func(s layout.Split, first, second string) string {
+		if s == layout.Horizontal {
+			return lipgloss.JoinHorizontal(lipgloss.Top, first, second)
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, first, second)
+	}
+	return l.Render(m.width, height, pane, join)
+}
+
+// layoutDirections maps the arrow in a layout key to a direction.
+var layoutDirections = map[string]layout.Direction{
+	"left":  layout.Left,
+	"right": layout.Right,
+	"up":    layout.Up,
+	"down":  layout.Down,
+}
+
+// handleLayoutKey applies a layout key to the active tab's layout and
+// reports whether key was one. F12 and Alt+Shift+arrows move the focus,
+// Alt+Ctrl+arrows move a divider, Alt+Shift+V and Alt+Shift+S split the
+// focused pane side by side or stacked, Alt+Shift+X closes it and
+// Alt+Shift+N changes what it shows. Alt+V in a file pane shows or hides a
+// preview pane.
+func (m *AppModel) handleLayoutKey(key string) (bool, tea.Cmd) {
+	l := m.activeLayout()
+	save := true
+	switch key {
+	case "f12":
+		l.FocusNext()
+		save = false
+	case "alt+V":
+		l.SplitFocused(layout.Horizontal, l.Unshown())
+	case "alt+S":
+		l.SplitFocused(layout.Vertical, l.Unshown())
+	case "alt+X":
+		if !l.CloseFocused() {
+			m.err = "The last pane cannot be closed"
+			return true, nil
+		}
+	case "alt+N":
+		l.CycleFocused()
+	case "alt+v":
+		switch l.FocusedKind() {
+		case layout.Local, layout.Remote, layout.Preview:
+		default:
+			return false, nil
+		}
+		if p := l.Find(layout.Preview); p != nil {
+			l.Close(p)
+		} else {
+			pane := l.Focused()
+			l.SplitFocused(layout.Horizontal, layout.Preview)
+			l.Focus(pane)
+		}
+	default:
+		if arrow, ok := strings.CutPrefix(key, "alt+shift+"); ok {
+			if d, ok := layoutDirections[arrow]; ok {
+				l.FocusDir(d, m.width, m.layoutHeight())
+				save = false
+				break
+			}
+		}
+		if arrow, ok := strings.CutPrefix(key, "alt+ctrl+"); ok {
+			if d, ok := layoutDirections[arrow]; ok {
+				if !l.Resize(d) {
+					return true, nil
+				}
+				break
+			}
+		}
+		return false, nil
+	}
+	m.err = ""
+	return true, m.layoutChanged(save)
+}
+
+// layoutChanged applies the active tab's layout after a layout key: the
+// preview loads while a pane shows it, the file browser follows the
+// focused panel and the terminal fits its pane. With save set the layout
+// is stored with the host.
+func (m *AppModel) layoutChanged(save bool) tea.Cmd {
+	l := m.activeLayout()
+	var cmds []tea.Cmd
+	if m.activeTab < len(m.browsers) {
+		browser := &m.browsers[m.activeTab]
+		cmds = append(cmds, browser.SetPreviewPane(l.Has(layout.Preview)), browser.FocusPanel(l.FocusedKind()))
+	}
+	m.resizeTerminals()
+	if save {
+		m.saveLayout()
+	}
+	return tea.Batch(cmds...)
+}
+
+// followBrowserPanel moves the layout focus to the file panel the browser
+// switched to, or switches the browser back when no pane shows that panel.
+func (m *AppModel) followBrowserPanel() tea.Cmd {
+	l := m.activeLayout()
+	if l == nil {
+		return nil
+	}
+	k := l.FocusedKind()
+	if k != layout.Local && k != layout.Remote {
+		return nil
+	}
+	panel := m.browsers[m.activeTab].FocusedPanel()
+	if panel == k || l.FocusKind(panel) {
+		return nil
+	}
+	return m.browsers[m.activeTab].FocusPanel(k)
+}
+
+// saveLayout stores the active tab's layout with its host, so the next
+// connection to the host starts with it.
+func (m *AppModel) saveLayout() {
+	if m.activeTab >= len(m.conns) {
+		return
+	}
+	root := m.layouts[m.activeTab].Root()
+	conn := &m.conns[m.activeTab]
+	conn.Layout = root
+	saved := m.cfg.Host(*conn)
+	saved.Layout = root
+	m.cfg.SetHost(*conn, saved)
+	if err := config.Save(m.cfg); err != nil {
+		log.Printf("[AppModel] failed to save config: %v", err)
+	}
+}
+
+// resizeTerminals fits every tab's terminal to its first terminal pane.
+func (m AppModel) resizeTerminals() {
+	height := m.layoutHeight()
+	if m.width == 0 || height <= 0 {
+		return
+	}
+	for i, t := range m.terminals {
+		if i >= len(m.layouts) {
+			continue
+		}
+		l := m.layouts[i]
+		if p := l.Find(layout.Terminal); p != nil {
+			r := l.Rects(m.width, height)[p]
+			t.SetDimensions(r.W, r.H)
+		}
+	}
+}
+
+// toggleRecording starts or stops recording the active tab's terminal.
+func (m *AppModel) toggleRecording() {
+	term := m.activeTerminal()
+	if term == nil {
+		return
+	}
+	if term.Recording() != "" {
+		path, err := term.StopRecording()
+		if err != nil {
+			log.Printf("[AppModel] stop recording: %v", err)
+			m.err = "Recording failed: " + err.Error()
+		} else {
+			log.Printf("[AppModel] saved recording %s", path)
+		}
+		m.tabs[m.activeTab].Recording = false
+		return
+	}
+	var hostKey string
+	if m.activeTab < len(m.conns) {
+		hostKey = config.HostKey(m.conns[m.activeTab])
+	}
+	path := m.cfg.RecordingPath(hostKey, time.Now())
+	if err := term.StartRecording(path, m.tabs[m.activeTab].Title, m.cfg.RecordInput); err != nil {
+		log.Printf("[AppModel] start recording: %v", err)
+		m.err = "Failed to start recording: " + err.Error()
+		return
+	}
+	m.err = ""
+	m.tabs[m.activeTab].Recording = true
+}
+
+// setBroadcast turns broadcasting on for the given tab indexes, or off.
+func (m *AppModel) setBroadcast(members []int, enabled bool) {
+	for i := range m.tabs {
+		m.tabs[i].Broadcast = false
+	}
+	if enabled {
+		for _, i := range members {
+			if i < len(m.tabs) {
+				m.tabs[i].Broadcast = true
+			}
+		}
+	}
+	m.broadcasting = enabled
+	for i, t := range m.terminals {
+		t.SetBroadcast(i < len(m.tabs) && m.tabs[i].Broadcast)
+	}
+	log.Printf("[AppModel] broadcast enabled=%v members=%v", enabled, members)
+}
+
+// startTerminalCmd opens the shell session of a new tab.
+func startTerminalCmd(term *ui.TerminalModel) tea.Cmd {
+	return func() tea.Msg {
+		return terminalStartedMsg{term: term, err: term.StartSession()}
+	}
+}
+
 func (m *AppModel) cleanup() {
+	if m.fleet != nil {
+		m.fleet.Stop()
+	}
+	for i := range m.browsers {
+		m.browsers[i].Close()
+	}
+	for _, t := range m.terminals {
+		if err := t.Close(); err != nil {
+			log.Printf("close terminal: %v", err)
+		}
+	}
 	for _, c := range m.clients {
 		if c != nil {
 			if err := c.Close(); err != nil {
@@ -533,6 +1422,15 @@ func (m *AppModel) cleanup() {
 }
 
 func (m *AppModel) closeTab(idx int) {
+	if idx < len(m.browsers) {
+		m.browsers[idx].Close()
+	}
+	if idx < len(m.terminals) {
+		if err := m.terminals[idx].Close(); err != nil {
+			log.Printf("close tab terminal: %v", err)
+		}
+		m.terminals = append(m.terminals[:idx], m.terminals[idx+1:]...)
+	}
 	if idx < len(m.clients) && m.clients[idx] != nil {
 		if err := m.clients[idx].Close(); err != nil {
 			log.Printf("close tab client: %v", err)
@@ -545,9 +1443,26 @@ func (m *AppModel) closeTab(idx int) {
 	if idx < len(m.browsers) {
 		m.browsers = append(m.browsers[:idx], m.browsers[idx+1:]...)
 	}
+	if idx < len(m.conns) {
+		m.conns = append(m.conns[:idx], m.conns[idx+1:]...)
+	}
+	if idx < len(m.layouts) {
+		m.layouts = append(m.layouts[:idx], m.layouts[idx+1:]...)
+	}
 	if m.activeTab >= len(m.tabs) && m.activeTab > 0 {
 		m.activeTab = len(m.tabs) - 1
 	}
+	if m.broadcasting {
+		var members []int
+		for i, t := range m.tabs {
+			if t.Broadcast {
+				members = append(members, i)
+			}
+		}
+		if len(members) < 2 {
+			m.setBroadcast(nil, false)
+		}
+	}
 }
 
 // waitForBridgeMsg returns a tea.Cmd that blocks until the connection
@@ -559,12 +1474,10 @@ func waitForBridgeMsg(bridge *passwordBridge) tea.Cmd {
 	}
 }
 
-// buildInteractiveAuthMethods assembles SSH auth methods that use the bridge
-// for any interactive challenges (password, keyboard-interactive).
-// Key-based and agent-based auth are tried first without user interaction.
-func buildInteractiveAuthMethods(conn config.Connection, bridge *passwordBridge, displayHost string) []ssh.AuthMethod {
+// keyAuthMethods assembles the SSH auth methods that need no user
+// interaction: the connection's key file, the agent and the default keys.
+func keyAuthMethods(conn config.Connection) []ssh.AuthMethod {
 	var methods []ssh.AuthMethod
-	username := conn.Username
 
 	// 1. Explicit key file.
 	if conn.KeyPath != "" {
@@ -588,6 +1501,15 @@ func buildInteractiveAuthMethods(conn config.Connection, bridge *passwordBridge,
 			methods = append(methods, am)
 		}
 	}
+	return methods
+}
+
+// buildInteractiveAuthMethods assembles SSH auth methods that use the bridge
+// for any interactive challenges (password, keyboard-interactive).
+// Key-based and agent-based auth are tried first without user interaction.
+func buildInteractiveAuthMethods(conn config.Connection, bridge *passwordBridge, displayHost string) []ssh.AuthMethod {
+	methods := keyAuthMethods(conn)
+	username := conn.Username
 
 	// 4. Password callback — prompts the user interactively via the bridge.
 	methods = append(methods, sshclient.PasswordCallbackAuth(func() (string, error) {
@@ -639,14 +1561,14 @@ func buildInteractiveAuthMethods(conn config.Connection, bridge *passwordBridge,
 func makeInteractiveHKCallback(bridge *passwordBridge, conn config.Connection) ssh.HostKeyCallback {
 	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
 		fp := fingerprintSHA256(key)
-		if acceptedHosts[hostname] == fp {
+		if hostKeyAccepted(hostname, fp) {
 			return nil
 		}
 
 		// Check StrictHostKeyChecking=no (already handled in ConnectOptions,
 		// but the callback still runs before opts are applied).
 		if strings.EqualFold(conn.StrictHostKeyChecking, "no") {
-			acceptedHosts[hostname] = fp
+			acceptHostKey(hostname, fp)
 			return nil
 		}
 
@@ -664,6 +1586,24 @@ func makeInteractiveHKCallback(bridge *passwordBridge, conn config.Connection) s
 	}
 }
 
+// makeBatchHKCallback returns an ssh.HostKeyCallback for connections
+// nobody is there to answer prompts for. It accepts host keys accepted
+// earlier in the session, or any key with StrictHostKeyChecking=no, and
+// rejects the rest.
+func makeBatchHKCallback(conn config.Connection) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fp := fingerprintSHA256(key)
+		if hostKeyAccepted(hostname, fp) {
+			return nil
+		}
+		if strings.EqualFold(conn.StrictHostKeyChecking, "no") {
+			acceptHostKey(hostname, fp)
+			return nil
+		}
+		return fmt.Errorf("unknown host key %s; connect to the host once to accept it", fp)
+	}
+}
+
 // makeConnectOptions builds SSH connect options from a connection's config fields.
 func makeConnectOptions(conn config.Connection) *sshclient.ConnectOptions {
 	opts := &sshclient.ConnectOptions{
@@ -671,6 +1611,8 @@ func makeConnectOptions(conn config.Connection) *sshclient.ConnectOptions {
 		PubkeyAcceptedTypes:   conn.PubkeyAcceptedTypes,
 		StrictHostKeyChecking: conn.StrictHostKeyChecking,
 		UserKnownHostsFile:    conn.UserKnownHostsFile,
+		SendEnv:               conn.SendEnv,
+		SetEnv:                conn.SetEnv,
 	}
 	log.Printf("[connectOpts] HostKeyAlgorithms=%q StrictHostKeyChecking=%q PubkeyAcceptedTypes=%q",
 		opts.HostKeyAlgorithms, opts.StrictHostKeyChecking, opts.PubkeyAcceptedTypes)
@@ -726,17 +1668,50 @@ func parseJumpSpec(spec string, sshHosts []config.SSHHost) config.Connection {
 	}
 }
 
-// connectWorker runs in a background goroutine and performs the full SSH
-// connection (optionally through a jump host), using the bridge for any
-// interactive prompts. The final result (success or error) is sent on
-// bridge.msgCh as a connectedMsg.
-func connectWorker(conn config.Connection, bridge *passwordBridge, sshHosts []config.SSHHost) {
-	destAuth := buildInteractiveAuthMethods(conn, bridge, conn.Host)
-	destHKCb := makeInteractiveHKCallback(bridge, conn)
+// mergeSSHConfig fills the options conn leaves unset from the SSH config
+// entry matching its host.
+func mergeSSHConfig(conn config.Connection, sshHosts []config.SSHHost) config.Connection {
+	match := config.MatchSSHHost(sshHosts, conn.Host)
+	if match == nil {
+		return conn
+	}
+	log.Printf("[AppModel] matched SSH config host %q for %s", match.Alias, conn.Host)
+	if conn.HostKeyAlgorithms == "" {
+		conn.HostKeyAlgorithms = match.HostKeyAlgorithms
+	}
+	if conn.PubkeyAcceptedTypes == "" {
+		conn.PubkeyAcceptedTypes = match.PubkeyAcceptedTypes
+	}
+	if conn.StrictHostKeyChecking == "" {
+		conn.StrictHostKeyChecking = match.StrictHostKeyChecking
+	}
+	if conn.UserKnownHostsFile == "" {
+		conn.UserKnownHostsFile = match.UserKnownHostsFile
+	}
+	if conn.KeyPath == "" && match.IdentityFile != "" {
+		conn.KeyPath = match.IdentityFile
+	}
+	if conn.ProxyJump == "" && match.ProxyJump != "" {
+		conn.ProxyJump = match.ProxyJump
+	}
+	if conn.SendEnv == nil && conn.SetEnv == nil {
+		conn.SendEnv, conn.SetEnv = match.SendEnv, match.SetEnv
+	}
+	return conn
+}
+
+// hopAuth returns the auth methods and host key callback used to log in to
+// one host of a connection: the destination or its jump host.
+type hopAuth func(conn config.Connection) ([]ssh.AuthMethod, ssh.HostKeyCallback)
+
+// dial performs the full SSH connection to conn, optionally through a jump
+// host, authenticating each hop with auth.
+func dial(conn config.Connection, sshHosts []config.SSHHost, auth hopAuth) (*sshclient.Client, error) {
+	destAuth, destHKCb := auth(conn)
 	destOpts := makeConnectOptions(conn)
 
 	if conn.ProxyJump != "" {
-		log.Printf("[connectWorker] using ProxyJump %q for %s", conn.ProxyJump, conn.Host)
+		log.Printf("[dial] using ProxyJump %q for %s", conn.ProxyJump, conn.Host)
 
 		jumpConn := parseJumpSpec(conn.ProxyJump, sshHosts)
 		// Default jump username to destination username if not specified.
@@ -744,55 +1719,68 @@ func connectWorker(conn config.Connection, bridge *passwordBridge, sshHosts []co
 			jumpConn.Username = conn.Username
 		}
 
-		// Merge SSH config options for the jump host.
-		if match := config.MatchSSHHost(sshHosts, jumpConn.Host); match != nil {
-			if jumpConn.HostKeyAlgorithms == "" {
-				jumpConn.HostKeyAlgorithms = match.HostKeyAlgorithms
-			}
-			if jumpConn.PubkeyAcceptedTypes == "" {
-				jumpConn.PubkeyAcceptedTypes = match.PubkeyAcceptedTypes
-			}
-			if jumpConn.StrictHostKeyChecking == "" {
-				jumpConn.StrictHostKeyChecking = match.StrictHostKeyChecking
-			}
-			if jumpConn.UserKnownHostsFile == "" {
-				jumpConn.UserKnownHostsFile = match.UserKnownHostsFile
-			}
-			if jumpConn.KeyPath == "" && match.IdentityFile != "" {
-				jumpConn.KeyPath = match.IdentityFile
-			}
-		}
+		// Merge SSH config options for the jump host. Nested jumps are not
+		// followed.
+		jumpConn = mergeSSHConfig(jumpConn, sshHosts)
 
-		jumpAuth := buildInteractiveAuthMethods(jumpConn, bridge, jumpConn.Host)
-		jumpHKCb := makeInteractiveHKCallback(bridge, jumpConn)
+		jumpAuth, jumpHKCb := auth(jumpConn)
 		jumpOpts := makeConnectOptions(jumpConn)
 
-		log.Printf("[connectWorker] connecting to jump host %s@%s:%s", jumpConn.Username, jumpConn.Host, jumpConn.Port)
+		log.Printf("[dial] connecting to jump host %s@%s:%s", jumpConn.Username, jumpConn.Host, jumpConn.Port)
 		jumpClient, err := sshclient.New(jumpConn.Host, jumpConn.Port, jumpConn.Username, jumpAuth, jumpHKCb, jumpOpts)
 		if err != nil {
-			bridge.msgCh <- connectedMsg{err: fmt.Errorf("jump host %s: %w", jumpConn.Host, err), conn: conn}
-			return
+			return nil, fmt.Errorf("jump host %s: %w", jumpConn.Host, err)
 		}
 
-		log.Printf("[connectWorker] dialling %s@%s:%s via jump host", conn.Username, conn.Host, conn.Port)
+		log.Printf("[dial] dialling %s@%s:%s via jump host", conn.Username, conn.Host, conn.Port)
 		client, err := sshclient.NewViaJump(jumpClient.SSHClient(), conn.Host, conn.Port, conn.Username, destAuth, destHKCb, destOpts)
 		if err != nil {
 			_ = jumpClient.Close()
-			bridge.msgCh <- connectedMsg{err: fmt.Errorf("destination via jump: %w", err), conn: conn}
-			return
+			return nil, fmt.Errorf("destination via jump: %w", err)
 		}
-		bridge.msgCh <- connectedMsg{client: client, conn: conn}
-		return
+		return client, nil
 	}
 
 	// Direct connection (no jump host).
-	log.Printf("[connectWorker] direct connection to %s@%s:%s", conn.Username, conn.Host, conn.Port)
-	client, err := sshclient.New(conn.Host, conn.Port, conn.Username, destAuth, destHKCb, destOpts)
-	if err != nil {
-		bridge.msgCh <- connectedMsg{err: err, conn: conn}
-		return
+	log.Printf("[dial] direct connection to %s@%s:%s", conn.Username, conn.Host, conn.Port)
+	return sshclient.New(conn.Host, conn.Port, conn.Username, destAuth, destHKCb, destOpts)
+}
+
+// connectWorker runs in a background goroutine and performs the full SSH
+// connection (optionally through a jump host), using the bridge for any
+// interactive prompts. The final result (success or error) is sent on
+// bridge.msgCh as a connectedMsg.
+func connectWorker(conn config.Connection, bridge *passwordBridge, sshHosts []config.SSHHost) {
+	client, err := dial(conn, sshHosts, func(c config.Connection) ([]ssh.AuthMethod, ssh.HostKeyCallback) {
+		return buildInteractiveAuthMethods(c, bridge, c.Host), makeInteractiveHKCallback(bridge, c)
+	})
+	bridge.msgCh <- connectedMsg{client: client, conn: conn, err: err}
+}
+
+// runFleetCommand connects to conn without any prompts, runs cmd in the
+// login directory and disconnects. Only keys and the agent are used to log
+// in, and host keys must have been accepted earlier in the session.
+func runFleetCommand(sshHosts []config.SSHHost) ui.FleetRunFunc {
+	return func(ctx context.Context, conn config.Connection, cmd string, line func(string, bool)) (int, error) {
+		conn = mergeSSHConfig(conn, sshHosts)
+		if conn.Username == "" {
+			return -1, errors.New("no username for this host")
+		}
+		if conn.Port == "" {
+			conn.Port = "22"
+		}
+		client, err := dial(conn, sshHosts, func(c config.Connection) ([]ssh.AuthMethod, ssh.HostKeyCallback) {
+			return keyAuthMethods(c), makeBatchHKCallback(c)
+		})
+		if err != nil {
+			return -1, err
+		}
+		defer func() { _ = client.Close() }()
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+		return client.Exec(ctx, "", cmd, line)
 	}
-	bridge.msgCh <- connectedMsg{client: client, conn: conn}
 }
 
 // fingerprintSHA256 computes the SHA256 fingerprint of a host key,
@@ -844,12 +1832,12 @@ func main() {
 	log.Printf("=== ssh-scp starting (log: %s) ===", logPath())
 
 	model := initialModel()
-	p := tea.NewProgram(
+	prog = tea.NewProgram(
 		model,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
-	if _, err := p.Run(); err != nil {
+	if _, err := prog.Run(); err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	"fmt"
+	"io"
+	"log"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"ssh-scp/internal/config"
+	"ssh-scp/internal/layout"
 	sshclient "ssh-scp/internal/ssh"
 	"ssh-scp/internal/ui"
 
@@ -729,6 +735,36 @@ func TestPasswordDialogRoundTrip(t *testing.T) {
 	}
 }
 
+// captureLog sends log output to a buffer until t ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(prev) })
+	return &buf
+}
+
+func TestPasswordKeysNotLogged(t *testing.T) {
+	m := initialModel()
+	m.state = stateMain
+	m.conns = []config.Connection{{Host: "h", Port: "22", Username: "u"}}
+	result, _ := m.Update(ui.SudoPasswordNeededMsg{})
+	m = result.(AppModel)
+	if !m.sudoPrompt {
+		t.Fatal("sudo prompt should be open")
+	}
+
+	buf := captureLog(t)
+	for _, r := range "hunter2" {
+		result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = result.(AppModel)
+	}
+	if strings.Contains(buf.String(), "key:") {
+		t.Errorf("password keys were logged:\n%s", buf)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // View dispatches to correct screen
 // ---------------------------------------------------------------------------
@@ -1183,6 +1219,10 @@ func TestMakeConnectOptions(t *testing.T) {
 	if opts.UserKnownHostsFile != "/dev/null" {
 		t.Errorf("UserKnownHostsFile = %q", opts.UserKnownHostsFile)
 	}
+	opts = makeConnectOptions(config.Connection{SendEnv: []string{"EDITOR"}, SetEnv: []string{"TZ=UTC"}})
+	if len(opts.SendEnv) != 1 || len(opts.SetEnv) != 1 {
+		t.Errorf("SendEnv = %q, SetEnv = %q, want the connection's", opts.SendEnv, opts.SetEnv)
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -1719,3 +1759,854 @@ func TestRenderMainEditorTakesPrecedence(t *testing.T) {
 		t.Error("editor view should be shown instead of browser")
 	}
 }
+
+// ---------------------------------------------------------------------------
+// AppModel - transfer history
+// ---------------------------------------------------------------------------
+
+func TestAppModelCtrlOLoadsHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := initialModel()
+	m.state = stateMain
+	m.tabs = []ui.Tab{{Title: "test"}}
+	m.conns = []config.Connection{{Host: "h", Port: "22", Username: "u"}}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if cmd == nil {
+		t.Fatal("Ctrl+O should return a history load command")
+	}
+	loaded, ok := cmd().(ui.HistoryLoadedMsg)
+	if !ok || loaded.Host != "u@h:22" {
+		t.Fatalf("expected HistoryLoadedMsg for u@h:22, got %#v", loaded)
+	}
+	result, _ := m.Update(loaded)
+	am := result.(AppModel)
+	if am.history == nil {
+		t.Fatal("history view should be open after HistoryLoadedMsg")
+	}
+
+	// Keys go to the history view; Esc closes it.
+	_, cmd = am.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("Esc in history should return a close command")
+	}
+	result, _ = am.Update(cmd())
+	if result.(AppModel).history != nil {
+		t.Error("history should be closed after HistoryCloseMsg")
+	}
+}
+
+func TestAppModelHistoryLoadError(t *testing.T) {
+	m := initialModel()
+	m.state = stateMain
+	result, _ := m.Update(ui.HistoryLoadedMsg{Err: fmt.Errorf("bad file")})
+	am := result.(AppModel)
+	if am.history != nil {
+		t.Error("history should not open on load error")
+	}
+	if !strings.Contains(am.err, "transfer history") {
+		t.Errorf("err = %q", am.err)
+	}
+}
+
+func TestAppModelHistoryRerun(t *testing.T) {
+	dir := t.TempDir()
+	m := initialModel()
+	m.state = stateMain
+	m.tabs = []ui.Tab{{Title: "test"}}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, dir, "/remote")}
+	history := ui.NewHistoryModel("u@h:22", nil)
+	m.history = &history
+
+	rec := config.TransferRecord{Upload: true, Source: dir + "/a.txt", Destination: "/remote/a.txt"}
+	result, cmd := m.Update(ui.HistoryRerunMsg{Record: rec})
+	am := result.(AppModel)
+	if am.history != nil {
+		t.Error("history should close when re-running a transfer")
+	}
+	if cmd == nil {
+		t.Error("re-run should start a transfer command")
+	}
+}
+
+func TestRenderMainWithHistory(t *testing.T) {
+	m := initialModel()
+	m.state = stateMain
+	m.width = 100
+	m.height = 30
+	m.tabs = []ui.Tab{{Title: "test"}}
+	history := ui.NewHistoryModel("u@h:22", nil)
+	m.history = &history
+	if view := m.renderMain(); !strings.Contains(view, "Transfer history") {
+		t.Error("renderMain should show the history view when open")
+	}
+}
+
+func TestCloseTabRemovesConn(t *testing.T) {
+	m := initialModel()
+	m.tabs = []ui.Tab{{Title: "a"}, {Title: "b"}}
+	m.clients = []*sshclient.Client{nil, nil}
+	m.conns = []config.Connection{{Host: "a"}, {Host: "b"}}
+	m.closeTab(0)
+	if len(m.conns) != 1 || m.conns[0].Host != "b" {
+		t.Errorf("conns after close = %+v", m.conns)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// AppModel - directory sync
+// ---------------------------------------------------------------------------
+
+func TestAppModelOpenSync(t *testing.T) {
+	m := initialModel()
+	m.state = stateMain
+	m.width = 100
+	m.height = 30
+	m.tabs = []ui.Tab{{Title: "test"}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/remote")}
+
+	result, _ := m.Update(ui.OpenSyncMsg{LocalDir: "/l", RemoteDir: "/r"})
+	am := result.(AppModel)
+	if am.syncView == nil {
+		t.Fatal("OpenSyncMsg should open the sync dialog")
+	}
+	if view := am.renderMain(); !strings.Contains(view, "Sync") {
+		t.Error("renderMain should show the sync dialog when open")
+	}
+
+	// Keys go to the sync dialog; Esc on the options screen closes it.
+	_, cmd := am.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("Esc in the sync dialog should return a close command")
+	}
+	result, _ = am.Update(cmd())
+	if result.(AppModel).syncView != nil {
+		t.Error("sync dialog should be closed after SyncCloseMsg")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// AppModel - cross-tab copy
+// ---------------------------------------------------------------------------
+
+func TestAppModelRemoteCopyNeedsSecondTab(t *testing.T) {
+	m := initialModel()
+	m.state = stateMain
+	m.tabs = []ui.Tab{{Title: "a"}}
+	m.clients = []*sshclient.Client{nil}
+	result, _ := m.Update(ui.RemoteCopyRequestMsg{Path: "/x"})
+	am := result.(AppModel)
+	if am.copyTarget != nil {
+		t.Error("picker should not open without another tab")
+	}
+	if !strings.Contains(am.err, "another connection tab") {
+		t.Errorf("err = %q", am.err)
+	}
+}
+
+func TestAppModelRemoteCopyFlow(t *testing.T) {
+	src, dst := &sshclient.Client{}, &sshclient.Client{}
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 100, 30
+	m.tabs = []ui.Tab{{Title: "staging"}, {Title: "prod"}}
+	m.clients = []*sshclient.Client{src, dst}
+	m.conns = []config.Connection{{Host: "s"}, {Host: "p", Port: "22", Username: "u"}}
+	m.browsers = []ui.FileBrowserModel{
+		ui.NewFileBrowserModel(src, t.TempDir(), "/srv"),
+		ui.NewFileBrowserModel(dst, t.TempDir(), "/opt"),
+	}
+
+	result, _ := m.Update(ui.RemoteCopyRequestMsg{Path: "/srv/app.tar.gz", Size: 10})
+	am := result.(AppModel)
+	if am.copyTarget == nil {
+		t.Fatal("picker should open")
+	}
+	if !strings.Contains(am.renderMain(), "prod") {
+		t.Error("picker should list the other tab")
+	}
+
+	_, cmd := am.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter should pick the target")
+	}
+	result, cmd = am.Update(cmd())
+	am = result.(AppModel)
+	if am.copyTarget != nil || cmd == nil {
+		t.Fatal("picking should close the picker and start the copy")
+	}
+
+	result, _ = am.Update(ui.RemoteCopyDoneMsg{Source: src, Dest: dst, Detail: "to prod"})
+	am = result.(AppModel)
+	if cmd := am.browsers[1].RefreshIfStale(); cmd == nil {
+		t.Error("destination tab should be marked stale")
+	}
+}
+
+func TestAppModelRemoteCopyDoneInHiddenTab(t *testing.T) {
+	src, dst := &sshclient.Client{}, &sshclient.Client{}
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 100, 30
+	m.tabs = []ui.Tab{{Title: "staging"}, {Title: "prod"}}
+	m.clients = []*sshclient.Client{src, dst}
+	m.conns = []config.Connection{{Host: "s"}, {Host: "p"}}
+	m.browsers = []ui.FileBrowserModel{
+		ui.NewFileBrowserModel(src, t.TempDir(), "/srv"),
+		ui.NewFileBrowserModel(dst, t.TempDir(), "/opt"),
+	}
+	for i := range m.browsers {
+		m.browsers[i].SetDimensions(100, 30)
+	}
+
+	result, _ := m.Update(ui.CopyTargetPickedMsg{
+		Request: ui.RemoteCopyRequestMsg{Path: "/srv/app.tar.gz", Size: 10},
+		Target:  ui.CopyTarget{Index: 1, Title: "prod", Dir: "/opt"},
+	})
+	am := result.(AppModel)
+	// The user switches to the destination tab while the destination is
+	// checked; the copy still starts in the source tab.
+	am.activeTab = 1
+	result, cmd := am.Update(am.browsers[0].RemoteCopyCheckedForTest(false))
+	am = result.(AppModel)
+	if cmd == nil || !strings.Contains(am.browsers[0].View(), "Copying app.tar.gz to prod") {
+		t.Fatal("the source tab should start the copy once the destination is checked")
+	}
+
+	result, cmd = am.Update(ui.RemoteCopyDoneMsg{Source: src, Dest: dst, Detail: "to prod"})
+	am = result.(AppModel)
+	if !strings.Contains(am.browsers[0].View(), "Transfer complete: app.tar.gz") {
+		t.Error("the source tab should show the result")
+	}
+	if strings.Contains(am.browsers[1].View(), "Transfer complete") {
+		t.Error("the destination tab should not take the source tab's result")
+	}
+	if cmd == nil {
+		t.Error("the shown destination tab should refresh its listing")
+	}
+	if am.browsers[0].RefreshIfStale() == nil {
+		t.Error("the hidden source tab should refresh when shown again")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// AppModel - sudo mode
+// ---------------------------------------------------------------------------
+
+func TestAppModelSudoPrompt(t *testing.T) {
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 100, 30
+	m.tabs = []ui.Tab{{Title: "web"}}
+	m.clients = []*sshclient.Client{{}}
+	m.conns = []config.Connection{{Host: "web1", Username: "deploy"}}
+
+	result, _ := m.Update(ui.SudoPasswordNeededMsg{})
+	am := result.(AppModel)
+	if !am.sudoPrompt || !am.passwordDialog.Visible() {
+		t.Fatal("sudo prompt should be shown")
+	}
+	if !strings.Contains(am.renderMain(), "[sudo] password for deploy@web1") {
+		t.Error("renderMain should show the sudo prompt")
+	}
+
+	// Esc cancels without contacting the server.
+	_, cmd := am.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	result, cmd = am.Update(cmd())
+	am = result.(AppModel)
+	if am.sudoPrompt || cmd == nil {
+		t.Fatal("cancelling should close the prompt and report it")
+	}
+	if done, ok := cmd().(ui.SudoDoneMsg); !ok || done.Err == nil {
+		t.Errorf("expected a cancelled SudoDoneMsg, got %#v", done)
+	}
+	if am.state != stateMain {
+		t.Errorf("state = %d, want stateMain", am.state)
+	}
+
+	// Submitting returns the login command.
+	result, _ = am.Update(ui.SudoPasswordNeededMsg{})
+	am = result.(AppModel)
+	result, cmd = am.Update(ui.PasswordResponseMsg{Password: "pw"})
+	if result.(AppModel).sudoPrompt || cmd == nil {
+		t.Error("submitting should close the prompt and start the sudo login")
+	}
+}
+
+func TestAppModelPanelViewChangedSaves(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := initialModel()
+	m.cfg = &config.Config{}
+	result, _ := m.Update(ui.PanelViewChangedMsg{
+		Local:  config.PanelView{Sort: "size"},
+		Remote: config.PanelView{HideHidden: true},
+	})
+	am := result.(AppModel)
+	if am.cfg.LocalPanel.Sort != "size" || !am.cfg.RemotePanel.HideHidden {
+		t.Errorf("panel settings not stored: %+v %+v", am.cfg.LocalPanel, am.cfg.RemotePanel)
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.LocalPanel.Sort != "size" || !saved.RemotePanel.HideHidden {
+		t.Errorf("panel settings not saved: %+v %+v", saved.LocalPanel, saved.RemotePanel)
+	}
+}
+
+func TestAppModelBookmarksChangedSaves(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := initialModel()
+	conn := config.Connection{Host: "h", Port: "22", Username: "u"}
+	m.cfg = &config.Config{RecentConnections: []config.Connection{conn}}
+	m.state = stateMain
+	m.tabs = []ui.Tab{{Title: "test"}}
+	m.conns = []config.Connection{conn}
+	marks := []config.Bookmark{{Name: "logs", Path: "/var/log"}}
+	result, _ := m.Update(ui.BookmarksChangedMsg{Bookmarks: marks})
+	am := result.(AppModel)
+	if len(am.conns[0].Bookmarks) != 1 {
+		t.Error("tab connection should get the bookmarks")
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Host(conn).Bookmarks) != 1 {
+		t.Errorf("bookmarks not saved: %+v", saved.Hosts)
+	}
+}
+
+func TestAppModelRateLimitChangedSaves(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := initialModel()
+	conn := config.Connection{Host: "h", Port: "22", Username: "u"}
+	m.cfg = &config.Config{RateLimitKBps: 100, Hosts: map[string]config.HostSettings{
+		config.HostKey(conn): {Compress: true},
+	}}
+	m.state = stateMain
+	m.tabs = []ui.Tab{{Title: "test"}}
+	m.conns = []config.Connection{conn}
+	result, _ := m.Update(ui.RateLimitChangedMsg{KBps: -1})
+	am := result.(AppModel)
+	if am.conns[0].RateLimitKBps != -1 {
+		t.Error("tab connection should get the limit")
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := saved.Host(conn)
+	if host.RateLimitKBps != -1 || !host.Compress {
+		t.Errorf("host settings = %+v, want the limit saved next to the others", host)
+	}
+	if saved.RateLimitFor(config.Connection{Host: "h", Port: "22", Username: "u", HostSettings: host}) != 0 {
+		t.Error("an unlimited host should not fall back to the global limit")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Terminal pane
+// ---------------------------------------------------------------------------
+
+// pipeTerminal returns a terminal whose input can be read from the returned
+// channel, one write per receive.
+func pipeTerminal(t *testing.T) (*ui.TerminalModel, <-chan string) {
+	t.Helper()
+	r, w := io.Pipe()
+	t.Cleanup(func() { _ = r.Close() })
+	term := ui.NewTerminalModel(nil)
+	term.SetStdinForTest(w)
+	got := make(chan string, 8)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+			got <- string(buf[:n])
+		}
+	}()
+	return term, got
+}
+
+func TestAppModelTerminalFocus(t *testing.T) {
+	term, input := pipeTerminal(t)
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 80, 30
+	m.tabs = []ui.Tab{{Title: "t1", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+
+	// The terminal is focused: Ctrl+C and ? go to the shell.
+	result, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	m = result.(AppModel)
+	if cmd != nil {
+		t.Error("Ctrl+C in the terminal should not quit")
+	}
+	if got := <-input; got != "\x03" {
+		t.Errorf("terminal got %q, want ^C", got)
+	}
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	m = result.(AppModel)
+	if m.showHelp || <-input != "?" {
+		t.Error("? should be typed into the terminal")
+	}
+
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyF12})
+	m = result.(AppModel)
+	if m.focusedKind() != layout.Local {
+		t.Fatalf("F12 should focus the next pane, got %v", m.focusedKind())
+	}
+	if !strings.Contains(m.renderMain(), "F12: next pane") {
+		t.Error("status line should offer F12 to move on")
+	}
+	for range 2 {
+		result, _ = m.Update(tea.KeyMsg{Type: tea.KeyF12})
+		m = result.(AppModel)
+	}
+	if m.focusedKind() != layout.Terminal {
+		t.Error("F12 should wrap around to the terminal")
+	}
+}
+
+func TestTerminalKeysNotLogged(t *testing.T) {
+	term, input := pipeTerminal(t)
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 80, 30
+	m.tabs = []ui.Tab{{Title: "t1", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+
+	buf := captureLog(t)
+	result, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hunter2")})
+	m = result.(AppModel)
+	if got := <-input; got != "hunter2" {
+		t.Errorf("terminal got %q", got)
+	}
+	if strings.Contains(buf.String(), "hunter2") || !strings.Contains(buf.String(), "terminal key: type=") {
+		t.Errorf("terminal keys should be logged by type only:\n%s", buf)
+	}
+}
+
+func TestAppModelTerminalOutputAndLayout(t *testing.T) {
+	term := ui.NewTerminalModel(nil)
+	m := initialModel()
+	m.state = stateMain
+	m.tabs = []ui.Tab{{Title: "t1", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+
+	result, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 44})
+	m = result.(AppModel)
+	result, _ = m.Update(ui.TerminalOutputMsg{Term: term, Data: []byte("\x1b[1;34muser@host\x1b[0m:~$ ")})
+	m = result.(AppModel)
+	if got := term.BufferedOutput(); got != "user@host:~$" {
+		t.Errorf("terminal screen = %q", got)
+	}
+	view := m.renderMain()
+	if lines := strings.Split(view, "\n"); len(lines) > 44 {
+		t.Errorf("main view is %d lines, taller than the window", len(lines))
+	}
+	if !strings.Contains(view, "user@host") {
+		t.Error("main view should show the terminal pane")
+	}
+
+	m.closeTab(0)
+	if len(m.terminals) != 0 {
+		t.Errorf("closing the tab should drop its terminal, %d left", len(m.terminals))
+	}
+}
+
+func TestAppModelTerminalCopyMode(t *testing.T) {
+	term, input := pipeTerminal(t)
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 80, 30
+	m.tabs = []ui.Tab{{Title: "t1", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+
+	result, _ := m.Update(tea.KeyMsg{Type: tea.KeyPgUp, Alt: true})
+	m = result.(AppModel)
+	if !term.CopyMode() {
+		t.Fatal("Alt+PgUp should enter copy mode")
+	}
+	// Keys drive copy mode instead of reaching the shell.
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	m = result.(AppModel)
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	m = result.(AppModel)
+	if term.CopyMode() {
+		t.Fatal("q should leave copy mode")
+	}
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m = result.(AppModel)
+	if got := <-input; got != "x" {
+		t.Errorf("terminal got %q, want only the key typed after copy mode", got)
+	}
+}
+
+func TestAppModelFollowShellDir(t *testing.T) {
+	term, input := pipeTerminal(t)
+	other := ui.NewTerminalModel(nil)
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 80, 30
+	m.tabs = []ui.Tab{{Title: "t1", Connected: true}, {Title: "t2", Connected: true}}
+	m.clients = []*sshclient.Client{nil, nil}
+	m.browsers = []ui.FileBrowserModel{
+		ui.NewFileBrowserModel(nil, t.TempDir(), "/home/u"),
+		ui.NewFileBrowserModel(nil, t.TempDir(), "/home/u"),
+	}
+	m.terminals = []*ui.TerminalModel{term, other}
+	m.layouts = []*layout.Layout{layout.New(nil), layout.New(nil)}
+
+	result, cmd := m.Update(ui.TerminalOutputMsg{Term: term, Data: []byte("\x1b]7;file:///srv/app\x07$ ")})
+	m = result.(AppModel)
+	if cmd == nil || m.browsers[0].RemoteDir() != "/srv/app" {
+		t.Fatalf("the remote panel should follow the shell, got %q", m.browsers[0].RemoteDir())
+	}
+	// A background tab follows its own shell and lists it when shown.
+	result, cmd = m.Update(ui.TerminalOutputMsg{Term: other, Data: []byte("\x1b]7;file:///tmp\x07")})
+	m = result.(AppModel)
+	if cmd != nil || m.browsers[1].RemoteDir() != "/tmp" || m.browsers[1].RefreshIfStale() == nil {
+		t.Errorf("a hidden tab should be marked stale, remote dir %q", m.browsers[1].RemoteDir())
+	}
+
+	// Alt+Shift+F stops following, even with the terminal focused.
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F"), Alt: true})
+	m = result.(AppModel)
+	m.Update(ui.TerminalOutputMsg{Term: term, Data: []byte("\x1b]7;file:///var\x07")})
+	if m.browsers[0].RemoteDir() != "/srv/app" {
+		t.Errorf("following is off, remote dir moved to %q", m.browsers[0].RemoteDir())
+	}
+
+	m.layouts[0].FocusKind(layout.Remote)
+	result, _ = m.Update(ui.CdTerminalMsg{Dir: "/srv/app"})
+	m = result.(AppModel)
+	if got := <-input; got != "\x15 cd -- '/srv/app'\r" {
+		t.Errorf("terminal got %q, want a cd command", got)
+	}
+	if m.focusedKind() != layout.Terminal {
+		t.Errorf("the terminal should be focused after cd, got %v", m.focusedKind())
+	}
+}
+
+func TestAppModelRecordingToggle(t *testing.T) {
+	term, _ := pipeTerminal(t)
+	m := initialModel()
+	m.cfg = &config.Config{RecordingDir: t.TempDir()}
+	m.state = stateMain
+	m.width, m.height = 80, 30
+	m.tabs = []ui.Tab{{Title: "u@h", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+	m.conns = []config.Connection{{Host: "h", Port: "22", Username: "u"}}
+
+	altR := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R"), Alt: true}
+	result, _ := m.Update(altR)
+	m = result.(AppModel)
+	path := term.Recording()
+	if path == "" || !m.tabs[0].Recording {
+		t.Fatalf("Alt+Shift+R in the terminal should start recording (err %q)", m.err)
+	}
+	if filepath.Dir(path) != m.cfg.RecordingDir || !strings.HasPrefix(filepath.Base(path), "u@h_22_") {
+		t.Errorf("recording path = %q", path)
+	}
+	result, _ = m.Update(altR)
+	m = result.(AppModel)
+	if term.Recording() != "" || m.tabs[0].Recording {
+		t.Error("Alt+Shift+R again should stop recording")
+	}
+
+	// Ctrl+P lists the recordings and opens the player.
+	m.layouts[0].FocusKind(layout.Local)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if cmd == nil {
+		t.Fatal("Ctrl+P should load the recordings")
+	}
+	result, _ = m.Update(cmd())
+	m = result.(AppModel)
+	if m.player == nil || !strings.Contains(m.renderMain(), filepath.Base(path)) {
+		t.Fatal("the player should list the new recording")
+	}
+	result, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = result.(AppModel)
+	result, _ = m.Update(cmd())
+	m = result.(AppModel)
+	if !m.player.Playing() {
+		t.Error("Enter should play the recording")
+	}
+	result, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	m = result.(AppModel)
+	result, _ = m.Update(cmd())
+	if result.(AppModel).player != nil {
+		t.Error("q should close the player")
+	}
+}
+
+func TestAppModelBroadcast(t *testing.T) {
+	var terms []*ui.TerminalModel
+	var inputs []<-chan string
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 100, 30
+	for i := 0; i < 3; i++ {
+		term, input := pipeTerminal(t)
+		terms, inputs = append(terms, term), append(inputs, input)
+		m.tabs = append(m.tabs, ui.Tab{Title: fmt.Sprintf("web%d", i+1), Connected: true})
+		m.clients = append(m.clients, nil)
+		m.browsers = append(m.browsers, ui.NewFileBrowserModel(nil, t.TempDir(), "/"))
+		m.layouts = append(m.layouts, layout.New(nil))
+	}
+	m.terminals = terms
+
+	altB := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("B"), Alt: true}
+	result, _ := m.Update(altB)
+	m = result.(AppModel)
+	if m.broadcastView == nil {
+		t.Fatal("Alt+Shift+B should open the broadcast dialog")
+	}
+	// The active tab is preselected; add the second and confirm.
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeySpace, Runes: []rune(" ")}, {Type: tea.KeyEnter}} {
+		result, _ = m.Update(key)
+		m = result.(AppModel)
+	}
+	result, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = result.(AppModel)
+	result, _ = m.Update(cmd())
+	m = result.(AppModel)
+	if !m.broadcasting || !m.tabs[0].Broadcast || !m.tabs[1].Broadcast || m.tabs[2].Broadcast {
+		t.Fatalf("broadcast group = %+v", m.tabs)
+	}
+	if !terms[1].Broadcast() || terms[2].Broadcast() {
+		t.Error("member panes should be marked")
+	}
+	if !strings.Contains(m.renderMain(), "BROADCAST to 2 tabs") {
+		t.Error("status line should show broadcasting")
+	}
+
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	m = result.(AppModel)
+	if <-inputs[0] != "u" || <-inputs[1] != "u" {
+		t.Error("keys should reach every member")
+	}
+	select {
+	case got := <-inputs[2]:
+		t.Errorf("non-member got %q", got)
+	default:
+	}
+
+	// Closing a member leaves one tab, which ends broadcasting.
+	m.closeTab(1)
+	if m.broadcasting || m.tabs[0].Broadcast || terms[0].Broadcast() {
+		t.Error("broadcast should stop when fewer than two members remain")
+	}
+}
+
+func TestAppModelLayoutKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	term, input := pipeTerminal(t)
+	conn := config.Connection{Host: "h", Port: "22", Username: "u"}
+	m := initialModel()
+	m.cfg = &config.Config{RecentConnections: []config.Connection{conn}}
+	m.state = stateMain
+	m.width, m.height = 100, 40
+	m.tabs = []ui.Tab{{Title: "u@h", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.conns = []config.Connection{conn}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+	press := func(k tea.KeyMsg) {
+		t.Helper()
+		result, _ := m.Update(k)
+		m = result.(AppModel)
+	}
+	alt := func(r string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(r), Alt: true} }
+
+	// Alt+Shift+S stacks a new pane below the terminal with the first
+	// kind not shown yet, and the layout is saved with the host.
+	press(alt("S"))
+	if m.focusedKind() != layout.Editor {
+		t.Fatalf("the new pane should show the editor, got %v", m.focusedKind())
+	}
+	if saved := m.cfg.Host(conn); saved.Layout == nil || len(layout.New(saved.Layout).Panes()) != 4 {
+		t.Errorf("the layout should be saved with the host, got %+v", saved.Layout)
+	}
+	view := m.renderMain()
+	if !strings.Contains(view, "Press Enter on a file") || len(strings.Split(view, "\n")) > m.height {
+		t.Errorf("the empty editor pane should be drawn within the window:\n%s", view)
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	select {
+	case got := <-input:
+		t.Errorf("keys in the editor pane should not reach the terminal, got %q", got)
+	default:
+	}
+	press(alt("X"))
+	if m.focusedKind() != layout.Terminal || m.layoutHas(layout.Editor) {
+		t.Fatalf("Alt+Shift+X should close the pane, focus %v", m.focusedKind())
+	}
+
+	// Alt+Shift+arrows move the focus; Tab in a file pane follows the
+	// browser to the other panel.
+	press(tea.KeyMsg{Type: tea.KeyShiftDown, Alt: true})
+	if m.focusedKind() != layout.Local {
+		t.Fatalf("Alt+Shift+Down should focus the local pane, got %v", m.focusedKind())
+	}
+	press(tea.KeyMsg{Type: tea.KeyTab})
+	if m.focusedKind() != layout.Remote || m.browsers[0].FocusedPanel() != layout.Remote {
+		t.Errorf("Tab should move to the remote pane, got %v", m.focusedKind())
+	}
+
+	// Alt+V adds a preview pane next to the file pane and keeps the focus.
+	press(alt("v"))
+	if !m.layoutHas(layout.Preview) || m.focusedKind() != layout.Remote {
+		t.Fatalf("Alt+V should add a preview pane, focus %v", m.focusedKind())
+	}
+	if !strings.Contains(m.renderMain(), "Preview") {
+		t.Error("the preview pane should be drawn")
+	}
+	press(alt("v"))
+	if m.layoutHas(layout.Preview) {
+		t.Error("Alt+V again should close the preview pane")
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyCtrlUp, Alt: true})
+	if r := m.layouts[0].Root().Ratio; r != 0.45 {
+		t.Errorf("Alt+Ctrl+Up should shrink the terminal, ratio %v", r)
+	}
+	if r := m.conns[0].Layout.Ratio; r != 0.45 {
+		t.Errorf("the tab's connection should hold the new layout, ratio %v", r)
+	}
+}
+
+func TestMergeSSHConfig(t *testing.T) {
+	hosts := []config.SSHHost{{
+		Alias:                 "web",
+		HostName:              "web.example.com",
+		StrictHostKeyChecking: "no",
+		IdentityFile:          "/key",
+		ProxyJump:             "bastion",
+		SendEnv:               []string{"EDITOR"},
+		SetEnv:                []string{"APP_ENV=staging"},
+	}}
+	conn := mergeSSHConfig(config.Connection{Host: "web", Port: "22", Username: "u", KeyPath: "/mine"}, hosts)
+	if conn.StrictHostKeyChecking != "no" || conn.ProxyJump != "bastion" || len(conn.SendEnv) != 1 || len(conn.SetEnv) != 1 {
+		t.Errorf("merged %+v, want the SSH config options", conn)
+	}
+	if conn.KeyPath != "/mine" {
+		t.Errorf("KeyPath = %q, options set on the connection should win", conn.KeyPath)
+	}
+	if got := mergeSSHConfig(config.Connection{Host: "other"}, hosts); got.KeyPath != "" || got.ProxyJump != "" {
+		t.Errorf("unmatched host changed to %+v", got)
+	}
+}
+
+func TestMakeBatchHKCallback(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := gossh.NewSignerFromKey(priv)
+	key := signer.PublicKey()
+	defer delete(acceptedHosts, "batch:22")
+	defer delete(acceptedHosts, "loose:22")
+
+	cb := makeBatchHKCallback(config.Connection{Host: "batch", Port: "22"})
+	if err := cb("batch:22", nil, key); err == nil {
+		t.Error("an unknown host key should be rejected without a prompt")
+	}
+	acceptHostKey("batch:22", fingerprintSHA256(key))
+	if err := cb("batch:22", nil, key); err != nil {
+		t.Errorf("a key accepted this session should pass, got %v", err)
+	}
+
+	cb = makeBatchHKCallback(config.Connection{Host: "loose", Port: "22", StrictHostKeyChecking: "no"})
+	if err := cb("loose:22", nil, key); err != nil || !hostKeyAccepted("loose:22", fingerprintSHA256(key)) {
+		t.Errorf("StrictHostKeyChecking=no should accept and remember the key, got %v", err)
+	}
+}
+
+func TestRunFleetCommandNeedsUsername(t *testing.T) {
+	run := runFleetCommand(nil)
+	code, err := run(context.Background(), config.Connection{Host: "h", Port: "22"}, "uptime", func(string, bool) {})
+	if code != -1 || err == nil || !strings.Contains(err.Error(), "username") {
+		t.Errorf("run = %d, %v; want a missing username error", code, err)
+	}
+}
+
+func TestAppModelFleet(t *testing.T) {
+	m := initialModel()
+	m.width, m.height = 100, 30
+	conns := []config.Connection{{Host: "h1", Port: "22", Username: "u"}, {Host: "h2", Port: "22", Username: "u"}}
+	model, _ := m.Update(ui.FleetMsg{Conns: conns})
+	m = model.(AppModel)
+	if m.fleet == nil || !strings.Contains(m.View(), "run on 2 host(s)") {
+		t.Fatal("FleetMsg should open the fleet view with its prompt")
+	}
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	m = model.(AppModel)
+	if cmd != nil {
+		if _, quit := cmd().(tea.QuitMsg); quit {
+			t.Fatal("Ctrl+C in the fleet view should not quit")
+		}
+	}
+
+	model, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = model.(AppModel)
+	model, _ = m.Update(cmd())
+	m = model.(AppModel)
+	if m.fleet != nil || m.state != stateConnection {
+		t.Error("Esc should close the fleet view and show the connection screen")
+	}
+}
+
+func TestAppModelSnippetToTerminal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := config.SaveSnippet("", config.Snippet{Name: "load", Command: "uptime"}); err != nil {
+		t.Fatal(err)
+	}
+	term, input := pipeTerminal(t)
+	m := initialModel()
+	m.state = stateMain
+	m.width, m.height = 80, 30
+	m.tabs = []ui.Tab{{Title: "t1", Connected: true}}
+	m.clients = []*sshclient.Client{nil}
+	m.browsers = []ui.FileBrowserModel{ui.NewFileBrowserModel(nil, t.TempDir(), "/")}
+	m.terminals = []*ui.TerminalModel{term}
+	m.layouts = []*layout.Layout{layout.New(nil)}
+
+	result, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("I"), Alt: true})
+	m = result.(AppModel)
+	if !m.browsers[0].OverlayActive() || !strings.Contains(m.View(), "uptime") {
+		t.Fatal("Alt+Shift+I in the terminal should open the snippet picker")
+	}
+	// Keys go to the picker rather than the terminal behind it.
+	result, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = result.(AppModel)
+	for _, c := range cmd().(tea.BatchMsg) {
+		result, _ = m.Update(c())
+		m = result.(AppModel)
+	}
+	if got := <-input; got != "uptime" {
+		t.Errorf("terminal got %q, want the snippet typed at the prompt", got)
+	}
+	if m.browsers[0].OverlayActive() || m.focusedKind() != layout.Terminal {
+		t.Error("picking a snippet should close the picker and focus the terminal")
+	}
+}
@@ -0,0 +1,254 @@
+// Package cast reads and writes terminal recordings in the asciinema v2
+// format: a JSON header line followed by one JSON array per event.
+package cast
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Event types.
+const (
+	Output = "o"
+	Input  = "i"
+	Resize = "r"
+)
+
+// Header is the first line of a recording.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is one recorded chunk of output or input, or a resize to "COLSxROWS".
+type Event struct {
+	Time float64 // seconds since the start of the recording
+	Type string
+	Data string
+}
+
+// MarshalJSON encodes the event as [time, type, data].
+func (e Event) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "[%s, %q, %s]", strconv.FormatFloat(e.Time, 'f', 6, 64), e.Type, data), nil
+}
+
+// UnmarshalJSON decodes an event from [time, type, data].
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("event has %d fields, want 3", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return fmt.Errorf("event time: %w", err)
+	}
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return fmt.Errorf("event type: %w", err)
+	}
+	if err := json.Unmarshal(raw[2], &e.Data); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+	return nil
+}
+
+// Size parses the "COLSxROWS" data of a resize event.
+func (e Event) Size() (cols, rows int, ok bool) {
+	c, r, found := strings.Cut(e.Data, "x")
+	if !found {
+		return 0, 0, false
+	}
+	cols, err1 := strconv.Atoi(c)
+	rows, err2 := strconv.Atoi(r)
+	return cols, rows, err1 == nil && err2 == nil && cols > 0 && rows > 0
+}
+
+// Writer appends events to a recording. It is safe for concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	w       io.WriteCloser
+	start   time.Time
+	now     func() time.Time
+	pending map[string][]byte // incomplete UTF-8 held back per event type
+	err     error
+}
+
+// Create starts a recording in a new file at path.
+func Create(path string, h Header) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, h, time.Now)
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+	return w, nil
+}
+
+// NewWriter writes the header to w and returns a Writer timing events with
+// now. The header timestamp defaults to the start time.
+func NewWriter(w io.WriteCloser, h Header, now func() time.Time) (*Writer, error) {
+	start := now()
+	h.Version = 2
+	if h.Timestamp == 0 {
+		h.Timestamp = start.Unix()
+	}
+	line, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, start: start, now: now, pending: map[string][]byte{}}, nil
+}
+
+// Output records terminal output.
+func (w *Writer) Output(data []byte) { w.write(Output, data) }
+
+// Input records keystrokes sent to the terminal.
+func (w *Writer) Input(data []byte) { w.write(Input, data) }
+
+// Resize records a change of the terminal size.
+func (w *Writer) Resize(cols, rows int) {
+	w.write(Resize, []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// write appends an event. A UTF-8 sequence split across writes is held
+// back until the rest arrives, since event data must be valid UTF-8.
+func (w *Writer) write(typ string, data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	if p := w.pending[typ]; len(p) > 0 {
+		data = append(p, data...)
+		delete(w.pending, typ)
+	}
+	if n := incompleteSuffix(data); n > 0 {
+		w.pending[typ] = append([]byte(nil), data[len(data)-n:]...)
+		data = data[:len(data)-n]
+	}
+	if len(data) == 0 {
+		return
+	}
+	ev := Event{Time: w.now().Sub(w.start).Seconds(), Type: typ, Data: string(data)}
+	line, err := ev.MarshalJSON()
+	if err == nil {
+		_, err = w.w.Write(append(line, '\n'))
+	}
+	w.err = err
+}
+
+// incompleteSuffix returns the length of a truncated UTF-8 sequence at the
+// end of b, or 0.
+func incompleteSuffix(b []byte) int {
+	for n := 1; n <= 3 && n <= len(b); n++ {
+		c := b[len(b)-n]
+		if c < 0x80 {
+			return 0
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(b[len(b)-n:]) {
+				return n
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// Close flushes held-back bytes, closes the file and returns the first
+// error seen while recording.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = map[string][]byte{}
+	w.mu.Unlock()
+	for _, typ := range []string{Output, Input} {
+		if p := pending[typ]; len(p) > 0 {
+			w.write(typ, []byte(strings.ToValidUTF8(string(p), "�")))
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return errors.Join(w.err, w.w.Close())
+}
+
+// Recording is a parsed recording.
+type Recording struct {
+	Header Header
+	Events []Event
+}
+
+// Duration returns the time of the last event.
+func (r *Recording) Duration() time.Duration {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return seconds(r.Events[len(r.Events)-1].Time)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Read parses an asciinema v2 recording.
+func Read(r io.Reader) (*Recording, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("empty recording")
+	}
+	var rec Recording
+	if err := json.Unmarshal(sc.Bytes(), &rec.Header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	if rec.Header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d", rec.Header.Version)
+	}
+	for line := 2; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rec.Events = append(rec.Events, ev)
+	}
+	return &rec, sc.Err()
+}
+
+// Load reads the recording at path.
+func Load(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Read(f)
+}
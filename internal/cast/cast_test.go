@@ -0,0 +1,90 @@
+package cast
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestWriterAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	clock := time.Unix(1700000000, 0)
+	now := func() time.Time { return clock }
+	w, err := NewWriter(nopCloser{&buf}, Header{Width: 80, Height: 24, Title: "u@h", Env: map[string]string{"TERM": "xterm-256color"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock = clock.Add(500 * time.Millisecond)
+	euro := []byte("€")
+	w.Output(append([]byte("price "), euro[:2]...)) // split rune is held back
+	clock = clock.Add(time.Second)
+	w.Output(euro[2:])
+	w.Input([]byte("ls\r"))
+	w.Resize(100, 30)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != `{"version":2,"width":80,"height":24,"timestamp":1700000000,"title":"u@h","env":{"TERM":"xterm-256color"}}` {
+		t.Errorf("header = %s", lines[0])
+	}
+	if lines[1] != `[0.500000, "o", "price "]` || lines[2] != `[1.500000, "o", "€"]` {
+		t.Errorf("output events = %q", lines[1:3])
+	}
+
+	rec, err := Read(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header.Width != 80 || len(rec.Events) != 4 {
+		t.Fatalf("read %+v", rec)
+	}
+	if ev := rec.Events[2]; ev.Type != Input || ev.Data != "ls\r" {
+		t.Errorf("input event = %+v", ev)
+	}
+	if cols, rows, ok := rec.Events[3].Size(); !ok || cols != 100 || rows != 30 {
+		t.Errorf("resize = %d×%d %v", cols, rows, ok)
+	}
+	if rec.Duration() != 1500*time.Millisecond {
+		t.Errorf("duration = %v", rec.Duration())
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		`{"version":1}`,
+		"{\"version\":2}\n[1, \"o\"]",
+		"{\"version\":2}\nnot json",
+	} {
+		if _, err := Read(strings.NewReader(in)); err == nil {
+			t.Errorf("Read(%q) should fail", in)
+		}
+	}
+}
+
+func TestCreateKeepsExistingFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.cast")
+	w, err := Create(path, Header{Width: 10, Height: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Output([]byte("hi"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(path, Header{}); err == nil {
+		t.Error("Create should not overwrite a recording")
+	}
+	rec, err := Load(path)
+	if err != nil || len(rec.Events) != 1 || rec.Events[0].Data != "hi" {
+		t.Errorf("Load = %+v, %v", rec, err)
+	}
+}
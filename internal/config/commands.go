@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// maxCommandHistory caps the number of commands kept per host.
+const maxCommandHistory = 200
+
+// commandsPath returns the command history file for the given host key.
+func commandsPath(hostKey string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "ssh-scp", "commands", safeFileName(hostKey)+".json")
+}
+
+// LoadCommandHistory returns the commands run on a host with the command
+// runner, newest first. A missing file yields an empty slice.
+func LoadCommandHistory(hostKey string) ([]string, error) {
+	data, err := os.ReadFile(commandsPath(hostKey))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cmds []string
+	if err := json.Unmarshal(data, &cmds); err != nil {
+		return nil, fmt.Errorf("parse command history: %w", err)
+	}
+	return cmds, nil
+}
+
+// AppendCommandHistory puts cmd at the front of the host's command
+// history, dropping an earlier copy of it, and writes the history back to
+// disk, keeping at most maxCommandHistory commands.
+func AppendCommandHistory(hostKey, cmd string) error {
+	cmds, err := LoadCommandHistory(hostKey)
+	if err != nil {
+		cmds = nil
+	}
+	out := []string{cmd}
+	for _, c := range cmds {
+		if c != cmd && len(out) < maxCommandHistory {
+			out = append(out, c)
+		}
+	}
+
+	p := commandsPath(hostKey)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		return err
+	}
+	FixOwnership(p)
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCommandHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if cmds, err := LoadCommandHistory("u@h:22"); err != nil || len(cmds) != 0 {
+		t.Fatalf("LoadCommandHistory() = %v, %v; want an empty history", cmds, err)
+	}
+	for _, c := range []string{"df -h", "uptime", "df -h"} {
+		if err := AppendCommandHistory("u@h:22", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmds, err := LoadCommandHistory("u@h:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cmds, ",") != "df -h,uptime" {
+		t.Errorf("history = %q, want newest first without duplicates", cmds)
+	}
+	if other, _ := LoadCommandHistory("u@other:22"); len(other) != 0 {
+		t.Errorf("another host's history = %q, want it empty", other)
+	}
+}
+
+func TestCommandHistoryCapped(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for i := range maxCommandHistory + 5 {
+		if err := AppendCommandHistory("u@h:22", fmt.Sprintf("echo %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmds, _ := LoadCommandHistory("u@h:22")
+	if len(cmds) != maxCommandHistory || cmds[0] != fmt.Sprintf("echo %d", maxCommandHistory+4) {
+		t.Errorf("history has %d commands starting %q", len(cmds), cmds[0])
+	}
+}
+
+func TestCommandHistoryCorrupt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := commandsPath("u@h:22")
+	if err := AppendCommandHistory("u@h:22", "ls"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCommandHistory("u@h:22"); err == nil {
+		t.Error("a corrupt history should be reported")
+	}
+	if err := AppendCommandHistory("u@h:22", "pwd"); err != nil {
+		t.Fatalf("a corrupt history should be replaced, got %v", err)
+	}
+}
@@ -3,7 +3,13 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"ssh-scp/internal/layout"
 )
 
 // Connection represents a saved SSH connection.
@@ -19,13 +25,96 @@ type Connection struct {
 	StrictHostKeyChecking string `json:"strict_host_key_checking,omitempty"`
 	UserKnownHostsFile    string `json:"user_known_hosts_file,omitempty"`
 	ProxyJump             string `json:"proxy_jump,omitempty"`
+
+	// Environment for the sessions, from SendEnv and SetEnv in
+	// ~/.ssh/config. Read again on every connect, so never saved.
+	SendEnv []string `json:"-"`
+	SetEnv  []string `json:"-"`
+
+	// Settings of the host, copied from Config.Hosts on connect. They are
+	// saved there rather than with the connection.
+	HostSettings `json:"-"`
+}
+
+// HostSettings are the settings of one host. They are not part of the
+// connection form: they are edited in the config file or saved by the app
+// (bookmarks, layout), and kept whether or not the host is still among the
+// recent connections.
+type HostSettings struct {
+	RateLimitKBps    int          `json:"rate_limit_kbps,omitempty"`    // transfer limit; 0 = use global, <0 = unlimited
+	Compress         bool         `json:"compress,omitempty"`           // compress transfers with remote gzip/zstd
+	DefaultRemoteDir string       `json:"default_remote_dir,omitempty"` // remote start dir; "" = home, may start with ~
+	DefaultLocalDir  string       `json:"default_local_dir,omitempty"`  // local start dir; "" = working dir, may start with ~
+	Bookmarks        []Bookmark   `json:"bookmarks,omitempty"`
+	AutoRecord       bool         `json:"auto_record,omitempty"`       // record every terminal session to the host
+	Layout           *layout.Node `json:"layout,omitempty"`            // pane layout of the tab; nil = default
+	ShellIntegration bool         `json:"shell_integration,omitempty"` // install a prompt hook reporting the shell's directory
+}
+
+// Bookmark is a named directory in the local or remote panel of a host.
+type Bookmark struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Local bool   `json:"local,omitempty"` // local panel bookmark; remote otherwise
+}
+
+// PanelView holds the sort and display settings of a file browser panel.
+type PanelView struct {
+	Sort       string `json:"sort,omitempty"` // "name" (default), "size", "mtime" or "ext"
+	Reverse    bool   `json:"reverse,omitempty"`
+	DirsFirst  bool   `json:"dirs_first,omitempty"`
+	HideHidden bool   `json:"hide_hidden,omitempty"` // hide dotfiles
+}
+
+// RemoteStartDir returns the directory the remote panel opens in: the
+// configured default with ~ expanded to home, or home itself.
+func (c Connection) RemoteStartDir(home string) string {
+	switch {
+	case c.DefaultRemoteDir == "":
+		return home
+	case c.DefaultRemoteDir == "~":
+		return home
+	case strings.HasPrefix(c.DefaultRemoteDir, "~/"):
+		return path.Join(home, c.DefaultRemoteDir[2:])
+	}
+	return c.DefaultRemoteDir
+}
+
+// LocalStartDir returns the directory the local panel opens in: the
+// configured default with ~ expanded, or cwd.
+func (c Connection) LocalStartDir(cwd string) string {
+	if c.DefaultLocalDir == "" {
+		return cwd
+	}
+	home, _ := os.UserHomeDir()
+	return expandTilde(c.DefaultLocalDir, home)
 }
 
 // Config holds application configuration.
 type Config struct {
-	RecentConnections []Connection `json:"recent_connections"`
+	RecentConnections []Connection            `json:"recent_connections"`
+	Hosts             map[string]HostSettings `json:"hosts,omitempty"`                // per-host settings by HostKey
+	RateLimitKBps     int                     `json:"rate_limit_kbps,omitempty"`      // global transfer limit; 0 = unlimited
+	SudoTimeoutMin    int                     `json:"sudo_timeout_minutes,omitempty"` // sudo password cache; 0 = default
+	ScrollbackLines   int                     `json:"scrollback_lines,omitempty"`     // terminal scrollback; 0 = default, <0 = none
+	RecordingDir      string                  `json:"recording_dir,omitempty"`        // session recordings; "" = default, may start with ~
+	RecordInput       bool                    `json:"record_input,omitempty"`         // also record keystrokes
+	FleetConcurrency  int                     `json:"fleet_concurrency,omitempty"`    // hosts a fleet command runs on at once; 0 = default
+	LocalPanel        PanelView               `json:"local_panel"`
+	RemotePanel       PanelView               `json:"remote_panel"`
 }
 
+// defaultSudoTimeout is how long a sudo password is cached by default,
+// matching sudo's own timestamp_timeout.
+const defaultSudoTimeout = 5 * time.Minute
+
+// defaultScrollback is how many lines each terminal keeps by default.
+const defaultScrollback = 10000
+
+// defaultFleetConcurrency is how many hosts a fleet command runs on at once
+// by default.
+const defaultFleetConcurrency = 8
+
 func configPath() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".config", "ssh-scp", "connections.json")
@@ -45,9 +134,29 @@ func Load() (*Config, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return &Config{}, nil
 	}
+	cfg.migrateHostSettings(data)
 	return &cfg, nil
 }
 
+// migrateHostSettings moves host settings that earlier versions kept in
+// the recent connections into Hosts. Entries already in Hosts win.
+func (c *Config) migrateHostSettings(data []byte) {
+	var legacy struct {
+		RecentConnections []HostSettings `json:"recent_connections"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return
+	}
+	for i, s := range legacy.RecentConnections {
+		if i >= len(c.RecentConnections) || reflect.ValueOf(s).IsZero() {
+			continue
+		}
+		if _, ok := c.Hosts[HostKey(c.RecentConnections[i])]; !ok {
+			c.SetHost(c.RecentConnections[i], s)
+		}
+	}
+}
+
 // Save writes the config to disk.
 func Save(cfg *Config) error {
 	p := configPath()
@@ -86,3 +195,89 @@ func (c *Config) AddRecent(conn Connection) {
 		c.RecentConnections = c.RecentConnections[:10]
 	}
 }
+
+// FindRecent returns the saved connection matching host, port and username,
+// or nil if there is none.
+func (c *Config) FindRecent(host, port, username string) *Connection {
+	for i := range c.RecentConnections {
+		rc := &c.RecentConnections[i]
+		if rc.Host == host && rc.Port == port && rc.Username == username {
+			return rc
+		}
+	}
+	return nil
+}
+
+// Host returns the settings saved for conn's host.
+func (c *Config) Host(conn Connection) HostSettings {
+	return c.Hosts[HostKey(conn)]
+}
+
+// SetHost saves the settings of conn's host.
+func (c *Config) SetHost(conn Connection, s HostSettings) {
+	if c.Hosts == nil {
+		c.Hosts = map[string]HostSettings{}
+	}
+	c.Hosts[HostKey(conn)] = s
+}
+
+// RateLimitFor returns the effective transfer limit in KB/s for conn: the
+// per-host limit when set, otherwise the global one (0 = unlimited).
+func (c *Config) RateLimitFor(conn Connection) int {
+	switch {
+	case conn.RateLimitKBps < 0:
+		return 0
+	case conn.RateLimitKBps > 0:
+		return conn.RateLimitKBps
+	}
+	if c.RateLimitKBps < 0 {
+		return 0
+	}
+	return c.RateLimitKBps
+}
+
+// SudoTimeout returns how long a sudo password is cached after it was
+// entered.
+func (c *Config) SudoTimeout() time.Duration {
+	if c.SudoTimeoutMin > 0 {
+		return time.Duration(c.SudoTimeoutMin) * time.Minute
+	}
+	return defaultSudoTimeout
+}
+
+// Scrollback returns how many lines scrolled off a terminal are kept.
+func (c *Config) Scrollback() int {
+	switch {
+	case c.ScrollbackLines < 0:
+		return 0
+	case c.ScrollbackLines > 0:
+		return c.ScrollbackLines
+	}
+	return defaultScrollback
+}
+
+// FleetParallel returns how many hosts a fleet command runs on at once.
+func (c *Config) FleetParallel() int {
+	if c.FleetConcurrency > 0 {
+		return c.FleetConcurrency
+	}
+	return defaultFleetConcurrency
+}
+
+// RecordingDirectory returns where terminal recordings are written: the
+// configured directory with ~ expanded, or recordings/ in the config
+// directory.
+func (c *Config) RecordingDirectory() string {
+	home, _ := os.UserHomeDir()
+	if c.RecordingDir != "" {
+		return expandTilde(c.RecordingDir, home)
+	}
+	return filepath.Join(home, ".config", "ssh-scp", "recordings")
+}
+
+// RecordingPath returns a file name in the recording directory for a
+// session to hostKey started at t.
+func (c *Config) RecordingPath(hostKey string, t time.Time) string {
+	name := safeFileName(hostKey) + "_" + t.Format("20060102-150405") + ".cast"
+	return filepath.Join(c.RecordingDirectory(), name)
+}
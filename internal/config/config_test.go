@@ -7,6 +7,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"ssh-scp/internal/layout"
 )
 
 func setupTestConfig(t *testing.T) (string, func()) {
@@ -269,3 +272,173 @@ func TestConnectionJSONIncludesNonEmpty(t *testing.T) {
 		t.Errorf("expected key_path in JSON, got %s", s)
 	}
 }
+
+func TestHostSettingsOutliveRecentList(t *testing.T) {
+	cfg := &Config{}
+	conn := Connection{Host: "h0", Port: "22", Username: "u"}
+	cfg.AddRecent(conn)
+	cfg.SetHost(conn, HostSettings{Compress: true, Bookmarks: []Bookmark{{Name: "logs", Path: "/var/log"}}})
+	for i := 1; i <= 10; i++ {
+		cfg.AddRecent(Connection{Host: fmt.Sprintf("h%d", i), Port: "22", Username: "u"})
+	}
+	if cfg.FindRecent("h0", "22", "u") != nil {
+		t.Fatal("h0 should have fallen off the recent list")
+	}
+	if s := cfg.Host(conn); !s.Compress || len(s.Bookmarks) != 1 {
+		t.Errorf("settings of a host off the recent list = %+v", s)
+	}
+	if s := cfg.Host(Connection{Host: "h0", Port: "2222", Username: "u"}); s.Compress {
+		t.Error("settings belong to one user@host:port")
+	}
+}
+
+func TestAddRecentLeavesHostSettings(t *testing.T) {
+	cfg := &Config{}
+	conn := Connection{Host: "h1", Port: "22", Username: "u1"}
+	cfg.SetHost(conn, HostSettings{Compress: true, AutoRecord: true})
+	cfg.AddRecent(conn)
+	cfg.SetHost(conn, HostSettings{})
+	cfg.AddRecent(conn)
+	if s := cfg.Host(conn); s.Compress || s.AutoRecord {
+		t.Errorf("turned-off settings should stay off, got %+v", s)
+	}
+}
+
+func TestLoadMigratesHostSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	old := `{"recent_connections": [
+		{"host": "h1", "port": "22", "username": "u1", "compress": true, "rate_limit_kbps": 256,
+		 "bookmarks": [{"name": "logs", "path": "/var/log"}], "layout": {"kind": "terminal"}},
+		{"host": "h2", "port": "22", "username": "u2", "auto_record": true},
+		{"host": "h3", "port": "22", "username": "u3"}
+	], "hosts": {"u2@h2:22": {"shell_integration": true}}}`
+	p := configPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(old), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cfg.Host(cfg.RecentConnections[0])
+	if !s.Compress || s.RateLimitKBps != 256 || len(s.Bookmarks) != 1 || s.Layout == nil || s.Layout.Kind != layout.Terminal {
+		t.Errorf("migrated settings = %+v", s)
+	}
+	if s := cfg.Host(cfg.RecentConnections[1]); s.AutoRecord || !s.ShellIntegration {
+		t.Errorf("an existing hosts entry should win, got %+v", s)
+	}
+	if len(cfg.Hosts) != 2 {
+		t.Errorf("hosts without settings should get no entry: %v", cfg.Hosts)
+	}
+
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(p)
+	var saved struct {
+		RecentConnections []map[string]any `json:"recent_connections"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := saved.RecentConnections[0]["compress"]; ok {
+		t.Errorf("settings should no longer be saved with the connection: %v", saved.RecentConnections[0])
+	}
+}
+
+func TestConnectionStartDirs(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		conn          Connection
+		remote, local string
+	}{
+		{Connection{}, "/home/u", "/cwd"},
+		{Connection{HostSettings: HostSettings{DefaultRemoteDir: "/opt/app", DefaultLocalDir: "/tmp"}}, "/opt/app", "/tmp"},
+		{Connection{HostSettings: HostSettings{DefaultRemoteDir: "~/releases", DefaultLocalDir: "~/work"}}, "/home/u/releases", filepath.Join(home, "work")},
+		{Connection{HostSettings: HostSettings{DefaultRemoteDir: "~", DefaultLocalDir: "~"}}, "/home/u", home},
+	}
+	for _, tt := range tests {
+		if got := tt.conn.RemoteStartDir("/home/u"); got != tt.remote {
+			t.Errorf("RemoteStartDir(%q) = %q, want %q", tt.conn.DefaultRemoteDir, got, tt.remote)
+		}
+		if got := tt.conn.LocalStartDir("/cwd"); got != tt.local {
+			t.Errorf("LocalStartDir(%q) = %q, want %q", tt.conn.DefaultLocalDir, got, tt.local)
+		}
+	}
+}
+
+func TestFindRecent(t *testing.T) {
+	cfg := &Config{
+		RecentConnections: []Connection{
+			{Host: "h1", Port: "22", Username: "u1"},
+			{Host: "h2", Port: "2222", Username: "u2"},
+		},
+	}
+	if got := cfg.FindRecent("h2", "2222", "u2"); got == nil || got.Host != "h2" {
+		t.Errorf("FindRecent(h2) = %v, want h2 entry", got)
+	}
+	if got := cfg.FindRecent("h2", "22", "u2"); got != nil {
+		t.Errorf("FindRecent with wrong port = %v, want nil", got)
+	}
+}
+
+func TestRateLimitFor(t *testing.T) {
+	cfg := &Config{RateLimitKBps: 100}
+	tests := []struct {
+		name string
+		conn Connection
+		want int
+	}{
+		{"global", Connection{}, 100},
+		{"per-host", Connection{HostSettings: HostSettings{RateLimitKBps: 50}}, 50},
+		{"per-host unlimited", Connection{HostSettings: HostSettings{RateLimitKBps: -1}}, 0},
+	}
+	for _, tt := range tests {
+		if got := cfg.RateLimitFor(tt.conn); got != tt.want {
+			t.Errorf("%s: RateLimitFor = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSudoTimeout(t *testing.T) {
+	if got := (&Config{}).SudoTimeout(); got != defaultSudoTimeout {
+		t.Errorf("default SudoTimeout = %v", got)
+	}
+	if got := (&Config{SudoTimeoutMin: 15}).SudoTimeout(); got != 15*time.Minute {
+		t.Errorf("SudoTimeout = %v, want 15m", got)
+	}
+}
+
+func TestFleetParallel(t *testing.T) {
+	tests := map[int]int{0: defaultFleetConcurrency, 3: 3, -2: defaultFleetConcurrency}
+	for n, want := range tests {
+		if got := (&Config{FleetConcurrency: n}).FleetParallel(); got != want {
+			t.Errorf("FleetParallel with %d = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestScrollback(t *testing.T) {
+	tests := map[int]int{0: defaultScrollback, 500: 500, -1: 0}
+	for lines, want := range tests {
+		if got := (&Config{ScrollbackLines: lines}).Scrollback(); got != want {
+			t.Errorf("Scrollback with %d = %d, want %d", lines, got, want)
+		}
+	}
+}
+
+func TestRecordingPath(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	at := time.Date(2024, 3, 5, 14, 7, 9, 0, time.Local)
+	got := (&Config{}).RecordingPath("u@h:22", at)
+	if want := filepath.Join(home, ".config", "ssh-scp", "recordings", "u@h_22_20240305-140709.cast"); got != want {
+		t.Errorf("default path = %q, want %q", got, want)
+	}
+	got = (&Config{RecordingDir: "~/casts"}).RecordingPath("u@h:22", at)
+	if want := filepath.Join(home, "casts", "u@h_22_20240305-140709.cast"); got != want {
+		t.Errorf("configured path = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxHistoryEntries caps the number of transfers kept per host.
+const maxHistoryEntries = 500
+
+// TransferRecord describes one completed (or failed) file transfer.
+type TransferRecord struct {
+	Time        time.Time     `json:"time"`
+	Upload      bool          `json:"upload"`
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Size        int64         `json:"size"`
+	Duration    time.Duration `json:"duration"`
+	Result      string        `json:"result"`             // "ok" or the error message
+	Checksum    string        `json:"checksum,omitempty"` // sha256 of the local copy
+}
+
+// OK reports whether the transfer succeeded.
+func (r TransferRecord) OK() bool {
+	return r.Result == "ok"
+}
+
+// HostKey returns the identifier used to key per-host data such as the
+// transfer history, e.g. "user@example.com:22".
+func HostKey(conn Connection) string {
+	return fmt.Sprintf("%s@%s:%s", conn.Username, conn.Host, conn.Port)
+}
+
+// historyPath returns the history file for the given host key.
+func historyPath(hostKey string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "ssh-scp", "history", safeFileName(hostKey)+".json")
+}
+
+// safeFileName replaces characters of a host key that are awkward in file
+// names with underscores.
+func safeFileName(hostKey string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '.' || r == '-' || r == '@':
+			return r
+		}
+		return '_'
+	}, hostKey)
+}
+
+// LoadHistory returns the transfer history for a host, newest first.
+// A missing history file yields an empty slice.
+func LoadHistory(hostKey string) ([]TransferRecord, error) {
+	data, err := os.ReadFile(historyPath(hostKey))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []TransferRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("parse history: %w", err)
+	}
+	return records, nil
+}
+
+// historyMu serializes AppendHistory, so transfers finishing at the same
+// time in different tabs do not overwrite each other's records.
+var historyMu sync.Mutex
+
+// AppendHistory prepends rec to the host's transfer history and writes it
+// back to disk, keeping at most maxHistoryEntries records.
+func AppendHistory(hostKey string, rec TransferRecord) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	records, err := LoadHistory(hostKey)
+	if err != nil {
+		// Don't let a corrupt file block recording new transfers.
+		records = nil
+	}
+	records = append([]TransferRecord{rec}, records...)
+	if len(records) > maxHistoryEntries {
+		records = records[:maxHistoryEntries]
+	}
+
+	p := historyPath(hostKey)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		return err
+	}
+	FixOwnership(p)
+	return nil
+}
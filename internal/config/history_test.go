@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostKey(t *testing.T) {
+	got := HostKey(Connection{Host: "example.com", Port: "22", Username: "deploy"})
+	if got != "deploy@example.com:22" {
+		t.Errorf("HostKey = %q, want deploy@example.com:22", got)
+	}
+}
+
+func TestHistoryPathSanitizes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := historyPath("u@[::1]:22/../x")
+	base := filepath.Base(p)
+	if strings.ContainsAny(base, "[]:/") {
+		t.Errorf("history file name %q should not contain path or bracket characters", base)
+	}
+	if filepath.Base(filepath.Dir(p)) != "history" {
+		t.Errorf("history file should live in the history dir, got %q", p)
+	}
+}
+
+func TestLoadHistoryMissing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	records, err := LoadHistory("u@h:22")
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("expected no records, got %d", len(records))
+	}
+}
+
+func TestAppendHistoryNewestFirst(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for i := 0; i < 3; i++ {
+		rec := TransferRecord{
+			Time:     time.Unix(int64(i), 0),
+			Source:   fmt.Sprintf("/src/%d", i),
+			Result:   "ok",
+			Duration: time.Second,
+		}
+		if err := AppendHistory("u@h:22", rec); err != nil {
+			t.Fatalf("AppendHistory() error = %v", err)
+		}
+	}
+	records, err := LoadHistory("u@h:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if records[0].Source != "/src/2" {
+		t.Errorf("newest record first, got %q", records[0].Source)
+	}
+	if other, _ := LoadHistory("u@other:22"); len(other) != 0 {
+		t.Error("history should be kept per host")
+	}
+}
+
+func TestAppendHistoryConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := AppendHistory("u@h:22", TransferRecord{Source: fmt.Sprintf("/src/%d", i), Result: "ok"}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	records, err := LoadHistory("u@h:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 20 {
+		t.Errorf("expected 20 records, got %d", len(records))
+	}
+}
+
+func TestAppendHistoryCapped(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for i := 0; i < maxHistoryEntries+5; i++ {
+		if err := AppendHistory("u@h:22", TransferRecord{Result: "ok"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, _ := LoadHistory("u@h:22")
+	if len(records) != maxHistoryEntries {
+		t.Errorf("expected %d records, got %d", maxHistoryEntries, len(records))
+	}
+}
+
+func TestLoadHistoryCorrupt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := historyPath("u@h:22")
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHistory("u@h:22"); err == nil {
+		t.Error("expected parse error for corrupt history")
+	}
+	// Appending still works and replaces the corrupt file.
+	if err := AppendHistory("u@h:22", TransferRecord{Result: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if records, err := LoadHistory("u@h:22"); err != nil || len(records) != 1 {
+		t.Errorf("after append: records=%d err=%v", len(records), err)
+	}
+}
+
+func TestTransferRecordOK(t *testing.T) {
+	if !(TransferRecord{Result: "ok"}).OK() {
+		t.Error("Result ok should report OK")
+	}
+	if (TransferRecord{Result: "permission denied"}).OK() {
+		t.Error("error result should not report OK")
+	}
+}
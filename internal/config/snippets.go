@@ -0,0 +1,168 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Snippet is a named command template. Parameters are written {{name}} or
+// {{name:default}} and are filled in before the command is used.
+type Snippet struct {
+	Name        string `json:"name"`
+	Command     string `json:"command"`
+	Description string `json:"description,omitempty"`
+	Host        bool   `json:"-"` // stored for one host rather than globally
+}
+
+// SnippetParam is a parameter of a snippet.
+type SnippetParam struct {
+	Name    string
+	Default string
+}
+
+// snippetParamRe matches {{name}} and {{name:default}}.
+var snippetParamRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*(?::([^}]*))?\}\}`)
+
+// Params returns the snippet's parameters in order of first use. The
+// default is taken from the first occurrence that gives one.
+func (s Snippet) Params() []SnippetParam {
+	var params []SnippetParam
+	seen := map[string]int{}
+	for _, m := range snippetParamRe.FindAllStringSubmatch(s.Command, -1) {
+		if i, ok := seen[m[1]]; ok {
+			if params[i].Default == "" {
+				params[i].Default = m[2]
+			}
+			continue
+		}
+		seen[m[1]] = len(params)
+		params = append(params, SnippetParam{Name: m[1], Default: m[2]})
+	}
+	return params
+}
+
+// Expand fills in the snippet's parameters from values, using a
+// parameter's default when values has no entry for it. Values are
+// inserted as typed, without quoting.
+func (s Snippet) Expand(values map[string]string) string {
+	return snippetParamRe.ReplaceAllStringFunc(s.Command, func(p string) string {
+		m := snippetParamRe.FindStringSubmatch(p)
+		if v, ok := values[m[1]]; ok {
+			return v
+		}
+		return m[2]
+	})
+}
+
+// snippetsPath returns the snippet file for the given host key, or the
+// global one for "".
+func snippetsPath(hostKey string) string {
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, ".config", "ssh-scp")
+	if hostKey == "" {
+		return filepath.Join(dir, "snippets.json")
+	}
+	return filepath.Join(dir, "snippets", safeFileName(hostKey)+".json")
+}
+
+func loadSnippetFile(p string) ([]Snippet, error) {
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snippets []Snippet
+	if err := json.Unmarshal(data, &snippets); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(p), err)
+	}
+	return snippets, nil
+}
+
+// LoadSnippets returns the snippets of a host followed by the global ones,
+// each in file order. A host snippet hides a global one of the same name.
+// A file that cannot be read is reported but does not hide the other.
+func LoadSnippets(hostKey string) ([]Snippet, error) {
+	var host []Snippet
+	var errs []error
+	if hostKey != "" {
+		var err error
+		if host, err = loadSnippetFile(snippetsPath(hostKey)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	global, err := loadSnippetFile(snippetsPath(""))
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	names := map[string]bool{}
+	out := make([]Snippet, 0, len(host)+len(global))
+	for _, s := range host {
+		s.Host = true
+		names[s.Name] = true
+		out = append(out, s)
+	}
+	for _, s := range global {
+		if !names[s.Name] {
+			out = append(out, s)
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// SaveSnippet adds s to the host's snippets, or to the global ones when
+// hostKey is "", replacing a snippet of the same name there.
+func SaveSnippet(hostKey string, s Snippet) error {
+	return updateSnippets(hostKey, func(snippets []Snippet) []Snippet {
+		for i := range snippets {
+			if snippets[i].Name == s.Name {
+				snippets[i] = s
+				return snippets
+			}
+		}
+		return append(snippets, s)
+	})
+}
+
+// DeleteSnippet removes the named snippet from the host's snippets, or
+// from the global ones when hostKey is "".
+func DeleteSnippet(hostKey, name string) error {
+	return updateSnippets(hostKey, func(snippets []Snippet) []Snippet {
+		out := snippets[:0]
+		for _, s := range snippets {
+			if s.Name != name {
+				out = append(out, s)
+			}
+		}
+		return out
+	})
+}
+
+// updateSnippets rewrites one snippet file with the result of change.
+func updateSnippets(hostKey string, change func([]Snippet) []Snippet) error {
+	p := snippetsPath(hostKey)
+	snippets, err := loadSnippetFile(p)
+	if err != nil {
+		// Refuse to overwrite a file someone may have mistyped by hand.
+		return err
+	}
+	snippets = change(snippets)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snippets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		return err
+	}
+	FixOwnership(p)
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSnippetParams(t *testing.T) {
+	s := Snippet{Command: "journalctl -u {{unit}} --since '{{since:1 hour ago}}' | grep {{ pattern }} | head -{{n:50}} # {{unit}}"}
+	params := s.Params()
+	want := []SnippetParam{{"unit", ""}, {"since", "1 hour ago"}, {"pattern", ""}, {"n", "50"}}
+	if len(params) != len(want) {
+		t.Fatalf("Params() = %+v, want %+v", params, want)
+	}
+	for i := range want {
+		if params[i] != want[i] {
+			t.Errorf("param %d = %+v, want %+v", i, params[i], want[i])
+		}
+	}
+
+	got := s.Expand(map[string]string{"unit": "nginx", "pattern": "error"})
+	if want := "journalctl -u nginx --since '1 hour ago' | grep error | head -50 # nginx"; got != want {
+		t.Errorf("Expand() = %q, want %q", got, want)
+	}
+}
+
+func TestSnippetParamsLaterDefault(t *testing.T) {
+	params := Snippet{Command: "echo {{x}} {{x:5}}"}.Params()
+	if len(params) != 1 || params[0].Default != "5" {
+		t.Errorf("Params() = %+v, want x with the default 5", params)
+	}
+	if got := (Snippet{Command: "echo {{x}} {{ 1bad }}"}).Expand(map[string]string{"x": "hi"}); got != "echo hi {{ 1bad }}" {
+		t.Errorf("Expand() = %q, want text that is not a parameter left alone", got)
+	}
+}
+
+func TestSnippetsHostAndGlobal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if snippets, err := LoadSnippets("u@h:22"); err != nil || len(snippets) != 0 {
+		t.Fatalf("LoadSnippets() = %v, %v; want none", snippets, err)
+	}
+	for _, s := range []Snippet{{Name: "logs", Command: "journalctl -u {{unit}}"}, {Name: "disk", Command: "df -h"}} {
+		if err := SaveSnippet("", s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveSnippet("u@h:22", Snippet{Name: "logs", Command: "tail -f /var/log/app.log"}); err != nil {
+		t.Fatal(err)
+	}
+
+	snippets, err := LoadSnippets("u@h:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snippets) != 2 || !snippets[0].Host || snippets[0].Command != "tail -f /var/log/app.log" || snippets[1].Name != "disk" {
+		t.Errorf("snippets = %+v, want the host's logs hiding the global one, then disk", snippets)
+	}
+	if other, _ := LoadSnippets("u@other:22"); len(other) != 2 || other[0].Host {
+		t.Errorf("another host's snippets = %+v, want only the global ones", other)
+	}
+
+	if err := SaveSnippet("", Snippet{Name: "disk", Command: "df -hT"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteSnippet("u@h:22", "logs"); err != nil {
+		t.Fatal(err)
+	}
+	snippets, _ = LoadSnippets("u@h:22")
+	if len(snippets) != 2 || snippets[0].Name != "logs" || snippets[0].Host || snippets[1].Command != "df -hT" {
+		t.Errorf("snippets = %+v, want the global ones with disk replaced", snippets)
+	}
+}
+
+func TestSnippetsCorrupt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SaveSnippet("u@h:22", Snippet{Name: "a", Command: "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(snippetsPath(""), []byte("["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	snippets, err := LoadSnippets("u@h:22")
+	if err == nil || len(snippets) != 1 {
+		t.Errorf("LoadSnippets() = %v, %v; want the host snippet and an error", snippets, err)
+	}
+	if err := SaveSnippet("", Snippet{Name: "b", Command: "pwd"}); err == nil {
+		t.Error("a corrupt snippet file should not be overwritten")
+	}
+}
@@ -10,16 +10,18 @@ import (
 
 // SSHHost represents a single Host block from ~/.ssh/config.
 type SSHHost struct {
-	Alias                 string // the Host alias (e.g. "myserver")
-	HostName              string // HostName directive (actual hostname / IP)
-	Port                  string // Port directive (default "22")
-	User                  string // User directive
-	IdentityFile          string // IdentityFile path (~ expanded)
-	HostKeyAlgorithms     string // HostKeyAlgorithms directive (comma-separated)
-	PubkeyAcceptedTypes   string // PubkeyAcceptedKeyTypes / PubkeyAcceptedAlgorithms
-	StrictHostKeyChecking string // StrictHostKeyChecking (yes/no/ask)
-	UserKnownHostsFile    string // UserKnownHostsFile path
-	ProxyJump             string // ProxyJump directive (user@host:port)
+	Alias                 string   // the Host alias (e.g. "myserver")
+	HostName              string   // HostName directive (actual hostname / IP)
+	Port                  string   // Port directive (default "22")
+	User                  string   // User directive
+	IdentityFile          string   // IdentityFile path (~ expanded)
+	HostKeyAlgorithms     string   // HostKeyAlgorithms directive (comma-separated)
+	PubkeyAcceptedTypes   string   // PubkeyAcceptedKeyTypes / PubkeyAcceptedAlgorithms
+	StrictHostKeyChecking string   // StrictHostKeyChecking (yes/no/ask)
+	UserKnownHostsFile    string   // UserKnownHostsFile path
+	ProxyJump             string   // ProxyJump directive (user@host:port)
+	SendEnv               []string // SendEnv patterns in order; a leading - removes patterns
+	SetEnv                []string // SetEnv NAME=VALUE pairs in order
 }
 
 // DisplayHost returns the effective hostname (HostName if set, otherwise Alias).
@@ -48,6 +50,8 @@ func (h SSHHost) ToConnection() Connection {
 		StrictHostKeyChecking: h.StrictHostKeyChecking,
 		UserKnownHostsFile:    h.UserKnownHostsFile,
 		ProxyJump:             h.ProxyJump,
+		SendEnv:               h.SendEnv,
+		SetEnv:                h.SetEnv,
 	}
 }
 
@@ -144,6 +148,14 @@ func ParseSSHConfig(r io.Reader) []SSHHost {
 			if current != nil {
 				current.ProxyJump = value
 			}
+		case "sendenv":
+			if current != nil {
+				current.SendEnv = append(current.SendEnv, strings.Fields(value)...)
+			}
+		case "setenv":
+			if current != nil {
+				current.SetEnv = append(current.SetEnv, splitQuoted(value)...)
+			}
 		}
 	}
 
@@ -196,6 +208,10 @@ func applyDefaults(dst, defaults *SSHHost) {
 	if dst.ProxyJump == "" && defaults.ProxyJump != "" {
 		dst.ProxyJump = defaults.ProxyJump
 	}
+	// Unlike the other options, ssh adds up SendEnv and SetEnv from every
+	// matching block; the host's own SetEnv values win.
+	dst.SendEnv = append(dst.SendEnv, defaults.SendEnv...)
+	dst.SetEnv = append(dst.SetEnv, defaults.SetEnv...)
 }
 
 // MatchSSHHost finds the first SSHHost whose Alias or HostName matches the
@@ -211,25 +227,47 @@ func MatchSSHHost(hosts []SSHHost, hostname string) *SSHHost {
 	return nil
 }
 
-// splitSSHConfigLine splits a line like "HostName example.com" or
-// "HostName=example.com" into key and value.
+// splitSSHConfigLine splits a line like "HostName example.com",
+// "HostName=example.com" or "HostName = example.com" into key and value.
+// Only the first = after the key is a delimiter, so values such as
+// "SetEnv FOO=bar" keep theirs.
 func splitSSHConfigLine(line string) (string, string) {
-	// Handle "Key=Value" form.
-	if idx := strings.IndexByte(line, '='); idx >= 0 {
-		key := strings.TrimSpace(line[:idx])
-		val := strings.TrimSpace(line[idx+1:])
-		return key, val
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return line, ""
 	}
+	key := line[:end]
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+	return key, strings.TrimSpace(rest)
+}
 
-	// Handle "Key Value" form — split on first whitespace.
-	parts := strings.SplitN(line, " ", 2)
-	if len(parts) < 2 {
-		parts = strings.SplitN(line, "\t", 2)
+// splitQuoted splits a SetEnv value into its NAME=VALUE words. Double
+// quotes group words containing spaces and are removed.
+func splitQuoted(s string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
 	}
-	if len(parts) < 2 {
-		return parts[0], ""
+	if inWord {
+		words = append(words, word.String())
 	}
-	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	return words
 }
 
 // isWildcard returns true if the host alias contains glob characters.
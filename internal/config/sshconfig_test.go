@@ -390,6 +390,39 @@ Host myserver
 	}
 }
 
+func TestParseSSHConfigEnv(t *testing.T) {
+	input := `
+Host app
+  User deploy
+  SendEnv EDITOR
+  SendEnv -LC_* GIT_*
+  SetEnv APP_ENV=staging GREETING="hello world"
+  SetEnv = TZ=UTC
+
+Host *
+  SendEnv LANG
+  SetEnv APP_ENV=prod
+`
+	hosts := ParseSSHConfig(strings.NewReader(input))
+	if len(hosts) != 1 {
+		t.Fatalf("expected 1 host, got %d", len(hosts))
+	}
+	c := hosts[0].ToConnection()
+	if got := strings.Join(c.SendEnv, " "); got != "EDITOR -LC_* GIT_* LANG" {
+		t.Errorf("SendEnv = %q", got)
+	}
+	if got := strings.Join(c.SetEnv, "|"); got != "APP_ENV=staging|GREETING=hello world|TZ=UTC|APP_ENV=prod" {
+		t.Errorf("SetEnv = %q", got)
+	}
+}
+
+func TestSplitSSHConfigLineSpacedEquals(t *testing.T) {
+	key, val := splitSSHConfigLine("Port = 2222")
+	if key != "Port" || val != "2222" {
+		t.Errorf("got (%q, %q)", key, val)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // MatchSSHHost
 // ---------------------------------------------------------------------------
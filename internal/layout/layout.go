@@ -0,0 +1,422 @@
+// Package layout is the tiling layout of a connection tab: a binary tree of
+// splits whose leaves are panes showing the terminal, a file panel, the
+// editor or the preview. It only does geometry and focus; drawing the
+// panes is up to the caller.
+package layout
+
+import "math"
+
+// Kind is what a pane shows.
+type Kind string
+
+// Pane contents.
+const (
+	Terminal Kind = "terminal"
+	Local    Kind = "local"
+	Remote   Kind = "remote"
+	Editor   Kind = "editor"
+	Preview  Kind = "preview"
+)
+
+// Kinds lists every pane kind in cycling order.
+var Kinds = []Kind{Terminal, Local, Remote, Editor, Preview}
+
+// Split is how a node divides its area between its children.
+type Split string
+
+// Split directions.
+const (
+	Horizontal Split = "h" // children side by side
+	Vertical   Split = "v" // children stacked
+)
+
+// Direction is used to move the focus and dividers.
+type Direction int
+
+// Directions.
+const (
+	Left Direction = iota
+	Right
+	Up
+	Down
+)
+
+const (
+	minRatio = 0.1
+	maxRatio = 0.9
+	// ResizeStep is how far Resize moves a divider.
+	ResizeStep = 0.05
+)
+
+// Node is a pane (Kind set) or a split with two children.
+type Node struct {
+	Kind   Kind    `json:"kind,omitempty"`
+	Split  Split   `json:"split,omitempty"`
+	Ratio  float64 `json:"ratio,omitempty"` // share of First; 0.5 when unset
+	First  *Node   `json:"first,omitempty"`
+	Second *Node   `json:"second,omitempty"`
+
+	parent *Node
+}
+
+// IsPane reports whether n is a leaf.
+func (n *Node) IsPane() bool {
+	return n.Split == ""
+}
+
+// Clone returns a deep copy of n without parent links, for saving.
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	return &Node{Kind: n.Kind, Split: n.Split, Ratio: n.Ratio, First: n.First.Clone(), Second: n.Second.Clone()}
+}
+
+// valid reports whether n is a well-formed tree with known pane kinds.
+func (n *Node) valid() bool {
+	if n == nil {
+		return false
+	}
+	if n.IsPane() {
+		for _, k := range Kinds {
+			if n.Kind == k {
+				return n.First == nil && n.Second == nil
+			}
+		}
+		return false
+	}
+	return (n.Split == Horizontal || n.Split == Vertical) && n.First.valid() && n.Second.valid()
+}
+
+func (n *Node) ratio() float64 {
+	if n.Ratio <= 0 {
+		return 0.5
+	}
+	return math.Min(math.Max(n.Ratio, minRatio), maxRatio)
+}
+
+// Default is the terminal above the local and remote panels.
+func Default() *Node {
+	return &Node{Split: Vertical, Ratio: 0.5,
+		First: &Node{Kind: Terminal},
+		Second: &Node{Split: Horizontal, Ratio: 0.5,
+			First:  &Node{Kind: Local},
+			Second: &Node{Kind: Remote},
+		},
+	}
+}
+
+// Rect is a pane's area in cells.
+type Rect struct {
+	X, Y, W, H int
+}
+
+// Layout is a tree of panes with one focused pane.
+type Layout struct {
+	root  *Node
+	focus *Node
+}
+
+// New returns a layout of a copy of root, or the default layout when root
+// is nil or malformed. The first terminal pane is focused, else the first
+// pane.
+func New(root *Node) *Layout {
+	if !root.valid() {
+		root = Default()
+	}
+	l := &Layout{root: root.Clone()}
+	l.root.link(nil)
+	l.focus = l.Panes()[0]
+	l.FocusKind(Terminal)
+	return l
+}
+
+func (n *Node) link(parent *Node) {
+	n.parent = parent
+	if !n.IsPane() {
+		n.First.link(n)
+		n.Second.link(n)
+	}
+}
+
+// Root returns a copy of the tree for saving.
+func (l *Layout) Root() *Node {
+	return l.root.Clone()
+}
+
+// Focused returns the focused pane.
+func (l *Layout) Focused() *Node {
+	return l.focus
+}
+
+// FocusedKind returns what the focused pane shows.
+func (l *Layout) FocusedKind() Kind {
+	return l.focus.Kind
+}
+
+// Panes returns the panes from left to right and top to bottom.
+func (l *Layout) Panes() []*Node {
+	var out []*Node
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if n.IsPane() {
+			out = append(out, n)
+			return
+		}
+		walk(n.First)
+		walk(n.Second)
+	}
+	walk(l.root)
+	return out
+}
+
+// Has reports whether a pane shows k.
+func (l *Layout) Has(k Kind) bool {
+	return l.Find(k) != nil
+}
+
+// Find returns the first pane showing k, or nil.
+func (l *Layout) Find(k Kind) *Node {
+	for _, p := range l.Panes() {
+		if p.Kind == k {
+			return p
+		}
+	}
+	return nil
+}
+
+// FocusKind focuses the first pane showing k and reports whether there is
+// one.
+func (l *Layout) FocusKind(k Kind) bool {
+	if l.focus.Kind == k {
+		return true
+	}
+	if p := l.Find(k); p != nil {
+		l.focus = p
+		return true
+	}
+	return false
+}
+
+// FocusNext focuses the next pane in reading order, wrapping around.
+func (l *Layout) FocusNext() {
+	panes := l.Panes()
+	for i, p := range panes {
+		if p == l.focus {
+			l.focus = panes[(i+1)%len(panes)]
+			return
+		}
+	}
+}
+
+// Rects returns the area of every pane in a width×height layout.
+func (l *Layout) Rects(width, height int) map[*Node]Rect {
+	out := map[*Node]Rect{}
+	var place func(n *Node, r Rect)
+	place = func(n *Node, r Rect) {
+		if n.IsPane() {
+			out[n] = r
+			return
+		}
+		a, b := n.divide(r)
+		place(n.First, a)
+		place(n.Second, b)
+	}
+	place(l.root, Rect{W: width, H: height})
+	return out
+}
+
+// Render draws a width×height layout: pane draws each pane at its size and
+// join puts the drawings of a split's two halves together.
+func (l *Layout) Render(width, height int, pane func(n *Node, width, height int) string, join func(s Split, first, second string) string) string {
+	var render func(n *Node, r Rect) string
+	render = func(n *Node, r Rect) string {
+		if n.IsPane() {
+			return pane(n, r.W, r.H)
+		}
+		a, b := n.divide(r)
+		return join(n.Split, render(n.First, a), render(n.Second, b))
+	}
+	return render(l.root, Rect{W: width, H: height})
+}
+
+// divide splits r between the children of split n.
+func (n *Node) divide(r Rect) (a, b Rect) {
+	a, b = r, r
+	if n.Split == Horizontal {
+		a.W = splitSize(r.W, n.ratio())
+		b.X, b.W = r.X+a.W, r.W-a.W
+	} else {
+		a.H = splitSize(r.H, n.ratio())
+		b.Y, b.H = r.Y+a.H, r.H-a.H
+	}
+	return a, b
+}
+
+// splitSize returns the first child's share of size, leaving each child at
+// least one cell where possible.
+func splitSize(size int, ratio float64) int {
+	n := int(math.Round(float64(size) * ratio))
+	return min(max(n, min(1, size)), max(size-1, 0))
+}
+
+// FocusDir focuses the nearest pane in direction d of the focused one in a
+// width×height layout and reports whether there was one.
+func (l *Layout) FocusDir(d Direction, width, height int) bool {
+	rects := l.Rects(width, height)
+	f := rects[l.focus]
+	var best *Node
+	bestScore := math.MaxInt
+	for n, r := range rects {
+		if n == l.focus {
+			continue
+		}
+		var gap, offset int
+		switch d {
+		case Left:
+			gap, offset = f.X-(r.X+r.W), overlap(f.Y, f.H, r.Y, r.H)
+		case Right:
+			gap, offset = r.X-(f.X+f.W), overlap(f.Y, f.H, r.Y, r.H)
+		case Up:
+			gap, offset = f.Y-(r.Y+r.H), overlap(f.X, f.W, r.X, r.W)
+		case Down:
+			gap, offset = r.Y-(f.Y+f.H), overlap(f.X, f.W, r.X, r.W)
+		}
+		if gap < 0 || offset < 0 {
+			continue
+		}
+		// Prefer the closest pane, then the one nearest the focused
+		// pane's top or left edge.
+		if score := gap*10000 + offset; score < bestScore || score == bestScore && less(r, rects[best]) {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return false
+	}
+	l.focus = best
+	return true
+}
+
+// overlap returns how far the span [b, b+bl) starts from a when it
+// overlaps [a, a+al), or -1 when the spans do not overlap.
+func overlap(a, al, b, bl int) int {
+	if b >= a+al || a >= b+bl {
+		return -1
+	}
+	if b > a {
+		return b - a
+	}
+	return 0
+}
+
+func less(a, b Rect) bool {
+	return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+}
+
+// Focus focuses pane n.
+func (l *Layout) Focus(n *Node) {
+	if n != nil && n.IsPane() {
+		l.focus = n
+	}
+}
+
+// Unshown returns the first kind no pane shows, or the focused pane's kind
+// when every kind is shown.
+func (l *Layout) Unshown() Kind {
+	for _, k := range Kinds {
+		if !l.Has(k) {
+			return k
+		}
+	}
+	return l.focus.Kind
+}
+
+// SplitFocused divides the focused pane in two, keeping it in the first
+// half and adding a pane showing k in the second. The new pane is focused
+// and returned.
+func (l *Layout) SplitFocused(s Split, k Kind) *Node {
+	old := l.focus
+	added := &Node{Kind: k}
+	split := &Node{Split: s, Ratio: 0.5, First: old, Second: added, parent: old.parent}
+	l.replace(old, split)
+	old.parent, added.parent = split, split
+	l.focus = added
+	return added
+}
+
+// Close removes pane n; its sibling takes its place, and the focus when n
+// was focused. The last pane cannot be closed.
+func (l *Layout) Close(n *Node) bool {
+	p := n.parent
+	if p == nil {
+		return false
+	}
+	sibling := p.First
+	if sibling == n {
+		sibling = p.Second
+	}
+	l.replace(p, sibling)
+	sibling.parent = p.parent
+	if l.focus == n {
+		l.focus = sibling
+		for !l.focus.IsPane() {
+			l.focus = l.focus.First
+		}
+	}
+	return true
+}
+
+// CloseFocused removes the focused pane.
+func (l *Layout) CloseFocused() bool {
+	return l.Close(l.focus)
+}
+
+// replace puts n where old is in the tree.
+func (l *Layout) replace(old, n *Node) {
+	switch p := old.parent; {
+	case p == nil:
+		l.root = n
+	case p.First == old:
+		p.First = n
+	default:
+		p.Second = n
+	}
+}
+
+// CycleFocused changes the focused pane to show the next kind.
+func (l *Layout) CycleFocused() {
+	for i, k := range Kinds {
+		if k == l.focus.Kind {
+			l.focus.Kind = Kinds[(i+1)%len(Kinds)]
+			return
+		}
+	}
+}
+
+// Resize moves the divider nearest to the focused pane in direction d:
+// Left and Right move the closest side-by-side divider, Up and Down the
+// closest stacked one. It reports whether a divider moved.
+func (l *Layout) Resize(d Direction) bool {
+	want, step := Horizontal, ResizeStep
+	switch d {
+	case Left:
+		step = -step
+	case Up:
+		want, step = Vertical, -step
+	case Down:
+		want = Vertical
+	}
+	for n := l.focus.parent; n != nil; n = n.parent {
+		if n.Split != want {
+			continue
+		}
+		r := math.Min(math.Max(n.ratio()+step, minRatio), maxRatio)
+		if r == n.ratio() {
+			return false
+		}
+		n.Ratio = math.Round(r*100) / 100
+		return true
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func kinds(l *Layout) []Kind {
+	var out []Kind
+	for _, p := range l.Panes() {
+		out = append(out, p.Kind)
+	}
+	return out
+}
+
+func equalKinds(a, b []Kind) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNewDefaultsAndFocus(t *testing.T) {
+	l := New(nil)
+	if got := kinds(l); !equalKinds(got, []Kind{Terminal, Local, Remote}) {
+		t.Errorf("default panes = %v", got)
+	}
+	if l.FocusedKind() != Terminal {
+		t.Errorf("focus = %v, want the terminal", l.FocusedKind())
+	}
+	for _, bad := range []*Node{
+		{Kind: "bogus"},
+		{Split: Horizontal, First: &Node{Kind: Local}},
+		{Split: "x", First: &Node{Kind: Local}, Second: &Node{Kind: Remote}},
+	} {
+		if got := kinds(New(bad)); !equalKinds(got, []Kind{Terminal, Local, Remote}) {
+			t.Errorf("New(%+v) panes = %v, want the default", bad, got)
+		}
+	}
+	l = New(&Node{Split: Horizontal, First: &Node{Kind: Local}, Second: &Node{Kind: Editor}})
+	if l.FocusedKind() != Local {
+		t.Errorf("without a terminal the first pane should be focused, got %v", l.FocusedKind())
+	}
+}
+
+func TestRects(t *testing.T) {
+	l := New(nil)
+	rects := l.Rects(80, 21)
+	panes := l.Panes()
+	want := []Rect{{0, 0, 80, 11}, {0, 11, 40, 10}, {40, 11, 40, 10}}
+	for i, p := range panes {
+		if rects[p] != want[i] {
+			t.Errorf("%s rect = %+v, want %+v", p.Kind, rects[p], want[i])
+		}
+	}
+	// Tiny areas still give every pane a cell where possible.
+	rects = l.Rects(1, 2)
+	if r := rects[panes[0]]; r.H != 1 {
+		t.Errorf("terminal rect in a 1x2 area = %+v", r)
+	}
+}
+
+func TestFocusMoves(t *testing.T) {
+	l := New(nil)
+	if !l.FocusDir(Down, 80, 20) || l.FocusedKind() != Local {
+		t.Fatalf("down from the terminal should reach the left pane below, got %v", l.FocusedKind())
+	}
+	if !l.FocusDir(Right, 80, 20) || l.FocusedKind() != Remote {
+		t.Fatalf("right should reach the remote pane, got %v", l.FocusedKind())
+	}
+	if l.FocusDir(Right, 80, 20) {
+		t.Error("there is nothing right of the remote pane")
+	}
+	if !l.FocusDir(Up, 80, 20) || l.FocusedKind() != Terminal {
+		t.Errorf("up should reach the terminal, got %v", l.FocusedKind())
+	}
+	l.FocusNext()
+	l.FocusNext()
+	l.FocusNext()
+	if l.FocusedKind() != Terminal {
+		t.Errorf("FocusNext should wrap around, got %v", l.FocusedKind())
+	}
+	if !l.FocusKind(Remote) || l.FocusKind(Editor) || l.FocusedKind() != Remote {
+		t.Error("FocusKind should focus an existing pane only")
+	}
+}
+
+func TestSplitCloseCycle(t *testing.T) {
+	l := New(nil)
+	term := l.Focused()
+	if added := l.SplitFocused(Horizontal, l.Unshown()); added != l.Focused() {
+		t.Error("the new pane should be focused")
+	}
+	if got := kinds(l); !equalKinds(got, []Kind{Terminal, Editor, Local, Remote}) || l.FocusedKind() != Editor {
+		t.Fatalf("after split panes = %v focus %v", got, l.FocusedKind())
+	}
+	l.CycleFocused()
+	if l.FocusedKind() != Preview {
+		t.Errorf("cycling the editor pane should show the preview, got %v", l.FocusedKind())
+	}
+	if !l.CloseFocused() || l.Focused() != term {
+		t.Fatalf("closing should focus the sibling, got %v", l.FocusedKind())
+	}
+	if got := kinds(l); !equalKinds(got, []Kind{Terminal, Local, Remote}) {
+		t.Errorf("after close panes = %v", got)
+	}
+	// Closing a pane whose sibling is a split keeps the split.
+	if !l.CloseFocused() || !equalKinds(kinds(l), []Kind{Local, Remote}) || l.FocusedKind() != Local {
+		t.Errorf("after closing the terminal panes = %v focus %v", kinds(l), l.FocusedKind())
+	}
+	if l.Root().Split != Horizontal {
+		t.Error("the remaining split should become the root")
+	}
+	l.CloseFocused()
+	if l.CloseFocused() {
+		t.Error("the last pane cannot be closed")
+	}
+}
+
+func TestCloseUnfocused(t *testing.T) {
+	l := New(nil)
+	remote := l.Find(Remote)
+	if !l.Close(remote) || l.FocusedKind() != Terminal || l.Has(Remote) {
+		t.Errorf("closing another pane should keep the focus, panes %v focus %v", kinds(l), l.FocusedKind())
+	}
+	if r := l.Rects(10, 10)[l.Find(Local)]; r != (Rect{0, 5, 10, 5}) {
+		t.Errorf("the local pane should take the remote pane's place, rect %+v", r)
+	}
+}
+
+func TestRender(t *testing.T) {
+	l := New(nil)
+	got := l.Render(80, 21, func(n *Node, w, h int) string {
+		return fmt.Sprintf("%s:%dx%d", n.Kind, w, h)
+	}, func(s Split, a, b string) string {
+		return "(" + a + " " + string(s) + " " + b + ")"
+	})
+	if want := "(terminal:80x11 v (local:40x10 h remote:40x10))"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestResize(t *testing.T) {
+	l := New(nil)
+	if !l.Resize(Down) || l.Root().Ratio != 0.55 {
+		t.Errorf("down should grow the terminal, ratio %v", l.Root().Ratio)
+	}
+	if l.Resize(Right) {
+		t.Error("the terminal has no side-by-side divider")
+	}
+	l.FocusKind(Remote)
+	if !l.Resize(Left) || l.Root().Second.Ratio != 0.45 {
+		t.Errorf("left should move the panel divider, ratio %v", l.Root().Second.Ratio)
+	}
+	if !l.Resize(Up) || l.Root().Ratio != 0.5 {
+		t.Error("up from a lower pane should move the stacked divider")
+	}
+	for l.Resize(Left) {
+	}
+	if r := l.Root().Second.Ratio; r != minRatio {
+		t.Errorf("ratio should stop at %v, got %v", minRatio, r)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	l := New(nil)
+	l.FocusKind(Local)
+	l.SplitFocused(Vertical, Preview)
+	l.Resize(Down)
+	data, err := json.Marshal(l.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n Node
+	if err := json.Unmarshal(data, &n); err != nil {
+		t.Fatal(err)
+	}
+	got := New(&n)
+	if !equalKinds(kinds(got), kinds(l)) || got.Root().Second.First.Ratio != 0.55 {
+		t.Errorf("restored layout %s differs: %v", data, kinds(got))
+	}
+}
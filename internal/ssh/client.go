@@ -9,7 +9,9 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bramvdbogaerde/go-scp"
@@ -19,11 +21,21 @@ import (
 
 // RemoteFile represents a file entry on the remote filesystem.
 type RemoteFile struct {
-	Name    string
-	Size    int64
-	Mode    os.FileMode
-	ModTime time.Time
-	IsDir   bool
+	Name       string
+	Size       int64
+	Mode       os.FileMode // permission, special and file type bits
+	ModTime    time.Time
+	IsDir      bool
+	Owner      string
+	Group      string
+	LinkTarget string // symlink target as shown by ls; empty for other types
+	LinkDir    bool   // symlink whose target is a directory
+	LinkBroken bool   // symlink whose target does not exist
+}
+
+// IsLink reports whether f is a symbolic link.
+func (f RemoteFile) IsLink() bool {
+	return f.Mode&os.ModeSymlink != 0
 }
 
 // Client wraps an SSH connection.
@@ -31,15 +43,33 @@ type Client struct {
 	client     *ssh.Client
 	config     *ssh.ClientConfig
 	address    string
-	jumpClient *ssh.Client // non-nil when connected via a jump host
+	jumpClient *ssh.Client       // non-nil when connected via a jump host
+	limiter    RateLimiter       // throttles every transfer; the zero value is unlimited
+	env        map[string]string // set in every session; see sessionEnv
+
+	compressMu  sync.Mutex
+	compressors []Compression // remote compressors; nil until detected
+
+	sudoMu sync.Mutex
+	sudo   sudoState // sudo mode for file operations and transfers
 }
 
 // ConnectOptions holds per-connection SSH options parsed from ~/.ssh/config.
 type ConnectOptions struct {
-	HostKeyAlgorithms     string // comma-separated list, may start with +
-	PubkeyAcceptedTypes   string // comma-separated list, may start with +
-	StrictHostKeyChecking string // "yes", "no", or "ask"
-	UserKnownHostsFile    string // path (e.g. /dev/null)
+	HostKeyAlgorithms     string   // comma-separated list, may start with +
+	PubkeyAcceptedTypes   string   // comma-separated list, may start with +
+	StrictHostKeyChecking string   // "yes", "no", or "ask"
+	UserKnownHostsFile    string   // path (e.g. /dev/null)
+	SendEnv               []string // SendEnv patterns for local variables to pass
+	SetEnv                []string // SetEnv NAME=VALUE pairs
+}
+
+// environment returns the variables the options ask to set in sessions.
+func (o *ConnectOptions) environment() map[string]string {
+	if o == nil {
+		return sessionEnv(nil, nil, os.Environ())
+	}
+	return sessionEnv(o.SendEnv, o.SetEnv, os.Environ())
 }
 
 // New creates a new SSH client connected to host:port with the given auth methods.
@@ -77,6 +107,7 @@ func New(host, port, username string, authMethods []ssh.AuthMethod, hkCallback s
 		client:  client,
 		config:  cfg,
 		address: address,
+		env:     opts.environment(),
 	}, nil
 }
 
@@ -124,6 +155,7 @@ func NewViaJump(jumpSSHClient *ssh.Client, host, port, username string, authMeth
 		config:     cfg,
 		address:    address,
 		jumpClient: jumpSSHClient,
+		env:        opts.environment(),
 	}, nil
 }
 
@@ -242,14 +274,15 @@ func (c *Client) Close() error {
 	return err
 }
 
-// NewSession creates a new SSH session.
+// NewSession creates a new SSH session with the connection's environment.
 func (c *Client) NewSession() (*ssh.Session, error) {
-	return c.client.NewSession()
+	return c.newSession()
 }
 
-// StartTerminal starts an interactive PTY session over the given session,
-// wiring stdin/stdout/stderr to the provided reader/writers.
-func (c *Client) StartTerminal(session *ssh.Session, stdin io.Reader, stdout, stderr io.Writer) error {
+// StartTerminal starts an interactive PTY session of width x height cells
+// (80x40 when unknown) over the given session, wiring stdin/stdout/stderr
+// to the provided reader/writers.
+func (c *Client) StartTerminal(session *ssh.Session, width, height int, stdin io.Reader, stdout, stderr io.Writer) error {
 	session.Stdin = stdin
 	session.Stdout = stdout
 	session.Stderr = stderr
@@ -259,7 +292,10 @@ func (c *Client) StartTerminal(session *ssh.Session, stdin io.Reader, stdout, st
 		ssh.TTY_OP_ISPEED: 14400,
 		ssh.TTY_OP_OSPEED: 14400,
 	}
-	if err := session.RequestPty("xterm-256color", 40, 80, modes); err != nil {
+	if width <= 0 || height <= 0 {
+		width, height = 80, 40
+	}
+	if err := session.RequestPty(c.Term(), height, width, modes); err != nil {
 		return fmt.Errorf("request pty: %w", err)
 	}
 	if err := session.Shell(); err != nil {
@@ -273,27 +309,39 @@ func (c *Client) ResizePty(session *ssh.Session, width, height int) error {
 	return session.WindowChange(height, width)
 }
 
+// SetRateLimit limits uploads and downloads to bytesPerSec bytes per second
+// (0 = unlimited). The change also applies to transfers already in
+// progress, including those started while unlimited.
+func (c *Client) SetRateLimit(bytesPerSec int64) {
+	c.limiter.SetRate(bytesPerSec)
+}
+
+// RateLimit returns the current transfer limit in bytes per second
+// (0 = unlimited).
+func (c *Client) RateLimit() int64 {
+	return c.limiter.Rate()
+}
+
+// transferPassThru returns an scp.PassThru that applies the client's rate
+// limit to a transfer stream.
+func (c *Client) transferPassThru() scp.PassThru {
+	return func(r io.Reader, _ int64) io.Reader {
+		return c.limiter.Reader(r)
+	}
+}
+
 // ListDir lists the contents of a remote directory.
-func (c *Client) ListDir(path string) (files []RemoteFile, retErr error) {
+func (c *Client) ListDir(path string) ([]RemoteFile, error) {
 	log.Printf("[SSH] listing remote dir: %s", path)
-	session, err := c.client.NewSession()
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if cErr := session.Close(); cErr != nil && !errors.Is(cErr, io.EOF) {
-			retErr = errors.Join(retErr, fmt.Errorf("close session: %w", cErr))
-		}
-	}()
 
-	// Use printf %q to safely single-quote the path, preventing shell injection.
-	escapedPath := shellQuote(path)
-	cmd := fmt.Sprintf("ls -la --time-style='+%%Y-%%m-%%d %%H:%%M:%%S' %s 2>/dev/null || ls -la %s", escapedPath, escapedPath)
-	out, err := session.Output(cmd)
+	out, err := c.run(listCmd(path), nil)
 	if err != nil {
 		return nil, err
 	}
-	return parseLS(string(out)), nil
+	ls, links := splitListing(string(out))
+	files := parseLS(ls)
+	applyLinkStatus(files, links)
+	return files, nil
 }
 
 // UploadFile uploads a local file to the remote destination path.
@@ -315,12 +363,16 @@ func (c *Client) UploadFile(localPath, remotePath string) (retErr error) {
 		}
 	}()
 
+	if c.SudoEnabled() {
+		return c.sudoUpload(f, remotePath)
+	}
+
 	info, err := f.Stat()
 	if err != nil {
 		return err
 	}
 
-	return scpClient.CopyFile(context.Background(), f, remotePath, fmt.Sprintf("0%o", info.Mode()))
+	return scpClient.CopyFromFilePassThru(context.Background(), *f, remotePath, fmt.Sprintf("0%o", info.Mode()), c.transferPassThru())
 }
 
 // DownloadFile downloads a remote file to a local destination path.
@@ -345,20 +397,17 @@ func (c *Client) DownloadFile(remotePath, localDir string) (retErr error) {
 		}
 	}()
 
-	return scpClient.CopyFromRemote(context.Background(), f, remotePath)
+	if c.SudoEnabled() {
+		return c.sudoDownload(remotePath, f)
+	}
+	return scpClient.CopyFromRemotePassThru(context.Background(), f, remotePath, c.transferPassThru())
 }
 
 // ReadFile reads the contents of a remote file via cat.
 func (c *Client) ReadFile(path string) (string, error) {
 	log.Printf("[SSH] reading remote file: %s", path)
-	session, err := c.client.NewSession()
-	if err != nil {
-		return "", err
-	}
-	defer func() { _ = session.Close() }()
-
 	cmd := fmt.Sprintf("cat %s", shellQuote(path))
-	out, err := session.Output(cmd)
+	out, err := c.run(cmd, nil)
 	if err != nil {
 		return "", fmt.Errorf("read remote file: %w", err)
 	}
@@ -368,54 +417,80 @@ func (c *Client) ReadFile(path string) (string, error) {
 // WriteFile writes content to a remote file.
 func (c *Client) WriteFile(path, content string) error {
 	log.Printf("[SSH] writing remote file: %s", path)
-	session, err := c.client.NewSession()
-	if err != nil {
-		return err
-	}
-	defer func() { _ = session.Close() }()
-
-	session.Stdin = strings.NewReader(content)
 	cmd := fmt.Sprintf("cat > %s", shellQuote(path))
-	return session.Run(cmd)
+	_, err := c.run(cmd, strings.NewReader(content))
+	return err
 }
 
 // MkDir creates a directory on the remote host.
 func (c *Client) MkDir(path string) error {
 	log.Printf("[SSH] mkdir: %s", path)
-	session, err := c.client.NewSession()
-	if err != nil {
-		return err
-	}
-	defer func() { _ = session.Close() }()
-
 	cmd := fmt.Sprintf("mkdir -p %s", shellQuote(path))
-	return session.Run(cmd)
+	_, err := c.run(cmd, nil)
+	return err
 }
 
 // Remove removes a file or directory on the remote host.
 func (c *Client) Remove(path string) error {
 	log.Printf("[SSH] remove: %s", path)
-	session, err := c.client.NewSession()
+	cmd := fmt.Sprintf("rm -rf %s", shellQuote(path))
+	_, err := c.run(cmd, nil)
+	return err
+}
+
+// Exists reports whether path exists on the remote host. A dangling
+// symlink counts as existing, since writing to it would create its target.
+func (c *Client) Exists(path string) (bool, error) {
+	q := shellQuote(path)
+	out, err := c.run(fmt.Sprintf("if [ -e %s ] || [ -L %s ]; then echo y; fi", q, q), nil)
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer func() { _ = session.Close() }()
-
-	cmd := fmt.Sprintf("rm -rf %s", shellQuote(path))
-	return session.Run(cmd)
+	return strings.TrimSpace(string(out)) == "y", nil
 }
 
 // Rename renames (moves) a file or directory on the remote host.
 func (c *Client) Rename(oldPath, newPath string) error {
 	log.Printf("[SSH] rename: %s -> %s", oldPath, newPath)
-	session, err := c.client.NewSession()
-	if err != nil {
-		return err
+	cmd := fmt.Sprintf("mv %s %s", shellQuote(oldPath), shellQuote(newPath))
+	_, err := c.run(cmd, nil)
+	return err
+}
+
+// Chmod changes the mode of a remote file. spec is passed to chmod as is,
+// so both octal ("0755") and symbolic ("u+x,go-w") modes work.
+func (c *Client) Chmod(path, spec string, recursive bool) error {
+	log.Printf("[SSH] chmod %s %s (recursive=%v)", spec, path, recursive)
+	cmd := fmt.Sprintf("chmod %s%s -- %s", recursiveFlag(recursive), shellQuote(spec), shellQuote(path))
+	_, err := c.run(cmd, nil)
+	return err
+}
+
+// Chown changes the owner and/or group of a remote file. Empty values are
+// left unchanged. Symbolic links themselves are changed, not their targets.
+func (c *Client) Chown(path, owner, group string, recursive bool) error {
+	log.Printf("[SSH] chown %s:%s %s (recursive=%v)", owner, group, path, recursive)
+	var cmd string
+	switch {
+	case owner != "" && group != "":
+		cmd = fmt.Sprintf("chown -h %s%s -- %s", recursiveFlag(recursive), shellQuote(owner+":"+group), shellQuote(path))
+	case owner != "":
+		cmd = fmt.Sprintf("chown -h %s%s -- %s", recursiveFlag(recursive), shellQuote(owner), shellQuote(path))
+	case group != "":
+		cmd = fmt.Sprintf("chgrp -h %s%s -- %s", recursiveFlag(recursive), shellQuote(group), shellQuote(path))
+	default:
+		return nil
 	}
-	defer func() { _ = session.Close() }()
+	_, err := c.run(cmd, nil)
+	return err
+}
 
-	cmd := fmt.Sprintf("mv %s %s", shellQuote(oldPath), shellQuote(newPath))
-	return session.Run(cmd)
+// recursiveFlag returns "-R " when recursive is set.
+func recursiveFlag(recursive bool) string {
+	if recursive {
+		return "-R "
+	}
+	return ""
 }
 
 // parseLS parses `ls -la` output into RemoteFile entries.
@@ -444,6 +519,12 @@ func shellQuote(s string) string {
 	return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
 }
 
+// ShellQuote quotes s for a POSIX shell, for commands typed into the
+// terminal.
+func ShellQuote(s string) string {
+	return shellQuote(s)
+}
+
 func splitLines(s string) []string {
 	var lines []string
 	start := 0
@@ -467,13 +548,30 @@ func parseLSLine(line string) *RemoteFile {
 	perm := fields[0]
 	isDir := len(perm) > 0 && perm[0] == 'd'
 
+	// The name starts after the date, which is one field with our epoch
+	// --time-style, two with an ISO one and three with the traditional
+	// format. It may contain spaces, so take the rest of the line from there.
 	name := ""
-	if len(fields) >= 9 {
-		name = fields[8]
-	} else if len(fields) >= 5 {
+	epoch := len(fields) >= 7 && isEpoch(fields[5])
+	iso := !epoch && len(fields) >= 8 && isISODate(fields[5])
+	switch {
+	case epoch:
+		name = line[fieldStart(line, 6):]
+	case iso:
+		name = line[fieldStart(line, 7):]
+	case len(fields) >= 9:
+		name = line[fieldStart(line, 8):]
+	default:
 		name = fields[len(fields)-1]
 	}
 
+	var target string
+	if len(perm) > 0 && perm[0] == 'l' {
+		if i := strings.Index(name, " -> "); i >= 0 {
+			name, target = name[:i], name[i+4:]
+		}
+	}
+
 	if name == "" {
 		return nil
 	}
@@ -483,15 +581,66 @@ func parseLSLine(line string) *RemoteFile {
 
 	mode := parsePerm(perm)
 
-	modTime := parseLSDate(fields)
+	var modTime time.Time
+	if epoch {
+		secs, _ := strconv.ParseInt(fields[5], 10, 64)
+		modTime = time.Unix(secs, 0)
+	} else if iso {
+		modTime, _ = time.ParseInLocation("2006-01-02 15:04:05", fields[5]+" "+fields[6], time.Local)
+	} else {
+		modTime = parseLSDate(fields)
+	}
 
 	return &RemoteFile{
-		Name:    name,
-		Size:    size,
-		Mode:    mode,
-		ModTime: modTime,
-		IsDir:   isDir,
+		Name:       name,
+		Size:       size,
+		Mode:       mode,
+		ModTime:    modTime,
+		IsDir:      isDir,
+		Owner:      fields[2],
+		Group:      fields[3],
+		LinkTarget: target,
+	}
+}
+
+// isEpoch reports whether s is a number of seconds, as printed by
+// --time-style=+%s.
+func isEpoch(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isISODate reports whether s looks like YYYY-MM-DD.
+func isISODate(s string) bool {
+	return len(s) == 10 && s[4] == '-' && s[7] == '-'
+}
+
+// fieldStart returns the byte offset of the n-th (0-based) whitespace
+// separated field in s, or len(s) if there are fewer fields.
+func fieldStart(s string, n int) int {
+	inField := false
+	count := -1
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\t' {
+			inField = false
+			continue
+		}
+		if !inField {
+			inField = true
+			count++
+			if count == n {
+				return i
+			}
+		}
 	}
+	return len(s)
 }
 
 func splitFields(s string) []string {
@@ -522,33 +671,56 @@ func parsePerm(perm string) os.FileMode {
 		return 0
 	}
 	var mode os.FileMode
+	switch perm[0] {
+	case 'd':
+		mode |= os.ModeDir
+	case 'l':
+		mode |= os.ModeSymlink
+	case 'p':
+		mode |= os.ModeNamedPipe
+	case 's':
+		mode |= os.ModeSocket
+	case 'c':
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 'b':
+		mode |= os.ModeDevice
+	}
 	if perm[1] == 'r' {
 		mode |= 0400
 	}
 	if perm[2] == 'w' {
 		mode |= 0200
 	}
-	if perm[3] == 'x' {
+	if perm[3] == 'x' || perm[3] == 's' {
 		mode |= 0100
 	}
+	if perm[3] == 's' || perm[3] == 'S' {
+		mode |= os.ModeSetuid
+	}
 	if perm[4] == 'r' {
 		mode |= 0040
 	}
 	if perm[5] == 'w' {
 		mode |= 0020
 	}
-	if perm[6] == 'x' {
+	if perm[6] == 'x' || perm[6] == 's' {
 		mode |= 0010
 	}
+	if perm[6] == 's' || perm[6] == 'S' {
+		mode |= os.ModeSetgid
+	}
 	if perm[7] == 'r' {
 		mode |= 0004
 	}
 	if perm[8] == 'w' {
 		mode |= 0002
 	}
-	if perm[9] == 'x' {
+	if perm[9] == 'x' || perm[9] == 't' {
 		mode |= 0001
 	}
+	if perm[9] == 't' || perm[9] == 'T' {
+		mode |= os.ModeSticky
+	}
 	return mode
 }
 
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	gossh "golang.org/x/crypto/ssh"
 )
@@ -147,8 +148,28 @@ func TestParsePermOwnerOnly(t *testing.T) {
 
 func TestParsePermDirectory(t *testing.T) {
 	mode := parsePerm("drwxr-xr-x")
-	if mode != 0755 {
-		t.Errorf("parsePerm drwxr-xr-x = %o, want 0755", mode)
+	if mode != os.ModeDir|0755 {
+		t.Errorf("parsePerm drwxr-xr-x = %v, want dir 0755", mode)
+	}
+}
+
+func TestParsePermSpecialBits(t *testing.T) {
+	cases := map[string]os.FileMode{
+		"-rwsr-xr-x": os.ModeSetuid | 0755,
+		"-rwSr--r--": os.ModeSetuid | 0644,
+		"-rwxr-sr-x": os.ModeSetgid | 0755,
+		"drwxrwxrwt": os.ModeDir | os.ModeSticky | 0777,
+		"drwxr-xr-T": os.ModeDir | os.ModeSticky | 0754,
+		"lrwxrwxrwx": os.ModeSymlink | 0777,
+		"prw-r--r--": os.ModeNamedPipe | 0644,
+		"srwxrwxrwx": os.ModeSocket | 0777,
+		"crw-rw-rw-": os.ModeDevice | os.ModeCharDevice | 0666,
+		"brw-rw----": os.ModeDevice | 0660,
+	}
+	for perm, want := range cases {
+		if got := parsePerm(perm); got != want {
+			t.Errorf("parsePerm(%q) = %v, want %v", perm, got, want)
+		}
 	}
 }
 
@@ -218,6 +239,59 @@ func TestParseLSLineDirectory(t *testing.T) {
 	}
 }
 
+func TestParseLSLineOwnerGroupAndSpaces(t *testing.T) {
+	line := "-rw-r----- 1 www-data adm 512 2024-01-15 10:30:00 access log.txt"
+	f := parseLSLine(line)
+	if f == nil {
+		t.Fatal("parseLSLine returned nil")
+	}
+	if f.Name != "access log.txt" {
+		t.Errorf("Name = %q, want name with space", f.Name)
+	}
+	if f.Owner != "www-data" || f.Group != "adm" {
+		t.Errorf("Owner/Group = %q/%q", f.Owner, f.Group)
+	}
+	want := time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local)
+	if !f.ModTime.Equal(want) {
+		t.Errorf("ModTime = %v, want %v", f.ModTime, want)
+	}
+}
+
+func TestParseLSLineSymlinkTarget(t *testing.T) {
+	line := "lrwxrwxrwx 1 root root 7 2024-01-15 10:30:00 current -> releases/42"
+	f := parseLSLine(line)
+	if f == nil {
+		t.Fatal("parseLSLine returned nil")
+	}
+	if f.Name != "current" || f.LinkTarget != "releases/42" {
+		t.Errorf("Name/LinkTarget = %q/%q", f.Name, f.LinkTarget)
+	}
+	if f.Mode&os.ModeSymlink == 0 {
+		t.Error("mode should have the symlink bit")
+	}
+}
+
+func TestParseLSLineEpochTime(t *testing.T) {
+	f := parseLSLine("lrwxrwxrwx 1 user group 7 1709296245 my link -> 2024 notes")
+	if f == nil || f.Name != "my link" || f.LinkTarget != "2024 notes" {
+		t.Fatalf("parsed %+v", f)
+	}
+	if !f.ModTime.Equal(time.Unix(1709296245, 0)) {
+		t.Errorf("ModTime = %v, want the epoch time whatever the timezone", f.ModTime)
+	}
+}
+
+func TestParseLSLineTraditionalDate(t *testing.T) {
+	line := "-rw-r--r-- 1 user group 10 Jan 15  2024 old file.txt"
+	f := parseLSLine(line)
+	if f == nil || f.Name != "old file.txt" {
+		t.Fatalf("parsed %+v", f)
+	}
+	if f.ModTime.Year() != 2024 {
+		t.Errorf("ModTime = %v", f.ModTime)
+	}
+}
+
 func TestParseLSLineShort(t *testing.T) {
 	f := parseLSLine("abc")
 	if f != nil {
@@ -521,3 +595,16 @@ func generateTestEd25519PEM(t *testing.T) []byte {
 	}
 	return pem.EncodeToMemory(pemBlock)
 }
+
+func TestChownNothingToChange(t *testing.T) {
+	c := &Client{}
+	if err := c.Chown("/srv/app", "", "", true); err != nil {
+		t.Errorf("Chown with no owner or group should be a no-op, got %v", err)
+	}
+}
+
+func TestRecursiveFlag(t *testing.T) {
+	if recursiveFlag(true) != "-R " || recursiveFlag(false) != "" {
+		t.Error("recursiveFlag should return \"-R \" only when recursive")
+	}
+}
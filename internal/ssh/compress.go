@@ -0,0 +1,207 @@
+package ssh
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Compression names a stream compressor used for compressed transfers.
+type Compression string
+
+const (
+	CompressNone Compression = ""
+	CompressGzip Compression = "gzip"
+	CompressZstd Compression = "zstd"
+)
+
+// localZstd reports whether a zstd binary is available locally. gzip is
+// always available through the standard library.
+var localZstd = func() bool {
+	_, err := exec.LookPath("zstd")
+	return err == nil
+}
+
+// RemoteCompressors returns the compressors installed on the remote host.
+// The result is detected once per connection and then cached.
+func (c *Client) RemoteCompressors() []Compression {
+	c.compressMu.Lock()
+	defer c.compressMu.Unlock()
+	if c.compressors == nil {
+		out, _ := c.output("command -v zstd; command -v gzip")
+		c.compressors = parseCompressors(string(out))
+		log.Printf("[SSH] remote compressors: %v", c.compressors)
+	}
+	return c.compressors
+}
+
+// parseCompressors returns the known compressors in command -v output, in
+// order of preference. The result is never nil.
+func parseCompressors(output string) []Compression {
+	found := []Compression{}
+	for _, name := range []Compression{CompressZstd, CompressGzip} {
+		for _, line := range splitLines(output) {
+			if filepath.Base(strings.TrimSpace(line)) == string(name) {
+				found = append(found, name)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// pickCompression returns the preferred compressor available on both sides.
+func pickCompression(remote []Compression, haveLocalZstd bool) Compression {
+	for _, comp := range remote {
+		if comp == CompressZstd && !haveLocalZstd {
+			continue
+		}
+		return comp
+	}
+	return CompressNone
+}
+
+// UploadCompressed uploads a local file, compressing it locally and piping it
+// into the remote decompressor. It falls back to a plain upload when no
+// compressor is available on both sides or in sudo mode, and returns the
+// compressor used.
+func (c *Client) UploadCompressed(localPath, remotePath string) (Compression, error) {
+	comp := CompressNone
+	if !c.SudoEnabled() {
+		comp = pickCompression(c.RemoteCompressors(), localZstd())
+	}
+	if comp == CompressNone {
+		log.Printf("[SSH] no common compressor, uploading uncompressed")
+		return CompressNone, c.UploadFile(localPath, remotePath)
+	}
+	log.Printf("[SSH] uploading %s -> %s with %s", localPath, remotePath, comp)
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return comp, err
+	}
+	stream, wait, err := compressFile(localPath, comp)
+	if err != nil {
+		return comp, err
+	}
+
+	session, err := c.newSession()
+	if err != nil {
+		_ = stream.Close()
+		_ = wait()
+		return comp, err
+	}
+	defer func() { _ = session.Close() }()
+
+	session.Stdin = c.limiter.Reader(stream)
+	q := shellQuote(remotePath)
+	cmd := fmt.Sprintf("%s -q -d -c > %s && chmod 0%o %s", comp, q, info.Mode().Perm(), q)
+	runErr := session.Run(cmd)
+	_ = stream.Close()
+	if err := errors.Join(runErr, wait()); err != nil {
+		return comp, fmt.Errorf("compressed upload: %w", err)
+	}
+	return comp, nil
+}
+
+// compressFile returns a stream of the compressed contents of p. wait must be
+// called after the stream has been consumed and reports compression errors.
+func compressFile(p string, comp Compression) (io.ReadCloser, func() error, error) {
+	if comp == CompressZstd {
+		cmd := exec.Command("zstd", "-q", "-c", "--", p)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, err
+		}
+		return out, cmd.Wait, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, f)
+		err = errors.Join(err, zw.Close(), f.Close())
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+	return pr, func() error { return <-done }, nil
+}
+
+// DownloadCompressed downloads a remote file into localDir, compressing it on
+// the remote host and decompressing it locally. It falls back to a plain
+// download when no compressor is available on both sides or in sudo mode,
+// and returns the compressor used.
+func (c *Client) DownloadCompressed(remotePath, localDir string) (comp Compression, retErr error) {
+	if !c.SudoEnabled() {
+		comp = pickCompression(c.RemoteCompressors(), localZstd())
+	}
+	if comp == CompressNone {
+		log.Printf("[SSH] no common compressor, downloading uncompressed")
+		return CompressNone, c.DownloadFile(remotePath, localDir)
+	}
+	log.Printf("[SSH] downloading %s -> %s with %s", remotePath, localDir, comp)
+
+	session, err := c.newSession()
+	if err != nil {
+		return comp, err
+	}
+	defer func() { _ = session.Close() }()
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return comp, err
+	}
+	if err := session.Start(fmt.Sprintf("%s -q -c -- %s", comp, shellQuote(remotePath))); err != nil {
+		return comp, err
+	}
+
+	in := c.limiter.Reader(stdout)
+	f, err := os.Create(filepath.Join(localDir, filepath.Base(remotePath)))
+	if err != nil {
+		return comp, err
+	}
+	defer func() {
+		if cErr := f.Close(); cErr != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("close local file: %w", cErr))
+		}
+	}()
+
+	decErr := decompress(f, in, comp)
+	// Drain whatever is left so the session can finish cleanly.
+	_, _ = io.Copy(io.Discard, in)
+	if err := errors.Join(decErr, session.Wait()); err != nil {
+		return comp, fmt.Errorf("compressed download: %w", err)
+	}
+	return comp, nil
+}
+
+// decompress writes the decompressed contents of r to w.
+func decompress(w io.Writer, r io.Reader, comp Compression) error {
+	if comp == CompressZstd {
+		cmd := exec.Command("zstd", "-q", "-d", "-c")
+		cmd.Stdin = r
+		cmd.Stdout = w
+		return cmd.Run()
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, zr); err != nil {
+		return err
+	}
+	return zr.Close()
+}
@@ -0,0 +1,171 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCompressors(t *testing.T) {
+	cases := []struct {
+		out  string
+		want []Compression
+	}{
+		{"/usr/bin/zstd\n/usr/bin/gzip\n", []Compression{CompressZstd, CompressGzip}},
+		{"/bin/gzip\n", []Compression{CompressGzip}},
+		{"", []Compression{}},
+	}
+	for _, tc := range cases {
+		if got := parseCompressors(tc.out); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseCompressors(%q) = %v, want %v", tc.out, got, tc.want)
+		}
+	}
+}
+
+func TestPickCompression(t *testing.T) {
+	both := []Compression{CompressZstd, CompressGzip}
+	if got := pickCompression(both, true); got != CompressZstd {
+		t.Errorf("with local zstd = %q, want zstd", got)
+	}
+	if got := pickCompression(both, false); got != CompressGzip {
+		t.Errorf("without local zstd = %q, want gzip", got)
+	}
+	if got := pickCompression([]Compression{CompressZstd}, false); got != CompressNone {
+		t.Errorf("only remote zstd = %q, want none", got)
+	}
+}
+
+func roundTrip(t *testing.T, comp Compression) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "log.txt")
+	data := []byte(strings.Repeat("GET /index.html 200\n", 1000))
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stream, wait, err := compressFile(p, comp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(data) {
+		t.Errorf("%s output is %d bytes, input %d", comp, len(compressed), len(data))
+	}
+	var out bytes.Buffer
+	if err := decompress(&out, bytes.NewReader(compressed), comp); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("%s round trip changed the data", comp)
+	}
+}
+
+func TestCompressRoundTripGzip(t *testing.T) {
+	roundTrip(t, CompressGzip)
+}
+
+func TestCompressRoundTripZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not available")
+	}
+	roundTrip(t, CompressZstd)
+}
+
+func TestCompressFileMissing(t *testing.T) {
+	if _, _, err := compressFile(filepath.Join(t.TempDir(), "nope"), CompressGzip); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// compressedRoundTrip uploads and downloads a file with c and checks both
+// copies and the compressor used.
+func compressedRoundTrip(t *testing.T, c *Client, want Compression) {
+	t.Helper()
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("compress me please\n"), 5000)
+	lp := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(lp, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	remoteDir := t.TempDir()
+	rp := filepath.Join(remoteDir, "up.txt")
+
+	comp, err := c.UploadCompressed(lp, rp)
+	if err != nil || comp != want {
+		t.Fatalf("UploadCompressed() = %q, %v; want %q", comp, err, want)
+	}
+	if got, _ := os.ReadFile(rp); !bytes.Equal(got, data) {
+		t.Error("uploaded file differs")
+	}
+	if info, err := os.Stat(rp); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("uploaded file mode = %v, %v; want 0640", info.Mode().Perm(), err)
+	}
+
+	downDir := t.TempDir()
+	comp, err = c.DownloadCompressed(rp, downDir)
+	if err != nil || comp != want {
+		t.Fatalf("DownloadCompressed() = %q, %v; want %q", comp, err, want)
+	}
+	if got, _ := os.ReadFile(filepath.Join(downDir, "up.txt")); !bytes.Equal(got, data) {
+		t.Error("downloaded file differs")
+	}
+}
+
+func TestCompressedTransferGzip(t *testing.T) {
+	s := newShellServer(t)
+	s.onlyTools(t, "gzip", "chmod")
+	c := s.client(t)
+	compressedRoundTrip(t, c, CompressGzip)
+
+	if _, err := c.DownloadCompressed("/no/such/file", t.TempDir()); err == nil {
+		t.Error("downloading a missing file should fail")
+	}
+	if _, err := c.UploadCompressed(filepath.Join(t.TempDir(), "gone"), "/tmp/x"); err == nil {
+		t.Error("uploading a missing file should fail")
+	}
+}
+
+func TestCompressedTransferZstd(t *testing.T) {
+	s := newShellServer(t)
+	s.onlyTools(t, "zstd", "gzip", "chmod")
+	compressedRoundTrip(t, s.client(t), CompressZstd)
+}
+
+func TestCompressedTransferWithoutLocalZstd(t *testing.T) {
+	s := newShellServer(t)
+	s.onlyTools(t, "zstd", "scp")
+	orig := localZstd
+	localZstd = func() bool { return false }
+	defer func() { localZstd = orig }()
+	compressedRoundTrip(t, s.client(t), CompressNone)
+}
+
+func TestCompressedTransferNoCompressor(t *testing.T) {
+	s := newShellServer(t)
+	s.onlyTools(t, "scp")
+	c := s.client(t)
+	compressedRoundTrip(t, c, CompressNone)
+
+	if got := c.RemoteCompressors(); len(got) != 0 {
+		t.Errorf("RemoteCompressors() = %v, want none", got)
+	}
+	detections := 0
+	for _, cmd := range s.commands() {
+		if strings.HasPrefix(cmd, "command -v zstd") {
+			detections++
+		}
+	}
+	if detections != 1 {
+		t.Errorf("compressors detected %d times, want once per connection", detections)
+	}
+}
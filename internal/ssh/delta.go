@@ -0,0 +1,240 @@
+package ssh
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DeltaBlockSize is the block size used to compare files for delta uploads.
+const DeltaBlockSize = 1 << 20
+
+// DeltaResult describes what a delta upload did.
+type DeltaResult struct {
+	Blocks  int   // number of blocks in the local file
+	Changed int   // number of blocks that were sent
+	Sent    int64 // bytes of file data sent
+	Full    bool  // true when a full copy was made instead
+}
+
+// blockRun is a run of consecutive changed blocks.
+type blockRun struct {
+	Start int64 // index of the first block
+	Count int64 // number of blocks
+}
+
+// DeltaUpload uploads localPath to remotePath, sending only the blocks that
+// differ from the existing remote file. The remote side hashes fixed-size
+// blocks with dd and sha256sum (or md5sum), and changed blocks are patched
+// in place with dd conv=notrunc. It falls back to a full UploadFile when the
+// remote file does not exist, the tools are missing, or the patched file
+// fails verification.
+func (c *Client) DeltaUpload(localPath, remotePath string) (DeltaResult, error) {
+	log.Printf("[SSH] delta uploading %s -> %s", localPath, remotePath)
+	res, err := c.deltaUpload(localPath, remotePath)
+	if err == nil {
+		return res, nil
+	}
+	log.Printf("[SSH] delta upload not possible (%v), copying the whole file", err)
+	info, statErr := os.Stat(localPath)
+	if statErr != nil {
+		return DeltaResult{}, statErr
+	}
+	res = DeltaResult{Blocks: blockCount(info.Size(), DeltaBlockSize), Sent: info.Size(), Full: true}
+	res.Changed = res.Blocks
+	return res, c.UploadFile(localPath, remotePath)
+}
+
+// deltaUpload performs the block-level update. Any error means the remote
+// file must be replaced by a full copy.
+func (c *Client) deltaUpload(localPath, remotePath string) (res DeltaResult, retErr error) {
+	tool, err := c.deltaHashTool()
+	if err != nil {
+		return res, err
+	}
+	remoteSize, err := c.remoteSize(remotePath)
+	if err != nil {
+		return res, err
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return res, err
+	}
+	defer func() {
+		if cErr := f.Close(); cErr != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("close local file: %w", cErr))
+		}
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return res, err
+	}
+	size := info.Size()
+
+	localSums, err := blockHashes(f, DeltaBlockSize, tool)
+	if err != nil {
+		return res, err
+	}
+	remoteBlocks := blockCount(remoteSize, DeltaBlockSize)
+	out, err := c.output(remoteBlockHashCmd(remotePath, tool, DeltaBlockSize, remoteBlocks))
+	if err != nil {
+		return res, fmt.Errorf("remote block hashes: %w", err)
+	}
+	remoteSums := parseBlockHashes(string(out))
+	if len(remoteSums) != remoteBlocks {
+		return res, fmt.Errorf("remote returned %d block hashes, expected %d", len(remoteSums), remoteBlocks)
+	}
+
+	runs := changedRuns(localSums, remoteSums)
+	res.Blocks = len(localSums)
+	for _, r := range runs {
+		if err := c.patchRun(f, remotePath, r, size); err != nil {
+			return res, err
+		}
+		res.Changed += int(r.Count)
+		res.Sent += runLength(r, size)
+	}
+	if size < remoteSize {
+		cmd := fmt.Sprintf("dd if=/dev/null of=%s bs=1 seek=%d 2>/dev/null", shellQuote(remotePath), size)
+		if _, err := c.output(cmd); err != nil {
+			return res, fmt.Errorf("truncate remote file: %w", err)
+		}
+	}
+
+	// Compare whole-file digests so a bad patch never goes unnoticed.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return res, err
+	}
+	h := newBlockHash(tool)
+	if _, err := io.Copy(h, f); err != nil {
+		return res, err
+	}
+	out, err = c.output(fmt.Sprintf("%s %s", tool, shellQuote(remotePath)))
+	if err != nil {
+		return res, fmt.Errorf("verify remote file: %w", err)
+	}
+	if sums := parseBlockHashes(string(out)); len(sums) != 1 || sums[0] != hex.EncodeToString(h.Sum(nil)) {
+		return res, errors.New("remote file does not match after patching")
+	}
+	log.Printf("[SSH] delta upload sent %d of %d blocks", res.Changed, res.Blocks)
+	return res, nil
+}
+
+// deltaHashTool returns the name of the block hashing tool available on the
+// remote host (sha256sum preferred, then md5sum). dd must also be present.
+func (c *Client) deltaHashTool() (string, error) {
+	out, err := c.output("command -v dd >/dev/null 2>&1 && { command -v sha256sum || command -v md5sum; }")
+	tool := path.Base(strings.TrimSpace(string(out)))
+	if err != nil || (tool != "sha256sum" && tool != "md5sum") {
+		return "", errors.New("remote host lacks dd and sha256sum/md5sum")
+	}
+	return tool, nil
+}
+
+// remoteSize returns the size of a remote file in bytes.
+func (c *Client) remoteSize(p string) (int64, error) {
+	out, err := c.output(fmt.Sprintf("wc -c < %s", shellQuote(p)))
+	if err != nil {
+		return 0, fmt.Errorf("remote file size: %w", err)
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+}
+
+// patchRun writes one run of blocks from f into the remote file in place.
+func (c *Client) patchRun(f *os.File, remotePath string, r blockRun, size int64) error {
+	data := c.limiter.Reader(io.NewSectionReader(f, r.Start*DeltaBlockSize, runLength(r, size)))
+	cmd := fmt.Sprintf("dd of=%s bs=%d seek=%d conv=notrunc 2>/dev/null", shellQuote(remotePath), DeltaBlockSize, r.Start)
+	if _, err := c.run(cmd, data); err != nil {
+		return fmt.Errorf("patch blocks %d-%d: %w", r.Start, r.Start+r.Count-1, err)
+	}
+	return nil
+}
+
+// runLength returns the number of bytes covered by r in a file of the given
+// size; the last block may be short.
+func runLength(r blockRun, size int64) int64 {
+	end := (r.Start + r.Count) * DeltaBlockSize
+	if end > size {
+		end = size
+	}
+	return end - r.Start*DeltaBlockSize
+}
+
+// blockCount returns the number of blocks needed to hold size bytes.
+func blockCount(size, blockSize int64) int {
+	return int((size + blockSize - 1) / blockSize)
+}
+
+// newBlockHash returns the hash matching the remote tool.
+func newBlockHash(tool string) hash.Hash {
+	if tool == "md5sum" {
+		return md5.New()
+	}
+	return sha256.New()
+}
+
+// blockHashes returns the hex digest of each blockSize block read from r.
+func blockHashes(r io.Reader, blockSize int64, tool string) ([]string, error) {
+	var sums []string
+	buf := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			h := newBlockHash(tool)
+			h.Write(buf[:n])
+			sums = append(sums, hex.EncodeToString(h.Sum(nil)))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sums, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// remoteBlockHashCmd returns a shell command that prints the digest of each
+// of the first blocks blocks of p, one per line.
+func remoteBlockHashCmd(p, tool string, blockSize int64, blocks int) string {
+	return fmt.Sprintf("i=0; while [ $i -lt %d ]; do dd if=%s bs=%d skip=$i count=1 2>/dev/null | %s; i=$((i+1)); done",
+		blocks, shellQuote(p), blockSize, tool)
+}
+
+// parseBlockHashes returns the digest column of sha256sum/md5sum output.
+func parseBlockHashes(output string) []string {
+	var sums []string
+	for _, line := range splitLines(output) {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			sums = append(sums, fields[0])
+		}
+	}
+	return sums
+}
+
+// changedRuns compares local and remote block digests and returns runs of
+// consecutive blocks that must be sent. Local blocks beyond the end of the
+// remote file always count as changed.
+func changedRuns(local, remote []string) []blockRun {
+	var runs []blockRun
+	for i := range local {
+		if i < len(remote) && local[i] == remote[i] {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1].Start+runs[n-1].Count == int64(i) {
+			runs[n-1].Count++
+			continue
+		}
+		runs = append(runs, blockRun{Start: int64(i), Count: 1})
+	}
+	return runs
+}
@@ -0,0 +1,215 @@
+package ssh
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlockHashes(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 10)
+	sums, err := blockHashes(bytes.NewReader(data), 4, "sha256sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(sums))
+	}
+	if sums[0] != sums[1] || sums[1] == sums[2] {
+		t.Errorf("full blocks should match and the short block differ: %v", sums)
+	}
+	md5s, _ := blockHashes(bytes.NewReader(data), 4, "md5sum")
+	if len(md5s[0]) != 32 || len(sums[0]) != 64 {
+		t.Errorf("digest lengths: md5=%d sha256=%d", len(md5s[0]), len(sums[0]))
+	}
+}
+
+func TestBlockHashesEmpty(t *testing.T) {
+	sums, err := blockHashes(bytes.NewReader(nil), 4, "sha256sum")
+	if err != nil || len(sums) != 0 {
+		t.Errorf("sums=%v err=%v", sums, err)
+	}
+}
+
+func TestChangedRuns(t *testing.T) {
+	local := []string{"a", "b", "c", "d", "e", "f"}
+	remote := []string{"a", "x", "x", "d", "x"}
+	want := []blockRun{{Start: 1, Count: 2}, {Start: 4, Count: 2}}
+	if got := changedRuns(local, remote); !reflect.DeepEqual(got, want) {
+		t.Errorf("changedRuns = %+v, want %+v", got, want)
+	}
+	if got := changedRuns(local, local); len(got) != 0 {
+		t.Errorf("identical files should need no runs, got %+v", got)
+	}
+}
+
+func TestRunLength(t *testing.T) {
+	size := int64(2*DeltaBlockSize + 100)
+	if got := runLength(blockRun{Start: 0, Count: 1}, size); got != DeltaBlockSize {
+		t.Errorf("first block length = %d", got)
+	}
+	if got := runLength(blockRun{Start: 1, Count: 2}, size); got != DeltaBlockSize+100 {
+		t.Errorf("tail run length = %d", got)
+	}
+}
+
+func TestBlockCount(t *testing.T) {
+	cases := map[int64]int{0: 0, 1: 1, 4: 1, 5: 2, 8: 2}
+	for size, want := range cases {
+		if got := blockCount(size, 4); got != want {
+			t.Errorf("blockCount(%d) = %d, want %d", size, got, want)
+		}
+	}
+}
+
+func TestParseBlockHashes(t *testing.T) {
+	out := "abc  -\ndef  -\n\n"
+	if got := parseBlockHashes(out); !reflect.DeepEqual(got, []string{"abc", "def"}) {
+		t.Errorf("parseBlockHashes = %v", got)
+	}
+}
+
+// TestRemoteBlockHashCmdLocal runs the generated remote script with the
+// local shell and checks it agrees with blockHashes.
+func TestRemoteBlockHashCmdLocal(t *testing.T) {
+	for _, tool := range []string{"sh", "dd", "sha256sum"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	p := filepath.Join(t.TempDir(), "it's a file")
+	data := append(bytes.Repeat([]byte("x"), 9), 'y')
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("sh", "-c", remoteBlockHashCmd(p, "sha256sum", 4, blockCount(int64(len(data)), 4))).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := blockHashes(bytes.NewReader(data), 4, "sha256sum")
+	if got := parseBlockHashes(string(out)); !reflect.DeepEqual(got, want) {
+		t.Errorf("remote hashes %v, want %v", got, want)
+	}
+}
+
+// deltaFiles writes local and remote versions of a file and returns their
+// paths. The data has no repeating blocks.
+func deltaFiles(t *testing.T, local, remote []byte) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	lp, rp := filepath.Join(dir, "local.bin"), filepath.Join(dir, "remote.bin")
+	if err := os.WriteFile(lp, local, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if remote != nil {
+		if err := os.WriteFile(rp, remote, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return lp, rp
+}
+
+func randomData(n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.NewChaCha8([32]byte{1}).Read(data)
+	return data
+}
+
+func checkRemote(t *testing.T, rp string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(rp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("remote file has %d bytes and differs from the %d local ones", len(got), len(want))
+	}
+}
+
+func TestDeltaUploadPatchesChangedBlocks(t *testing.T) {
+	s := newShellServer(t)
+	remote := randomData(3*DeltaBlockSize + 1000)
+	local := bytes.Clone(remote)
+	local[DeltaBlockSize+10] ^= 0xff
+	local = append(local, randomData(DeltaBlockSize)[:2000]...)
+	lp, rp := deltaFiles(t, local, remote)
+
+	res, err := s.client(t).DeltaUpload(lp, rp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeltaResult{Blocks: 4, Changed: 2, Sent: DeltaBlockSize + int64(len(local)-3*DeltaBlockSize)}
+	if res != want {
+		t.Errorf("result = %+v, want %+v", res, want)
+	}
+	checkRemote(t, rp, local)
+	for _, cmd := range s.commands() {
+		if strings.HasPrefix(cmd, "scp ") {
+			t.Errorf("a delta upload should not copy the whole file: %s", cmd)
+		}
+	}
+}
+
+func TestDeltaUploadTruncatesWithMD5(t *testing.T) {
+	s := newShellServer(t)
+	s.onlyTools(t, "dd", "md5sum", "wc")
+	remote := randomData(2*DeltaBlockSize + 500)
+	local := remote[:DeltaBlockSize+100]
+	lp, rp := deltaFiles(t, local, remote)
+
+	res, err := s.client(t).DeltaUpload(lp, rp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Full || res.Blocks != 2 || res.Changed != 1 || res.Sent != 100 {
+		t.Errorf("result = %+v, want only the short last block sent", res)
+	}
+	checkRemote(t, rp, local)
+}
+
+func TestDeltaUploadFallsBackToFullCopy(t *testing.T) {
+	remote := randomData(2 * DeltaBlockSize)
+	local := bytes.Clone(remote)
+	local[0] ^= 0xff
+
+	tests := []struct {
+		name  string
+		setup func(s *shellServer, rp string)
+	}{
+		{"missing remote file", func(s *shellServer, rp string) { _ = os.Remove(rp) }},
+		{"patch does not verify", func(s *shellServer, rp string) {
+			// A dd that drops the patch leaves the old block in place.
+			s.tool(t, "dd", "case \"$*\" in *conv=notrunc*) cat >/dev/null; exit 0;; esac\nexec /usr/bin/dd \"$@\"\n")
+		}},
+		{"no hashing tool", func(s *shellServer, rp string) { s.onlyTools(t, "scp") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newShellServer(t)
+			lp, rp := deltaFiles(t, local, remote)
+			tt.setup(s, rp)
+
+			res, err := s.client(t).DeltaUpload(lp, rp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := DeltaResult{Blocks: 2, Changed: 2, Sent: int64(len(local)), Full: true}
+			if res != want {
+				t.Errorf("result = %+v, want %+v", res, want)
+			}
+			checkRemote(t, rp, local)
+		})
+	}
+}
+
+func TestDeltaUploadMissingLocalFile(t *testing.T) {
+	s := newShellServer(t)
+	if _, err := s.client(t).DeltaUpload(filepath.Join(t.TempDir(), "gone"), "/tmp/x"); err == nil {
+		t.Error("a missing local file should fail")
+	}
+}
@@ -0,0 +1,151 @@
+package ssh
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DUNode is a file or directory in a disk usage tree. Size is the disk
+// space used in bytes, including everything below a directory.
+type DUNode struct {
+	Name     string
+	Size     int64
+	IsDir    bool
+	Parent   *DUNode
+	Children []*DUNode
+}
+
+// Path returns the node's path relative to the root of its tree; the root
+// itself is ".".
+func (n *DUNode) Path() string {
+	if n.Parent == nil {
+		return "."
+	}
+	return path.Join(n.Parent.Path(), n.Name)
+}
+
+// Remove detaches n from its parent and subtracts its size from all
+// ancestors.
+func (n *DUNode) Remove() {
+	p := n.Parent
+	if p == nil {
+		return
+	}
+	for i, c := range p.Children {
+		if c == n {
+			p.Children = append(p.Children[:i], p.Children[i+1:]...)
+			break
+		}
+	}
+	for a := p; a != nil; a = a.Parent {
+		a.Size -= n.Size
+	}
+	n.Parent = nil
+}
+
+// DiskSpace is the usage of the filesystem a path lives on, in bytes.
+type DiskSpace struct {
+	Total int64
+	Used  int64
+	Avail int64
+	Mount string
+}
+
+// DiskUsage measures everything below dir with du and returns it as a tree
+// rooted at dir. It stays on dir's filesystem; unreadable entries are left
+// out.
+func (c *Client) DiskUsage(dir string) (*DUNode, error) {
+	log.Printf("[SSH] du: %s", dir)
+	out, err := c.run(diskUsageCmd(dir), nil)
+	if err != nil {
+		return nil, fmt.Errorf("du %s: %w", dir, err)
+	}
+	return parseDiskUsage(string(out))
+}
+
+// diskUsageCmd lists every entry with its size in KiB, then the empty
+// directories (which du cannot tell from files) after a "/" line.
+func diskUsageCmd(dir string) string {
+	return fmt.Sprintf(`cd -- %s && { du -akx . 2>/dev/null; echo /; find . -xdev -type d -empty 2>/dev/null; true; }`, shellQuote(dir))
+}
+
+// parseDiskUsage builds the tree from diskUsageCmd output. du prints every
+// entry after its contents, so parents may be seen after their children.
+func parseDiskUsage(out string) (*DUNode, error) {
+	nodes := map[string]*DUNode{}
+	var node func(p string) *DUNode
+	node = func(p string) *DUNode {
+		if n, ok := nodes[p]; ok {
+			return n
+		}
+		n := &DUNode{Name: path.Base(p)}
+		nodes[p] = n
+		if p != "." {
+			parent := node(path.Dir(p))
+			parent.IsDir = true
+			n.Parent = parent
+			parent.Children = append(parent.Children, n)
+		}
+		return n
+	}
+	lines := splitLines(out)
+	i := 0
+	for ; i < len(lines) && lines[i] != "/"; i++ {
+		size, p, ok := strings.Cut(lines[i], "\t")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+		if err != nil || (p != "." && !strings.HasPrefix(p, "./")) {
+			continue
+		}
+		node(path.Clean(p)).Size = kb * 1024
+	}
+	for _, p := range lines[min(i+1, len(lines)):] {
+		if n, ok := nodes[path.Clean(p)]; ok {
+			n.IsDir = true
+		}
+	}
+	root, ok := nodes["."]
+	if !ok {
+		return nil, fmt.Errorf("du printed no usage")
+	}
+	root.IsDir = true
+	return root, nil
+}
+
+// DiskFree returns the usage of the filesystem holding path, from df.
+func (c *Client) DiskFree(p string) (DiskSpace, error) {
+	out, err := c.run(fmt.Sprintf("df -Pk -- %s", shellQuote(p)), nil)
+	if err != nil {
+		return DiskSpace{}, fmt.Errorf("df %s: %w", p, err)
+	}
+	return parseDF(string(out))
+}
+
+// parseDF reads the POSIX df -Pk output: a header line, then
+// "filesystem 1024-blocks used available capacity mountpoint".
+func parseDF(out string) (DiskSpace, error) {
+	lines := splitLines(out)
+	if len(lines) < 2 {
+		return DiskSpace{}, fmt.Errorf("unexpected df output %q", out)
+	}
+	f := strings.Fields(lines[1])
+	if len(f) < 6 {
+		return DiskSpace{}, fmt.Errorf("unexpected df output %q", lines[1])
+	}
+	var nums [3]int64
+	for i := range nums {
+		n, err := strconv.ParseInt(f[i+1], 10, 64)
+		if err != nil {
+			return DiskSpace{}, fmt.Errorf("unexpected df output %q", lines[1])
+		}
+		nums[i] = n * 1024
+	}
+	// The mount point is last and may contain spaces.
+	mount := strings.Join(f[5:], " ")
+	return DiskSpace{Total: nums[0], Used: nums[1], Avail: nums[2], Mount: mount}, nil
+}
@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func findNode(n *DUNode, name string) *DUNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	out := "4\t./logs/a.log\n8\t./logs/b.log\n16\t./logs\n0\t./empty\n2\t./notes\n22\t.\n/\n./empty\n"
+	root, err := parseDiskUsage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Size != 22*1024 || !root.IsDir || len(root.Children) != 3 {
+		t.Fatalf("root = %+v", root)
+	}
+	logs := findNode(root, "logs")
+	if logs == nil || !logs.IsDir || logs.Size != 16*1024 || len(logs.Children) != 2 {
+		t.Fatalf("logs = %+v", logs)
+	}
+	if e := findNode(root, "empty"); e == nil || !e.IsDir {
+		t.Errorf("empty dir should be marked as a directory: %+v", e)
+	}
+	if n := findNode(root, "notes"); n == nil || n.IsDir {
+		t.Errorf("notes should be a file: %+v", n)
+	}
+	if p := findNode(logs, "b.log").Path(); p != "logs/b.log" {
+		t.Errorf("Path() = %q", p)
+	}
+
+	findNode(logs, "b.log").Remove()
+	if logs.Size != 8*1024 || root.Size != 14*1024 || len(logs.Children) != 1 {
+		t.Errorf("after Remove: logs %d, root %d, children %d", logs.Size, root.Size, len(logs.Children))
+	}
+
+	if _, err := parseDiskUsage("/\n"); err == nil {
+		t.Error("expected an error for empty du output")
+	}
+}
+
+func TestDiskUsageCmdLocal(t *testing.T) {
+	for _, tool := range []string{"sh", "du", "find"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	dir := filepath.Join(t.TempDir(), "it's here")
+	for _, d := range []string{"a/b", "empty"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a/b/data"), make([]byte, 64<<10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("sh", "-c", diskUsageCmd(dir)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := parseDiskUsage(string(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := findNode(root, "a")
+	if a == nil || !a.IsDir || a.Size < 64<<10 {
+		t.Fatalf("a = %+v", a)
+	}
+	if data := findNode(findNode(a, "b"), "data"); data == nil || data.IsDir {
+		t.Errorf("a/b/data = %+v", data)
+	}
+	if e := findNode(root, "empty"); e == nil || !e.IsDir {
+		t.Errorf("empty = %+v", e)
+	}
+}
+
+func TestParseDF(t *testing.T) {
+	out := "Filesystem     1024-blocks    Used Available Capacity Mounted on\n" +
+		"/dev/sda1          1000000  250000    750000      25% /mnt/my disk\n"
+	s, err := parseDF(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DiskSpace{Total: 1000000 * 1024, Used: 250000 * 1024, Avail: 750000 * 1024, Mount: "/mnt/my disk"}
+	if s != want {
+		t.Errorf("parseDF = %+v, want %+v", s, want)
+	}
+	if _, err := parseDF("Filesystem\n"); err == nil {
+		t.Error("expected an error for a missing data line")
+	}
+}
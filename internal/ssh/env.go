@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultTerm is the terminal type requested for the PTY, matching what
+// the terminal pane emulates.
+const DefaultTerm = "xterm-256color"
+
+// defaultSendEnv are the local variables passed to every session, as most
+// distributions' ssh_config does, so remote tools use the local locale.
+var defaultSendEnv = []string{"LANG", "LC_*"}
+
+// sessionEnv returns the variables to set in each session: those of
+// environ whose names match the default and SendEnv patterns, then the
+// SetEnv pairs. A SendEnv pattern starting with - removes the earlier
+// patterns it matches. For SetEnv the first value of a name wins, as in
+// ssh. TERM is not forwarded, since the PTY request carries it.
+func sessionEnv(sendEnv, setEnv, environ []string) map[string]string {
+	patterns := slices.Clone(defaultSendEnv)
+	for _, p := range sendEnv {
+		if rm, ok := strings.CutPrefix(p, "-"); ok {
+			patterns = slices.DeleteFunc(patterns, func(q string) bool {
+				matched, _ := path.Match(rm, q)
+				return matched
+			})
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+
+	env := map[string]string{}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "TERM" {
+			continue
+		}
+		for _, p := range patterns {
+			if matched, _ := path.Match(p, name); matched {
+				env[name] = value
+				break
+			}
+		}
+	}
+	set := map[string]bool{}
+	for _, kv := range setEnv {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" || set[name] {
+			continue
+		}
+		set[name] = true
+		env[name] = value
+	}
+	return env
+}
+
+// newSession opens a session and sets the connection's environment in it.
+// Like ssh, the requests ask for no reply: servers only accept the names
+// listed in AcceptEnv, and a refusal should neither fail the session nor
+// cost a round trip.
+func (c *Client) newSession() (*ssh.Session, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(c.env))
+	for name := range c.env {
+		if name != "TERM" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		payload := ssh.Marshal(struct{ Name, Value string }{name, c.env[name]})
+		if _, err := session.SendRequest("env", false, payload); err != nil {
+			return nil, errors.Join(err, session.Close())
+		}
+	}
+	return session, nil
+}
+
+// Term returns the terminal type requested for the PTY: DefaultTerm, or
+// TERM from SetEnv in ~/.ssh/config.
+func (c *Client) Term() string {
+	if t := c.env["TERM"]; t != "" {
+		return t
+	}
+	return DefaultTerm
+}
@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestSessionEnv(t *testing.T) {
+	environ := []string{
+		"LANG=en_US.UTF-8", "LC_ALL=C.UTF-8", "LC_TIME=de_DE.UTF-8", "HOME=/home/me",
+		"EDITOR=vim", "TERM=alacritty", "GIT_AUTHOR=me", "APP_ENV=dev",
+	}
+	tests := []struct {
+		name    string
+		sendEnv []string
+		setEnv  []string
+		want    map[string]string
+	}{
+		{
+			name: "locale by default",
+			want: map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "C.UTF-8", "LC_TIME": "de_DE.UTF-8"},
+		},
+		{
+			name:    "SendEnv adds patterns and removes defaults",
+			sendEnv: []string{"EDITOR", "GIT_*", "-LC_*", "TERM"},
+			want:    map[string]string{"LANG": "en_US.UTF-8", "EDITOR": "vim", "GIT_AUTHOR": "me"},
+		},
+		{
+			name:    "SetEnv overrides and the first value wins",
+			sendEnv: []string{"-*"},
+			setEnv:  []string{"APP_ENV=staging", "APP_ENV=prod", "TERM=xterm", "EMPTY=", "bogus"},
+			want:    map[string]string{"APP_ENV": "staging", "TERM": "xterm", "EMPTY": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionEnv(tt.sendEnv, tt.setEnv, environ); !maps.Equal(got, tt.want) {
+				t.Errorf("sessionEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientTerm(t *testing.T) {
+	if got := (&Client{}).Term(); got != DefaultTerm {
+		t.Errorf("Term() = %q, want %q", got, DefaultTerm)
+	}
+	c := &Client{env: map[string]string{"TERM": "xterm"}}
+	if got := c.Term(); got != "xterm" {
+		t.Errorf("Term() = %q with SetEnv TERM, want xterm", got)
+	}
+}
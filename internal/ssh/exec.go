@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// execKillDelay is how long Exec waits for an interrupted command to exit
+// before closing its session.
+const execKillDelay = 3 * time.Second
+
+// Exec runs cmd with the login shell in dir, or in the login directory
+// when dir is empty, and calls line for each line it writes, with stderr
+// set for lines from standard error. line is never called concurrently.
+// Exec returns the command's exit code, or -1 when it did not report one.
+// Cancelling ctx interrupts the command with SIGINT and closes the session
+// if it is still running after a short delay; Exec then returns ctx.Err()
+// with whatever exit code was reported.
+func (c *Client) Exec(ctx context.Context, dir, cmd string, line func(text string, stderr bool)) (int, error) {
+	log.Printf("[SSH] exec in %s: %s", dir, cmd)
+	session, err := c.newSession()
+	if err != nil {
+		return -1, err
+	}
+	defer func() { _ = session.Close() }()
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return -1, err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return -1, err
+	}
+	if err := session.Start(execCmd(dir, cmd)); err != nil {
+		return -1, err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+			return
+		}
+		// Not every server supports signals; closing the session ends
+		// the command in any case.
+		_ = session.Signal(ssh.SIGINT)
+		select {
+		case <-time.After(execKillDelay):
+			_ = session.Close()
+		case <-stop:
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	scan := func(r io.Reader, isErr bool) {
+		defer wg.Done()
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64<<10), 1<<20)
+		for sc.Scan() {
+			mu.Lock()
+			line(sc.Text(), isErr)
+			mu.Unlock()
+		}
+		// Keep draining after an overlong line so the command is not
+		// blocked on a full pipe.
+		_, _ = io.Copy(io.Discard, r)
+	}
+	wg.Add(2)
+	go scan(stdout, false)
+	go scan(stderr, true)
+	wg.Wait()
+
+	code, err := exitCode(session.Wait())
+	if ctx.Err() != nil {
+		return code, ctx.Err()
+	}
+	return code, err
+}
+
+// execCmd returns the command used by Exec. The command goes on its own
+// line so that it is parsed exactly as typed.
+func execCmd(dir, cmd string) string {
+	if dir == "" {
+		return cmd
+	}
+	return "cd -- " + shellQuote(dir) + " || exit 1\n" + cmd
+}
+
+// exitCode turns the error of session.Wait into an exit code. A non-zero
+// exit is not an error; a command that ended without an exit status
+// yields -1 and the error.
+func exitCode(err error) (int, error) {
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus(), nil
+	}
+	return -1, err
+}
@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestExecCmdLocal(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := filepath.Join(t.TempDir(), "it's here")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("sh", "-c", execCmd(dir, "pwd; exit 3")).Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("exit = %v, want the command's exit code 3", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != dir {
+		t.Errorf("command ran in %q, want %q", got, dir)
+	}
+
+	err = exec.Command("sh", "-c", execCmd(filepath.Join(dir, "missing"), "echo ran")).Run()
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("a missing directory should stop the command, got %v", err)
+	}
+
+	if got := execCmd("", "uptime"); got != "uptime" {
+		t.Errorf("execCmd without a directory = %q, want the command alone", got)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code, err := exitCode(nil); code != 0 || err != nil {
+		t.Errorf("exitCode(nil) = %d, %v", code, err)
+	}
+	if code, err := exitCode(&ssh.ExitMissingError{}); code != -1 || err == nil {
+		t.Errorf("a missing exit status should be -1 with the error, got %d, %v", code, err)
+	}
+}
+
+func TestClientExec(t *testing.T) {
+	addr, cleanup := testSSHServer(t)
+	defer cleanup()
+
+	host, port, _ := net.SplitHostPort(addr)
+	client, err := New(host, port, "testuser",
+		[]ssh.AuthMethod{PasswordAuth("testpass")},
+		ssh.InsecureIgnoreHostKey(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	var lines []string
+	code, err := client.Exec(context.Background(), "/home/testuser", "true", func(text string, stderr bool) {
+		lines = append(lines, text)
+	})
+	if err != nil || code != 0 {
+		t.Errorf("Exec() = %d, %v; want exit 0", code, err)
+	}
+	if len(lines) != 0 {
+		t.Errorf("Exec() output = %q, want none", lines)
+	}
+}
@@ -3,18 +3,44 @@ package ssh
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	osexec "os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"golang.org/x/crypto/ssh"
 )
 
+// execHandler runs the command of an exec request on the test server and
+// returns its exit status.
+type execHandler func(cmd string, stdin io.Reader, stdout, stderr io.Writer) uint32
+
+// cannedExec answers the few commands the client sends on connect and when
+// listing with fixed output, and accepts anything else silently.
+func cannedExec(cmd string, _ io.Reader, stdout, _ io.Writer) uint32 {
+	if cmd == "echo $HOME" {
+		_, _ = stdout.Write([]byte("/home/testuser\n"))
+	} else if len(cmd) > 3 && cmd[:3] == "ls " {
+		_, _ = stdout.Write([]byte("total 4\n-rw-r--r-- 1 user user 100 2024-01-15 10:00:00 testfile.txt\ndrwxr-xr-x 2 user user 4096 2024-01-15 10:00:00 testdir\n"))
+	}
+	return 0
+}
+
 // testSSHServer starts a minimal SSH server for integration tests.
 // It returns the address and a cleanup function.
 func testSSHServer(t *testing.T) (addr string, cleanup func()) {
+	return startTestServer(t, cannedExec)
+}
+
+// startTestServer starts the test server with exec requests handled by exec.
+func startTestServer(t *testing.T, exec execHandler) (addr string, cleanup func()) {
 	t.Helper()
 
 	_, priv, err := ed25519.GenerateKey(rand.Reader)
@@ -49,7 +75,7 @@ func testSSHServer(t *testing.T) (addr string, cleanup func()) {
 			if err != nil {
 				return
 			}
-			go handleConn(conn, config)
+			go handleConn(conn, config, exec)
 		}
 	}()
 
@@ -59,7 +85,19 @@ func testSSHServer(t *testing.T) (addr string, cleanup func()) {
 	}
 }
 
-func handleConn(conn net.Conn, config *ssh.ServerConfig) {
+// seenRequests records the env and pty-req requests the test server got.
+var seenRequests struct {
+	sync.Mutex
+	reqs []string
+}
+
+func recordRequest(s string) {
+	seenRequests.Lock()
+	defer seenRequests.Unlock()
+	seenRequests.reqs = append(seenRequests.reqs, s)
+}
+
+func handleConn(conn net.Conn, config *ssh.ServerConfig, exec execHandler) {
 	defer func() { _ = conn.Close() }()
 
 	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
@@ -85,25 +123,32 @@ func handleConn(conn net.Conn, config *ssh.ServerConfig) {
 			for req := range requests {
 				switch req.Type {
 				case "exec":
-					// Parse the command from the payload: uint32 length + string
-					if len(req.Payload) > 4 {
-						cmdLen := int(req.Payload[0])<<24 | int(req.Payload[1])<<16 | int(req.Payload[2])<<8 | int(req.Payload[3])
-						if cmdLen > 0 && 4+cmdLen <= len(req.Payload) {
-							cmd := string(req.Payload[4 : 4+cmdLen])
-							if cmd == "echo $HOME" {
-								_, _ = ch.Write([]byte("/home/testuser\n"))
-							} else if len(cmd) > 3 && cmd[:3] == "ls " {
-								_, _ = ch.Write([]byte("total 4\n-rw-r--r-- 1 user user 100 2024-01-15 10:00:00 testfile.txt\ndrwxr-xr-x 2 user user 4096 2024-01-15 10:00:00 testdir\n"))
-							}
-						}
+					var payload struct{ Command string }
+					if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+						_ = req.Reply(false, nil)
+						return
 					}
 					if req.WantReply {
 						_ = req.Reply(true, nil)
 					}
-					_, _ = ch.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
+					status := exec(payload.Command, ch, ch, ch.Stderr())
+					_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
 					_ = ch.Close()
 					return
+				case "env":
+					var env struct{ Name, Value string }
+					if ssh.Unmarshal(req.Payload, &env) == nil {
+						recordRequest("env " + env.Name + "=" + env.Value)
+					}
 				case "pty-req":
+					var pty struct {
+						Term                         string
+						Columns, Rows, Width, Height uint32
+						Modes                        string
+					}
+					if ssh.Unmarshal(req.Payload, &pty) == nil {
+						recordRequest(fmt.Sprintf("pty %s %dx%d", pty.Term, pty.Columns, pty.Rows))
+					}
 					if req.WantReply {
 						_ = req.Reply(true, nil)
 					}
@@ -247,7 +292,7 @@ func TestClientStartTerminal(t *testing.T) {
 	}
 
 	var buf fakeWriter
-	err = client.StartTerminal(session, nil, &buf, &buf)
+	err = client.StartTerminal(session, 120, 30, nil, &buf, &buf)
 	if err != nil {
 		t.Fatalf("StartTerminal() error = %v", err)
 	}
@@ -256,6 +301,42 @@ func TestClientStartTerminal(t *testing.T) {
 	_ = session.Close()
 }
 
+func TestClientSessionEnvAndPty(t *testing.T) {
+	addr, cleanup := testSSHServer(t)
+	defer cleanup()
+	t.Setenv("LANG", "de_DE.UTF-8")
+	seenRequests.Lock()
+	seenRequests.reqs = nil
+	seenRequests.Unlock()
+
+	host, port, _ := net.SplitHostPort(addr)
+	client, err := New(host, port, "testuser",
+		[]ssh.AuthMethod{PasswordAuth("testpass")},
+		ssh.InsecureIgnoreHostKey(), &ConnectOptions{SendEnv: []string{"-LC_*"}, SetEnv: []string{"TERM=xterm", "APP_ENV=staging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf fakeWriter
+	if err := client.StartTerminal(session, 132, 43, nil, &buf, &buf); err != nil {
+		t.Fatalf("StartTerminal() error = %v", err)
+	}
+	_ = session.Close()
+
+	seenRequests.Lock()
+	got := strings.Join(seenRequests.reqs, "; ")
+	seenRequests.Unlock()
+	want := "env APP_ENV=staging; env LANG=de_DE.UTF-8; pty xterm 132x43"
+	if got != want {
+		t.Errorf("server got %q, want %q", got, want)
+	}
+}
+
 type fakeWriter struct {
 	data []byte
 }
@@ -350,3 +431,105 @@ func TestUploadFileNonexistentLocal(t *testing.T) {
 		t.Error("expected error for nonexistent local file")
 	}
 }
+
+// shellServer is a test server that runs exec requests with the local sh,
+// standing in for a real remote host. Its bin directory comes first on the
+// commands' PATH, so tests can replace remote tools with scripts.
+type shellServer struct {
+	addr string
+	bin  string
+	path string   // PATH of the commands
+	env  []string // extra environment of the commands
+
+	mu   sync.Mutex
+	cmds []string
+}
+
+func newShellServer(t *testing.T) *shellServer {
+	t.Helper()
+	s := &shellServer{bin: t.TempDir()}
+	s.path = s.bin + ":/usr/local/bin:/usr/bin:/bin"
+	addr, cleanup := startTestServer(t, s.exec)
+	t.Cleanup(cleanup)
+	s.addr = addr
+	return s
+}
+
+func (s *shellServer) exec(cmd string, stdin io.Reader, stdout, stderr io.Writer) uint32 {
+	s.mu.Lock()
+	s.cmds = append(s.cmds, cmd)
+	env := append(os.Environ(), "PATH="+s.path)
+	env = append(env, s.env...)
+	s.mu.Unlock()
+
+	c := osexec.Command("/bin/sh", "-c", cmd)
+	c.Env = env
+	c.Stdout = stdout
+	c.Stderr = stderr
+	// Copy stdin by hand: exec.Cmd would wait for the client to close it,
+	// which clients that send nothing never do.
+	in, err := c.StdinPipe()
+	if err != nil {
+		return 255
+	}
+	if err := c.Start(); err != nil {
+		return 127
+	}
+	go func() {
+		_, _ = io.Copy(in, stdin)
+		_ = in.Close()
+	}()
+	if err := c.Wait(); err != nil {
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			return uint32(exitErr.ExitCode())
+		}
+		return 255
+	}
+	return 0
+}
+
+// commands returns the commands run on the server so far.
+func (s *shellServer) commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.cmds)
+}
+
+// tool installs an executable shell script named name in the bin directory.
+func (s *shellServer) tool(t *testing.T, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(s.bin, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// client connects to the server; the connection is closed when t ends.
+func (s *shellServer) client(t *testing.T) *Client {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(s.addr)
+	c, err := New(host, port, "testuser", []ssh.AuthMethod{PasswordAuth("testpass")}, ssh.InsecureIgnoreHostKey(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// onlyTools limits the commands' PATH to the bin directory, holding links
+// to the named local tools.
+func (s *shellServer) onlyTools(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		p, err := osexec.LookPath(name)
+		if err != nil {
+			t.Skipf("%s not installed", name)
+		}
+		if err := os.Symlink(p, filepath.Join(s.bin, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.mu.Lock()
+	s.path = s.bin
+	s.mu.Unlock()
+}
@@ -0,0 +1,53 @@
+package ssh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// remotePathExpr returns p as a shell word. A leading ~ is expanded to the
+// remote home directory; everything else is quoted.
+func remotePathExpr(p string) string {
+	switch {
+	case p == "~":
+		return `"$HOME"`
+	case strings.HasPrefix(p, "~/"):
+		return `"$HOME"/` + shellQuote(p[2:])
+	}
+	return shellQuote(p)
+}
+
+// ResolveDir returns the absolute path of the remote directory p, which may
+// start with ~. It fails if p is not an accessible directory.
+func (c *Client) ResolveDir(p string) (string, error) {
+	out, err := c.run(fmt.Sprintf("cd -- %s && pwd", remotePathExpr(p)), nil)
+	if err != nil {
+		return "", fmt.Errorf("cd %s: %w", p, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CompleteDir returns the names of the subdirectories of dir that start
+// with prefix, sorted. As in a shell, dot directories are only included
+// when prefix starts with a dot.
+func (c *Client) CompleteDir(dir, prefix string) ([]string, error) {
+	out, err := c.run(completeDirCmd(dir, prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range splitLines(string(out)) {
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// completeDirCmd returns the shell command used by CompleteDir.
+func completeDirCmd(dir, prefix string) string {
+	return fmt.Sprintf(`cd -- %s && for f in %s*; do case $f in .|..) continue ;; esac; [ -d "$f" ] && printf '%%s\n' "$f"; done; true`,
+		remotePathExpr(dir), shellQuote(prefix))
+}
@@ -0,0 +1,57 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemotePathExpr(t *testing.T) {
+	tests := map[string]string{
+		"~":          `"$HOME"`,
+		"~/app logs": `"$HOME"/'app logs'`,
+		"/opt/~x":    `'/opt/~x'`,
+		"~other":     `'~other'`,
+	}
+	for in, want := range tests {
+		if got := remotePathExpr(in); got != want {
+			t.Errorf("remotePathExpr(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestCompleteDirCmdLocal(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	for _, d := range []string{"releases", "rel ease's", "run", ".rc", "other"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(prefix string) string {
+		out, err := exec.Command("sh", "-c", completeDirCmd(dir, prefix)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(strings.Fields(strings.ReplaceAll(string(out), " ", "_")), ",")
+	}
+	if got := run("re"); got != "rel_ease's,releases" {
+		t.Errorf("prefix re = %s", got)
+	}
+	if got := run("zz"); got != "" {
+		t.Errorf("no match should print nothing, got %s", got)
+	}
+	if got := run("."); got != ".rc" {
+		t.Errorf("prefix . = %s, want .rc", got)
+	}
+	if got := run(""); strings.Contains(got, ".rc") || !strings.Contains(got, "other") {
+		t.Errorf("empty prefix = %s, want visible dirs only", got)
+	}
+}
@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ArchiveKind returns the archive format implied by a file name: "zip",
+// "tar", "tar.gz", "tar.bz2", "tar.xz" or "tar.zst". It returns "" for
+// other files.
+func ArchiveKind(name string) string {
+	n := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(n, ".zip"), strings.HasSuffix(n, ".jar"):
+		return "zip"
+	case strings.HasSuffix(n, ".tar"):
+		return "tar"
+	case strings.HasSuffix(n, ".tar.gz"), strings.HasSuffix(n, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(n, ".tar.bz2"), strings.HasSuffix(n, ".tbz2"):
+		return "tar.bz2"
+	case strings.HasSuffix(n, ".tar.xz"), strings.HasSuffix(n, ".txz"):
+		return "tar.xz"
+	case strings.HasSuffix(n, ".tar.zst"):
+		return "tar.zst"
+	}
+	return ""
+}
+
+// ReadHead returns at most the first n bytes of a remote file.
+func (c *Client) ReadHead(p string, n int64) ([]byte, error) {
+	out, err := c.run(fmt.Sprintf("head -c %d -- %s", n, shellQuote(p)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", p, err)
+	}
+	return out, nil
+}
+
+// ListArchive lists up to limit entries of a remote zip or tar archive
+// with unzip -l or tar -tv.
+func (c *Client) ListArchive(p string, limit int) ([]string, error) {
+	cmd := archiveListCmd(p, limit)
+	if cmd == "" {
+		return nil, fmt.Errorf("%s: not an archive", p)
+	}
+	out, err := c.run(cmd, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", p, err)
+	}
+	return splitLines(string(out)), nil
+}
+
+// archiveListCmd returns the command listing the archive p, or "" if p
+// is not one. tar detects the compression itself.
+func archiveListCmd(p string, limit int) string {
+	var list string
+	switch ArchiveKind(p) {
+	case "":
+		return ""
+	case "zip":
+		list = "unzip -l " + shellQuote(p)
+	default:
+		list = "tar -tvf " + shellQuote(p)
+	}
+	return fmt.Sprintf("%s 2>&1 | head -n %d", list, limit)
+}
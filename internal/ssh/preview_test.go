@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveKind(t *testing.T) {
+	tests := map[string]string{
+		"a.zip":           "zip",
+		"lib.JAR":         "zip",
+		"b.tar":           "tar",
+		"c.tar.gz":        "tar.gz",
+		"c.tgz":           "tar.gz",
+		"d.tar.bz2":       "tar.bz2",
+		"e.tar.xz":        "tar.xz",
+		"f.tar.zst":       "tar.zst",
+		"notes.txt":       "",
+		"archive.gz":      "",
+		"tarball":         "",
+		"release.tar.gz/": "",
+	}
+	for name, want := range tests {
+		if got := ArchiveKind(name); got != want {
+			t.Errorf("ArchiveKind(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestArchiveListCmdLocal(t *testing.T) {
+	for _, tool := range []string{"sh", "tar", "head"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"one.txt", "two.txt", "three.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := filepath.Join(dir, "it's.tar.gz")
+	if out, err := exec.Command("tar", "-czf", archive, "-C", dir, "one.txt", "two.txt", "three.txt").CombinedOutput(); err != nil {
+		t.Skipf("tar -czf failed: %v %s", err, out)
+	}
+	out, err := exec.Command("sh", "-c", archiveListCmd(archive, 2)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := splitLines(string(out))
+	if len(lines) != 2 || !strings.Contains(lines[0], "one.txt") {
+		t.Errorf("listing = %q, want the first 2 entries", lines)
+	}
+	if archiveListCmd(filepath.Join(dir, "one.txt"), 10) != "" {
+		t.Error("non-archives should not get a list command")
+	}
+}
@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// maxLimiterSleep caps a single throttling sleep so that rate changes made
+// while a transfer is running take effect quickly.
+const maxLimiterSleep = 100 * time.Millisecond
+
+// RateLimiter is a token-bucket limiter for byte streams. One limiter can be
+// shared by several concurrent streams, and its rate may be changed at any
+// time — running streams pick up the new rate on their next read. The zero
+// value is an unlimited limiter.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   int64 // bytes per second; 0 means unlimited
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing bytesPerSec bytes per second.
+// A rate of 0 (or less) disables limiting.
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetRate(bytesPerSec)
+	return l
+}
+
+// SetRate changes the limit to bytesPerSec. A rate of 0 (or less) disables
+// limiting.
+func (l *RateLimiter) SetRate(bytesPerSec int64) {
+	if bytesPerSec < 0 {
+		bytesPerSec = 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = bytesPerSec
+	l.tokens = 0
+	l.last = time.Now()
+}
+
+// Rate returns the current limit in bytes per second (0 = unlimited).
+func (l *RateLimiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// chunkSize returns the largest read size that keeps throttling smooth at
+// the current rate (roughly a tenth of a second's worth of data).
+func (l *RateLimiter) chunkSize() int {
+	rate := l.Rate()
+	if rate <= 0 {
+		return 0
+	}
+	n := int(rate / 10)
+	if n < 512 {
+		n = 512
+	}
+	return n
+}
+
+// WaitN blocks until n bytes may be passed through the limiter.
+func (l *RateLimiter) WaitN(n int) {
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return
+		}
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+		l.last = now
+		// Allow a burst of one second, or of n when n exceeds that.
+		burst := float64(l.rate)
+		if float64(n) > burst {
+			burst = float64(n)
+		}
+		if l.tokens > burst {
+			l.tokens = burst
+		}
+		if l.tokens >= float64(n) {
+			l.tokens -= float64(n)
+			l.mu.Unlock()
+			return
+		}
+		wait := time.Duration((float64(n) - l.tokens) / float64(l.rate) * float64(time.Second))
+		l.mu.Unlock()
+
+		if wait > maxLimiterSleep {
+			wait = maxLimiterSleep
+		}
+		time.Sleep(wait)
+	}
+}
+
+// Reader wraps r so that reads from it are throttled by the limiter.
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	return &limitedReader{r: r, limiter: l}
+}
+
+// limitedReader is an io.Reader throttled by a RateLimiter.
+type limitedReader struct {
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if chunk := lr.limiter.chunkSize(); chunk > 0 && len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		lr.limiter.WaitN(n)
+	}
+	return n, err
+}
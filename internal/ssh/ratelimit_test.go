@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	l := NewRateLimiter(0)
+	start := time.Now()
+	l.WaitN(10 << 20)
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("unlimited limiter should not block")
+	}
+}
+
+func TestRateLimiterSetRate(t *testing.T) {
+	l := NewRateLimiter(1024)
+	if l.Rate() != 1024 {
+		t.Errorf("Rate() = %d, want 1024", l.Rate())
+	}
+	l.SetRate(-5)
+	if l.Rate() != 0 {
+		t.Errorf("negative rate should clamp to 0, got %d", l.Rate())
+	}
+}
+
+func TestRateLimiterThrottles(t *testing.T) {
+	// 20 KB at 100 KB/s should take roughly 200ms.
+	l := NewRateLimiter(100 * 1024)
+	src := bytes.NewReader(make([]byte, 20*1024))
+	start := time.Now()
+	n, err := io.Copy(io.Discard, l.Reader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 20*1024 {
+		t.Errorf("copied %d bytes, want %d", n, 20*1024)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("transfer took %v, expected throttling to ~200ms", elapsed)
+	}
+}
+
+func TestRateLimiterRaisedMidStream(t *testing.T) {
+	l := NewRateLimiter(1024)
+	r := l.Reader(bytes.NewReader(make([]byte, 64*1024)))
+	buf := make([]byte, 512)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	l.SetRate(0)
+	start := time.Now()
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("lifting the limit should let the rest of the stream through")
+	}
+}
+
+func TestRateLimiterChunkSize(t *testing.T) {
+	if got := NewRateLimiter(0).chunkSize(); got != 0 {
+		t.Errorf("unlimited chunkSize = %d, want 0", got)
+	}
+	if got := NewRateLimiter(1024).chunkSize(); got != 512 {
+		t.Errorf("small-rate chunkSize = %d, want 512", got)
+	}
+	if got := NewRateLimiter(1 << 20).chunkSize(); got != (1<<20)/10 {
+		t.Errorf("chunkSize = %d, want %d", got, (1<<20)/10)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	c := &Client{}
+	if c.RateLimit() != 0 {
+		t.Errorf("default RateLimit = %d, want 0", c.RateLimit())
+	}
+	c.SetRateLimit(2048)
+	if c.RateLimit() != 2048 {
+		t.Errorf("RateLimit = %d, want 2048", c.RateLimit())
+	}
+	c.SetRateLimit(4096)
+	if c.RateLimit() != 4096 {
+		t.Errorf("RateLimit after change = %d, want 4096", c.RateLimit())
+	}
+}
+
+func TestClientRateLimitSetMidTransfer(t *testing.T) {
+	// A transfer started while unlimited is throttled once a limit is set:
+	// the remaining 20 KB at 100 KB/s take roughly 200ms.
+	c := &Client{}
+	r := c.transferPassThru()(bytes.NewReader(make([]byte, 64*1024+20*1024)), 0)
+	if _, err := io.CopyN(io.Discard, r, 64*1024); err != nil {
+		t.Fatal(err)
+	}
+	c.SetRateLimit(100 * 1024)
+	start := time.Now()
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("rest of the transfer took %v, expected throttling to ~200ms", elapsed)
+	}
+}
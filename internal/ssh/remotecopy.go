@@ -0,0 +1,134 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+
+	"github.com/bramvdbogaerde/go-scp"
+)
+
+// CopyToClient streams srcPath on c straight into dstPath on dst through
+// dst's SCP sink, without touching local disk. progress, if non-nil, is
+// called with the cumulative number of bytes copied. dst's rate limit
+// applies to the stream.
+func (c *Client) CopyToClient(srcPath string, dst *Client, dstPath string, mode os.FileMode, progress func(int64)) (retErr error) {
+	log.Printf("[SSH] streaming %s -> %s:%s", srcPath, dst.address, dstPath)
+	size, err := c.remoteSize(srcPath)
+	if err != nil {
+		return err
+	}
+
+	cmd, stdin, err := c.sudoWrap(fmt.Sprintf("cat -- %s", shellQuote(srcPath)), nil)
+	if err != nil {
+		return err
+	}
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := session.Close(); cErr != nil && !errors.Is(cErr, io.EOF) {
+			retErr = errors.Join(retErr, fmt.Errorf("close session: %w", cErr))
+		}
+	}()
+	session.Stdin = stdin
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	scpClient, err := scp.NewClientBySSH(dst.client)
+	if err != nil {
+		return err
+	}
+	defer scpClient.Close()
+
+	var r io.Reader = stdout
+	if progress != nil {
+		r = &progressReader{r: r, fn: progress}
+	}
+	copyErr := scpClient.CopyPassThru(context.Background(), r, dstPath, fmt.Sprintf("0%o", mode.Perm()), size, dst.transferPassThru())
+	if copyErr != nil {
+		// Unblock cat if the sink gave up early.
+		_ = session.Close()
+		return fmt.Errorf("copy to %s: %w", dst.address, copyErr)
+	}
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("read %s: %w", srcPath, err)
+	}
+	return nil
+}
+
+// progressReader reports the running total of bytes read.
+type progressReader struct {
+	r     io.Reader
+	total int64
+	fn    func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.total += int64(n)
+		p.fn(p.total)
+	}
+	return n, err
+}
+
+// scpSafePath matches remote paths that survive the remote shell of legacy
+// scp unquoted.
+var scpSafePath = regexp.MustCompile(`^[A-Za-z0-9._/+,=@%-]+$`)
+
+// errDirectSudo is returned by DirectCopy in sudo mode.
+var errDirectSudo = errors.New("direct scp cannot read files through sudo")
+
+// DirectCopy runs scp on c's host to copy srcPath to dstPath on user@host.
+// It only works when c's host can reach the destination and authenticate
+// without a password; BatchMode makes it fail fast instead of prompting.
+// scp would read the file as the login user, so it is refused in sudo mode.
+func (c *Client) DirectCopy(srcPath, user, host, port, dstPath string) error {
+	if c.SudoEnabled() {
+		return errDirectSudo
+	}
+	if !scpSafePath.MatchString(dstPath) {
+		return fmt.Errorf("destination path %q needs quoting, which scp cannot do reliably", dstPath)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+	target := fmt.Sprintf("%s@%s:%s", user, host, dstPath)
+	cmd := fmt.Sprintf("scp -B -q -p -o ConnectTimeout=10 -P %s %s %s",
+		shellQuote(port), shellQuote(srcPath), shellQuote(target))
+	log.Printf("[SSH] direct copy on %s: %s", c.address, cmd)
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+	if out, err := session.CombinedOutput(cmd); err != nil {
+		if len(out) > 0 {
+			return fmt.Errorf("direct scp: %s", firstLine(string(out)))
+		}
+		return fmt.Errorf("direct scp: %w", err)
+	}
+	return nil
+}
+
+// firstLine returns the first non-empty line of s.
+func firstLine(s string) string {
+	for _, line := range splitLines(s) {
+		if line != "" {
+			return line
+		}
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package ssh
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgressReader(t *testing.T) {
+	var seen []int64
+	r := &progressReader{r: strings.NewReader("hello world"), fn: func(n int64) { seen = append(seen, n) }}
+	buf := make([]byte, 4)
+	for {
+		if _, err := r.Read(buf); err == io.EOF {
+			break
+		}
+	}
+	if len(seen) == 0 || seen[len(seen)-1] != 11 {
+		t.Errorf("progress = %v, want final 11", seen)
+	}
+}
+
+func TestDirectCopyRejectsUnsafePath(t *testing.T) {
+	c := &Client{}
+	err := c.DirectCopy("/src", "u", "h", "22", "/dst/with space.txt")
+	if err == nil || !strings.Contains(err.Error(), "quoting") {
+		t.Errorf("err = %v, want quoting error", err)
+	}
+}
+
+func TestDirectCopyRefusedInSudoMode(t *testing.T) {
+	c := &Client{sudo: sudoState{on: true, nopasswd: true}}
+	if err := c.DirectCopy("/src", "u", "h", "22", "/dst"); !errors.Is(err, errDirectSudo) {
+		t.Errorf("err = %v, want the sudo refusal", err)
+	}
+}
+
+func TestExistsServer(t *testing.T) {
+	s := newShellServer(t)
+	c := s.client(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "it's here"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("nowhere", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"it's here": true, "dangling": true, "missing": false} {
+		got, err := c.Exists(filepath.Join(dir, name))
+		if err != nil || got != want {
+			t.Errorf("Exists(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+}
+
+func TestSCPSafePath(t *testing.T) {
+	for _, p := range []string{"/srv/app/release-1.2_final.tar.gz", "relative/file"} {
+		if !scpSafePath.MatchString(p) {
+			t.Errorf("%q should be safe", p)
+		}
+	}
+	for _, p := range []string{"/a b", "/x;rm", "/$HOME", "/it's"} {
+		if scpSafePath.MatchString(p) {
+			t.Errorf("%q should not be safe", p)
+		}
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	if got := firstLine("\nHost key verification failed.\nlost connection\n"); got != "Host key verification failed." {
+		t.Errorf("firstLine = %q", got)
+	}
+}
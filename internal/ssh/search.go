@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SearchSpec describes a file search. Zero fields do not filter.
+type SearchSpec struct {
+	Name           string        // shell glob matched against the base name
+	Content        string        // text the file must contain (not a regex)
+	MinSize        int64         // bytes
+	MaxSize        int64         // bytes
+	ModifiedWithin time.Duration // only files modified this recently
+}
+
+// SearchResult is one file or directory found by a search.
+type SearchResult struct {
+	Path  string
+	IsDir bool
+}
+
+// Matches reports whether an entry passes the name, size and mtime filters
+// of s. Content is not checked.
+func (s SearchSpec) Matches(name string, size int64, mtime, now time.Time) bool {
+	if s.Name != "" {
+		if ok, err := path.Match(s.Name, name); err != nil || !ok {
+			return false
+		}
+	}
+	if s.MinSize > 0 && size < s.MinSize {
+		return false
+	}
+	if s.MaxSize > 0 && size > s.MaxSize {
+		return false
+	}
+	if s.ModifiedWithin > 0 && now.Sub(mtime) > s.ModifiedWithin {
+		return false
+	}
+	return true
+}
+
+// Search looks for entries below dir matching spec and calls found for each
+// one as the remote command prints it. It runs find, or grep -rIlF for a
+// plain content search, and stops when ctx is cancelled. Unreadable
+// entries are skipped silently.
+func (c *Client) Search(ctx context.Context, dir string, spec SearchSpec, found func(SearchResult)) error {
+	cmd := searchCmd(dir, spec)
+	log.Printf("[SSH] search: %s", cmd)
+	n := 0
+	err := c.stream(ctx, cmd, func(line string) {
+		if r, ok := parseSearchLine(line); ok {
+			n++
+			found(r)
+		}
+	})
+	// find and grep exit with 1 for unreadable entries or no matches.
+	// grep -r exits with 2 when a file is unreadable, even after finding
+	// matches elsewhere.
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) && (exitErr.ExitStatus() == 1 || exitErr.ExitStatus() == 2 && n > 0) {
+		return nil
+	}
+	return err
+}
+
+// searchCmd builds the remote command for a search. Directories are printed
+// with a trailing slash.
+func searchCmd(dir string, spec SearchSpec) string {
+	var filters []string
+	if spec.Name != "" {
+		filters = append(filters, "-name "+shellQuote(spec.Name))
+	}
+	if spec.MinSize > 0 {
+		filters = append(filters, fmt.Sprintf("-size +%dc", spec.MinSize-1))
+	}
+	if spec.MaxSize > 0 {
+		filters = append(filters, fmt.Sprintf("-size -%dc", spec.MaxSize+1))
+	}
+	if spec.ModifiedWithin > 0 {
+		mins := int64((spec.ModifiedWithin + time.Minute - 1) / time.Minute)
+		filters = append(filters, fmt.Sprintf("-mmin -%d", mins))
+	}
+	q := shellQuote(dir)
+
+	if spec.Content != "" {
+		if spec.MinSize == 0 && spec.MaxSize == 0 && spec.ModifiedWithin == 0 {
+			include := ""
+			if spec.Name != "" {
+				include = "--include=" + shellQuote(spec.Name) + " "
+			}
+			return fmt.Sprintf("grep -rIlF %s-e %s -- %s 2>/dev/null", include, shellQuote(spec.Content), q)
+		}
+		return fmt.Sprintf("find %s -mindepth 1 -type f %s -exec grep -IlF -e %s -- {} + 2>/dev/null",
+			q, strings.Join(filters, " "), shellQuote(spec.Content))
+	}
+	f := ""
+	if len(filters) > 0 {
+		f = strings.Join(filters, " ") + " "
+	}
+	return fmt.Sprintf(`find %s -mindepth 1 %s\( -type d -exec printf '%%s/\n' {} + -o -print \) 2>/dev/null`, q, f)
+}
+
+// parseSearchLine turns a line of searchCmd output into a result.
+func parseSearchLine(line string) (SearchResult, bool) {
+	if line == "" {
+		return SearchResult{}, false
+	}
+	if len(line) > 1 && strings.HasSuffix(line, "/") {
+		return SearchResult{Path: strings.TrimSuffix(line, "/"), IsDir: true}, true
+	}
+	return SearchResult{Path: line}, true
+}
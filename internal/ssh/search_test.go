@@ -0,0 +1,151 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// searchTree creates a small tree for running searchCmd with the local shell.
+func searchTree(t *testing.T) string {
+	t.Helper()
+	for _, tool := range []string{"sh", "find", "grep"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	dir := filepath.Join(t.TempDir(), "it's here")
+	files := map[string]string{
+		"etc/nginx/nginx.conf": "server_name example.com;\n",
+		"etc/app.conf":         "listen 80\n",
+		"var/big.log":          strings.Repeat("x", 5000),
+		"var/bin.conf":         "server_name\x00binary",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "etc/app.conf"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func runSearchCmd(t *testing.T, dir string, spec SearchSpec) string {
+	t.Helper()
+	out, _ := exec.Command("sh", "-c", searchCmd(dir, spec)).Output()
+	var got []string
+	for _, line := range splitLines(string(out)) {
+		if r, ok := parseSearchLine(line); ok {
+			rel, _ := filepath.Rel(dir, r.Path)
+			if r.IsDir {
+				rel += "/"
+			}
+			got = append(got, rel)
+		}
+	}
+	sort.Strings(got)
+	return strings.Join(got, ",")
+}
+
+func TestSearchCmdLocal(t *testing.T) {
+	dir := searchTree(t)
+	tests := []struct {
+		spec SearchSpec
+		want string
+	}{
+		{SearchSpec{}, "etc/,etc/app.conf,etc/nginx/,etc/nginx/nginx.conf,var/,var/big.log,var/bin.conf"},
+		{SearchSpec{Name: "*.conf"}, "etc/app.conf,etc/nginx/nginx.conf,var/bin.conf"},
+		{SearchSpec{Name: "nginx"}, "etc/nginx/"},
+		{SearchSpec{MinSize: 4096}, "etc/,etc/nginx/,var/,var/big.log"},
+		{SearchSpec{Name: "*.conf", MaxSize: 10}, "etc/app.conf"},
+		{SearchSpec{Name: "*.conf", ModifiedWithin: time.Hour}, "etc/nginx/nginx.conf,var/bin.conf"},
+		{SearchSpec{Content: "server_name"}, "etc/nginx/nginx.conf"},
+		{SearchSpec{Content: "listen", Name: "*.conf"}, "etc/app.conf"},
+		{SearchSpec{Content: "server_name", ModifiedWithin: time.Hour}, "etc/nginx/nginx.conf"},
+		{SearchSpec{Content: "listen", ModifiedWithin: time.Hour}, ""},
+	}
+	for _, tt := range tests {
+		if got := runSearchCmd(t, dir, tt.spec); got != tt.want {
+			t.Errorf("search %+v = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSearchSpecMatches(t *testing.T) {
+	now := time.Now()
+	spec := SearchSpec{Name: "*.log", MinSize: 10, MaxSize: 100, ModifiedWithin: time.Hour}
+	if !spec.Matches("app.log", 50, now.Add(-time.Minute), now) {
+		t.Error("entry within all limits should match")
+	}
+	for _, c := range []struct {
+		name  string
+		size  int64
+		mtime time.Time
+	}{
+		{"app.txt", 50, now},
+		{"app.log", 5, now},
+		{"app.log", 500, now},
+		{"app.log", 50, now.Add(-2 * time.Hour)},
+	} {
+		if spec.Matches(c.name, c.size, c.mtime, now) {
+			t.Errorf("%s size %d should not match", c.name, c.size)
+		}
+	}
+	if !(SearchSpec{}).Matches("anything", 0, time.Time{}, now) {
+		t.Error("empty spec should match everything")
+	}
+}
+
+func TestParseSearchLine(t *testing.T) {
+	if r, ok := parseSearchLine("/etc/nginx/"); !ok || !r.IsDir || r.Path != "/etc/nginx" {
+		t.Errorf("dir line = %+v", r)
+	}
+	if r, ok := parseSearchLine("/etc/hosts"); !ok || r.IsDir {
+		t.Errorf("file line = %+v", r)
+	}
+	if _, ok := parseSearchLine(""); ok {
+		t.Error("empty line should be skipped")
+	}
+}
+
+func TestSearchServer(t *testing.T) {
+	dir := searchTree(t)
+	s := newShellServer(t)
+	c := s.client(t)
+	search := func(spec SearchSpec) ([]string, error) {
+		var got []string
+		err := c.Search(context.Background(), dir, spec, func(r SearchResult) {
+			got = append(got, filepath.Base(r.Path))
+		})
+		return got, err
+	}
+
+	if got, err := search(SearchSpec{Content: "server_name"}); err != nil || len(got) != 1 || got[0] != "nginx.conf" {
+		t.Errorf("content search = %q, %v", got, err)
+	}
+	if got, err := search(SearchSpec{Content: "nowhere"}); err != nil || len(got) != 0 {
+		t.Errorf("search without matches = %q, %v; want none and no error", got, err)
+	}
+
+	// grep -r exits with 2 when a file is unreadable, even after matches.
+	s.tool(t, "grep", `echo "$(eval echo \${$#})/found.conf"; echo "grep: secret: Permission denied" >&2; exit 2`)
+	if got, err := search(SearchSpec{Content: "x"}); err != nil || len(got) != 1 || got[0] != "found.conf" {
+		t.Errorf("search with an unreadable file = %q, %v; want the match and no error", got, err)
+	}
+	s.tool(t, "grep", `exit 2`)
+	if _, err := search(SearchSpec{Content: "x"}); err == nil {
+		t.Error("grep failing without results should be reported")
+	}
+}
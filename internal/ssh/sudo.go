@@ -0,0 +1,219 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrSudoPassword is returned by operations in sudo mode when sudo needs a
+// password and none is cached (or the cached one has expired).
+var ErrSudoPassword = errors.New("sudo password required")
+
+// sudoState is the per-connection sudo mode.
+type sudoState struct {
+	on       bool
+	nopasswd bool // sudo works without a password (NOPASSWD)
+	password string
+	expires  time.Time
+}
+
+// SudoEnabled reports whether remote commands run through sudo.
+func (c *Client) SudoEnabled() bool {
+	c.sudoMu.Lock()
+	defer c.sudoMu.Unlock()
+	return c.sudo.on
+}
+
+// EnableSudo turns on sudo mode. It returns ErrSudoPassword when sudo needs
+// a password that is not cached; call SudoLogin with the password then.
+func (c *Client) EnableSudo() error {
+	// -k ignores sudo's own credential cache, so this only succeeds with
+	// NOPASSWD rules. The check runs without sudoMu, which the view takes
+	// on every render through SudoEnabled.
+	_, err := c.rawOutput("sudo -k -n true", nil)
+	c.sudoMu.Lock()
+	defer c.sudoMu.Unlock()
+	if err == nil {
+		log.Printf("[SSH] sudo mode on (no password needed)")
+		c.sudo = sudoState{on: true, nopasswd: true}
+		return nil
+	}
+	if c.sudo.password != "" && time.Now().Before(c.sudo.expires) {
+		c.sudo.on = true
+		return nil
+	}
+	return ErrSudoPassword
+}
+
+// SudoLogin verifies password with sudo, caches it for ttl and turns on sudo
+// mode. Once ttl has passed, commands fail with ErrSudoPassword until the
+// password is entered again.
+func (c *Client) SudoLogin(password string, ttl time.Duration) error {
+	// A wrong password can take sudo seconds to reject, so sudoMu is only
+	// held to store the result.
+	if _, err := c.rawOutput("sudo -k -S -p '' true", strings.NewReader(password+"\n")); err != nil {
+		log.Printf("[SSH] sudo login failed: %v", err)
+		return errors.New("sudo: incorrect password or not allowed to run sudo")
+	}
+	log.Printf("[SSH] sudo mode on (password cached for %s)", ttl)
+	c.sudoMu.Lock()
+	defer c.sudoMu.Unlock()
+	c.sudo = sudoState{on: true, password: password, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// DisableSudo turns off sudo mode and forgets the cached password.
+func (c *Client) DisableSudo() {
+	c.sudoMu.Lock()
+	defer c.sudoMu.Unlock()
+	c.sudo = sudoState{}
+}
+
+// sudoWrap prepares cmd for the current mode. It returns the command to run
+// and the stdin to send, which in password mode starts with the password
+// line that sudo -S consumes before the command reads the rest.
+func (c *Client) sudoWrap(cmd string, stdin io.Reader) (string, io.Reader, error) {
+	c.sudoMu.Lock()
+	defer c.sudoMu.Unlock()
+	s := c.sudo
+	if !s.on {
+		return cmd, stdin, nil
+	}
+	if s.nopasswd {
+		return "sudo -n sh -c " + shellQuote(cmd), stdin, nil
+	}
+	if s.password == "" || time.Now().After(s.expires) {
+		c.sudo.password = ""
+		return "", nil, ErrSudoPassword
+	}
+	// -k makes sudo always read the password, so it never ends up in the
+	// command's input.
+	pw := strings.NewReader(s.password + "\n")
+	if stdin == nil {
+		stdin = pw
+	} else {
+		stdin = io.MultiReader(pw, stdin)
+	}
+	return "sudo -k -S -p '' sh -c " + shellQuote(cmd), stdin, nil
+}
+
+// run runs cmd (through sudo in sudo mode) with the given stdin and returns
+// its stdout.
+func (c *Client) run(cmd string, stdin io.Reader) ([]byte, error) {
+	wrapped, in, err := c.sudoWrap(cmd, stdin)
+	if err != nil {
+		return nil, err
+	}
+	return c.rawOutput(wrapped, in)
+}
+
+// stream runs cmd (through sudo in sudo mode) and calls line for each line
+// of its output as it arrives. Cancelling ctx closes the session, ending
+// the remote command; stream then returns ctx.Err().
+func (c *Client) stream(ctx context.Context, cmd string, line func(string)) error {
+	wrapped, stdin, err := c.sudoWrap(cmd, nil)
+	if err != nil {
+		return err
+	}
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+	session.Stdin = stdin
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.Start(wrapped); err != nil {
+		return err
+	}
+
+	// Closing the session ends the remote command and unblocks the scanner.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Close()
+		case <-stop:
+		}
+	}()
+
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line(sc.Text())
+	}
+	err = session.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// rawOutput runs cmd in a new session exactly as given. Stderr is included
+// in the error when the command fails.
+func (c *Client) rawOutput(cmd string, stdin io.Reader) (out []byte, retErr error) {
+	session, err := c.newSession()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cErr := session.Close(); cErr != nil && !errors.Is(cErr, io.EOF) {
+			retErr = errors.Join(retErr, fmt.Errorf("close session: %w", cErr))
+		}
+	}()
+	var stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stderr = &stderr
+	out, err = session.Output(cmd)
+	if err != nil && stderr.Len() > 0 {
+		return out, fmt.Errorf("%w: %s", err, firstLine(stderr.String()))
+	}
+	return out, err
+}
+
+// sudoUpload writes a local file to remotePath with sudo tee.
+func (c *Client) sudoUpload(src io.Reader, remotePath string) error {
+	_, err := c.run(fmt.Sprintf("tee -- %s >/dev/null", shellQuote(remotePath)), c.limiter.Reader(src))
+	return err
+}
+
+// sudoDownload streams remotePath to w with sudo cat.
+func (c *Client) sudoDownload(remotePath string, w io.Writer) error {
+	cmd, stdin, err := c.sudoWrap(fmt.Sprintf("cat -- %s", shellQuote(remotePath)), nil)
+	if err != nil {
+		return err
+	}
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+	var stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stderr = &stderr
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+	_, copyErr := io.Copy(w, c.limiter.Reader(stdout))
+	if err := errors.Join(copyErr, session.Wait()); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%w: %s", err, firstLine(stderr.String()))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSudoWrapOff(t *testing.T) {
+	c := &Client{}
+	in := strings.NewReader("data")
+	cmd, stdin, err := c.sudoWrap("ls /etc", in)
+	if err != nil || cmd != "ls /etc" || stdin != in {
+		t.Errorf("cmd=%q stdin=%v err=%v, want unchanged", cmd, stdin, err)
+	}
+}
+
+func TestSudoWrapNoPasswd(t *testing.T) {
+	c := &Client{sudo: sudoState{on: true, nopasswd: true}}
+	cmd, _, err := c.sudoWrap("cat '/etc/shadow'", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd != `sudo -n sh -c 'cat '\''/etc/shadow'\'''` {
+		t.Errorf("cmd = %q", cmd)
+	}
+}
+
+func TestSudoWrapPasswordPrefixesStdin(t *testing.T) {
+	c := &Client{sudo: sudoState{on: true, password: "s3cret", expires: time.Now().Add(time.Minute)}}
+	cmd, stdin, err := c.sudoWrap("tee -- '/etc/motd' >/dev/null", strings.NewReader("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cmd, "sudo -k -S -p '' sh -c ") {
+		t.Errorf("cmd = %q", cmd)
+	}
+	data, _ := io.ReadAll(stdin)
+	if string(data) != "s3cret\nhello\n" {
+		t.Errorf("stdin = %q, want password line then data", data)
+	}
+}
+
+func TestSudoWrapExpired(t *testing.T) {
+	c := &Client{sudo: sudoState{on: true, password: "s3cret", expires: time.Now().Add(-time.Second)}}
+	if _, _, err := c.sudoWrap("true", nil); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("err = %v, want ErrSudoPassword", err)
+	}
+	if c.sudo.password != "" {
+		t.Error("expired password should be forgotten")
+	}
+	if !c.SudoEnabled() {
+		t.Error("sudo mode should stay on until disabled")
+	}
+}
+
+func TestSudoRunWithoutPassword(t *testing.T) {
+	c := &Client{sudo: sudoState{on: true}}
+	if _, err := c.run("true", nil); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("err = %v, want ErrSudoPassword", err)
+	}
+}
+
+func TestDisableSudo(t *testing.T) {
+	c := &Client{sudo: sudoState{on: true, password: "x", expires: time.Now().Add(time.Hour)}}
+	c.DisableSudo()
+	if c.SudoEnabled() || c.sudo.password != "" {
+		t.Error("DisableSudo should turn the mode off and forget the password")
+	}
+}
+
+// fakeSudo installs a sudo on s that accepts -k, -n, -S and -p like the real
+// one. -S reads the password from the first line of stdin and checks it
+// against password; -n succeeds only when nopasswd is set. The command then
+// runs with the rest of stdin.
+func fakeSudo(t *testing.T, s *shellServer, password string, nopasswd bool) {
+	t.Helper()
+	pwFile := filepath.Join(s.bin, "sudo-password")
+	if err := os.WriteFile(pwFile, []byte(password), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	allow := "false"
+	if nopasswd {
+		allow = "true"
+	}
+	s.tool(t, "sudo", `
+ask=0; nonint=0
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-k) shift ;;
+	-n) nonint=1; shift ;;
+	-S) ask=1; shift ;;
+	-p) shift 2 ;;
+	*) break ;;
+	esac
+done
+if [ $nonint = 1 ]; then
+	`+allow+` || { echo "sudo: a password is required" >&2; exit 1; }
+elif [ $ask = 1 ]; then
+	IFS= read -r pw
+	[ "$pw" = "$(cat '`+pwFile+`')" ] || { echo "sudo: incorrect password" >&2; exit 1; }
+fi
+exec "$@"
+`)
+}
+
+func TestSudoNoPasswdServer(t *testing.T) {
+	s := newShellServer(t)
+	fakeSudo(t, s, "", true)
+	c := s.client(t)
+	if err := c.EnableSudo(); err != nil {
+		t.Fatalf("EnableSudo() = %v", err)
+	}
+
+	p := filepath.Join(t.TempDir(), "f.txt")
+	if err := c.WriteFile(p, "root only\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.ReadFile(p); err != nil || got != "root only\n" {
+		t.Errorf("ReadFile() = %q, %v", got, err)
+	}
+	if !slices.Contains(s.commands(), "sudo -n sh -c "+shellQuote("cat "+shellQuote(p))) {
+		t.Errorf("commands = %q, want cat run with sudo -n", s.commands())
+	}
+}
+
+func TestSudoPasswordServer(t *testing.T) {
+	s := newShellServer(t)
+	fakeSudo(t, s, "s3cret", false)
+	c := s.client(t)
+	if err := c.EnableSudo(); !errors.Is(err, ErrSudoPassword) {
+		t.Fatalf("EnableSudo() = %v, want ErrSudoPassword", err)
+	}
+	if err := c.SudoLogin("wrong", time.Minute); err == nil || c.SudoEnabled() {
+		t.Fatal("SudoLogin with a wrong password should fail and leave sudo off")
+	}
+	if err := c.SudoLogin("s3cret", time.Minute); err != nil || !c.SudoEnabled() {
+		t.Fatalf("SudoLogin() = %v, enabled = %v", err, c.SudoEnabled())
+	}
+
+	// Uploads go through sudo tee: the password line must not reach the file.
+	dir := t.TempDir()
+	data := []byte("line one\nline two\n")
+	local := filepath.Join(dir, "up.txt")
+	if err := os.WriteFile(local, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(t.TempDir(), "up.txt")
+	if err := c.UploadFile(local, remote); err != nil {
+		t.Fatalf("UploadFile() = %v", err)
+	}
+	if got, _ := os.ReadFile(remote); !bytes.Equal(got, data) {
+		t.Errorf("uploaded %q, want %q", got, data)
+	}
+
+	down := t.TempDir()
+	if err := c.DownloadFile(remote, down); err != nil {
+		t.Fatalf("DownloadFile() = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(down, "up.txt")); !bytes.Equal(got, data) {
+		t.Errorf("downloaded %q, want %q", got, data)
+	}
+	err := c.DownloadFile(filepath.Join(dir, "missing"), down)
+	if err == nil || !strings.Contains(err.Error(), "No such file") {
+		t.Errorf("DownloadFile(missing) = %v, want cat's error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lines []string
+	err = c.Tail(ctx, remote, 10, func(line string) {
+		lines = append(lines, line)
+		if len(lines) == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) || !slices.Equal(lines, []string{"line one", "line two"}) {
+		t.Errorf("Tail() = %v, lines %q", err, lines)
+	}
+
+	// DisableSudo forgets the password, so enabling again asks for it.
+	c.DisableSudo()
+	if err := c.EnableSudo(); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("EnableSudo() after DisableSudo = %v, want ErrSudoPassword", err)
+	}
+}
+
+func TestSudoPasswordExpiresServer(t *testing.T) {
+	s := newShellServer(t)
+	fakeSudo(t, s, "s3cret", false)
+	c := s.client(t)
+	if err := c.SudoLogin("s3cret", 300*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "f.txt")
+	if err := c.WriteFile(p, "x"); err != nil {
+		t.Fatalf("WriteFile() before expiry = %v", err)
+	}
+	time.Sleep(350 * time.Millisecond)
+
+	n := len(s.commands())
+	if _, err := c.ReadFile(p); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("ReadFile() after expiry = %v, want ErrSudoPassword", err)
+	}
+	if err := c.DownloadFile(p, t.TempDir()); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("DownloadFile() after expiry = %v, want ErrSudoPassword", err)
+	}
+	if err := c.Tail(context.Background(), p, 1, func(string) {}); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("Tail() after expiry = %v, want ErrSudoPassword", err)
+	}
+	if got := s.commands()[n:]; len(got) != 0 {
+		t.Errorf("commands run after expiry: %q", got)
+	}
+	if err := c.EnableSudo(); !errors.Is(err, ErrSudoPassword) {
+		t.Errorf("EnableSudo() after expiry = %v, want ErrSudoPassword", err)
+	}
+}
+
+func TestSudoLoginDoesNotBlockSudoEnabled(t *testing.T) {
+	s := newShellServer(t)
+	release := filepath.Join(t.TempDir(), "release")
+	// sudo hangs until the test releases it, like a slow rejection.
+	s.tool(t, "sudo", `while [ ! -e '`+release+`' ]; do sleep 0.01; done; exit 1`)
+	c := s.client(t)
+
+	done := make(chan error, 1)
+	go func() { done <- c.SudoLogin("wrong", time.Minute) }()
+	for !slices.ContainsFunc(s.commands(), func(cmd string) bool { return strings.HasPrefix(cmd, "sudo") }) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	checked := make(chan bool, 1)
+	go func() { checked <- c.SudoEnabled() }()
+	select {
+	case on := <-checked:
+		if on {
+			t.Error("sudo mode on before the login finished")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("SudoEnabled blocked while sudo was checking the password")
+	}
+	if err := os.WriteFile(release, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err == nil {
+		t.Error("SudoLogin succeeded with a rejected password")
+	}
+}
@@ -0,0 +1,70 @@
+package ssh
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// linksMarker separates the ls output of listCmd from the status of the
+// symlinks. ls -l never prints a line starting with a colon.
+const linksMarker = ":links"
+
+// listCmd returns the shell command ListDir runs: ls -la of dir, then
+// linksMarker and one line per symlink in dir, "d/name" if it resolves to a
+// directory, "f/name" if it resolves to anything else and "x/name" if its
+// target does not exist. ls -l cannot tell these apart, and testing them in
+// the same command saves a round trip. The command fails if ls does.
+func listCmd(dir string) string {
+	q := shellQuote(dir)
+	// Times come as seconds since the epoch, so they do not depend on the
+	// server's timezone.
+	return fmt.Sprintf(`ls -la --time-style=+%%s %s 2>/dev/null || ls -la %s || exit; echo %s; `+
+		`cd -- %s 2>/dev/null && for f in .* *; do [ -L "$f" ] || continue; `+
+		`if [ -d "$f" ]; then s=d; elif [ -e "$f" ]; then s=f; else s=x; fi; printf '%%s/%%s\n' "$s" "$f"; done; true`,
+		q, q, linksMarker, q)
+}
+
+// splitListing splits the output of listCmd into the ls output and the
+// link status lines.
+func splitListing(output string) (ls, links string) {
+	if i := strings.Index(output, "\n"+linksMarker+"\n"); i >= 0 {
+		return output[:i+1], output[i+len(linksMarker)+2:]
+	}
+	if strings.HasPrefix(output, linksMarker+"\n") {
+		return "", output[len(linksMarker)+1:]
+	}
+	return output, ""
+}
+
+// applyLinkStatus applies the link status lines of listCmd to the symlinks
+// in files, by name. Links without a status are left as plain entries.
+func applyLinkStatus(files []RemoteFile, output string) {
+	status := map[string]string{}
+	for _, line := range splitLines(output) {
+		if s, name, ok := strings.Cut(line, "/"); ok && name != "" {
+			status[name] = s
+		}
+	}
+	for j := range files {
+		if !files[j].IsLink() {
+			continue
+		}
+		s, ok := status[files[j].Name]
+		if !ok {
+			log.Printf("[SSH] no status for symlink %s", files[j].Name)
+			continue
+		}
+		files[j].LinkDir = s == "d"
+		files[j].LinkBroken = s == "x"
+	}
+}
+
+// Symlink creates a symbolic link at linkPath pointing to target. The
+// target is stored as given, so relative targets resolve against the
+// link's directory.
+func (c *Client) Symlink(target, linkPath string) error {
+	log.Printf("[SSH] symlink %s -> %s", linkPath, target)
+	_, err := c.run(fmt.Sprintf("ln -s -- %s %s", shellQuote(target), shellQuote(linkPath)), nil)
+	return err
+}
@@ -0,0 +1,138 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// linkDir makes a directory holding a file, a subdirectory and links to
+// both and to a missing target.
+func linkDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "my dir")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"to dir": "sub", "to-file": "file", "it's gone": "missing", ".hidden": "sub"} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// checkLinks checks the entries of linkDir as listed.
+func checkLinks(t *testing.T, files []RemoteFile) {
+	t.Helper()
+	want := map[string][2]bool{ // LinkDir, LinkBroken
+		"to dir": {true, false}, "to-file": {false, false}, "it's gone": {false, true}, ".hidden": {true, false},
+	}
+	seen := 0
+	for _, f := range files {
+		w, ok := want[f.Name]
+		if !ok {
+			if f.LinkDir || f.LinkBroken {
+				t.Errorf("%s = %+v, not a link", f.Name, f)
+			}
+			continue
+		}
+		seen++
+		if !f.IsLink() || f.LinkDir != w[0] || f.LinkBroken != w[1] {
+			t.Errorf("%s = %+v, want LinkDir %v, LinkBroken %v", f.Name, f, w[0], w[1])
+		}
+	}
+	if seen != len(want) {
+		t.Errorf("listed %d of %d links: %+v", seen, len(want), files)
+	}
+}
+
+func TestListCmdLocal(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := linkDir(t)
+	out, err := exec.Command("sh", "-c", listCmd(dir)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, links := splitListing(string(out))
+	files := parseLS(ls)
+	applyLinkStatus(files, links)
+	checkLinks(t, files)
+}
+
+func TestListCmdMissingDir(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	out, err := exec.Command("sh", "-c", listCmd(filepath.Join(t.TempDir(), "nope"))).Output()
+	if err == nil {
+		t.Errorf("listing a missing directory succeeded: %q", out)
+	}
+}
+
+func TestListDirLinksServer(t *testing.T) {
+	s := newShellServer(t)
+	c := s.client(t)
+	dir := linkDir(t)
+	before := len(s.commands())
+	files, err := c.ListDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLinks(t, files)
+	if n := len(s.commands()) - before; n != 1 {
+		t.Errorf("listing ran %d commands, want 1", n)
+	}
+}
+
+func TestSplitListing(t *testing.T) {
+	ls, links := splitListing("total 0\nlrwxrwxrwx 1 u u 3 0 a -> b\n" + linksMarker + "\nf/a\n")
+	if ls != "total 0\nlrwxrwxrwx 1 u u 3 0 a -> b\n" || links != "f/a\n" {
+		t.Errorf("splitListing = %q, %q", ls, links)
+	}
+	if ls, links := splitListing("total 0\n"); ls != "total 0\n" || links != "" {
+		t.Errorf("without marker = %q, %q", ls, links)
+	}
+}
+
+func TestApplyLinkStatus(t *testing.T) {
+	files := []RemoteFile{
+		{Name: "a", Mode: os.ModeSymlink | 0o777},
+		{Name: "dir", Mode: os.ModeDir | 0o755, IsDir: true},
+		{Name: "b", Mode: os.ModeSymlink | 0o777},
+		{Name: "c", Mode: os.ModeSymlink | 0o777},
+		{Name: "d", Mode: os.ModeSymlink | 0o777},
+	}
+	applyLinkStatus(files, "f/c\nd/a\nx/b\nd/dir\n")
+	if !files[0].LinkDir || files[0].LinkBroken {
+		t.Errorf("a = %+v, want directory link", files[0])
+	}
+	if files[1].LinkDir || files[1].LinkBroken {
+		t.Errorf("plain directory should be untouched: %+v", files[1])
+	}
+	if files[2].LinkDir || !files[2].LinkBroken {
+		t.Errorf("b = %+v, want broken link", files[2])
+	}
+	if files[3].LinkDir || files[3].LinkBroken {
+		t.Errorf("c = %+v, want file link", files[3])
+	}
+	if files[4].LinkDir || files[4].LinkBroken {
+		t.Errorf("d without a status = %+v, want plain link", files[4])
+	}
+}
+
+func TestRemoteFileIsLink(t *testing.T) {
+	f := parseLSLine("lrwxrwxrwx 1 root root 7 2024-01-15 10:30:00 bin -> usr/bin")
+	if f == nil || !f.IsLink() || f.IsDir {
+		t.Fatalf("parsed %+v, want a symlink", f)
+	}
+	if (RemoteFile{Name: "x", Mode: 0o644}).IsLink() {
+		t.Error("regular file reported as link")
+	}
+}
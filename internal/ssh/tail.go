@@ -0,0 +1,21 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Tail follows a remote file with tail -F in its own session, calling line
+// for the last n lines and then for each new line. tail's own messages
+// (e.g. when the file is rotated or missing) arrive as lines too. Tail runs
+// until ctx is cancelled, which closes the session.
+func (c *Client) Tail(ctx context.Context, p string, n int, line func(string)) error {
+	log.Printf("[SSH] tail: %s", p)
+	return c.stream(ctx, tailCmd(p, n), line)
+}
+
+// tailCmd returns the command used by Tail.
+func tailCmd(p string, n int) string {
+	return fmt.Sprintf("tail -n %d -F -- %s 2>&1", n, shellQuote(p))
+}
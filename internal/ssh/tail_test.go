@@ -0,0 +1,43 @@
+package ssh
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTailCmdLocal(t *testing.T) {
+	for _, tool := range []string{"sh", "tail"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	p := filepath.Join(t.TempDir(), "it's.log")
+	if err := os.WriteFile(p, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// tail -F never exits by itself: read what it prints, then kill it.
+	cmd := exec.Command("sh", "-c", "exec "+tailCmd(p, 2))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	sc := bufio.NewScanner(stdout)
+	var got []string
+	for len(got) < 2 && sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	if strings.Join(got, ",") != "two,three" {
+		t.Errorf("tail output = %q, want the last 2 lines", got)
+	}
+}
@@ -0,0 +1,168 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// TreeEntry is a file or directory found by a recursive listing. Path is
+// slash-separated and relative to the listing root.
+type TreeEntry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// checksumBatch is the number of files hashed per remote command, keeping
+// the command line well below ARG_MAX.
+const checksumBatch = 100
+
+// output runs cmd (through sudo in sudo mode) and returns its stdout.
+func (c *Client) output(cmd string) ([]byte, error) {
+	return c.run(cmd, nil)
+}
+
+// ListTree recursively lists everything below root. It uses GNU find's
+// -printf when available and otherwise falls back to walking the tree with
+// ListDir, which only needs a POSIX ls. Subdirectories that cannot be read
+// are returned in skipped, relative to root; their contents are missing
+// from entries.
+func (c *Client) ListTree(root string) (entries []TreeEntry, skipped []string, err error) {
+	log.Printf("[SSH] listing remote tree: %s", root)
+	// Unreadable directories are printed with type "u" as well; find then
+	// exits with 1 but still lists everything else.
+	cmd := fmt.Sprintf(`find %s -mindepth 1 -printf '%%y\t%%s\t%%T@\t%%P\n' `+
+		`\( -type d \( ! -readable -o ! -executable \) -printf 'u\t0\t0\t%%P\n' \)`, shellQuote(root))
+	out, err := c.output(cmd)
+	var exitErr *ssh.ExitError
+	if err == nil || errors.As(err, &exitErr) && exitErr.ExitStatus() == 1 && len(out) > 0 {
+		entries, skipped = parseFindPrintf(string(out))
+		if len(skipped) > 0 {
+			log.Printf("[SSH] %d unreadable directories skipped below %s", len(skipped), root)
+		}
+		return entries, skipped, nil
+	}
+	log.Printf("[SSH] find -printf failed (%v), falling back to ls walk", err)
+	if err := c.walkTree(root, "", &entries, &skipped); err != nil {
+		return nil, nil, err
+	}
+	return entries, skipped, nil
+}
+
+// walkTree appends the entries of root/rel to entries and recurses into
+// subdirectories. Subdirectories that cannot be listed are appended to
+// skipped; only a failure to list root itself is an error.
+func (c *Client) walkTree(root, rel string, entries *[]TreeEntry, skipped *[]string) error {
+	dir := root
+	if rel != "" {
+		dir = strings.TrimRight(root, "/") + "/" + rel
+	}
+	files, err := c.ListDir(dir)
+	if err != nil && len(files) == 0 {
+		if rel != "" {
+			log.Printf("[SSH] skipping unreadable %s: %v", dir, err)
+			*skipped = append(*skipped, rel)
+			return nil
+		}
+		return err
+	}
+	for _, f := range files {
+		p := f.Name
+		if rel != "" {
+			p = rel + "/" + f.Name
+		}
+		*entries = append(*entries, TreeEntry{Path: p, Size: f.Size, ModTime: f.ModTime, IsDir: f.IsDir})
+		if f.IsDir {
+			if err := c.walkTree(root, p, entries, skipped); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseFindPrintf parses lines of the form "type\tsize\tmtime\tpath" as
+// produced by find -printf '%y\t%s\t%T@\t%P\n'. Type "u" marks an
+// unreadable directory, returned in skipped. Entries other than regular
+// files and directories are left out.
+func parseFindPrintf(output string) (entries []TreeEntry, skipped []string) {
+	for _, line := range splitLines(output) {
+		parts := strings.SplitN(line, "\t", 4)
+		if len(parts) != 4 || parts[3] == "" {
+			continue
+		}
+		if parts[0] == "u" {
+			skipped = append(skipped, parts[3])
+			continue
+		}
+		if parts[0] != "f" && parts[0] != "d" {
+			continue
+		}
+		size, _ := strconv.ParseInt(parts[1], 10, 64)
+		var mtime time.Time
+		if secs, err := strconv.ParseFloat(parts[2], 64); err == nil {
+			mtime = time.Unix(int64(secs), 0)
+		}
+		entries = append(entries, TreeEntry{
+			Path:    parts[3],
+			Size:    size,
+			ModTime: mtime,
+			IsDir:   parts[0] == "d",
+		})
+	}
+	return entries, skipped
+}
+
+// Checksums returns the SHA-256 digests of the given files, which are
+// relative to root, keyed by relative path. Files that cannot be hashed are
+// left out of the result.
+func (c *Client) Checksums(root string, paths []string) (map[string]string, error) {
+	sums := make(map[string]string, len(paths))
+	for start := 0; start < len(paths); start += checksumBatch {
+		end := start + checksumBatch
+		if end > len(paths) {
+			end = len(paths)
+		}
+		quoted := make([]string, 0, end-start)
+		for _, p := range paths[start:end] {
+			quoted = append(quoted, shellQuote(p))
+		}
+		cmd := fmt.Sprintf("cd %s && sha256sum -- %s", shellQuote(root), strings.Join(quoted, " "))
+		out, err := c.output(cmd)
+		if err != nil && len(out) == 0 {
+			return nil, fmt.Errorf("remote sha256sum: %w", err)
+		}
+		for k, v := range parseChecksums(string(out)) {
+			sums[k] = v
+		}
+	}
+	return sums, nil
+}
+
+// parseChecksums parses sha256sum/md5sum output ("digest  path" per line).
+func parseChecksums(output string) map[string]string {
+	sums := map[string]string{}
+	for _, line := range splitLines(output) {
+		idx := strings.Index(line, " ")
+		if idx <= 0 || idx+2 > len(line) {
+			continue
+		}
+		// The separator is two characters: a space and ' ' or '*' (binary mode).
+		sums[line[idx+2:]] = line[:idx]
+	}
+	return sums
+}
+
+// SetModTime sets the modification time of a remote file using POSIX touch.
+func (c *Client) SetModTime(path string, t time.Time) error {
+	cmd := fmt.Sprintf("TZ=UTC touch -m -t %s %s", t.UTC().Format("200601021504.05"), shellQuote(path))
+	_, err := c.output(cmd)
+	return err
+}
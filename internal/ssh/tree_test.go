@@ -0,0 +1,283 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFindPrintf(t *testing.T) {
+	out := "d\t4096\t1700000000.5\tsrc\n" +
+		"f\t120\t1700000100.0000000000\tsrc/main.go\n" +
+		"l\t7\t1700000000.0\tlink\n" +
+		"malformed line\n" +
+		"f\t0\t1700000200.0\tname with\ttab\n"
+	entries, skipped := parseFindPrintf(out + "u\t0\t0\tsrc/private\n")
+	if len(skipped) != 1 || skipped[0] != "src/private" {
+		t.Errorf("skipped = %q, want src/private", skipped)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+	if !entries[0].IsDir || entries[0].Path != "src" {
+		t.Errorf("entry 0 = %+v, want dir src", entries[0])
+	}
+	if entries[1].Size != 120 || !entries[1].ModTime.Equal(time.Unix(1700000100, 0)) {
+		t.Errorf("entry 1 = %+v", entries[1])
+	}
+	if entries[2].Path != "name with\ttab" {
+		t.Errorf("path with tab = %q", entries[2].Path)
+	}
+}
+
+func TestParseFindPrintfEmpty(t *testing.T) {
+	if got, _ := parseFindPrintf(""); len(got) != 0 {
+		t.Errorf("expected no entries, got %d", len(got))
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	out := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty.txt\n" +
+		"abcd *bin/tool\n" +
+		"garbage\n"
+	sums := parseChecksums(out)
+	if sums["empty.txt"] != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty.txt sum = %q", sums["empty.txt"])
+	}
+	if sums["bin/tool"] != "abcd" {
+		t.Errorf("binary-mode sum = %q", sums["bin/tool"])
+	}
+	if len(sums) != 2 {
+		t.Errorf("expected 2 sums, got %d", len(sums))
+	}
+}
+
+// makeTree creates a small tree whose entries all have mtime.
+func makeTree(t *testing.T, mtime time.Time) string {
+	t.Helper()
+	root := t.TempDir()
+	for p, data := range map[string]string{
+		"a.txt":             "alpha",
+		"sub/b.txt":         "bravo!",
+		"sub/deep/c d.txt":  "charlie",
+		"sub/deep/empty.md": "",
+	} {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{"sub/deep/c d.txt", "sub/deep/empty.md", "sub/deep", "a.txt", "sub/b.txt", "sub"} {
+		if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(p)), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func checkTree(t *testing.T, entries []TreeEntry, mtime time.Time) {
+	t.Helper()
+	got := map[string]TreeEntry{}
+	for _, e := range entries {
+		got[e.Path] = e
+	}
+	want := map[string]int64{"a.txt": 5, "sub/b.txt": 6, "sub/deep/c d.txt": 7, "sub/deep/empty.md": 0}
+	for p, size := range want {
+		e, ok := got[p]
+		if !ok || e.IsDir || e.Size != size {
+			t.Errorf("%s = %+v, want a file of %d bytes", p, e, size)
+			continue
+		}
+		if !e.ModTime.Equal(mtime) {
+			t.Errorf("%s mtime = %v, want %v", p, e.ModTime, mtime)
+		}
+	}
+	for _, p := range []string{"sub", "sub/deep"} {
+		if !got[p].IsDir {
+			t.Errorf("%s should be listed as a directory", p)
+		}
+	}
+	if len(got) != len(want)+2 {
+		t.Errorf("listed %d entries, want %d: %+v", len(got), len(want)+2, entries)
+	}
+}
+
+func TestListTreeFind(t *testing.T) {
+	s := newShellServer(t)
+	mtime := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	root := makeTree(t, mtime)
+
+	entries, skipped, err := s.client(t).ListTree(root)
+	if err != nil || len(skipped) != 0 {
+		t.Fatal(err, skipped)
+	}
+	checkTree(t, entries, mtime)
+	if cmds := s.commands(); len(cmds) != 1 || !strings.HasPrefix(cmds[0], "find ") {
+		t.Errorf("commands = %q, want a single find", cmds)
+	}
+}
+
+func TestListTreeLsFallback(t *testing.T) {
+	s := newShellServer(t)
+	s.tool(t, "find", "echo 'find: unknown predicate -printf' >&2; exit 1\n")
+	// A server in another timezone must not shift the times.
+	s.env = []string{"TZ=Pacific/Kiritimati"}
+	mtime := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	root := makeTree(t, mtime)
+
+	entries, skipped, err := s.client(t).ListTree(root)
+	if err != nil || len(skipped) != 0 {
+		t.Fatal(err, skipped)
+	}
+	checkTree(t, entries, mtime)
+
+	if _, _, err := s.client(t).ListTree(filepath.Join(root, "missing")); err == nil {
+		t.Error("listing a missing directory should fail")
+	}
+}
+
+func TestListTreeFindSkipsUnreadable(t *testing.T) {
+	s := newShellServer(t)
+	mtime := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	root := makeTree(t, mtime)
+	// find lists what it can, reports the unreadable directory and exits
+	// with 1, as it does for a permission error.
+	s.tool(t, "find", "/usr/bin/find \"$@\" | grep -v '^.\tsub/deep/locked' ; printf 'd\t0\t0\tsub/deep/locked\nu\t0\t0\tsub/deep/locked\n'; exit 1\n")
+
+	entries, skipped, err := s.client(t).ListTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0] != "sub/deep/locked" {
+		t.Errorf("skipped = %q, want sub/deep/locked", skipped)
+	}
+	var kept []TreeEntry
+	for _, e := range entries {
+		if e.Path != "sub/deep/locked" {
+			kept = append(kept, e)
+		}
+	}
+	checkTree(t, kept, mtime)
+	if n := len(s.commands()); n != 1 {
+		t.Errorf("ran %d commands, want a single find without the ls walk", n)
+	}
+}
+
+func TestListTreeFindUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read every directory")
+	}
+	s := newShellServer(t)
+	root := makeTree(t, time.Now())
+	locked := filepath.Join(root, "sub", "locked")
+	if err := os.Mkdir(locked, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(locked, "secret"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o755) })
+
+	_, skipped, err := s.client(t).ListTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0] != "sub/locked" {
+		t.Errorf("skipped = %q, want sub/locked", skipped)
+	}
+}
+
+func TestListTreeLsFallbackSkipsUnreadable(t *testing.T) {
+	s := newShellServer(t)
+	s.tool(t, "find", "echo 'find: unknown predicate -printf' >&2; exit 1\n")
+	s.tool(t, "ls", "case \"$*\" in *locked*) echo 'ls: Permission denied' >&2; exit 2 ;; esac; exec /bin/ls \"$@\"\n")
+	mtime := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	root := makeTree(t, mtime)
+	if err := os.Mkdir(filepath.Join(root, "sub", "locked"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(root, "sub", "locked"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, skipped, err := s.client(t).ListTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0] != "sub/locked" {
+		t.Errorf("skipped = %q, want sub/locked", skipped)
+	}
+	if len(entries) != 7 {
+		t.Errorf("listed %d entries, want the 6 readable ones and sub/locked: %+v", len(entries), entries)
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	s := newShellServer(t)
+	root := makeTree(t, time.Now())
+	paths := []string{"a.txt", "sub/deep/c d.txt", "no such file"}
+	// Enough files for a second batch.
+	for i := range checksumBatch {
+		name := fmt.Sprintf("n%03d", i)
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, name)
+	}
+
+	sums, err := s.client(t).Checksums(root, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := func(data string) string {
+		h := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(h[:])
+	}
+	if sums["a.txt"] != digest("alpha") || sums["sub/deep/c d.txt"] != digest("charlie") || sums["n099"] != digest("n099") {
+		t.Errorf("wrong digests: a.txt=%s c=%s n099=%s", sums["a.txt"], sums["sub/deep/c d.txt"], sums["n099"])
+	}
+	if _, ok := sums["no such file"]; ok || len(sums) != len(paths)-1 {
+		t.Errorf("got %d digests, want %d without the missing file", len(sums), len(paths)-1)
+	}
+	if len(s.commands()) != 2 {
+		t.Errorf("ran %d commands, want 2 batches", len(s.commands()))
+	}
+
+	if _, err := s.client(t).Checksums(filepath.Join(root, "missing"), []string{"a.txt"}); err == nil {
+		t.Error("hashing in a missing directory should fail")
+	}
+}
+
+func TestSetModTime(t *testing.T) {
+	s := newShellServer(t)
+	p := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(p, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2023, 11, 5, 23, 59, 58, 0, time.FixedZone("X", 5*3600))
+	c := s.client(t)
+	if err := c.SetModTime(p, want); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(want) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), want)
+	}
+	if err := c.SetModTime(filepath.Join(p, "x", "y"), want); err == nil {
+		t.Error("touching a file in a missing directory should fail")
+	}
+}
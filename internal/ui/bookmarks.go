@@ -0,0 +1,214 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"ssh-scp/internal/config"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// BookmarksChangedMsg is sent when bookmarks are added or removed, so the app
+// can save them with the host's connection.
+type BookmarksChangedMsg struct {
+	Bookmarks []config.Bookmark
+}
+
+// bookmarkJumpMsg requests showing a bookmarked directory.
+type bookmarkJumpMsg struct{ path string }
+
+// bookmarkAddMsg requests bookmarking the panel's directory under name.
+type bookmarkAddMsg struct{ name string }
+
+// bookmarkDeleteMsg requests removing the bookmark at index in the full list.
+type bookmarkDeleteMsg struct{ index int }
+
+// bookmarksCloseMsg is sent when the bookmark list is closed.
+type bookmarksCloseMsg struct{}
+
+// BookmarksModel lists the bookmarks of one panel and lets the user jump to,
+// add or delete them.
+type BookmarksModel struct {
+	all    []config.Bookmark
+	items  []int // indexes into all for this panel
+	local  bool
+	dir    string // directory the panel shows, offered for adding
+	cursor int
+	adding bool
+	input  textinput.Model
+	width  int
+	height int
+}
+
+// NewBookmarksModel creates a bookmark list for the local or remote panel,
+// which currently shows dir.
+func NewBookmarksModel(all []config.Bookmark, local bool, dir string) BookmarksModel {
+	m := BookmarksModel{all: all, local: local, dir: dir}
+	for i, b := range all {
+		if b.Local == local {
+			m.items = append(m.items, i)
+		}
+	}
+	return m
+}
+
+// SetDimensions sets the view's display dimensions.
+func (m *BookmarksModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Update handles key events for the bookmark list.
+func (m BookmarksModel) Update(msg tea.Msg) (BookmarksModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.adding {
+		switch key.Type {
+		case tea.KeyEsc:
+			m.adding = false
+			return m, nil
+		case tea.KeyEnter:
+			name := strings.TrimSpace(m.input.Value())
+			if name == "" {
+				return m, nil
+			}
+			m.adding = false
+			return m, func() tea.Msg { return bookmarkAddMsg{name: name} }
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+	switch key.String() {
+	case "esc", "q":
+		return m, func() tea.Msg { return bookmarksCloseMsg{} }
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	case "enter":
+		if len(m.items) > 0 {
+			p := m.all[m.items[m.cursor]].Path
+			return m, func() tea.Msg { return bookmarkJumpMsg{path: p} }
+		}
+	case "a":
+		ti := textinput.New()
+		ti.CharLimit = 64
+		ti.Width = 30
+		ti.SetValue(path.Base(m.dir))
+		ti.CursorEnd()
+		ti.Focus()
+		m.input = ti
+		m.adding = true
+		return m, textinput.Blink
+	case "d":
+		if len(m.items) > 0 {
+			idx := m.items[m.cursor]
+			return m, func() tea.Msg { return bookmarkDeleteMsg{index: idx} }
+		}
+	}
+	return m, nil
+}
+
+// View renders the bookmark list.
+func (m BookmarksModel) View() string {
+	side := "remote"
+	if m.local {
+		side = "local"
+	}
+	lines := []string{messageStyle.Render(fmt.Sprintf("Bookmarks (%s)", side)), ""}
+	if len(m.items) == 0 {
+		lines = append(lines, statusBarStyle.Render("No bookmarks yet — press a to bookmark "+m.dir))
+	}
+	nameWidth := 12
+	for _, i := range m.items {
+		if n := len(m.all[i].Name); n > nameWidth {
+			nameWidth = n
+		}
+	}
+	for row, i := range m.items {
+		b := m.all[i]
+		line := fmt.Sprintf("%-*s  %s", nameWidth, b.Name, b.Path)
+		if row == m.cursor {
+			line = fileSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "")
+	if m.adding {
+		lines = append(lines, messageStyle.Render("Name for "+m.dir+": ")+m.input.View())
+	} else {
+		lines = append(lines, statusBarStyle.Render("Enter: go • a: add current dir • d: delete • Esc: close"))
+	}
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// SetBookmarks sets the host's bookmarks for both panels.
+func (m *FileBrowserModel) SetBookmarks(b []config.Bookmark) {
+	m.marks = b
+}
+
+// openBookmarks shows the bookmark list of the focused panel.
+func (m FileBrowserModel) openBookmarks() (FileBrowserModel, tea.Cmd) {
+	local := m.focus == panelLocal
+	dir := m.remoteDir
+	if local {
+		dir = m.localDir
+	}
+	bm := NewBookmarksModel(m.marks, local, dir)
+	m.bookmarks = &bm
+	return m, nil
+}
+
+// handleBookmarkMsg applies the actions of the bookmark list.
+func (m FileBrowserModel) handleBookmarkMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case bookmarksCloseMsg:
+		m.bookmarks = nil
+	case bookmarkJumpMsg:
+		m.bookmarks = nil
+		if m.focus == panelLocal {
+			m.chdirLocal(msg.path)
+			return m, nil
+		}
+		return m, m.chdirRemote(msg.path)
+	case bookmarkAddMsg:
+		b := config.Bookmark{Name: msg.name, Path: m.bookmarks.dir, Local: m.bookmarks.local}
+		marks := make([]config.Bookmark, 0, len(m.marks)+1)
+		for _, old := range m.marks {
+			// A new bookmark replaces one with the same name on the same side.
+			if old.Name != b.Name || old.Local != b.Local {
+				marks = append(marks, old)
+			}
+		}
+		return m.updateBookmarks(append(marks, b))
+	case bookmarkDeleteMsg:
+		marks := make([]config.Bookmark, 0, len(m.marks))
+		marks = append(marks, m.marks[:msg.index]...)
+		return m.updateBookmarks(append(marks, m.marks[msg.index+1:]...))
+	}
+	return m, nil
+}
+
+// updateBookmarks stores a new bookmark list, refreshes the open list view
+// and asks the app to save it.
+func (m FileBrowserModel) updateBookmarks(marks []config.Bookmark) (FileBrowserModel, tea.Cmd) {
+	m.marks = marks
+	cursor := m.bookmarks.cursor
+	bm := NewBookmarksModel(marks, m.bookmarks.local, m.bookmarks.dir)
+	if cursor >= len(bm.items) {
+		cursor = len(bm.items) - 1
+	}
+	bm.cursor = max(cursor, 0)
+	m.bookmarks = &bm
+	return m, func() tea.Msg { return BookmarksChangedMsg{Bookmarks: marks} }
+}
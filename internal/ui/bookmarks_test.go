@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"ssh-scp/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runBookmarkCmds feeds the messages of cmd back into m until no command is left.
+func runBookmarkCmds(t *testing.T, m FileBrowserModel, cmd tea.Cmd) (FileBrowserModel, []tea.Msg) {
+	t.Helper()
+	var out []tea.Msg
+	for cmd != nil {
+		msg := cmd()
+		if msg == nil {
+			break
+		}
+		out = append(out, msg)
+		if _, ok := msg.(BookmarksChangedMsg); ok {
+			break
+		}
+		m, cmd = m.Update(msg)
+	}
+	return m, out
+}
+
+func TestFBBookmarksAddJumpDelete(t *testing.T) {
+	dir := navDir(t)
+	m := NewFileBrowserModel(nil, dir, "/srv")
+	m.SetDimensions(100, 30)
+	m.SetBookmarks([]config.Bookmark{{Name: "logs", Path: "/var/log"}})
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true})
+	if m.bookmarks == nil || !m.InputActive() {
+		t.Fatal("Alt+B should open the bookmark list")
+	}
+	if strings.Contains(m.View(), "/var/log") {
+		t.Error("remote bookmarks should not be listed for the local panel")
+	}
+
+	// Add the current local dir.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m.bookmarks.input.SetValue("proj")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, msgs := runBookmarkCmds(t, m, cmd)
+	changed, ok := msgs[len(msgs)-1].(BookmarksChangedMsg)
+	if !ok || len(changed.Bookmarks) != 2 {
+		t.Fatalf("expected BookmarksChangedMsg with 2 bookmarks, got %#v", msgs)
+	}
+	if b := changed.Bookmarks[1]; b.Name != "proj" || b.Path != dir || !b.Local {
+		t.Errorf("new bookmark = %+v", b)
+	}
+	if !strings.Contains(m.View(), "proj") {
+		t.Error("list should show the new bookmark")
+	}
+
+	// Jump to it from elsewhere.
+	m.bookmarks = nil
+	m.chdirLocal(dir + "/run")
+	m, _ = m.openBookmarks()
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = runBookmarkCmds(t, m, cmd)
+	if m.localDir != dir || m.bookmarks != nil {
+		t.Errorf("Enter should jump to %q and close, got %q", dir, m.localDir)
+	}
+
+	// Delete it.
+	m, _ = m.openBookmarks()
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	_, msgs = runBookmarkCmds(t, m, cmd)
+	changed = msgs[len(msgs)-1].(BookmarksChangedMsg)
+	if len(changed.Bookmarks) != 1 || changed.Bookmarks[0].Name != "logs" {
+		t.Errorf("after delete: %+v", changed.Bookmarks)
+	}
+}
+
+func TestFBBookmarkReplacesSameName(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv/new")
+	m.focus = panelRemote
+	m.SetBookmarks([]config.Bookmark{{Name: "app", Path: "/srv/old"}, {Name: "app", Path: "/tmp", Local: true}})
+	m, _ = m.openBookmarks()
+	m, cmd := m.Update(bookmarkAddMsg{name: "app"})
+	changed := cmd().(BookmarksChangedMsg)
+	if len(changed.Bookmarks) != 2 {
+		t.Fatalf("bookmarks = %+v", changed.Bookmarks)
+	}
+	for _, b := range changed.Bookmarks {
+		if !b.Local && b.Path != "/srv/new" {
+			t.Errorf("remote bookmark should be replaced, got %+v", b)
+		}
+	}
+	if m.bookmarks == nil {
+		t.Error("list should stay open after adding")
+	}
+}
+
+func TestBookmarksModelEscCloses(t *testing.T) {
+	bm := NewBookmarksModel(nil, false, "/srv")
+	_, cmd := bm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, ok := cmd().(bookmarksCloseMsg); !ok {
+		t.Error("Esc should close the list")
+	}
+}
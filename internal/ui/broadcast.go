@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// BroadcastTab is a tab that can join the broadcast group.
+type BroadcastTab struct {
+	Index  int // tab index
+	Title  string
+	Member bool
+}
+
+// BroadcastSetMsg is sent when the broadcast group is confirmed or
+// broadcasting is stopped.
+type BroadcastSetMsg struct {
+	Members []int // tab indexes in the group
+	Enabled bool
+}
+
+// BroadcastCloseMsg is sent when the broadcast dialog is cancelled.
+type BroadcastCloseMsg struct{}
+
+// BroadcastModel chooses the tabs whose terminals receive keystrokes typed
+// into any of them. Turning broadcasting on asks for confirmation.
+type BroadcastModel struct {
+	tabs    []BroadcastTab
+	cursor  int
+	enabled bool // broadcasting is already on
+	confirm bool
+	status  string
+	width   int
+	height  int
+}
+
+// NewBroadcastModel creates the dialog. enabled reports whether
+// broadcasting is already on for the members among tabs.
+func NewBroadcastModel(tabs []BroadcastTab, enabled bool) BroadcastModel {
+	return BroadcastModel{tabs: tabs, enabled: enabled}
+}
+
+// SetDimensions sets the dialog's display dimensions.
+func (m *BroadcastModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m BroadcastModel) members() []int {
+	var idx []int
+	for _, t := range m.tabs {
+		if t.Member {
+			idx = append(idx, t.Index)
+		}
+	}
+	return idx
+}
+
+// Update handles key events for the dialog.
+func (m BroadcastModel) Update(msg tea.Msg) (BroadcastModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.confirm {
+		switch key.String() {
+		case "y", "Y":
+			set := BroadcastSetMsg{Members: m.members(), Enabled: true}
+			return m, func() tea.Msg { return set }
+		case "n", "N", "esc":
+			m.confirm = false
+		}
+		return m, nil
+	}
+	m.status = ""
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return BroadcastCloseMsg{} }
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.tabs)-1 {
+			m.cursor++
+		}
+	case " ":
+		if m.cursor < len(m.tabs) {
+			m.tabs[m.cursor].Member = !m.tabs[m.cursor].Member
+		}
+	case "a":
+		all := len(m.members()) < len(m.tabs)
+		for i := range m.tabs {
+			m.tabs[i].Member = all
+		}
+	case "x":
+		if m.enabled {
+			return m, func() tea.Msg { return BroadcastSetMsg{Enabled: false} }
+		}
+	case "enter":
+		if len(m.members()) < 2 {
+			m.status = "Choose at least two tabs"
+			return m, nil
+		}
+		if !m.enabled {
+			m.confirm = true
+			return m, nil
+		}
+		set := BroadcastSetMsg{Members: m.members(), Enabled: true}
+		return m, func() tea.Msg { return set }
+	}
+	return m, nil
+}
+
+var broadcastStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("#FF9500"))
+
+// View renders the dialog.
+func (m BroadcastModel) View() string {
+	innerWidth := max(m.width-4, 20)
+	var rows []string
+	for i, t := range m.tabs {
+		box := "[ ]"
+		if t.Member {
+			box = "[x]"
+		}
+		line := truncate(box+" "+t.Title, innerWidth)
+		if i == m.cursor {
+			line = fileSelectedStyle.Width(innerWidth).Render(line)
+		}
+		rows = append(rows, line)
+	}
+
+	var footer string
+	switch {
+	case m.confirm:
+		footer = broadcastStyle.Render(fmt.Sprintf(
+			"Keystrokes typed into any of these %d tabs will be sent to all of them. Enable broadcast? (y/n)",
+			len(m.members())))
+	case m.status != "":
+		footer = historyFailStyle.Render(m.status)
+	case m.enabled:
+		footer = statusBarStyle.Render("Broadcasting is on")
+	}
+	hints := "↑/↓: select • Space: toggle • a: all • Enter: start • Esc: cancel"
+	if m.enabled {
+		hints = "↑/↓: select • Space: toggle • a: all • Enter: apply • x: stop broadcasting • Esc: cancel"
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		messageStyle.Render("Broadcast keystrokes to:"),
+		"",
+		strings.Join(rows, "\n"),
+		"",
+		footer,
+		statusBarStyle.Render(hints),
+	)
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(content)
+}
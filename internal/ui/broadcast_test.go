@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func broadcastKey(m BroadcastModel, key string) (BroadcastModel, tea.Msg) {
+	var msg tea.KeyMsg
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	case "down":
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	case " ":
+		msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	m, cmd := m.Update(msg)
+	if cmd == nil {
+		return m, nil
+	}
+	return m, cmd()
+}
+
+func TestBroadcastModelConfirm(t *testing.T) {
+	m := NewBroadcastModel([]BroadcastTab{
+		{Index: 0, Title: "web1", Member: true},
+		{Index: 1, Title: "web2"},
+		{Index: 2, Title: "db"},
+	}, false)
+	m.SetDimensions(80, 20)
+
+	m, msg := broadcastKey(m, "enter")
+	if msg != nil || !strings.Contains(m.View(), "at least two") {
+		t.Fatal("a single tab should not start broadcasting")
+	}
+	m, _ = broadcastKey(m, "down")
+	m, _ = broadcastKey(m, " ")
+	m, msg = broadcastKey(m, "enter")
+	if msg != nil || !strings.Contains(m.View(), "Enable broadcast? (y/n)") {
+		t.Fatal("Enter should ask for confirmation")
+	}
+	m, msg = broadcastKey(m, "n")
+	if msg != nil || m.confirm {
+		t.Fatal("n should cancel the confirmation")
+	}
+	m, _ = broadcastKey(m, "enter")
+	_, msg = broadcastKey(m, "y")
+	set, ok := msg.(BroadcastSetMsg)
+	if !ok || !set.Enabled || !reflect.DeepEqual(set.Members, []int{0, 1}) {
+		t.Errorf("confirmed = %#v", msg)
+	}
+}
+
+func TestBroadcastModelWhileEnabled(t *testing.T) {
+	m := NewBroadcastModel([]BroadcastTab{
+		{Index: 0, Title: "web1", Member: true},
+		{Index: 1, Title: "web2", Member: true},
+		{Index: 2, Title: "web3"},
+	}, true)
+	m, _ = broadcastKey(m, "a")
+	_, msg := broadcastKey(m, "enter")
+	if set, ok := msg.(BroadcastSetMsg); !ok || len(set.Members) != 3 {
+		t.Errorf("changes should apply without confirmation, got %#v", msg)
+	}
+	_, msg = broadcastKey(m, "x")
+	if set, ok := msg.(BroadcastSetMsg); !ok || set.Enabled {
+		t.Errorf("x should stop broadcasting, got %#v", msg)
+	}
+	if _, msg = broadcastKey(m, "esc"); msg != (BroadcastCloseMsg{}) {
+		t.Errorf("esc = %#v", msg)
+	}
+}
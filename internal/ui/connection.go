@@ -61,19 +61,25 @@ type ConnectionModel struct {
 	err           string
 	connecting    bool
 	connectTarget string
+	marked        []config.Connection // hosts a fleet command runs on
 }
 
 // connItem is a list item representing either a recent connection or an SSH config host.
 type connItem struct {
 	conn   config.Connection
 	source string // "recent" or "ssh-config"
+	marked bool   // marked for a fleet command
 }
 
 func (c connItem) Title() string {
+	title := c.conn.Host
 	if c.conn.Name != "" {
-		return c.conn.Name
+		title = c.conn.Name
 	}
-	return c.conn.Host
+	if c.marked {
+		return fleetMark + title
+	}
+	return title
 }
 func (c connItem) Description() string {
 	if c.conn.Username == "" {
@@ -183,6 +189,27 @@ func (m ConnectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlC, tea.KeyEsc:
 			return m, tea.Quit
 
+		case tea.KeySpace:
+			if m.activePane == paneRecent && m.recentIdx < m.recentMax() {
+				m.toggleMark(m.cfg.RecentConnections[m.recentIdx])
+				return m, nil
+			}
+			if m.activePane == paneList {
+				if item, ok := m.connList.SelectedItem().(connItem); ok {
+					m.toggleMark(item.conn)
+				}
+				return m, nil
+			}
+
+		case tea.KeyCtrlR:
+			if len(m.marked) == 0 {
+				m.err = "Mark hosts with Space in the lists first"
+				return m, nil
+			}
+			m.err = ""
+			conns := append([]config.Connection(nil), m.marked...)
+			return m, func() tea.Msg { return FleetMsg{Conns: conns} }
+
 		case tea.KeyEnter:
 			log.Printf("[ConnectionModel] Enter pressed, pane=%d host=%q user=%q toggle=%v",
 				m.activePane, m.inputs[fieldHost].Value(), m.inputs[fieldUser].Value(), m.focusOnToggle)
@@ -317,6 +344,48 @@ func (m ConnectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// fleetMark prefixes the hosts marked for a fleet command.
+const fleetMark = "● "
+
+// fleetKey identifies a host for marking, so that a recent connection and
+// an SSH config entry for the same login are marked together.
+func fleetKey(c config.Connection) string {
+	return c.Username + "@" + c.Host + ":" + c.Port
+}
+
+// isMarked reports whether c is marked for a fleet command.
+func (m *ConnectionModel) isMarked(c config.Connection) bool {
+	for _, mc := range m.marked {
+		if fleetKey(mc) == fleetKey(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleMark marks or unmarks c for a fleet command.
+func (m *ConnectionModel) toggleMark(c config.Connection) {
+	for i, mc := range m.marked {
+		if fleetKey(mc) == fleetKey(c) {
+			m.marked = append(m.marked[:i:i], m.marked[i+1:]...)
+			m.syncListMarks()
+			return
+		}
+	}
+	m.marked = append(m.marked, c)
+	m.syncListMarks()
+}
+
+// syncListMarks shows the marks in the SSH config list.
+func (m *ConnectionModel) syncListMarks() {
+	for i, it := range m.connList.Items() {
+		if item, ok := it.(connItem); ok && item.marked != m.isMarked(item.conn) {
+			item.marked = !item.marked
+			m.connList.SetItem(i, item)
+		}
+	}
+}
+
 // recentMax returns the number of visible recent entries (capped at 8).
 func (m *ConnectionModel) recentMax() int {
 	n := len(m.cfg.RecentConnections)
@@ -546,6 +615,9 @@ func (m ConnectionModel) View() string {
 		}
 		statusText = errorStyle.Render(errText)
 		statusMsg = statusText
+	} else if len(m.marked) > 0 {
+		statusMsg = lipgloss.NewStyle().Foreground(lipgloss.Color("#7D56F4")).Render(
+			fmt.Sprintf("%d host(s) marked • Ctrl+R: run a command on them", len(m.marked)))
 	}
 
 	// recent connections section.
@@ -582,6 +654,9 @@ func (m ConnectionModel) View() string {
 			} else {
 				label = fmt.Sprintf("%s:%s", c.Host, c.Port)
 			}
+			if m.isMarked(c) {
+				label = fleetMark + label
+			}
 			if m.activePane == paneRecent && i == m.recentIdx {
 				recentRows = append(recentRows, selectedStyle.Render(label))
 			} else {
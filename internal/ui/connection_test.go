@@ -1224,3 +1224,62 @@ func TestRecentPaneKeysDoNotLeakToInputs(t *testing.T) {
 		t.Errorf("host input changed to %q while in recent pane", m.inputs[fieldHost].Value())
 	}
 }
+
+func TestSpaceMarksHostsForFleet(t *testing.T) {
+	cfg := &config.Config{
+		RecentConnections: []config.Connection{
+			{Host: "h1", Port: "22", Username: "u1"},
+		},
+	}
+	hosts := []config.SSHHost{{Alias: "srv", HostName: "h2", User: "u2", Port: "22"}}
+	m := NewConnectionModelWithSSH(cfg, hosts)
+	m.width, m.height = 120, 40
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+
+	m.activePane = paneRecent
+	model, _ := m.Update(space)
+	m = model.(ConnectionModel)
+	m.activePane = paneList
+	model, _ = m.Update(space)
+	m = model.(ConnectionModel)
+	if len(m.marked) != 2 {
+		t.Fatalf("marked %d hosts, want 2", len(m.marked))
+	}
+	view := m.View()
+	for _, want := range []string{fleetMark + "u1@h1:22", fleetMark + "srv", "2 host(s) marked"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q", want)
+		}
+	}
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m = model.(ConnectionModel)
+	msg, ok := cmd().(FleetMsg)
+	if !ok || len(msg.Conns) != 2 || msg.Conns[0].Host != "h1" || msg.Conns[1].Host != "h2" {
+		t.Errorf("Ctrl+R = %+v, want both marked hosts in order", msg)
+	}
+
+	model, _ = m.Update(space)
+	m = model.(ConnectionModel)
+	if len(m.marked) != 1 || strings.Contains(m.View(), fleetMark+"srv") {
+		t.Error("Space on a marked host should unmark it")
+	}
+}
+
+func TestCtrlRWithoutMarkedHosts(t *testing.T) {
+	m := NewConnectionModelWithSSH(&config.Config{}, nil)
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m = model.(ConnectionModel)
+	if cmd != nil || m.err == "" {
+		t.Error("Ctrl+R without marked hosts should explain how to mark them")
+	}
+}
+
+func TestSpaceInFormTypesSpace(t *testing.T) {
+	m := NewConnectionModelWithSSH(&config.Config{}, nil)
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	m = model.(ConnectionModel)
+	if len(m.marked) != 0 || m.inputs[fieldHost].Value() != " " {
+		t.Errorf("Space in the form should type into the field, host = %q", m.inputs[fieldHost].Value())
+	}
+}
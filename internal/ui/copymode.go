@@ -0,0 +1,509 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Selection kinds in copy mode.
+const (
+	selectNone = iota
+	selectChars
+	selectLines
+)
+
+// copyMode browses a snapshot of a terminal's scrollback and screen with
+// vim-style keys. Output that arrives meanwhile goes to the live screen,
+// which is shown again when copy mode ends.
+type copyMode struct {
+	lines    []string
+	row, col int
+	want     int // column kept across vertical moves
+	top      int // first line shown
+	rows     int // lines shown, without the status line
+	cols     int
+
+	selection int
+	anchorRow int
+	anchorCol int
+
+	search     *regexp.Regexp
+	searchBack bool
+	input      textinput.Model
+	inputOpen  bool
+
+	status string
+	copied string // text yanked when copy mode ended
+}
+
+func newCopyMode(lines []string, row, col, cols, rows int) *copyMode {
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	c := &copyMode{lines: lines, cols: max(cols, 1), rows: max(rows-1, 1)}
+	c.row = min(max(row, 0), len(lines)-1)
+	c.top = max(len(lines)-c.rows, 0)
+	c.setCol(col)
+	c.scroll()
+	return c
+}
+
+// lineLen returns the number of runes on line r.
+func (c *copyMode) lineLen(r int) int {
+	return utf8.RuneCountInString(c.lines[r])
+}
+
+// setCol moves to column col of the current line, clamped to its last rune.
+func (c *copyMode) setCol(col int) {
+	c.col = min(max(col, 0), max(c.lineLen(c.row)-1, 0))
+	c.want = c.col
+}
+
+// moveRow moves n lines down (up when negative), keeping the column.
+func (c *copyMode) moveRow(n int) {
+	c.row = min(max(c.row+n, 0), len(c.lines)-1)
+	c.col = min(c.want, max(c.lineLen(c.row)-1, 0))
+}
+
+// scroll keeps the cursor line in view.
+func (c *copyMode) scroll() {
+	if c.row < c.top {
+		c.top = c.row
+	}
+	if c.row >= c.top+c.rows {
+		c.top = c.row - c.rows + 1
+	}
+}
+
+// page scrolls the view and the cursor by n lines.
+func (c *copyMode) page(n int) {
+	c.top = min(max(c.top+n, 0), max(len(c.lines)-c.rows, 0))
+	c.moveRow(n)
+}
+
+// update handles a key and reports whether copy mode should end.
+func (c *copyMode) update(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if c.inputOpen {
+		return false, c.updateInput(msg)
+	}
+	c.status = ""
+	switch msg.String() {
+	case "q":
+		return true, nil
+	case "esc":
+		if c.selection == selectNone {
+			return true, nil
+		}
+		c.selection = selectNone
+	case "h", "left":
+		c.setCol(c.col - 1)
+	case "l", "right":
+		c.setCol(c.col + 1)
+	case "k", "up":
+		c.moveRow(-1)
+	case "j", "down":
+		c.moveRow(1)
+	case "0", "home":
+		c.setCol(0)
+	case "^":
+		line := []rune(c.lines[c.row])
+		i := 0
+		for i < len(line) && unicode.IsSpace(line[i]) {
+			i++
+		}
+		c.setCol(i)
+	case "$", "end":
+		c.setCol(c.lineLen(c.row) - 1)
+	case "w":
+		c.wordForward()
+	case "b":
+		c.wordBackward()
+	case "e":
+		c.wordEnd()
+	case "g":
+		c.row = 0
+		c.setCol(0)
+	case "G":
+		c.row = len(c.lines) - 1
+		c.setCol(0)
+	case "ctrl+u":
+		c.page(-c.rows / 2)
+	case "ctrl+d":
+		c.page(c.rows / 2)
+	case "ctrl+b", "pgup", "alt+pgup":
+		c.page(-c.rows)
+	case "ctrl+f", "pgdown":
+		c.page(c.rows)
+	case "v", "V":
+		kind := selectChars
+		if msg.String() == "V" {
+			kind = selectLines
+		}
+		if c.selection == kind {
+			c.selection = selectNone
+		} else {
+			if c.selection == selectNone {
+				c.anchorRow, c.anchorCol = c.row, c.col
+			}
+			c.selection = kind
+		}
+	case "y", "enter":
+		if c.selection == selectNone {
+			c.status = "Nothing selected (v or V to select)"
+			return false, nil
+		}
+		c.copied = c.selectedText()
+		return true, nil
+	case "/", "?":
+		ti := textinput.New()
+		ti.Prompt = msg.String()
+		ti.CharLimit = 256
+		ti.Width = max(c.cols-4, 10)
+		ti.Focus()
+		c.input = ti
+		c.inputOpen = true
+		c.searchBack = msg.String() == "?"
+		return false, textinput.Blink
+	case "n":
+		c.findNext(c.searchBack)
+	case "N":
+		c.findNext(!c.searchBack)
+	}
+	c.scroll()
+	return false, nil
+}
+
+// updateInput edits the search regex. Enter searches, Esc cancels.
+func (c *copyMode) updateInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		c.inputOpen = false
+		return nil
+	case tea.KeyEnter:
+		re, err := regexp.Compile(c.input.Value())
+		if err != nil {
+			c.status = "Invalid regex: " + err.Error()
+			return nil
+		}
+		c.inputOpen = false
+		if c.input.Value() == "" {
+			return nil
+		}
+		c.search = re
+		c.findNext(c.searchBack)
+		c.scroll()
+		return nil
+	}
+	var cmd tea.Cmd
+	c.input, cmd = c.input.Update(msg)
+	return cmd
+}
+
+// matchCols returns the rune columns where matches of re start on line r.
+func matchCols(re *regexp.Regexp, line string) [][2]int {
+	var cols [][2]int
+	for _, loc := range re.FindAllStringIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		start := utf8.RuneCountInString(line[:loc[0]])
+		cols = append(cols, [2]int{start, start + utf8.RuneCountInString(line[loc[0]:loc[1]])})
+	}
+	return cols
+}
+
+// findNext moves to the next match after the cursor (before it when back
+// is set), wrapping around the ends.
+func (c *copyMode) findNext(back bool) {
+	if c.search == nil {
+		c.status = "No previous search"
+		return
+	}
+	n := len(c.lines)
+	for i := 0; i <= n; i++ {
+		r := c.row + i
+		if back {
+			r = c.row - i
+		}
+		wrapped := r < 0 || r >= n
+		r = (r%n + n) % n
+		matches := matchCols(c.search, c.lines[r])
+		if back {
+			for j := len(matches) - 1; j >= 0; j-- {
+				if i > 0 || matches[j][0] < c.col {
+					c.jump(r, matches[j][0], wrapped, "TOP", "BOTTOM")
+					return
+				}
+			}
+			continue
+		}
+		for _, m := range matches {
+			if i > 0 || m[0] > c.col {
+				c.jump(r, m[0], wrapped, "BOTTOM", "TOP")
+				return
+			}
+		}
+	}
+	c.status = "Pattern not found: " + c.search.String()
+}
+
+func (c *copyMode) jump(row, col int, wrapped bool, hit, cont string) {
+	c.row = row
+	c.setCol(col)
+	if wrapped {
+		c.status = fmt.Sprintf("search hit %s, continuing at %s", hit, cont)
+	}
+}
+
+// isWordRune reports whether r belongs to a word for w, b and e.
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r)
+}
+
+// wordForward moves to the start of the next word, across lines.
+func (c *copyMode) wordForward() {
+	line := []rune(c.lines[c.row])
+	i := c.col
+	for i < len(line) && isWordRune(line[i]) {
+		i++
+	}
+	for {
+		for i < len(line) && !isWordRune(line[i]) {
+			i++
+		}
+		if i < len(line) || c.row == len(c.lines)-1 {
+			break
+		}
+		c.row++
+		line, i = []rune(c.lines[c.row]), 0
+	}
+	c.setCol(i)
+}
+
+// wordBackward moves to the start of the previous word, across lines.
+func (c *copyMode) wordBackward() {
+	line := []rune(c.lines[c.row])
+	i := c.col - 1
+	for {
+		for i >= 0 && !isWordRune(line[i]) {
+			i--
+		}
+		if i >= 0 || c.row == 0 {
+			break
+		}
+		c.row--
+		line = []rune(c.lines[c.row])
+		i = len(line) - 1
+	}
+	for i > 0 && isWordRune(line[i-1]) {
+		i--
+	}
+	c.setCol(i)
+}
+
+// wordEnd moves to the end of the current or next word, across lines.
+func (c *copyMode) wordEnd() {
+	line := []rune(c.lines[c.row])
+	i := c.col + 1
+	for {
+		for i < len(line) && !isWordRune(line[i]) {
+			i++
+		}
+		if i < len(line) || c.row == len(c.lines)-1 {
+			break
+		}
+		c.row++
+		line, i = []rune(c.lines[c.row]), 0
+	}
+	for i+1 < len(line) && isWordRune(line[i+1]) {
+		i++
+	}
+	c.setCol(i)
+}
+
+// selectionBounds returns the selection as (row, col) from start to end,
+// both inclusive.
+func (c *copyMode) selectionBounds() (r0, c0, r1, c1 int) {
+	r0, c0, r1, c1 = c.anchorRow, c.anchorCol, c.row, c.col
+	if r1 < r0 || r1 == r0 && c1 < c0 {
+		r0, c0, r1, c1 = r1, c1, r0, c0
+	}
+	if c.selection == selectLines {
+		c0, c1 = 0, max(c.lineLen(r1)-1, 0)
+	}
+	return r0, c0, r1, c1
+}
+
+// selected reports whether column col of line r is selected.
+func (c *copyMode) selected(r, col int) bool {
+	if c.selection == selectNone {
+		return false
+	}
+	r0, c0, r1, c1 := c.selectionBounds()
+	switch {
+	case r < r0 || r > r1:
+		return false
+	case c.selection == selectLines:
+		return true
+	case r0 == r1:
+		return col >= c0 && col <= c1
+	case r == r0:
+		return col >= c0
+	case r == r1:
+		return col <= c1
+	}
+	return true
+}
+
+// selectedText returns the selected text, lines joined with newlines.
+func (c *copyMode) selectedText() string {
+	r0, c0, r1, c1 := c.selectionBounds()
+	var out []string
+	for r := r0; r <= r1; r++ {
+		line := []rune(c.lines[r])
+		from, to := 0, len(line)
+		if r == r0 {
+			from = min(c0, len(line))
+		}
+		if r == r1 {
+			to = min(c1+1, len(line))
+		}
+		out = append(out, string(line[from:max(to, from)]))
+	}
+	return strings.Join(out, "\n")
+}
+
+var (
+	copyCursorStyle = lipgloss.NewStyle().Reverse(true)
+	copySelectStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#7D56F4")).
+			Foreground(lipgloss.Color("#FFFFFF"))
+)
+
+// view renders the visible lines and a status line.
+func (c *copyMode) view() string {
+	var out []string
+	for r := c.top; r < c.top+c.rows; r++ {
+		if r >= len(c.lines) {
+			out = append(out, "")
+			continue
+		}
+		out = append(out, c.renderLine(r))
+	}
+	return strings.Join(append(out, c.statusLine()), "\n")
+}
+
+// renderLine draws line r with search matches, the selection and the
+// cursor highlighted, cut to the pane width.
+func (c *copyMode) renderLine(r int) string {
+	line := []rune(c.lines[r])
+	if r == c.row && c.col >= len(line) {
+		line = append(line, ' ') // show the cursor on an empty line
+	}
+	var matches [][2]int
+	if c.search != nil {
+		matches = matchCols(c.search, c.lines[r])
+	}
+	styleAt := func(col int) *lipgloss.Style {
+		switch {
+		case r == c.row && col == c.col:
+			return &copyCursorStyle
+		case c.selected(r, col):
+			return &copySelectStyle
+		}
+		for _, m := range matches {
+			if col >= m[0] && col < m[1] {
+				return &tailMatchStyle
+			}
+		}
+		return nil
+	}
+
+	var b strings.Builder
+	var run []rune
+	var runStyle *lipgloss.Style
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runStyle == nil {
+			b.WriteString(string(run))
+		} else {
+			b.WriteString(runStyle.Render(string(run)))
+		}
+		run = run[:0]
+	}
+	width := 0
+	for i, ch := range line {
+		if ch < ' ' || ch == 0x7f {
+			ch = '.'
+		}
+		w := runewidth.RuneWidth(ch)
+		if width+w > c.cols {
+			break
+		}
+		width += w
+		if st := styleAt(i); st != runStyle {
+			flush()
+			runStyle = st
+		}
+		run = append(run, ch)
+	}
+	flush()
+	return b.String()
+}
+
+func (c *copyMode) statusLine() string {
+	if c.inputOpen {
+		return c.input.View()
+	}
+	mode := "-- COPY --"
+	switch c.selection {
+	case selectChars:
+		mode = "-- VISUAL --"
+	case selectLines:
+		mode = "-- VISUAL LINE --"
+	}
+	pos := fmt.Sprintf(" %d/%d", c.row+1, len(c.lines))
+	left := messageStyle.Render(mode) + " "
+	switch {
+	case c.status != "":
+		left += historyFailStyle.Render(truncate(c.status, max(c.cols-len(mode)-len(pos)-2, 1)))
+	default:
+		left += statusBarStyle.Render(truncate("v/V: select • y: copy • /?: search • n/N • q: quit", max(c.cols-len(mode)-len(pos)-2, 1)))
+	}
+	gap := max(c.cols-lipgloss.Width(left)-len(pos), 0)
+	return left + strings.Repeat(" ", gap) + statusBarStyle.Render(pos)
+}
+
+// clipboardOut is where OSC 52 clipboard sequences are written: the
+// terminal the application runs in.
+var clipboardOut io.Writer = os.Stdout
+
+// copyToClipboard returns a command that puts text on the system clipboard
+// of the local terminal with OSC 52, passed through tmux or screen when the
+// application runs inside one.
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		switch {
+		case os.Getenv("TMUX") != "":
+			seq = seq.Tmux()
+		case os.Getenv("STY") != "":
+			seq = seq.Screen()
+		}
+		_, _ = seq.WriteTo(clipboardOut)
+		return nil
+	}
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func copyKeys(c *copyMode, keys ...string) {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		c.update(msg)
+	}
+}
+
+func TestCopyModeNavigation(t *testing.T) {
+	c := newCopyMode([]string{"  first line", "", "third  word here"}, 0, 0, 40, 5)
+	copyKeys(c, "^")
+	if c.col != 2 {
+		t.Errorf("^ col = %d, want 2", c.col)
+	}
+	copyKeys(c, "w")
+	if c.row != 0 || c.col != 8 {
+		t.Errorf("w = %d,%d, want 0,8", c.row, c.col)
+	}
+	copyKeys(c, "w")
+	if c.row != 2 || c.col != 0 {
+		t.Errorf("w across lines = %d,%d, want 2,0", c.row, c.col)
+	}
+	copyKeys(c, "e", "e")
+	if c.col != 10 {
+		t.Errorf("e e col = %d, want 10", c.col)
+	}
+	copyKeys(c, "b", "b")
+	if c.row != 2 || c.col != 0 {
+		t.Errorf("b b = %d,%d, want 2,0", c.row, c.col)
+	}
+	copyKeys(c, "$", "k")
+	if c.row != 1 || c.col != 0 {
+		t.Errorf("k onto an empty line = %d,%d", c.row, c.col)
+	}
+	copyKeys(c, "k")
+	if c.col != 11 {
+		t.Errorf("k should restore the wanted column, got %d", c.col)
+	}
+	copyKeys(c, "G")
+	if c.row != 2 {
+		t.Errorf("G row = %d", c.row)
+	}
+	copyKeys(c, "g")
+	if c.row != 0 || c.col != 0 {
+		t.Errorf("g = %d,%d", c.row, c.col)
+	}
+}
+
+func TestCopyModeScrollsWithCursor(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	c := newCopyMode(lines, 49, 0, 20, 11) // ten lines and the status line
+	if c.top != 40 {
+		t.Fatalf("top = %d, want 40", c.top)
+	}
+	c.update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	if c.row != 44 || c.top != 35 {
+		t.Errorf("ctrl+u: row %d top %d", c.row, c.top)
+	}
+	copyKeys(c, "g")
+	if c.top != 0 {
+		t.Errorf("g: top = %d", c.top)
+	}
+	view := c.view()
+	if got := strings.Count(view, "\n") + 1; got != 11 {
+		t.Errorf("view has %d lines, want 11", got)
+	}
+	if !strings.Contains(view, "line 9") || strings.Contains(view, "line 10") {
+		t.Error("view should show the first ten lines")
+	}
+	if !strings.Contains(view, "1/50") {
+		t.Error("status line should show the position")
+	}
+}
+
+func TestCopyModeSearch(t *testing.T) {
+	c := newCopyMode([]string{"error: a", "ok", "ERROR b", "error: c"}, 0, 0, 40, 5)
+	copyKeys(c, "/", "e", "r", "r", "o", "r", ":", "enter")
+	if c.row != 3 || c.col != 0 {
+		t.Errorf("/error: = %d,%d, want 3,0", c.row, c.col)
+	}
+	copyKeys(c, "n")
+	if c.row != 0 || !strings.Contains(c.status, "BOTTOM") {
+		t.Errorf("n should wrap to the top: row %d, status %q", c.row, c.status)
+	}
+	copyKeys(c, "N")
+	if c.row != 3 || !strings.Contains(c.status, "TOP") {
+		t.Errorf("N should wrap to the bottom: row %d, status %q", c.row, c.status)
+	}
+
+	copyKeys(c, "?", "(", "?", "i", ")", "e", "r", "r", "o", "r", " ", "enter")
+	if c.row != 2 {
+		t.Errorf("?(?i)error  = row %d, want 2", c.row)
+	}
+
+	copyKeys(c, "/", "n", "o", "p", "e", "enter")
+	if c.row != 2 || !strings.HasPrefix(c.status, "Pattern not found") {
+		t.Errorf("missing pattern: row %d, status %q", c.row, c.status)
+	}
+	copyKeys(c, "/", "(", "enter")
+	if !c.inputOpen || !strings.HasPrefix(c.status, "Invalid regex") {
+		t.Errorf("a bad regex should keep the input open, status %q", c.status)
+	}
+	copyKeys(c, "esc")
+	if c.inputOpen {
+		t.Error("esc should close the search input")
+	}
+}
+
+func TestCopyModeSelection(t *testing.T) {
+	c := newCopyMode([]string{"alpha beta", "gamma", "delta epsilon"}, 0, 6, 40, 5)
+	if done, _ := c.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}); done {
+		t.Fatal("y without a selection should not end copy mode")
+	}
+	if !strings.HasPrefix(c.status, "Nothing selected") {
+		t.Errorf("status = %q", c.status)
+	}
+
+	copyKeys(c, "v", "j", "j", "0", "e")
+	if got := c.selectedText(); got != "beta\ngamma\ndelta" {
+		t.Errorf("character selection = %q", got)
+	}
+	copyKeys(c, "V")
+	if got := c.selectedText(); got != "alpha beta\ngamma\ndelta epsilon" {
+		t.Errorf("line selection = %q", got)
+	}
+	copyKeys(c, "esc")
+	if c.selection != selectNone {
+		t.Error("esc should clear the selection first")
+	}
+	if done, _ := c.update(tea.KeyMsg{Type: tea.KeyEsc}); !done {
+		t.Error("a second esc should leave copy mode")
+	}
+}
+
+func TestTerminalCopyModeCopiesWithOSC52(t *testing.T) {
+	var out bytes.Buffer
+	old := clipboardOut
+	clipboardOut = &out
+	defer func() { clipboardOut = old }()
+	t.Setenv("TMUX", "")
+	t.Setenv("STY", "")
+
+	m := &TerminalModel{}
+	m.SetScrollback(100)
+	m.SetDimensions(44, 6) // 40×4 screen
+	for i := 0; i < 10; i++ {
+		m.AppendOutput([]byte(fmt.Sprintf("line %d\r\n", i)))
+	}
+	m.AppendOutput([]byte("$ "))
+
+	m.EnterCopyMode()
+	if !m.CopyMode() {
+		t.Fatal("copy mode should be active")
+	}
+	if !strings.Contains(m.RenderTerminal(true, 44, 6), "-- COPY --") {
+		t.Error("pane should show the copy view")
+	}
+	for _, k := range []string{"g", "j", "V", "j"} {
+		m.UpdateCopyMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+	cmd := m.UpdateCopyMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if m.CopyMode() || cmd == nil {
+		t.Fatal("y should leave copy mode and copy")
+	}
+	cmd()
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("line 1\nline 2")) + "\x07"
+	if out.String() != want {
+		t.Errorf("clipboard sequence = %q, want %q", out.String(), want)
+	}
+	if !strings.Contains(m.RenderTerminal(true, 44, 6), "Copied 13 bytes") {
+		t.Error("pane should confirm the copy")
+	}
+}
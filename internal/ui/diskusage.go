@@ -0,0 +1,327 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// duLoadedMsg carries the result of a disk usage scan.
+type duLoadedMsg struct {
+	root  *sshclient.DUNode
+	space *sshclient.DiskSpace // nil if df failed
+	err   error
+}
+
+// duDeletedMsg reports the deletion of a node from the disk usage view.
+type duDeletedMsg struct {
+	node  *sshclient.DUNode
+	space *sshclient.DiskSpace
+	err   error
+}
+
+// duCloseMsg is sent when the disk usage view is closed; changed is set when
+// something was deleted.
+type duCloseMsg struct{ changed bool }
+
+// DiskUsageModel is an ncdu-like view of the space used below a remote
+// directory.
+type DiskUsageModel struct {
+	dir     string // directory the scan started at
+	scan    func() (*sshclient.DUNode, error)
+	df      func() (sshclient.DiskSpace, error)
+	remove  func(p string) error
+	root    *sshclient.DUNode
+	cur     *sshclient.DUNode // directory being shown
+	space   *sshclient.DiskSpace
+	cursor  int
+	scroll  int
+	loading bool
+	confirm *sshclient.DUNode // node waiting for delete confirmation
+	changed bool
+	status  string
+	width   int
+	height  int
+}
+
+// NewDiskUsageModel creates a disk usage view for the remote dir.
+func NewDiskUsageModel(client *sshclient.Client, dir string) DiskUsageModel {
+	return DiskUsageModel{
+		dir:    dir,
+		scan:   func() (*sshclient.DUNode, error) { return client.DiskUsage(dir) },
+		df:     func() (sshclient.DiskSpace, error) { return client.DiskFree(dir) },
+		remove: client.Remove,
+	}
+}
+
+// SetDimensions sets the view's display dimensions.
+func (m *DiskUsageModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Init starts the scan.
+func (m *DiskUsageModel) Init() tea.Cmd {
+	m.loading = true
+	m.status = ""
+	scan, df := m.scan, m.df
+	return func() tea.Msg {
+		root, err := scan()
+		return duLoadedMsg{root: root, space: diskFree(df), err: err}
+	}
+}
+
+// diskFree calls df, returning nil on failure: free space is nice to have.
+func diskFree(df func() (sshclient.DiskSpace, error)) *sshclient.DiskSpace {
+	s, err := df()
+	if err != nil {
+		return nil
+	}
+	return &s
+}
+
+func (m DiskUsageModel) visibleRows() int {
+	return max(m.height-9, 1) // title, df, total, status, hints, borders
+}
+
+// children returns the entries of the current directory, largest first.
+func (m DiskUsageModel) children() []*sshclient.DUNode {
+	if m.cur == nil {
+		return nil
+	}
+	return m.cur.Children
+}
+
+// sortBySize orders every directory of the tree largest first.
+func sortBySize(n *sshclient.DUNode) {
+	slices.SortStableFunc(n.Children, func(a, b *sshclient.DUNode) int {
+		if a.Size != b.Size {
+			if a.Size > b.Size {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, c := range n.Children {
+		sortBySize(c)
+	}
+}
+
+// Update handles scan results and keys.
+func (m DiskUsageModel) Update(msg tea.Msg) (DiskUsageModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case duLoadedMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.status = "Scan failed: " + msg.err.Error()
+			return m, sudoPromptCmd(msg.err)
+		}
+		sortBySize(msg.root)
+		m.root, m.cur, m.space = msg.root, msg.root, msg.space
+		m.cursor, m.scroll = 0, 0
+	case duDeletedMsg:
+		if msg.err != nil {
+			m.status = "Delete failed: " + msg.err.Error()
+			return m, sudoPromptCmd(msg.err)
+		}
+		msg.node.Remove()
+		m.changed = true
+		if msg.space != nil {
+			m.space = msg.space
+		}
+		m.status = fmt.Sprintf("Deleted %s (%s)", msg.node.Name, formatSize(msg.node.Size))
+		m.moveCursor(0)
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m DiskUsageModel) handleKey(msg tea.KeyMsg) (DiskUsageModel, tea.Cmd) {
+	if m.confirm != nil {
+		n := m.confirm
+		m.confirm = nil
+		if msg.String() != "y" {
+			m.status = ""
+			return m, nil
+		}
+		m.status = "Deleting " + n.Name + "..."
+		p := path.Join(m.dir, n.Path())
+		remove, df := m.remove, m.df
+		return m, func() tea.Msg {
+			err := remove(p)
+			return duDeletedMsg{node: n, space: diskFree(df), err: err}
+		}
+	}
+	switch msg.String() {
+	case "esc", "q":
+		changed := m.changed
+		return m, func() tea.Msg { return duCloseMsg{changed: changed} }
+	case "r":
+		if !m.loading {
+			return m, m.Init()
+		}
+	}
+	if m.cur == nil {
+		return m, nil
+	}
+	items := m.children()
+	switch msg.String() {
+	case "up", "k":
+		m.moveCursor(m.cursor - 1)
+	case "down", "j":
+		m.moveCursor(m.cursor + 1)
+	case "enter", "right", "l":
+		if len(items) > 0 && items[m.cursor].IsDir {
+			m.cur = items[m.cursor]
+			m.cursor, m.scroll = 0, 0
+			m.status = ""
+		}
+	case "backspace", "left", "h":
+		if parent := m.cur.Parent; parent != nil {
+			from := m.cur
+			m.cur = parent
+			m.cursor, m.scroll = 0, 0
+			m.moveCursor(slices.Index(parent.Children, from))
+			m.status = ""
+		}
+	case "d":
+		if len(items) > 0 {
+			n := items[m.cursor]
+			m.confirm = n
+			m.status = fmt.Sprintf("Delete %s (%s)? y to confirm, any other key cancels", path.Join(m.dir, n.Path()), formatSize(n.Size))
+		}
+	}
+	return m, nil
+}
+
+// moveCursor puts the cursor on entry i, clamped, and scrolls to it.
+func (m *DiskUsageModel) moveCursor(i int) {
+	n := len(m.children())
+	m.cursor = max(min(i, n-1), 0)
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if vis := m.visibleRows(); m.cursor >= m.scroll+vis {
+		m.scroll = m.cursor - vis + 1
+	}
+}
+
+// View renders the disk usage view.
+func (m DiskUsageModel) View() string {
+	title := "Disk usage: " + m.dir
+	if m.cur != nil && m.cur != m.root {
+		title = "Disk usage: " + path.Join(m.dir, m.cur.Path())
+	}
+	lines := []string{messageStyle.Render(title)}
+	if s := m.space; s != nil {
+		lines = append(lines, statusBarStyle.Render(dfLabel(*s)))
+	} else {
+		lines = append(lines, "")
+	}
+
+	switch {
+	case m.loading:
+		lines = append(lines, "", "Scanning... (this can take a while on large trees)")
+	case m.cur == nil:
+		lines = append(lines, "")
+	default:
+		lines = append(lines, fmt.Sprintf("Total %s in %d entries", formatSize(m.cur.Size), len(m.cur.Children)), "")
+		items := m.children()
+		nameWidth := max(m.width-34, 10)
+		for i := m.scroll; i < len(items) && i < m.scroll+m.visibleRows(); i++ {
+			n := items[i]
+			name := truncate(n.Name, nameWidth)
+			if n.IsDir {
+				name = dirStyle.Render(name + "/")
+			}
+			line := fmt.Sprintf("%8s %5.1f%% [%s] %s", formatSize(n.Size), percent(n.Size, m.cur.Size), usageBar(n.Size, m.cur.Size, 10), name)
+			if i == m.cursor {
+				line = fileSelectedStyle.Render(line)
+			}
+			lines = append(lines, line)
+		}
+		if len(items) == 0 {
+			lines = append(lines, statusBarStyle.Render("(empty)"))
+		}
+	}
+
+	lines = append(lines, "")
+	if m.status != "" {
+		lines = append(lines, messageStyle.Render(m.status))
+	}
+	lines = append(lines, statusBarStyle.Render("Enter/→: open • ←/Backspace: up • d: delete • r: rescan • Esc: close"))
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// dfLabel describes a filesystem's usage, e.g.
+// "/: 12.0G used of 50.0G (24%), 38.0G free".
+func dfLabel(s sshclient.DiskSpace) string {
+	used := s.Used + s.Avail // df's capacity is relative to used+available
+	return fmt.Sprintf("%s: %s used of %s (%.0f%%), %s free",
+		s.Mount, formatSize(s.Used), formatSize(s.Total), percent(s.Used, used), formatSize(s.Avail))
+}
+
+// spaceLabel is the short form of dfLabel shown in the remote panel header.
+func spaceLabel(s sshclient.DiskSpace) string {
+	return fmt.Sprintf(" [%s free, %.0f%% used]", formatSize(s.Avail), percent(s.Used, s.Used+s.Avail))
+}
+
+func percent(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// usageBar draws part/total as a bar of width cells.
+func usageBar(part, total int64, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(part * int64(width) / total)
+	}
+	filled = max(min(filled, width), 0)
+	return strings.Repeat("#", filled) + strings.Repeat(" ", width-filled)
+}
+
+// openDiskUsage scans the selected remote directory, or the panel's
+// directory when the cursor is not on one.
+func (m FileBrowserModel) openDiskUsage() (FileBrowserModel, tea.Cmd) {
+	if m.focus != panelRemote {
+		m.statusMsg = "Disk usage works on the remote panel"
+		return m, nil
+	}
+	dir := m.remoteDir
+	if len(m.remoteFiles) > 0 {
+		if f := m.remoteFiles[m.remoteCursor]; remoteIsDir(f) {
+			dir = joinRemotePath(m.remoteDir, f.Name)
+		}
+	}
+	du := NewDiskUsageModel(m.client, dir)
+	cmd := du.Init()
+	m.du = &du
+	return m, cmd
+}
+
+// handleDiskUsageMsg routes messages of the disk usage view.
+func (m FileBrowserModel) handleDiskUsageMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	if c, ok := msg.(duCloseMsg); ok {
+		m.du = nil
+		if c.changed {
+			return m, refreshRemoteCmd(m.client, m.remoteDir)
+		}
+		return m, nil
+	}
+	if m.du == nil {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	*m.du, cmd = m.du.Update(msg)
+	return m, cmd
+}
@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// duTree builds /srv with logs/ (two files) and a small file.
+func duTree() *sshclient.DUNode {
+	root := &sshclient.DUNode{Name: ".", IsDir: true, Size: 110 << 20}
+	logs := &sshclient.DUNode{Name: "logs", IsDir: true, Size: 100 << 20, Parent: root}
+	logs.Children = []*sshclient.DUNode{
+		{Name: "old.log", Size: 10 << 20, Parent: logs},
+		{Name: "app.log", Size: 90 << 20, Parent: logs},
+	}
+	root.Children = []*sshclient.DUNode{
+		{Name: "notes", Size: 10 << 20, Parent: root},
+		logs,
+	}
+	return root
+}
+
+func newTestDiskUsage(removed *[]string) DiskUsageModel {
+	m := DiskUsageModel{
+		dir:  "/srv",
+		scan: func() (*sshclient.DUNode, error) { return duTree(), nil },
+		df: func() (sshclient.DiskSpace, error) {
+			return sshclient.DiskSpace{Total: 100 << 30, Used: 40 << 30, Avail: 60 << 30, Mount: "/"}, nil
+		},
+		remove: func(p string) error { *removed = append(*removed, p); return nil },
+	}
+	m.SetDimensions(100, 30)
+	return m
+}
+
+func TestDiskUsageBrowseAndDelete(t *testing.T) {
+	var removed []string
+	m := newTestDiskUsage(&removed)
+	m, _ = m.Update(m.Init()())
+
+	if m.cur.Children[0].Name != "logs" {
+		t.Fatalf("largest entry should come first, got %q", m.cur.Children[0].Name)
+	}
+	view := m.View()
+	if !strings.Contains(view, "/: 40.0G used of 100.0G (40%), 60.0G free") {
+		t.Errorf("view should show df usage:\n%s", view)
+	}
+	if !strings.Contains(view, "Total 110.0M") {
+		t.Errorf("view should show the total:\n%s", view)
+	}
+
+	// Drill into logs and delete the largest file.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.cur.Name != "logs" || m.cur.Children[0].Name != "app.log" {
+		t.Fatalf("expected logs sorted by size, got %q", m.cur.Name)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if !strings.Contains(m.status, "/srv/logs/app.log") {
+		t.Errorf("status = %q", m.status)
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m, _ = m.Update(cmd())
+	if len(removed) != 1 || removed[0] != "/srv/logs/app.log" {
+		t.Fatalf("removed = %v", removed)
+	}
+	if m.cur.Size != 10<<20 || m.root.Size != 20<<20 || len(m.cur.Children) != 1 {
+		t.Errorf("sizes not updated: logs %d, root %d", m.cur.Size, m.root.Size)
+	}
+
+	// Back up: the cursor returns to logs, now smaller than notes.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.cur != m.root || m.cur.Children[m.cursor].Name != "logs" {
+		t.Errorf("cursor should be on logs after going up")
+	}
+
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if c, ok := cmd().(duCloseMsg); !ok || !c.changed {
+		t.Errorf("close should report the deletion, got %#v", c)
+	}
+}
+
+func TestDiskUsageDeleteCancelled(t *testing.T) {
+	var removed []string
+	m := newTestDiskUsage(&removed)
+	m, _ = m.Update(m.Init()())
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if cmd != nil || m.confirm != nil || len(removed) != 0 {
+		t.Error("any key but y should cancel the delete")
+	}
+}
+
+func TestFBDiskUsageOpensOnSelectedDir(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m.SetDimensions(100, 30)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u"), Alt: true})
+	if m.du != nil || !strings.Contains(m.statusMsg, "remote panel") {
+		t.Fatal("disk usage should only open on the remote panel")
+	}
+
+	m.focus = panelRemote
+	m, _ = m.Update(remoteFilesMsg{files: []sshclient.RemoteFile{{Name: "data", IsDir: true}}})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u"), Alt: true})
+	if m.du == nil || !m.InputActive() || cmd == nil {
+		t.Fatal("Alt+U should open the disk usage view and start a scan")
+	}
+	if m.du.dir != "/srv/data" {
+		t.Errorf("dir = %q, want the selected directory", m.du.dir)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m, cmd = m.Update(duCloseMsg{})
+	if m.du != nil || cmd != nil {
+		t.Error("closing without changes should not refresh the panel")
+	}
+}
+
+func TestUsageBar(t *testing.T) {
+	if got := usageBar(5, 10, 10); got != "#####     " {
+		t.Errorf("usageBar = %q", got)
+	}
+	if got := usageBar(1, 0, 4); got != "    " {
+		t.Errorf("usageBar with zero total = %q", got)
+	}
+}
+
+func TestFBRemoteHeaderShowsSpace(t *testing.T) {
+	client := &sshclient.Client{}
+	m := NewFileBrowserModel(client, t.TempDir(), "/srv")
+	m.SetDimensions(160, 30)
+	space := sshclient.DiskSpace{Mount: "/", Total: 100 << 30, Used: 75 << 30, Avail: 25 << 30}
+
+	m, _ = m.Update(RemoteSpaceMsg{client: &sshclient.Client{}, dir: "/srv", space: space})
+	m, _ = m.Update(RemoteSpaceMsg{client: client, dir: "/var", space: space})
+	if m.remoteSpace != nil {
+		t.Fatal("df of another tab or an old directory should be ignored")
+	}
+	m, _ = m.Update(RemoteSpaceMsg{client: client, dir: "/srv", space: space})
+	if !strings.Contains(m.View(), "[25.0G free, 75% used]") {
+		t.Error("remote header should show the free space")
+	}
+
+	cmd := m.setRemoteDir("/var")
+	if m.remoteSpace != nil || cmd == nil {
+		t.Error("changing directory should clear the space and fetch it again")
+	}
+}
+
+func TestFBRemoteHeaderDropsSpaceWhenNarrow(t *testing.T) {
+	client := &sshclient.Client{}
+	m := NewFileBrowserModel(client, t.TempDir(), "/srv/a/rather/long/directory/name")
+	m.SetDimensions(60, 30)
+	m, _ = m.Update(RemoteSpaceMsg{client: client, dir: m.remoteDir, space: sshclient.DiskSpace{Used: 1, Avail: 1}})
+	if strings.Contains(m.View(), "free") {
+		t.Error("a narrow panel should keep the path rather than the space")
+	}
+}
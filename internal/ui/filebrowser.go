@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
+	"ssh-scp/internal/config"
 	sshclient "ssh-scp/internal/ssh"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -23,20 +27,36 @@ type TransferMsg struct {
 
 // TransferDoneMsg is sent when a transfer completes.
 type TransferDoneMsg struct {
-	Err error
+	Err    error
+	Detail string // optional summary shown after the file name
 }
 
 // RefreshRemoteMsg requests a refresh of the remote file list.
 type RefreshRemoteMsg struct{}
 
+// RateLimitChangedMsg is sent when the transfer limit is changed with
+// Ctrl+L, so the app can save it with the host's settings. KBps follows
+// HostSettings.RateLimitKBps: <0 = unlimited.
+type RateLimitChangedMsg struct {
+	KBps int
+}
+
 // fileOpKind identifies which file management operation is being performed.
 type fileOpKind int
 
 const (
-	opNone   fileOpKind = iota
-	opMkDir             // create a new directory
-	opDelete            // delete the selected file/dir
-	opRename            // rename the selected file/dir
+	opNone        fileOpKind = iota
+	opMkDir                  // create a new directory
+	opDelete                 // delete the selected file/dir
+	opRename                 // rename the selected file/dir
+	opRateLimit              // change the transfer rate limit
+	opProperties             // change mode/owner/group from the properties dialog
+	opSymlink                // create a symbolic link
+	opFilter                 // type-to-filter the focused panel
+	opGoto                   // go to a typed path
+	opTransfer               // transfer the selected entry
+	opLinkChoice             // act on a symlink or on its target
+	opReplaceCopy            // replace the destination of a cross-tab copy
 )
 
 // FileOpDoneMsg is sent when a file management operation completes.
@@ -55,50 +75,125 @@ const (
 // FileBrowserModel manages the dual-panel file browser.
 type FileBrowserModel struct {
 	localDir    string
-	localFiles  []os.FileInfo
+	localAll    []os.FileInfo        // every entry of localDir
+	localFiles  []os.FileInfo        // entries shown, sorted and filtered
+	localLinks  map[string]localLink // symlinks in localAll, by name
 	localCursor int
 	localScroll int
+	localView   config.PanelView
+	localFilter string
+	localHist   dirHistory
 
 	remoteDir    string
+	remoteAll    []sshclient.RemoteFile
 	remoteFiles  []sshclient.RemoteFile
 	remoteCursor int
 	remoteScroll int
+	remoteView   config.PanelView
+	remoteFilter string
+	remoteHist   dirHistory
 
 	focus            panelFocus
 	width            int
 	height           int
+	localPane        int // height of the local panel's layout pane; 0 in the two-panel view
+	remotePane       int // height of the remote panel's layout pane
 	transferring     bool
 	transferProgress string
 	statusMsg        string
 	client           *sshclient.Client
+	historyHost      string // host key transfers are recorded under; "" disables history
+	compress         bool   // compress transfers through remote gzip/zstd
+
+	// Cross-tab copy progress; copyBytes is nil unless a copy is running.
+	copyBytes   *atomic.Int64
+	copyTotal   int64
+	copyLabel   string
+	pendingCopy *pendingRemoteCopy // copy waiting for its destination check
+
+	remoteStale bool // remote dir changed while the tab was hidden
+
+	remoteSpace *sshclient.DiskSpace // filesystem of remoteDir, nil until known
+
+	followShell bool   // the remote panel follows the shell's working directory
+	shellDir    string // working directory the shell last reported
 
 	// File operation input dialog state.
 	inputActive bool
 	inputOp     fileOpKind
 	inputPrompt string
 	inputModel  textinput.Model
+	inputHint   string // shown after the input, e.g. completion candidates
+
+	// Operation on a symlink waiting for the link-or-target choice, and the
+	// answer: linkTarget is set when it acts on the link's target.
+	linkOp       fileOpKind
+	linkCompress bool
+	linkTarget   bool
+
+	props     *PropertiesModel // open properties dialog, if any
+	marks     []config.Bookmark
+	bookmarks *BookmarksModel // open bookmark list, if any
+	search    *SearchModel    // open search dialog, if any
+	du        *DiskUsageModel // open disk usage view, if any
+	preview   *PreviewModel   // preview pane, if shown
+	tail      *TailModel      // followed log files, if any
+	tailShown bool            // the tail viewer is on screen
+
+	runner      *RunnerModel // one-off commands, if any were run
+	runnerShown bool         // the command runner is on screen
+
+	snippets *SnippetsModel // open snippet picker, if any
+
+	// Entry to select once the remote listing arrives, and whether to open
+	// it in the editor then; set when showing a search result.
+	remoteSelect string
+	remoteEdit   bool
 }
 
 // NewFileBrowserModel creates a new file browser model.
 func NewFileBrowserModel(client *sshclient.Client, localDir, remoteDir string) FileBrowserModel {
 	m := FileBrowserModel{
-		localDir:  localDir,
-		remoteDir: remoteDir,
-		client:    client,
-		focus:     panelLocal,
+		localDir:    localDir,
+		remoteDir:   remoteDir,
+		client:      client,
+		focus:       panelLocal,
+		followShell: true,
 	}
 	m.refreshLocal()
 	return m
 }
 
+// Close stops the background work of the browser: followed files and a
+// running search. It is called when the tab closes.
+func (m *FileBrowserModel) Close() {
+	if m.tail != nil {
+		m.tail.Stop()
+	}
+	if m.runner != nil {
+		m.runner.Stop()
+	}
+	if m.search != nil {
+		m.search.Stop()
+	}
+}
+
 // SetDimensions sets the width and height for the file browser.
 func (m *FileBrowserModel) SetDimensions(width, height int) {
 	m.width = width
 	m.height = height
 }
 
-// visibleHeight returns the number of file rows visible in a panel.
+// visibleHeight returns the number of file rows visible in the focused
+// panel.
 func (m FileBrowserModel) visibleHeight() int {
+	pane := m.localPane
+	if m.focus == panelRemote {
+		pane = m.remotePane
+	}
+	if pane > 0 {
+		return max(pane-6, 1) // borders, header
+	}
 	v := m.height - 8 // account for panel borders, header, status bar
 	if v < 1 {
 		v = 1
@@ -109,6 +204,7 @@ func (m FileBrowserModel) visibleHeight() int {
 func (m *FileBrowserModel) refreshLocal() {
 	entries, err := os.ReadDir(m.localDir)
 	if err != nil {
+		m.localAll = nil
 		m.localFiles = nil
 		return
 	}
@@ -119,10 +215,9 @@ func (m *FileBrowserModel) refreshLocal() {
 			files = append(files, info)
 		}
 	}
-	m.localFiles = files
-	if m.localCursor >= len(m.localFiles) {
-		m.localCursor = 0
-	}
+	m.localAll = files
+	m.localLinks = readLocalLinks(m.localDir, files)
+	m.applyLocalView("")
 }
 
 func refreshRemoteCmd(client *sshclient.Client, dir string) tea.Cmd {
@@ -140,11 +235,41 @@ type remoteFilesMsg struct {
 	err   error
 }
 
+// RemoteSpaceMsg carries the free space of the filesystem holding dir. It
+// is routed to every tab's browser, since the tab may have been switched
+// away from before df answered.
+type RemoteSpaceMsg struct {
+	client *sshclient.Client
+	dir    string
+	space  sshclient.DiskSpace
+	err    error
+}
+
+// remoteSpaceCmd runs df for dir, shown in the remote panel header.
+func remoteSpaceCmd(client *sshclient.Client, dir string) tea.Cmd {
+	if client == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		space, err := client.DiskFree(dir)
+		return RemoteSpaceMsg{client: client, dir: dir, space: space, err: err}
+	}
+}
+
 func (m FileBrowserModel) Init() tea.Cmd {
-	return refreshRemoteCmd(m.client, m.remoteDir)
+	return tea.Batch(refreshRemoteCmd(m.client, m.remoteDir), remoteSpaceCmd(m.client, m.remoteDir))
 }
 
 func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	m, cmd := m.update(msg)
+	// Keep the preview pane on the selection, wherever the cursor moved.
+	if sync := m.syncPreview(); sync != nil {
+		return m, tea.Batch(cmd, sync)
+	}
+	return m, cmd
+}
+
+func (m FileBrowserModel) update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -153,23 +278,63 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 	case remoteFilesMsg:
 		if msg.err == nil {
 			log.Printf("[FileBrowser] remote listing: %d files in %s", len(msg.files), m.remoteDir)
-			m.remoteFiles = msg.files
-			if m.remoteCursor >= len(m.remoteFiles) {
-				m.remoteCursor = 0
+			m.remoteAll = msg.files
+			m.applyRemoteView(m.remoteSelect)
+			selected := m.remoteSelect != "" && m.selectedName() == m.remoteSelect
+			edit := m.remoteEdit && selected && m.focus == panelRemote
+			m.remoteSelect, m.remoteEdit = "", false
+			if edit {
+				return m.editSelected()
 			}
 		} else {
 			log.Printf("[FileBrowser] remote listing error: %v", msg.err)
 			m.statusMsg = "Error: " + msg.err.Error()
+			return m, sudoPromptCmd(msg.err)
+		}
+
+	case RemoteSpaceMsg:
+		if msg.client != m.client || msg.dir != m.remoteDir {
+			return m, nil
+		}
+		if msg.err != nil {
+			log.Printf("[FileBrowser] df %s: %v", msg.dir, msg.err)
+			m.remoteSpace = nil
+			return m, nil
+		}
+		m.remoteSpace = &msg.space
+
+	case SudoDoneMsg:
+		if msg.Err != nil {
+			m.statusMsg = msg.Err.Error()
+			return m, nil
+		}
+		m.statusMsg = "sudo mode on: file operations and transfers run as root"
+		return m, refreshRemoteCmd(m.client, m.remoteDir)
+
+	case RemoteCopyCheckedMsg:
+		if msg.copy == m.pendingCopy && msg.copy != nil {
+			return m.remoteCopyChecked(msg)
+		}
+
+	case RemoteCopyTickMsg:
+		if m.transferring && m.copyBytes != nil && m.copyBytes == msg.copied {
+			m.statusMsg = m.copyStatus()
+			return m, remoteCopyTick(msg.copied)
 		}
 
 	case TransferDoneMsg:
 		m.transferring = false
+		m.copyBytes = nil
 		if msg.Err != nil {
 			log.Printf("[FileBrowser] transfer failed (%s): %v", m.transferProgress, msg.Err)
 			m.statusMsg = fmt.Sprintf("Transfer failed (%s): %s", m.transferProgress, msg.Err.Error())
+			return m, sudoPromptCmd(msg.Err)
 		} else {
 			log.Printf("[FileBrowser] transfer complete: %s", m.transferProgress)
 			m.statusMsg = fmt.Sprintf("Transfer complete: %s", m.transferProgress)
+			if msg.Detail != "" {
+				m.statusMsg += " (" + msg.Detail + ")"
+			}
 			m.refreshLocal()
 			return m, refreshRemoteCmd(m.client, m.remoteDir)
 		}
@@ -178,6 +343,7 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 		if msg.Err != nil {
 			log.Printf("[FileBrowser] file op %d failed: %v", msg.Op, msg.Err)
 			m.statusMsg = fmt.Sprintf("Operation failed: %s", msg.Err.Error())
+			return m, sudoPromptCmd(msg.Err)
 		} else {
 			switch msg.Op {
 			case opMkDir:
@@ -186,12 +352,83 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 				m.statusMsg = "Deleted successfully"
 			case opRename:
 				m.statusMsg = "Renamed successfully"
+			case opProperties:
+				m.statusMsg = "Properties updated"
+			case opSymlink:
+				m.statusMsg = "Symlink created"
 			}
 			m.refreshLocal()
 			return m, refreshRemoteCmd(m.client, m.remoteDir)
 		}
 
+	case gotoDirMsg, completionMsg:
+		return m.handleNavigateMsg(msg)
+
+	case bookmarksCloseMsg, bookmarkJumpMsg, bookmarkAddMsg, bookmarkDeleteMsg:
+		return m.handleBookmarkMsg(msg)
+
+	case SearchBatchMsg, searchOpenMsg, searchCloseMsg:
+		return m.handleSearchMsg(msg)
+
+	case duLoadedMsg, duDeletedMsg, duCloseMsg:
+		return m.handleDiskUsageMsg(msg)
+
+	case previewTickMsg, previewLoadedMsg:
+		return m.handlePreviewMsg(msg)
+
+	case TailLinesMsg, tailHideMsg, tailCloseMsg:
+		return m.handleTailMsg(msg)
+
+	case RunnerOutputMsg, runnerHideMsg, runnerCloseMsg, runnerSaveMsg:
+		return m.handleRunnerMsg(msg)
+
+	case snippetsCloseMsg, snippetRunMsg:
+		return m.handleSnippetMsg(msg)
+
+	case propertiesCloseMsg:
+		m.props = nil
+
+	case propertiesApplyMsg:
+		m.props = nil
+		m.statusMsg = "Updating properties..."
+		return m, applyPropertiesCmd(m.client, msg)
+
 	case tea.KeyMsg:
+		if m.props != nil {
+			var cmd tea.Cmd
+			*m.props, cmd = m.props.Update(msg)
+			return m, cmd
+		}
+		if m.bookmarks != nil {
+			var cmd tea.Cmd
+			*m.bookmarks, cmd = m.bookmarks.Update(msg)
+			return m, cmd
+		}
+		if m.search != nil {
+			var cmd tea.Cmd
+			*m.search, cmd = m.search.Update(msg)
+			return m, cmd
+		}
+		if m.du != nil {
+			var cmd tea.Cmd
+			*m.du, cmd = m.du.Update(msg)
+			return m, cmd
+		}
+		if m.tail != nil && m.tailShown {
+			var cmd tea.Cmd
+			*m.tail, cmd = m.tail.Update(msg)
+			return m, cmd
+		}
+		if m.snippets != nil {
+			var cmd tea.Cmd
+			*m.snippets, cmd = m.snippets.Update(msg)
+			return m, cmd
+		}
+		if m.runner != nil && m.runnerShown {
+			var cmd tea.Cmd
+			*m.runner, cmd = m.runner.Update(msg)
+			return m, cmd
+		}
 		// When the text input dialog is active, route keys there.
 		if m.inputActive {
 			return m.handleInputKey(msg)
@@ -234,11 +471,8 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 		case "enter":
 			if m.focus == panelLocal && len(m.localFiles) > 0 {
 				f := m.localFiles[m.localCursor]
-				if f.IsDir() {
-					m.localDir = filepath.Join(m.localDir, f.Name())
-					m.localCursor = 0
-					m.localScroll = 0
-					m.refreshLocal()
+				if m.localIsDir(f) {
+					m.chdirLocal(filepath.Join(m.localDir, f.Name()))
 				} else if f.Size() > MaxEditableSize {
 					m.statusMsg = "File too large to edit (max 1 MB)"
 				} else {
@@ -249,11 +483,8 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 				}
 			} else if m.focus == panelRemote && len(m.remoteFiles) > 0 {
 				f := m.remoteFiles[m.remoteCursor]
-				if f.IsDir {
-					m.remoteDir = joinRemotePath(m.remoteDir, f.Name)
-					m.remoteCursor = 0
-					m.remoteScroll = 0
-					return m, refreshRemoteCmd(m.client, m.remoteDir)
+				if remoteIsDir(f) {
+					return m, m.chdirRemote(joinRemotePath(m.remoteDir, f.Name))
 				} else if f.Size > MaxEditableSize {
 					m.statusMsg = "File too large to edit (max 1 MB)"
 				} else {
@@ -266,24 +497,11 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 
 		case "backspace":
 			if m.focus == panelLocal {
-				parent := filepath.Dir(m.localDir)
-				if parent != m.localDir {
-					m.localDir = parent
-					m.localCursor = 0
-					m.localScroll = 0
-					m.refreshLocal()
-				}
-			} else {
-				parts := strings.Split(strings.TrimRight(m.remoteDir, "/"), "/")
-				if len(parts) > 1 {
-					m.remoteDir = strings.Join(parts[:len(parts)-1], "/")
-					if m.remoteDir == "" {
-						m.remoteDir = "/"
-					}
-					m.remoteCursor = 0
-					m.remoteScroll = 0
-					return m, refreshRemoteCmd(m.client, m.remoteDir)
+				if parent := filepath.Dir(m.localDir); parent != m.localDir {
+					m.chdirLocal(parent)
 				}
+			} else if parent, ok := remoteParent(m.remoteDir); ok {
+				return m, m.chdirRemote(parent)
 			}
 
 		case "ctrl+u":
@@ -291,41 +509,80 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 
 		case "ctrl+t":
 			// Context-aware transfer: upload if local panel focused, download if remote panel focused.
-			if !m.transferring {
-				if m.focus == panelLocal && len(m.localFiles) > 0 {
-					f := m.localFiles[m.localCursor]
-					if !f.IsDir() {
-						localPath := filepath.Join(m.localDir, f.Name())
-						remotePath := joinRemotePath(m.remoteDir, f.Name())
-						m.transferring = true
-						m.transferProgress = f.Name()
-						m.statusMsg = "Uploading " + f.Name() + "..."
-						client := m.client
-						return m, func() tea.Msg {
-							err := client.UploadFile(localPath, remotePath)
-							return TransferDoneMsg{Err: err}
-						}
-					}
-				} else if m.focus == panelRemote && len(m.remoteFiles) > 0 {
-					f := m.remoteFiles[m.remoteCursor]
-					if !f.IsDir {
-						remotePath := joinRemotePath(m.remoteDir, f.Name)
-						m.transferring = true
-						m.transferProgress = f.Name
-						m.statusMsg = "Downloading " + f.Name + "..."
-						client := m.client
-						localDir := m.localDir
-						return m, func() tea.Msg {
-							err := client.DownloadFile(remotePath, localDir)
-							return TransferDoneMsg{Err: err}
-						}
-					}
-				}
-			}
+			return m.transferSelected(m.compress)
+
+		case "alt+t":
+			// Same as Ctrl+T, but compressed regardless of the host setting.
+			return m.transferSelected(true)
+
+		case "alt+s":
+			// Toggle sudo mode for this tab's remote operations.
+			return m.toggleSudo()
+
+		case "/":
+			return m.startFilter()
+
+		case "ctrl+g":
+			return m.startGoto()
+
+		case "alt+left":
+			return m.historyStep(true)
+
+		case "alt+right":
+			return m.historyStep(false)
+
+		case "alt+b":
+			return m.openBookmarks()
+
+		case "alt+f":
+			return m.openSearch()
+
+		case "alt+u":
+			return m.openDiskUsage()
+
+		case "alt+v":
+			return m.togglePreview()
+
+		case "alt+g":
+			// cd the terminal to the remote panel's directory.
+			dir := m.remoteDir
+			return m, func() tea.Msg { return CdTerminalMsg{Dir: dir} }
+
+		case "alt+w":
+			return m.followSelected()
+
+		case "!":
+			return m.openRunner()
+
+		case "alt+o":
+			return m.cycleSort()
+
+		case "alt+r":
+			return m.changeView(func(v *config.PanelView) { v.Reverse = !v.Reverse })
+
+		case "alt+d":
+			return m.changeView(func(v *config.PanelView) { v.DirsFirst = !v.DirsFirst })
+
+		case "alt+h":
+			return m.changeView(func(v *config.PanelView) { v.HideHidden = !v.HideHidden })
+
+		case "alt+l":
+			return m.startSymlink()
+
+		case "alt+p":
+			return m.openProperties()
+		case "alt+c":
+			// Copy the selected remote file to another tab's host.
+			return m.requestRemoteCopy()
 
 		case "ctrl+d":
 			name := m.selectedName()
-			if name != "" {
+			m.linkTarget = false
+			if _, broken, isLink := m.selectedLink(); isLink && !broken {
+				return m.askLinkChoice(opDelete, false), nil
+			} else if isLink {
+				m.startInput(opDelete, fmt.Sprintf("Delete link '%s'? (y/yes to confirm):", name))
+			} else if name != "" {
 				m.startInput(opDelete, fmt.Sprintf("Delete '%s'? (y/yes to confirm):", name))
 			}
 			return m, nil
@@ -336,10 +593,32 @@ func (m FileBrowserModel) Update(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
 
 		case "ctrl+r":
 			name := m.selectedName()
-			if name != "" {
+			m.linkTarget = false
+			if _, broken, isLink := m.selectedLink(); isLink && !broken {
+				return m.askLinkChoice(opRename, false), nil
+			} else if isLink {
+				m.startInput(opRename, "Rename link '"+name+"' to:")
+				return m, nil
+			} else if name != "" {
 				m.startInput(opRename, "Rename '"+name+"' to:")
 				return m, nil
 			}
+
+		case "ctrl+s":
+			// Compare and synchronize the two panel directories.
+			localDir, remoteDir := m.localDir, m.remoteDir
+			return m, func() tea.Msg {
+				return OpenSyncMsg{LocalDir: localDir, RemoteDir: remoteDir}
+			}
+
+		case "ctrl+l":
+			// Adjust the transfer rate limit; applies to a running transfer too.
+			if m.client != nil {
+				m.startInput(opRateLimit, "Rate limit in KB/s (0 = unlimited):")
+				m.inputModel.SetValue(strconv.FormatInt(m.client.RateLimit()/1024, 10))
+				m.inputModel.CursorEnd()
+				return m, nil
+			}
 		}
 	}
 	return m, nil
@@ -360,6 +639,15 @@ func (m FileBrowserModel) selectedName() string {
 	return m.remoteFiles[m.remoteCursor].Name
 }
 
+// rateLimitSuffix returns a status suffix describing the active transfer
+// limit, or "" when transfers are unlimited.
+func (m FileBrowserModel) rateLimitSuffix() string {
+	if m.client == nil || m.client.RateLimit() <= 0 {
+		return ""
+	}
+	return " (limit " + formatRateLimit(m.client.RateLimit()) + ")"
+}
+
 // startInput opens the inline text input dialog for the given operation.
 func (m *FileBrowserModel) startInput(op fileOpKind, prompt string) {
 	ti := textinput.New()
@@ -370,14 +658,28 @@ func (m *FileBrowserModel) startInput(op fileOpKind, prompt string) {
 	m.inputOp = op
 	m.inputPrompt = prompt
 	m.inputModel = ti
+	m.inputHint = ""
 }
 
 // handleInputKey processes key events while the text input is active.
 func (m FileBrowserModel) handleInputKey(msg tea.KeyMsg) (FileBrowserModel, tea.Cmd) {
+	if m.inputOp == opFilter {
+		return m.handleFilterKey(msg)
+	}
+	if m.inputOp == opGoto {
+		var cmd tea.Cmd
+		var handled bool
+		if m, cmd, handled = m.handleGotoKey(msg); handled {
+			return m, cmd
+		}
+	}
 	switch msg.Type {
 	case tea.KeyEsc:
 		m.inputActive = false
 		m.statusMsg = ""
+		if m.inputOp == opReplaceCopy {
+			m.pendingCopy = nil
+		}
 		return m, nil
 	case tea.KeyEnter:
 		value := strings.TrimSpace(m.inputModel.Value())
@@ -389,6 +691,17 @@ func (m FileBrowserModel) handleInputKey(msg tea.KeyMsg) (FileBrowserModel, tea.
 			m.statusMsg = "Delete cancelled"
 			return m, nil
 		}
+		if m.inputOp == opLinkChoice {
+			return m.answerLinkChoice(value)
+		}
+		if m.inputOp == opReplaceCopy {
+			if strings.EqualFold(value, "y") || strings.EqualFold(value, "yes") {
+				return m.beginRemoteCopy()
+			}
+			m.pendingCopy = nil
+			m.statusMsg = "Copy cancelled"
+			return m, nil
+		}
 		if value == "" {
 			m.statusMsg = "Cancelled (empty name)"
 			return m, nil
@@ -407,10 +720,46 @@ func (m FileBrowserModel) executeFileOp(op fileOpKind, name string) (FileBrowser
 		return m.executeMkDir(name)
 	case opRename:
 		return m.executeRename(name)
+	case opRateLimit:
+		return m.applyRateLimit(name)
+	case opSymlink:
+		return m.executeSymlink(name)
+	case opGoto:
+		return m.executeGoto(name)
 	}
 	return m, nil
 }
 
+// applyRateLimit parses a KB/s value and applies it to the client's
+// transfer limiter, including a running transfer.
+func (m FileBrowserModel) applyRateLimit(value string) (FileBrowserModel, tea.Cmd) {
+	kbps, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || kbps < 0 {
+		m.statusMsg = "Invalid rate limit: " + value
+		return m, nil
+	}
+	m.client.SetRateLimit(kbps * 1024)
+	log.Printf("[FileBrowser] rate limit set to %d KB/s", kbps)
+	m.statusMsg = "Rate limit: " + formatRateLimit(kbps*1024)
+	if m.transferring {
+		m.statusMsg += ", applied to the running transfer of " + m.transferProgress
+	}
+	saved := int(kbps)
+	if saved == 0 {
+		// 0 in the host settings would fall back to the global limit.
+		saved = -1
+	}
+	return m, func() tea.Msg { return RateLimitChangedMsg{KBps: saved} }
+}
+
+// formatRateLimit renders a bytes-per-second limit for display.
+func formatRateLimit(bytesPerSec int64) string {
+	if bytesPerSec <= 0 {
+		return "unlimited"
+	}
+	return formatSize(bytesPerSec) + "/s"
+}
+
 // executeMkDir creates a directory locally or remotely.
 func (m FileBrowserModel) executeMkDir(name string) (FileBrowserModel, tea.Cmd) {
 	if m.focus == panelLocal {
@@ -439,6 +788,9 @@ func (m FileBrowserModel) executeDelete() (FileBrowserModel, tea.Cmd) {
 		}
 		f := m.localFiles[m.localCursor]
 		path := filepath.Join(m.localDir, f.Name())
+		if m.linkTarget {
+			path = m.linkTargetPath()
+		}
 		m.statusMsg = "Deleting..."
 		return m, func() tea.Msg {
 			err := os.RemoveAll(path)
@@ -451,6 +803,9 @@ func (m FileBrowserModel) executeDelete() (FileBrowserModel, tea.Cmd) {
 	}
 	f := m.remoteFiles[m.remoteCursor]
 	path := joinRemotePath(m.remoteDir, f.Name)
+	if m.linkTarget {
+		path = m.linkTargetPath()
+	}
 	client := m.client
 	m.statusMsg = "Deleting remote file..."
 	return m, func() tea.Msg {
@@ -468,6 +823,10 @@ func (m FileBrowserModel) executeRename(newName string) (FileBrowserModel, tea.C
 		f := m.localFiles[m.localCursor]
 		oldPath := filepath.Join(m.localDir, f.Name())
 		newPath := filepath.Join(m.localDir, newName)
+		if m.linkTarget {
+			oldPath = m.linkTargetPath()
+			newPath = filepath.Join(filepath.Dir(oldPath), newName)
+		}
 		m.statusMsg = "Renaming..."
 		return m, func() tea.Msg {
 			err := os.Rename(oldPath, newPath)
@@ -481,6 +840,10 @@ func (m FileBrowserModel) executeRename(newName string) (FileBrowserModel, tea.C
 	f := m.remoteFiles[m.remoteCursor]
 	oldPath := joinRemotePath(m.remoteDir, f.Name)
 	newPath := joinRemotePath(m.remoteDir, newName)
+	if m.linkTarget {
+		oldPath = m.linkTargetPath()
+		newPath = joinRemotePath(path.Dir(oldPath), newName)
+	}
 	client := m.client
 	m.statusMsg = "Renaming remote file..."
 	return m, func() tea.Msg {
@@ -543,15 +906,15 @@ func formatSize(size int64) string {
 	}
 }
 
-func (m FileBrowserModel) renderLocalPanel(panelWidth, panelHeight int) string {
-	active := m.focus == panelLocal
+func (m FileBrowserModel) renderLocalPanel(panelWidth, panelHeight int, active bool) string {
 	style := panelStyle
 	if active {
 		style = activePanelStyle
 	}
 
 	header := headerStyle.Width(panelWidth - 4).Render(
-		fmt.Sprintf("Local: %s", truncatePath(m.localDir, panelWidth-10)),
+		fmt.Sprintf("Local%s: %s", viewLabel(m.localView, m.localFilter),
+			truncatePath(m.localDir, panelWidth-10-len(viewLabel(m.localView, m.localFilter)))),
 	)
 
 	var rows []string
@@ -574,7 +937,9 @@ func (m FileBrowserModel) renderLocalPanel(panelWidth, panelHeight int) string {
 		modTime := f.ModTime().Format("2006-01-02")
 
 		var line string
-		if f.IsDir() {
+		if l, ok := m.localLinks[name]; ok {
+			line = renderLinkRow(name, l.target, l.dir, l.broken, contentWidth)
+		} else if f.IsDir() {
 			line = dirStyle.Render("▸ " + truncate(name, nameWidth-3) + "/")
 		} else {
 			line = fileStyle.Render(fmt.Sprintf("%-*s %6s  %s", nameWidth, truncate(name, nameWidth), size, modTime))
@@ -585,21 +950,31 @@ func (m FileBrowserModel) renderLocalPanel(panelWidth, panelHeight int) string {
 		}
 		rows = append(rows, line)
 	}
+	if len(m.localFiles) == 0 && m.localFilter != "" {
+		rows = append(rows, statusBarStyle.Render("no matches for /"+m.localFilter))
+	}
 
 	body := strings.Join(rows, "\n")
 	content := header + "\n" + body
 	return style.Width(panelWidth).Height(panelHeight).Render(content)
 }
 
-func (m FileBrowserModel) renderRemotePanel(panelWidth, panelHeight int) string {
-	active := m.focus == panelRemote
+func (m FileBrowserModel) renderRemotePanel(panelWidth, panelHeight int, active bool) string {
 	style := panelStyle
 	if active {
 		style = activePanelStyle
 	}
 
+	labels := m.sudoLabel() + m.followLabel() + viewLabel(m.remoteView, m.remoteFilter)
+	space := ""
+	if m.remoteSpace != nil {
+		space = spaceLabel(*m.remoteSpace)
+		if panelWidth-10-len(labels)-len(space) < 12 {
+			space = "" // the path matters more
+		}
+	}
 	header := headerStyle.Width(panelWidth - 4).Render(
-		fmt.Sprintf("Remote: %s", truncatePath(m.remoteDir, panelWidth-10)),
+		fmt.Sprintf("Remote%s: %s%s", labels, truncatePath(m.remoteDir, panelWidth-10-len(labels)-len(space)), space),
 	)
 
 	var rows []string
@@ -621,7 +996,9 @@ func (m FileBrowserModel) renderRemotePanel(panelWidth, panelHeight int) string
 		modTime := f.ModTime.Format("2006-01-02")
 
 		var line string
-		if f.IsDir {
+		if f.IsLink() {
+			line = renderLinkRow(f.Name, f.LinkTarget, f.LinkDir, f.LinkBroken, contentWidth)
+		} else if f.IsDir {
 			line = dirStyle.Render("▸ " + truncate(f.Name, nameWidth-3) + "/")
 		} else {
 			line = fileStyle.Render(fmt.Sprintf("%-*s %6s  %s", nameWidth, truncate(f.Name, nameWidth), size, modTime))
@@ -632,6 +1009,9 @@ func (m FileBrowserModel) renderRemotePanel(panelWidth, panelHeight int) string
 		}
 		rows = append(rows, line)
 	}
+	if len(m.remoteFiles) == 0 && m.remoteFilter != "" {
+		rows = append(rows, statusBarStyle.Render("no matches for /"+m.remoteFilter))
+	}
 
 	body := strings.Join(rows, "\n")
 	content := header + "\n" + body
@@ -649,24 +1029,50 @@ func (m FileBrowserModel) View() string {
 		panelHeight = 4
 	}
 
-	local := m.renderLocalPanel(panelWidth, panelHeight)
-	remote := m.renderRemotePanel(panelWidth, panelHeight)
-	panels := lipgloss.JoinHorizontal(lipgloss.Top, local, remote)
-
-	// Show inline input dialog when active.
-	if m.inputActive {
-		inputLine := messageStyle.Render(
-			fmt.Sprintf(" %s ", m.inputPrompt),
-		) + m.inputModel.View()
-		return lipgloss.JoinVertical(lipgloss.Left, panels, inputLine)
+	if m.props != nil {
+		m.props.SetDimensions(m.width, m.height)
+		return m.props.View()
+	}
+	if m.bookmarks != nil {
+		m.bookmarks.SetDimensions(m.width, m.height)
+		return m.bookmarks.View()
+	}
+	if m.search != nil {
+		m.search.SetDimensions(m.width, m.height)
+		return m.search.View()
+	}
+	if m.du != nil {
+		m.du.SetDimensions(m.width, m.height)
+		return m.du.View()
+	}
+	if m.tail != nil && m.tailShown {
+		m.tail.SetDimensions(m.width, m.height)
+		return m.tail.View()
+	}
+	if m.snippets != nil {
+		m.snippets.SetDimensions(m.width, m.height)
+		return m.snippets.View()
+	}
+	if m.runner != nil && m.runnerShown {
+		m.runner.SetDimensions(m.width, m.height)
+		return m.runner.View()
 	}
 
-	hints := statusBarStyle.Render(" ^←/→: panels • ^T: transfer • ^Y: mkdir • ^D: delete • ^R: rename")
-	if m.statusMsg != "" {
-		hints += statusBarStyle.Render(" | ") + messageStyle.Render(m.statusMsg)
+	var panels string
+	switch {
+	case m.preview != nil && m.focus == panelLocal:
+		// The preview takes the place of the other panel.
+		panels = lipgloss.JoinHorizontal(lipgloss.Top,
+			m.renderLocalPanel(panelWidth, panelHeight, m.focus == panelLocal), m.renderPreview(panelWidth, panelHeight, false))
+	case m.preview != nil:
+		panels = lipgloss.JoinHorizontal(lipgloss.Top,
+			m.renderPreview(panelWidth, panelHeight, false), m.renderRemotePanel(panelWidth, panelHeight, m.focus == panelRemote))
+	default:
+		panels = lipgloss.JoinHorizontal(lipgloss.Top,
+			m.renderLocalPanel(panelWidth, panelHeight, m.focus == panelLocal), m.renderRemotePanel(panelWidth, panelHeight, m.focus == panelRemote))
 	}
 
-	return lipgloss.JoinVertical(lipgloss.Left, panels, hints)
+	return lipgloss.JoinVertical(lipgloss.Left, panels, m.Footer())
 }
 
 // SelectedLocalFile returns the full path of the currently selected local file.
@@ -685,6 +1091,11 @@ func (m FileBrowserModel) SelectedRemoteFile() string {
 	return joinRemotePath(m.remoteDir, m.remoteFiles[m.remoteCursor].Name)
 }
 
+// RemoteDir returns the directory shown in the remote panel.
+func (m FileBrowserModel) RemoteDir() string {
+	return m.remoteDir
+}
+
 func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
@@ -693,6 +1104,9 @@ func truncate(s string, n int) string {
 }
 
 func truncatePath(s string, n int) string {
+	if n < 2 {
+		n = 2
+	}
 	if len(s) <= n {
 		return s
 	}
@@ -709,10 +1123,26 @@ func (m FileBrowserModel) RefreshRemoteCmd() tea.Cmd {
 	return refreshRemoteCmd(m.client, m.remoteDir)
 }
 
+// MarkRemoteStale records that the remote directory was changed from
+// another tab, so the listing is refreshed when this tab is shown again.
+func (m *FileBrowserModel) MarkRemoteStale() {
+	m.remoteStale = true
+}
+
+// RefreshIfStale returns a remote refresh command if the listing was marked
+// stale, and nil otherwise.
+func (m *FileBrowserModel) RefreshIfStale() tea.Cmd {
+	if !m.remoteStale {
+		return nil
+	}
+	m.remoteStale = false
+	return m.RefreshRemoteCmd()
+}
+
 // InputActive reports whether the file browser has an active text input dialog,
 // meaning it should capture all key events.
 func (m FileBrowserModel) InputActive() bool {
-	return m.inputActive
+	return m.inputActive || m.props != nil || m.bookmarks != nil || m.search != nil || m.du != nil || m.tailShown || m.runnerShown || m.snippets != nil
 }
 
 // joinRemotePath joins a remote directory and a filename, avoiding double slashes.
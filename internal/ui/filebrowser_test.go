@@ -745,7 +745,7 @@ func TestFBRenderLocalPanelWithFiles(t *testing.T) {
 	m.width = 80
 	m.height = 30
 
-	view := m.renderLocalPanel(40, 20)
+	view := m.renderLocalPanel(40, 20, true)
 	if !strings.Contains(view, "Local") {
 		t.Error("should contain 'Local' header")
 	}
@@ -762,7 +762,7 @@ func TestFBRenderRemotePanelWithFiles(t *testing.T) {
 		height: 30,
 	}
 
-	view := m.renderRemotePanel(40, 20)
+	view := m.renderRemotePanel(40, 20, true)
 	if !strings.Contains(view, "Remote") {
 		t.Error("should contain 'Remote' header")
 	}
@@ -782,7 +782,7 @@ func TestFBRenderLocalPanelWithSelection(t *testing.T) {
 	m.height = 30
 	m.localCursor = 1
 
-	view := m.renderLocalPanel(40, 20)
+	view := m.renderLocalPanel(40, 20, true)
 	if view == "" {
 		t.Error("view should not be empty")
 	}
@@ -800,7 +800,7 @@ func TestFBRenderRemotePanelWithSelection(t *testing.T) {
 		height:       30,
 	}
 
-	view := m.renderRemotePanel(40, 20)
+	view := m.renderRemotePanel(40, 20, true)
 	if view == "" {
 		t.Error("view should not be empty")
 	}
@@ -871,6 +871,61 @@ func TestFBTransferDoneSuccess(t *testing.T) {
 	}
 }
 
+func TestFBTransferDoneShowsDetail(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/remote")
+	m.transferring = true
+	m.transferProgress = "disk.img"
+
+	m, _ = m.Update(TransferDoneMsg{Detail: "delta: 2/4096 blocks sent"})
+	if !strings.Contains(m.statusMsg, "disk.img (delta: 2/4096 blocks sent)") {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+}
+
+func TestFBAltTCompressedTransfer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/app.log", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewFileBrowserModel(nil, dir, "/remote")
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t"), Alt: true})
+	if cmd == nil || !m.transferring {
+		t.Fatal("Alt+T should start a transfer")
+	}
+	if !strings.Contains(m.statusMsg, "(compressed)") {
+		t.Errorf("statusMsg = %q, want compressed note", m.statusMsg)
+	}
+}
+
+func TestFBCtrlTUsesHostCompression(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/app.log", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewFileBrowserModel(nil, dir, "/remote")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if strings.Contains(m.statusMsg, "compressed") {
+		t.Errorf("statusMsg = %q, compression is off", m.statusMsg)
+	}
+
+	m = NewFileBrowserModel(nil, dir, "/remote")
+	m.SetCompress(true)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if !strings.Contains(m.statusMsg, "(compressed)") {
+		t.Errorf("statusMsg = %q, want compressed note", m.statusMsg)
+	}
+}
+
+func TestCompressionDetail(t *testing.T) {
+	if got := compressionDetail(sshclient.CompressZstd); got != "zstd compressed" {
+		t.Errorf("detail = %q", got)
+	}
+	if got := compressionDetail(sshclient.CompressNone); !strings.Contains(got, "uncompressed") {
+		t.Errorf("detail = %q", got)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // FileBrowserModel - local scroll up tracking
 // ---------------------------------------------------------------------------
@@ -992,7 +1047,7 @@ func TestFBRenderLocalPanelSmallHeight(t *testing.T) {
 		width:    80,
 		height:   30,
 	}
-	view := m.renderLocalPanel(40, 2)
+	view := m.renderLocalPanel(40, 2, true)
 	if view == "" {
 		t.Error("should render even at small height")
 	}
@@ -1004,7 +1059,7 @@ func TestFBRenderRemotePanelSmallHeight(t *testing.T) {
 		width:     80,
 		height:    30,
 	}
-	view := m.renderRemotePanel(40, 2)
+	view := m.renderRemotePanel(40, 2, true)
 	if view == "" {
 		t.Error("should render even at small height")
 	}
@@ -2065,3 +2120,89 @@ func TestStartInput(t *testing.T) {
 		t.Errorf("inputPrompt = %q, want 'Rename to:'", m.inputPrompt)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Rate limit (Ctrl+L)
+// ---------------------------------------------------------------------------
+
+func TestFBRateLimitNilClient(t *testing.T) {
+	m := FileBrowserModel{height: 30}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if m.inputActive {
+		t.Error("Ctrl+L without a client should not open the input dialog")
+	}
+}
+
+func TestFBRateLimitApply(t *testing.T) {
+	client := &sshclient.Client{}
+	m := FileBrowserModel{client: client, height: 30}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if !m.inputActive || m.inputOp != opRateLimit {
+		t.Fatal("Ctrl+L should open the rate limit input")
+	}
+	if m.inputModel.Value() != "0" {
+		t.Errorf("input should be prefilled with current limit, got %q", m.inputModel.Value())
+	}
+	m.inputModel.SetValue("512")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || cmd() != (RateLimitChangedMsg{KBps: 512}) {
+		t.Error("the new limit should be sent for saving with the host")
+	}
+	if client.RateLimit() != 512*1024 {
+		t.Errorf("RateLimit = %d, want %d", client.RateLimit(), 512*1024)
+	}
+	if !strings.Contains(m.statusMsg, "512.0K/s") {
+		t.Errorf("statusMsg = %q, want it to mention the new limit", m.statusMsg)
+	}
+	if got := m.rateLimitSuffix(); !strings.Contains(got, "limit") {
+		t.Errorf("rateLimitSuffix = %q, want limit description", got)
+	}
+}
+
+func TestFBRateLimitDuringTransfer(t *testing.T) {
+	client := &sshclient.Client{}
+	m := FileBrowserModel{client: client, height: 30, transferring: true, transferProgress: "big.iso"}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if !m.inputActive {
+		t.Fatal("Ctrl+L should open the rate limit input while a transfer runs")
+	}
+	m.inputModel.SetValue("256")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if client.RateLimit() != 256*1024 {
+		t.Errorf("RateLimit = %d, want %d", client.RateLimit(), 256*1024)
+	}
+	if !strings.Contains(m.statusMsg, "running transfer of big.iso") {
+		t.Errorf("statusMsg = %q, want it to name the running transfer", m.statusMsg)
+	}
+}
+
+func TestFBRateLimitUnlimitedSavesNegative(t *testing.T) {
+	client := &sshclient.Client{}
+	client.SetRateLimit(1024)
+	m := FileBrowserModel{client: client}
+	_, cmd := m.applyRateLimit("0")
+	if cmd == nil || cmd() != (RateLimitChangedMsg{KBps: -1}) {
+		t.Error("0 should be saved as unlimited for the host, not as use-global")
+	}
+}
+
+func TestFBRateLimitInvalid(t *testing.T) {
+	client := &sshclient.Client{}
+	m := FileBrowserModel{client: client}
+	m, _ = m.applyRateLimit("fast")
+	if !strings.Contains(m.statusMsg, "Invalid") {
+		t.Errorf("statusMsg = %q, want invalid message", m.statusMsg)
+	}
+	if client.RateLimit() != 0 {
+		t.Error("invalid input should leave the limit unchanged")
+	}
+}
+
+func TestFormatRateLimit(t *testing.T) {
+	if got := formatRateLimit(0); got != "unlimited" {
+		t.Errorf("formatRateLimit(0) = %q, want unlimited", got)
+	}
+	if got := formatRateLimit(2048); got != "2.0K/s" {
+		t.Errorf("formatRateLimit(2048) = %q, want 2.0K/s", got)
+	}
+}
@@ -0,0 +1,609 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ssh-scp/internal/config"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxFleetLines is how much output is kept per host.
+const maxFleetLines = 2000
+
+// FleetMsg is sent when a command is to be run on the hosts marked on the
+// connection screen.
+type FleetMsg struct {
+	Conns []config.Connection
+}
+
+// FleetRunFunc connects to conn, runs cmd in the login directory and calls
+// line for each line of output. It is called from its own goroutine and
+// returns the command's exit code.
+type FleetRunFunc func(ctx context.Context, conn config.Connection, cmd string, line func(text string, stderr bool)) (int, error)
+
+// FleetResultMsg reports that a fleet command finished on one host.
+type FleetResultMsg struct {
+	run   *fleetRun
+	host  int
+	code  int
+	lines []runnerLine
+	cut   bool // output beyond maxFleetLines was dropped
+	err   error
+	took  time.Duration
+}
+
+// FleetCloseMsg is sent when the fleet view is closed.
+type FleetCloseMsg struct{}
+
+type fleetState int
+
+const (
+	fleetPending fleetState = iota
+	fleetRunning
+	fleetDone
+	fleetCancelled
+)
+
+// fleetHost is the result of a fleet command on one host.
+type fleetHost struct {
+	state fleetState
+	code  int
+	err   error
+	lines []runnerLine
+	cut   bool
+	took  time.Duration
+}
+
+// failed reports whether the command could not run or exited non-zero.
+func (h fleetHost) failed() bool {
+	return h.state == fleetDone && (h.err != nil || h.code != 0)
+}
+
+// status describes the host's result in a few words.
+func (h fleetHost) status() string {
+	switch h.state {
+	case fleetPending:
+		return "waiting"
+	case fleetRunning:
+		return "running"
+	case fleetCancelled:
+		return "cancelled"
+	}
+	switch {
+	case h.err != nil:
+		return "error"
+	case h.code == 0:
+		return "ok"
+	}
+	return fmt.Sprintf("exit %d", h.code)
+}
+
+// text returns the output as plain text, or the error for a host the
+// command could not run on.
+func (h fleetHost) text() string {
+	if h.err != nil && len(h.lines) == 0 {
+		return h.err.Error()
+	}
+	var b strings.Builder
+	for _, l := range h.lines {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// preview returns the first non-empty line of output.
+func (h fleetHost) preview() string {
+	if h.err != nil && len(h.lines) == 0 {
+		return h.err.Error()
+	}
+	for _, l := range h.lines {
+		if s := strings.TrimSpace(cleanLine(lastSegment(l.text))); s != "" {
+			if len(h.lines) > 1 {
+				s += fmt.Sprintf(" (+%d lines)", len(h.lines)-1)
+			}
+			return s
+		}
+	}
+	return ""
+}
+
+// fleetRun is one command run on every host.
+type fleetRun struct {
+	cmd     string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	hosts   []fleetHost
+	next    int // first host not started yet
+	started time.Time
+}
+
+// running returns how many hosts have not finished.
+func (r *fleetRun) running() int {
+	n := 0
+	for _, h := range r.hosts {
+		if h.state == fleetPending || h.state == fleetRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// fleetRow is a row of the results table: one host, or every host with
+// the same output when grouped.
+type fleetRow struct {
+	hosts []int
+}
+
+// FleetModel runs one command on several hosts at once, at most limit at a
+// time, and shows a table of the results. Hosts can be grouped by
+// identical output, and the full output of a row can be opened.
+type FleetModel struct {
+	conns     []config.Connection
+	runFn     FleetRunFunc
+	limit     int
+	run       *fleetRun
+	prompting bool
+	input     textinput.Model
+	grouped   bool
+	cursor    int
+	detail    bool // showing the full output of the row under the cursor
+	offset    int  // detail lines scrolled down
+	status    string
+	width     int
+	height    int
+}
+
+// NewFleetModel creates the fleet view for conns with the command prompt
+// open. run is started for at most limit hosts at a time.
+func NewFleetModel(conns []config.Connection, run FleetRunFunc, limit int) FleetModel {
+	m := FleetModel{conns: conns, runFn: run, limit: max(limit, 1)}
+	m.startPrompt("")
+	return m
+}
+
+// Init starts the prompt's cursor blinking.
+func (m FleetModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetDimensions sets the view's display dimensions.
+func (m *FleetModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+	m.input.Width = max(width-12, 20)
+}
+
+// Stop cancels the command on every host.
+func (m *FleetModel) Stop() {
+	if m.run == nil {
+		return
+	}
+	m.run.cancel()
+	for i := range m.run.hosts {
+		if m.run.hosts[i].state == fleetPending {
+			m.run.hosts[i].state = fleetCancelled
+		}
+	}
+}
+
+// Running returns how many hosts the command has not finished on.
+func (m FleetModel) Running() int {
+	if m.run == nil {
+		return 0
+	}
+	return m.run.running()
+}
+
+func (m *FleetModel) startPrompt(value string) tea.Cmd {
+	ti := textinput.New()
+	ti.CharLimit = 1024
+	ti.Width = max(m.width-12, 20)
+	ti.SetValue(value)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.prompting = true
+	return textinput.Blink
+}
+
+// Run starts cmd on every host.
+func (m *FleetModel) Run(cmd string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.run = &fleetRun{
+		cmd:     cmd,
+		ctx:     ctx,
+		cancel:  cancel,
+		hosts:   make([]fleetHost, len(m.conns)),
+		started: time.Now(),
+	}
+	m.cursor, m.detail, m.prompting = 0, false, false
+	var cmds []tea.Cmd
+	for m.run.next < len(m.conns) && m.run.next < m.limit {
+		cmds = append(cmds, m.startNext())
+	}
+	return tea.Batch(cmds...)
+}
+
+// startNext starts the command on the next waiting host.
+func (m *FleetModel) startNext() tea.Cmd {
+	r, i := m.run, m.run.next
+	r.next++
+	r.hosts[i].state = fleetRunning
+	conn, cmd, run := m.conns[i], r.cmd, m.runFn
+	return func() tea.Msg {
+		start := time.Now()
+		msg := FleetResultMsg{run: r, host: i}
+		msg.code, msg.err = run(r.ctx, conn, cmd, func(text string, stderr bool) {
+			if len(msg.lines) < maxFleetLines {
+				msg.lines = append(msg.lines, runnerLine{text: text, stderr: stderr})
+			} else {
+				msg.cut = true
+			}
+		})
+		msg.took = time.Since(start)
+		return msg
+	}
+}
+
+// rows returns the rows of the results table. Grouped rows put finished
+// hosts with the same output together, largest group first; hosts still
+// running stay on their own.
+func (m FleetModel) rows() []fleetRow {
+	if m.run == nil {
+		return nil
+	}
+	var rows []fleetRow
+	if !m.grouped {
+		for i := range m.run.hosts {
+			rows = append(rows, fleetRow{hosts: []int{i}})
+		}
+		return rows
+	}
+	byText := map[string]int{}
+	var pending []fleetRow
+	for i, h := range m.run.hosts {
+		if h.state != fleetDone {
+			pending = append(pending, fleetRow{hosts: []int{i}})
+			continue
+		}
+		key := h.text()
+		if at, ok := byText[key]; ok {
+			rows[at].hosts = append(rows[at].hosts, i)
+			continue
+		}
+		byText[key] = len(rows)
+		rows = append(rows, fleetRow{hosts: []int{i}})
+	}
+	sort.SliceStable(rows, func(a, b int) bool { return len(rows[a].hosts) > len(rows[b].hosts) })
+	return append(rows, pending...)
+}
+
+func (m FleetModel) visibleRows() int {
+	return max(m.height-7, 1) // command, status, hints, borders
+}
+
+// outputRows returns how many rows of hosts or output fit below the table
+// header or the output's title.
+func (m FleetModel) outputRows() int {
+	return max(m.visibleRows()-1, 1)
+}
+
+// Update handles results and keys.
+func (m FleetModel) Update(msg tea.Msg) (FleetModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case FleetResultMsg:
+		r := msg.run
+		if r != m.run {
+			return m, nil
+		}
+		h := &r.hosts[msg.host]
+		h.state, h.code, h.lines, h.cut, h.took = fleetDone, msg.code, msg.lines, msg.cut, msg.took
+		switch {
+		case r.ctx.Err() != nil && (msg.err == nil || errors.Is(msg.err, context.Canceled)):
+			h.state = fleetCancelled
+		default:
+			h.err = msg.err
+		}
+		if r.ctx.Err() == nil && r.next < len(r.hosts) {
+			return m, m.startNext()
+		}
+		if r.running() == 0 {
+			r.cancel()
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.prompting {
+			return m.handleInputKey(msg)
+		}
+		if m.detail {
+			m.handleDetailKey(msg)
+			return m, nil
+		}
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m FleetModel) close() (FleetModel, tea.Cmd) {
+	m.Stop()
+	return m, func() tea.Msg { return FleetCloseMsg{} }
+}
+
+func (m FleetModel) handleInputKey(msg tea.KeyMsg) (FleetModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		if m.run == nil {
+			return m.close()
+		}
+		m.prompting = false
+		return m, nil
+	case "ctrl+c":
+		m.input.SetValue("")
+		return m, nil
+	case "enter":
+		cmd := strings.TrimSpace(m.input.Value())
+		if cmd == "" {
+			return m, nil
+		}
+		return m, m.Run(cmd)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m FleetModel) handleKey(msg tea.KeyMsg) (FleetModel, tea.Cmd) {
+	m.status = ""
+	rows := m.rows()
+	switch msg.String() {
+	case "esc", "q":
+		return m.close()
+	case "ctrl+c", "x":
+		if m.Running() > 0 {
+			m.Stop()
+			m.status = "Cancelling…"
+		}
+	case "!", "i":
+		if m.Running() > 0 {
+			m.status = "Wait for the command to finish or cancel it with Ctrl+C"
+			return m, nil
+		}
+		return m, m.startPrompt(m.run.cmd)
+	case "r":
+		if m.Running() > 0 {
+			m.status = "Wait for the command to finish or cancel it with Ctrl+C"
+			return m, nil
+		}
+		return m, m.Run(m.run.cmd)
+	case "g":
+		m.grouped = !m.grouped
+		m.cursor = 0
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = max(min(m.cursor+1, len(rows)-1), 0)
+	case "pgup":
+		m.cursor = max(m.cursor-m.outputRows(), 0)
+	case "pgdown":
+		m.cursor = max(min(m.cursor+m.outputRows(), len(rows)-1), 0)
+	case "home":
+		m.cursor = 0
+	case "end":
+		m.cursor = max(len(rows)-1, 0)
+	case "enter":
+		if m.cursor < len(rows) {
+			m.detail, m.offset = true, 0
+		}
+	}
+	return m, nil
+}
+
+func (m *FleetModel) handleDetailKey(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc", "q", "enter":
+		m.detail = false
+		return
+	case "ctrl+c", "x":
+		m.Stop()
+	case "up", "k":
+		m.offset--
+	case "down", "j":
+		m.offset++
+	case "pgup":
+		m.offset -= m.outputRows()
+	case "pgdown":
+		m.offset += m.outputRows()
+	case "home", "g":
+		m.offset = 0
+	case "end", "G":
+		m.offset = len(m.detailLines())
+	}
+	m.offset = max(min(m.offset, len(m.detailLines())-m.outputRows()), 0)
+}
+
+// detailLines returns the output of the row under the cursor.
+func (m FleetModel) detailLines() []runnerLine {
+	rows := m.rows()
+	if m.cursor >= len(rows) {
+		return nil
+	}
+	h := m.run.hosts[rows[m.cursor].hosts[0]]
+	if h.err != nil && len(h.lines) == 0 {
+		return []runnerLine{{text: h.err.Error(), stderr: true}}
+	}
+	return h.lines
+}
+
+// fleetHostName names a host in the fleet view.
+func fleetHostName(c config.Connection) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if c.Username == "" {
+		return c.Host + ":" + c.Port
+	}
+	return c.Username + "@" + c.Host + ":" + c.Port
+}
+
+// rowName names the hosts of a row.
+func (m FleetModel) rowName(row fleetRow) string {
+	names := make([]string, len(row.hosts))
+	for i, h := range row.hosts {
+		names[i] = fleetHostName(m.conns[h])
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return fmt.Sprintf("%d hosts: %s", len(names), strings.Join(names, ", "))
+}
+
+// rowStatus summarises the results of a row's hosts; a group's time is
+// that of its slowest host.
+func (m FleetModel) rowStatus(row fleetRow) (status, took string) {
+	h := m.run.hosts[row.hosts[0]]
+	if len(row.hosts) == 1 {
+		if h.state == fleetDone {
+			took = formatDuration(h.took)
+		}
+		return h.status(), took
+	}
+	codes := map[string]bool{}
+	var longest time.Duration
+	for _, i := range row.hosts {
+		codes[m.run.hosts[i].status()] = true
+		longest = max(longest, m.run.hosts[i].took)
+	}
+	if len(codes) > 1 {
+		status = "mixed"
+	} else {
+		status = h.status()
+	}
+	return status, formatDuration(longest)
+}
+
+// View renders the fleet view.
+func (m FleetModel) View() string {
+	width := max(m.width-4, 20)
+	var lines []string
+	if m.run == nil {
+		lines = append(lines, statusBarStyle.Render(truncate(fmt.Sprintf(
+			"Type a command to run on %d host(s), %d at a time", len(m.conns), m.limit), width)))
+	} else {
+		lines = append(lines, m.summary(width))
+		if m.detail {
+			lines = append(lines, m.detailView(width)...)
+		} else {
+			lines = append(lines, m.tableView(width)...)
+		}
+	}
+	for i := len(lines); i < m.visibleRows()+2; i++ {
+		lines = append(lines, "")
+	}
+
+	switch {
+	case m.prompting:
+		lines = append(lines, messageStyle.Render("all hosts $ ")+m.input.View())
+	case m.status != "":
+		lines = append(lines, messageStyle.Render(truncate(m.status, width)))
+	case m.detail:
+		lines = append(lines, statusBarStyle.Render("↑↓ PgUp/PgDn g/G: scroll • ^C: cancel • Esc: back"))
+	default:
+		lines = append(lines, statusBarStyle.Render("Enter: output • g: group by output • !: new command • r: run again • ^C: cancel • q: close"))
+	}
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// summary renders the command and how many hosts it succeeded on.
+func (m FleetModel) summary(width int) string {
+	r := m.run
+	var ok, failed, cancelled int
+	for _, h := range r.hosts {
+		switch {
+		case h.state == fleetCancelled:
+			cancelled++
+		case h.failed():
+			failed++
+		case h.state == fleetDone:
+			ok++
+		}
+	}
+	info := fmt.Sprintf("$ %s — %d ok, %d failed", r.cmd, ok, failed)
+	if cancelled > 0 {
+		info += fmt.Sprintf(", %d cancelled", cancelled)
+	}
+	style := historyOKStyle
+	if n := r.running(); n > 0 {
+		info += fmt.Sprintf(", %d running — %s", n, formatDuration(time.Since(r.started).Round(time.Second)))
+		style = statusBarStyle
+	} else if failed > 0 || cancelled > 0 {
+		style = historyFailStyle
+	}
+	return style.Render(truncate(info, width))
+}
+
+// tableView renders a row per host, or per group of hosts.
+func (m FleetModel) tableView(width int) []string {
+	const statusW, tookW = 10, 8
+	nameW := min(max(width/3, 16), 40)
+	previewW := max(width-nameW-statusW-tookW-3, 10)
+	lines := []string{headerStyle.Width(width).Render(fmt.Sprintf("%-*s %-*s %*s %s",
+		nameW, "Host", statusW, "Status", tookW, "Took", "Output"))}
+
+	rows := m.rows()
+	vis := m.outputRows()
+	start := max(min(m.cursor-vis/2, len(rows)-vis), 0)
+	for i := start; i < len(rows) && i < start+vis; i++ {
+		row := rows[i]
+		status, took := m.rowStatus(row)
+		h := m.run.hosts[row.hosts[0]]
+		line := fmt.Sprintf("%-*s %-*s %*s %s",
+			nameW, truncate(m.rowName(row), nameW),
+			statusW, truncate(status, statusW),
+			tookW, took,
+			truncate(h.preview(), previewW))
+		switch {
+		case i == m.cursor:
+			line = fileSelectedStyle.Width(width).Render(line)
+		case h.failed():
+			line = historyFailStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// detailView renders the full output of the row under the cursor.
+func (m FleetModel) detailView(width int) []string {
+	rows := m.rows()
+	if m.cursor >= len(rows) {
+		return nil
+	}
+	row := rows[m.cursor]
+	status, took := m.rowStatus(row)
+	head := m.rowName(row) + " — " + status
+	if took != "" {
+		head += " after " + took
+	}
+	if h := m.run.hosts[row.hosts[0]]; h.cut {
+		head += fmt.Sprintf(" — first %d lines", maxFleetLines)
+	}
+	lines := []string{messageStyle.Render(truncate(head, width))}
+	out := m.detailLines()
+	vis := m.outputRows()
+	for _, l := range out[min(m.offset, len(out)):min(m.offset+vis, len(out))] {
+		text := truncate(cleanLine(lastSegment(l.text)), width)
+		if l.stderr {
+			text = historyFailStyle.Render(text)
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
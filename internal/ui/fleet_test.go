@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"ssh-scp/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func fleetConns(hosts ...string) []config.Connection {
+	var conns []config.Connection
+	for _, h := range hosts {
+		conns = append(conns, config.Connection{Host: h, Port: "22", Username: "u"})
+	}
+	return conns
+}
+
+// runFleet feeds the fleet view the results of cmd until every host is
+// done, running the host commands one at a time.
+func runFleet(t *testing.T, m FleetModel, cmd tea.Cmd) FleetModel {
+	t.Helper()
+	queue := []tea.Cmd{cmd}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if c == nil {
+			continue
+		}
+		switch msg := c().(type) {
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		case FleetResultMsg:
+			var next tea.Cmd
+			m, next = m.Update(msg)
+			queue = append(queue, next)
+		default:
+			t.Fatalf("got %T, want host results", msg)
+		}
+	}
+	return m
+}
+
+func typeFleet(m FleetModel, s string) FleetModel {
+	for _, r := range s {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+func TestFleetRunsOnEveryHost(t *testing.T) {
+	run := func(ctx context.Context, conn config.Connection, cmd string, line func(string, bool)) (int, error) {
+		switch conn.Host {
+		case "down":
+			return -1, errors.New("connection refused")
+		case "old":
+			line("5.4.0", false)
+			return 0, nil
+		case "bad":
+			line("uname: not found", true)
+			return 127, nil
+		}
+		line("6.1.0", false)
+		return 0, nil
+	}
+	m := NewFleetModel(fleetConns("a", "old", "b", "down", "bad"), run, 2)
+	m.SetDimensions(120, 30)
+	m = typeFleet(m, "uname -r")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = runFleet(t, m, cmd)
+
+	want := []string{"ok", "ok", "ok", "error", "exit 127"}
+	for i, h := range m.run.hosts {
+		if h.status() != want[i] {
+			t.Errorf("host %d status = %q, want %q", i, h.status(), want[i])
+		}
+	}
+	view := m.View()
+	for _, s := range []string{"$ uname -r — 3 ok, 2 failed", "u@down:22", "connection refused", "6.1.0", "exit 127"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("view should contain %q", s)
+		}
+	}
+}
+
+func TestFleetConcurrencyLimit(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	run := func(ctx context.Context, conn config.Connection, cmd string, line func(string, bool)) (int, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		return 0, nil
+	}
+	m := NewFleetModel(fleetConns("a", "b", "c", "d", "e"), run, 2)
+	cmd := m.Run("true")
+	if m.run.next != 2 || m.Running() != 5 {
+		t.Fatalf("started %d hosts, %d unfinished; want 2 started", m.run.next, m.Running())
+	}
+
+	// Run the first batch concurrently, as Bubble Tea would.
+	var wg sync.WaitGroup
+	results := make(chan tea.Msg, 2)
+	for _, c := range cmd().(tea.BatchMsg) {
+		wg.Add(1)
+		go func() { defer wg.Done(); results <- c() }()
+	}
+	wg.Wait()
+	close(results)
+	var next []tea.Cmd
+	for msg := range results {
+		var c tea.Cmd
+		m, c = m.Update(msg)
+		next = append(next, c)
+	}
+	if m.run.next != 4 {
+		t.Errorf("each finished host should start the next one, %d started", m.run.next)
+	}
+	m = runFleet(t, m, tea.Batch(next...))
+	if m.Running() != 0 || peak > 2 {
+		t.Errorf("%d hosts unfinished, %d ran at once; want all done, at most 2", m.Running(), peak)
+	}
+}
+
+func TestFleetCancel(t *testing.T) {
+	started := make(chan struct{})
+	run := func(ctx context.Context, conn config.Connection, cmd string, line func(string, bool)) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 130, ctx.Err()
+	}
+	m := NewFleetModel(fleetConns("a", "b", "c"), run, 1)
+	cmd := m.Run("sleep 100")
+	done := make(chan tea.Msg)
+	go func() { done <- cmd() }()
+	<-started
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	m, next := m.Update(<-done)
+	if next != nil {
+		t.Error("no host should start after cancelling")
+	}
+	for i, h := range m.run.hosts {
+		if h.state != fleetCancelled {
+			t.Errorf("host %d status = %q, want cancelled", i, h.status())
+		}
+	}
+	if m.Running() != 0 {
+		t.Errorf("%d hosts still running after cancelling", m.Running())
+	}
+}
+
+func TestFleetGroupsByOutput(t *testing.T) {
+	run := func(ctx context.Context, conn config.Connection, cmd string, line func(string, bool)) (int, error) {
+		if conn.Host == "c" {
+			line("Ubuntu 20.04", false)
+		} else {
+			line("Ubuntu 22.04", false)
+		}
+		return 0, nil
+	}
+	m := NewFleetModel(fleetConns("a", "c", "b"), run, 8)
+	m.SetDimensions(120, 30)
+	m = runFleet(t, m, m.Run("lsb_release -d"))
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+
+	rows := m.rows()
+	if len(rows) != 2 || fmt.Sprint(rows[0].hosts) != "[0 2]" || fmt.Sprint(rows[1].hosts) != "[1]" {
+		t.Fatalf("grouped rows = %+v, want the two 22.04 hosts first", rows)
+	}
+	if !strings.Contains(m.View(), "2 hosts: u@a:22, u@b:22") {
+		t.Error("a group should list its hosts")
+	}
+}
+
+func TestFleetOutputView(t *testing.T) {
+	run := func(ctx context.Context, conn config.Connection, cmd string, line func(string, bool)) (int, error) {
+		for i := range 100 {
+			line(fmt.Sprintf("%s line %d", conn.Host, i), false)
+		}
+		return 0, nil
+	}
+	m := NewFleetModel(fleetConns("a", "b"), run, 8)
+	m.SetDimensions(100, 20)
+	m = runFleet(t, m, m.Run("seq 100"))
+	if !strings.Contains(m.View(), "a line 0 (+99 lines)") {
+		t.Error("the table should preview the first line")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	view := m.View()
+	if !m.detail || !strings.Contains(view, "b line 99") || strings.Contains(view, "a line") {
+		t.Error("Enter should show the full output of the host under the cursor")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.detail {
+		t.Error("Esc should go back to the table")
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if _, ok := cmd().(FleetCloseMsg); !ok {
+		t.Error("q should close the fleet view")
+	}
+}
+
+func TestFleetEscWithoutCommandCloses(t *testing.T) {
+	m := NewFleetModel(fleetConns("a"), nil, 8)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("Esc at the first prompt should close the view")
+	}
+	if _, ok := cmd().(FleetCloseMsg); !ok {
+		t.Error("Esc at the first prompt should close the view")
+	}
+}
@@ -5,13 +5,53 @@ import (
 )
 
 var helpContent = `
+  Layout
+  F12       Focus the next pane
+  Alt+⇧←/→  Focus the pane left / right (also ↑/↓)
+  Alt+^←/→  Move the nearest divider (also ↑/↓)
+  Alt+⇧V    Split the pane side by side
+  Alt+⇧S    Split the pane stacked
+  Alt+⇧X    Close the pane
+  Alt+⇧N    Change what the pane shows
+
+  Terminal
+  ^]        Switch to next tab (all other keys go to the shell)
+  Alt+PgUp  Copy mode: vim keys, / ? search, v V select, y copy
+  Alt+⇧R    Start/stop recording the session (asciinema .cast)
+  ^P        Play back a recording
+  Alt+⇧B    Broadcast keystrokes to a group of tabs
+  Alt+⇧F    Remote panel follows the shell's directory (on/off)
+  Alt+⇧I    Command snippets: type into the terminal or run (!)
+
   File Browser
   ^←/→      Switch between local and remote panels
   Tab       Switch between local and remote panels
   ^T        Transfer selected file (upload or download)
+  Alt+T     Transfer selected file compressed (gzip/zstd)
+  Alt+C     Copy selected remote file to another tab's host
+  Alt+S     Toggle sudo mode for remote operations
+  Alt+P     Properties (mode, owner, group)
+  Alt+L     Create symlink (name -> target)
+  /         Filter panel (fuzzy; Enter keeps, Esc clears)
+  ^G        Go to path (Tab completes directories)
+  Alt+←/→   Back / forward in the panel's directory history
+  Alt+B     Bookmarks (Enter go, a add, d delete)
+  Alt+F     Search files by name, size, age, content
+  Alt+U     Remote disk usage (du/df), delete large entries
+  Alt+V     Show/hide preview pane (text, hex, image, archive)
+  Alt+W     Follow remote file with tail -F (Esc hides)
+  !         Run a command in the remote directory (↑ history, s save)
+  Alt+G     cd the terminal to the remote panel's directory
+  Alt+O     Cycle sort: name, size, mtime, extension
+  Alt+R     Reverse sort order
+  Alt+D     Toggle directories first
+  Alt+H     Toggle hidden (dot) files
   ^K        Create new directory
   ^D        Delete selected file/directory
   ^R        Rename selected file/directory
+  ^S        Sync local and remote directories (review, then apply)
+  ^L        Set transfer rate limit (KB/s, also mid-transfer)
+  ^O        Transfer history (filter, Enter to re-run)
   ^]        Switch to next tab
   ^N        New connection tab
   ^W        Close current tab
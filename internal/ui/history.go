@@ -0,0 +1,268 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ssh-scp/internal/config"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// HistoryLoadedMsg carries a host's transfer history for the history view.
+type HistoryLoadedMsg struct {
+	Host    string
+	Records []config.TransferRecord
+	Err     error
+}
+
+// HistoryRerunMsg requests repeating a transfer from the history.
+type HistoryRerunMsg struct {
+	Record config.TransferRecord
+}
+
+// HistoryCloseMsg requests closing the history view.
+type HistoryCloseMsg struct{}
+
+// LoadHistoryCmd returns a command that loads the transfer history for a host.
+func LoadHistoryCmd(hostKey string) tea.Cmd {
+	return func() tea.Msg {
+		records, err := config.LoadHistory(hostKey)
+		return HistoryLoadedMsg{Host: hostKey, Records: records, Err: err}
+	}
+}
+
+// HistoryModel is a filterable list of past transfers for one host.
+type HistoryModel struct {
+	host     string
+	records  []config.TransferRecord
+	filtered []int // indexes into records matching the filter
+	cursor   int
+	scroll   int
+	width    int
+	height   int
+	filter   textinput.Model
+}
+
+// NewHistoryModel creates a history view for the given host's records.
+func NewHistoryModel(host string, records []config.TransferRecord) HistoryModel {
+	ti := textinput.New()
+	ti.Placeholder = "type to filter (e.g. upload, failed, .tar.gz)"
+	ti.Prompt = "/ "
+	ti.CharLimit = 128
+	ti.Focus()
+	m := HistoryModel{host: host, records: records, filter: ti}
+	m.applyFilter()
+	return m
+}
+
+// SetDimensions sets the view's display dimensions.
+func (m *HistoryModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m HistoryModel) visibleRows() int {
+	v := m.height - 7 // title, filter, header, detail lines, hints
+	if v < 1 {
+		v = 1
+	}
+	return v
+}
+
+// searchText returns the text a record is matched against when filtering.
+func searchText(r config.TransferRecord) string {
+	dir := "download"
+	if r.Upload {
+		dir = "upload"
+	}
+	status := "ok"
+	if !r.OK() {
+		status = "failed"
+	}
+	return strings.ToLower(strings.Join([]string{dir, status, r.Source, r.Destination, r.Result}, " "))
+}
+
+// applyFilter recomputes the filtered index list. Every whitespace-separated
+// term of the filter must occur in the record.
+func (m *HistoryModel) applyFilter() {
+	terms := strings.Fields(strings.ToLower(m.filter.Value()))
+	m.filtered = nil
+	for i, r := range m.records {
+		text := searchText(r)
+		match := true
+		for _, t := range terms {
+			if !strings.Contains(text, t) {
+				match = false
+				break
+			}
+		}
+		if match {
+			m.filtered = append(m.filtered, i)
+		}
+	}
+	if m.cursor >= len(m.filtered) {
+		m.cursor = len(m.filtered) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.scroll = 0
+	m.ensureVisible()
+}
+
+func (m *HistoryModel) ensureVisible() {
+	vis := m.visibleRows()
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if m.cursor >= m.scroll+vis {
+		m.scroll = m.cursor - vis + 1
+	}
+}
+
+// Selected returns the record under the cursor.
+func (m HistoryModel) Selected() (config.TransferRecord, bool) {
+	if len(m.filtered) == 0 {
+		return config.TransferRecord{}, false
+	}
+	return m.records[m.filtered[m.cursor]], true
+}
+
+// Update handles key events for the history view.
+func (m HistoryModel) Update(msg tea.Msg) (HistoryModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.Type {
+	case tea.KeyEsc:
+		if m.filter.Value() != "" {
+			m.filter.SetValue("")
+			m.applyFilter()
+			return m, nil
+		}
+		return m, func() tea.Msg { return HistoryCloseMsg{} }
+	case tea.KeyEnter:
+		if rec, ok := m.Selected(); ok {
+			return m, func() tea.Msg { return HistoryRerunMsg{Record: rec} }
+		}
+		return m, nil
+	case tea.KeyUp:
+		if m.cursor > 0 {
+			m.cursor--
+			m.ensureVisible()
+		}
+		return m, nil
+	case tea.KeyDown:
+		if m.cursor < len(m.filtered)-1 {
+			m.cursor++
+			m.ensureVisible()
+		}
+		return m, nil
+	case tea.KeyPgUp:
+		m.cursor -= m.visibleRows()
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+		m.ensureVisible()
+		return m, nil
+	case tea.KeyPgDown:
+		m.cursor += m.visibleRows()
+		if m.cursor > len(m.filtered)-1 {
+			m.cursor = len(m.filtered) - 1
+		}
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+		m.ensureVisible()
+		return m, nil
+	}
+
+	before := m.filter.Value()
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	if m.filter.Value() != before {
+		m.applyFilter()
+	}
+	return m, cmd
+}
+
+var (
+	historyBoxStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#7D56F4")).
+			Padding(0, 1)
+
+	historyOKStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#50FA7B"))
+
+	historyFailStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF5555"))
+)
+
+// formatDuration renders a transfer duration compactly.
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// View renders the history view.
+func (m HistoryModel) View() string {
+	innerWidth := m.width - 4
+	if innerWidth < 20 {
+		innerWidth = 20
+	}
+
+	title := messageStyle.Render(fmt.Sprintf("Transfer history — %s (%d/%d)", m.host, len(m.filtered), len(m.records)))
+	header := headerStyle.Width(innerWidth).Render(
+		fmt.Sprintf("%-16s %-2s %7s %8s  %s", "Time", "", "Size", "Took", "Source → Destination"),
+	)
+
+	var rows []string
+	if len(m.filtered) == 0 {
+		rows = append(rows, statusBarStyle.Render("No transfers recorded"))
+	}
+	vis := m.visibleRows()
+	for i := m.scroll; i < len(m.filtered) && i < m.scroll+vis; i++ {
+		r := m.records[m.filtered[i]]
+		arrow := "↓"
+		if r.Upload {
+			arrow = "↑"
+		}
+		mark := historyOKStyle.Render("✓")
+		if !r.OK() {
+			mark = historyFailStyle.Render("✗")
+		}
+		prefix := fmt.Sprintf("%-16s %s%s %7s %8s  ", r.Time.Local().Format("2006-01-02 15:04"), arrow, mark, formatSize(r.Size), formatDuration(r.Duration))
+		avail := innerWidth - lipgloss.Width(prefix)
+		if avail < 10 {
+			avail = 10
+		}
+		paths := truncatePath(r.Source+" → "+r.Destination, avail)
+		line := prefix + paths
+		if i == m.cursor {
+			line = fileSelectedStyle.Width(innerWidth).Render(line)
+		}
+		rows = append(rows, line)
+	}
+
+	var detail string
+	if rec, ok := m.Selected(); ok {
+		if rec.OK() {
+			detail = statusBarStyle.Render(truncate("sha256: "+rec.Checksum, innerWidth))
+		} else {
+			detail = historyFailStyle.Render(truncate("error: "+rec.Result, innerWidth))
+		}
+	}
+
+	hints := statusBarStyle.Render("↑/↓: select • Enter: re-run • Esc: clear filter / close")
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title, m.filter.View(), header, strings.Join(rows, "\n"), "", detail, hints)
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(content)
+}
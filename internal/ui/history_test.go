@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ssh-scp/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func sampleHistory() []config.TransferRecord {
+	return []config.TransferRecord{
+		{Time: time.Now(), Upload: true, Source: "/build/app.tar.gz", Destination: "/srv/app.tar.gz", Size: 2048, Duration: 2 * time.Second, Result: "ok", Checksum: "abc123"},
+		{Time: time.Now(), Upload: false, Source: "/var/log/syslog", Destination: "/tmp/syslog", Size: 100, Duration: 10 * time.Millisecond, Result: "permission denied"},
+		{Time: time.Now(), Upload: true, Source: "/build/notes.txt", Destination: "/srv/notes.txt", Result: "ok"},
+	}
+}
+
+func typeString(m HistoryModel, s string) HistoryModel {
+	for _, r := range s {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+func TestHistoryModelFilter(t *testing.T) {
+	m := NewHistoryModel("u@h:22", sampleHistory())
+	if len(m.filtered) != 3 {
+		t.Fatalf("unfiltered count = %d, want 3", len(m.filtered))
+	}
+	m = typeString(m, "upload")
+	if len(m.filtered) != 2 {
+		t.Errorf("'upload' matches = %d, want 2", len(m.filtered))
+	}
+	m = typeString(m, " tar")
+	if len(m.filtered) != 1 {
+		t.Errorf("'upload tar' matches = %d, want 1", len(m.filtered))
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.filter.Value() != "" || len(m.filtered) != 3 {
+		t.Error("Esc should clear a non-empty filter first")
+	}
+	m = typeString(m, "failed")
+	if rec, ok := m.Selected(); !ok || rec.Source != "/var/log/syslog" {
+		t.Errorf("'failed' should select the failed transfer, got %+v", rec)
+	}
+}
+
+func TestHistoryModelNavigateAndRerun(t *testing.T) {
+	m := NewHistoryModel("u@h:22", sampleHistory())
+	m.SetDimensions(100, 30)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != 2 {
+		t.Errorf("cursor = %d, want 2 (clamped)", m.cursor)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter should return a command")
+	}
+	rerun, ok := cmd().(HistoryRerunMsg)
+	if !ok || rerun.Record.Source != "/var/log/syslog" {
+		t.Errorf("expected rerun of selected record, got %#v", cmd())
+	}
+}
+
+func TestHistoryModelEscCloses(t *testing.T) {
+	m := NewHistoryModel("u@h:22", nil)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("Esc should return a command")
+	}
+	if _, ok := cmd().(HistoryCloseMsg); !ok {
+		t.Error("Esc with empty filter should close the view")
+	}
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Error("Enter on an empty history should do nothing")
+	}
+}
+
+func TestHistoryModelView(t *testing.T) {
+	m := NewHistoryModel("u@h:22", sampleHistory())
+	m.SetDimensions(120, 30)
+	view := m.View()
+	for _, want := range []string{"u@h:22", "app.tar.gz", "sha256: abc123"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q", want)
+		}
+	}
+	empty := NewHistoryModel("u@h:22", nil)
+	empty.SetDimensions(80, 20)
+	if !strings.Contains(empty.View(), "No transfers recorded") {
+		t.Error("empty history should say so")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	if got := formatDuration(250 * time.Millisecond); got != "250ms" {
+		t.Errorf("formatDuration(250ms) = %q", got)
+	}
+	if got := formatDuration(1500 * time.Millisecond); got != "1.5s" {
+		t.Errorf("formatDuration(1.5s) = %q", got)
+	}
+}
+
+func TestLoadHistoryCmd(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	msg := LoadHistoryCmd("u@h:22")().(HistoryLoadedMsg)
+	if msg.Err != nil || msg.Host != "u@h:22" || len(msg.Records) != 0 {
+		t.Errorf("unexpected HistoryLoadedMsg %+v", msg)
+	}
+}
+
+func TestRecordTransfer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	local := filepath.Join(dir, "artifact.bin")
+	if err := os.WriteFile(local, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recordTransfer("u@h:22", true, local, "/srv/artifact.bin", local, time.Now(), nil)
+	// A failed download leaves a truncated local file behind.
+	partial := filepath.Join(dir, "partial")
+	if err := os.WriteFile(partial, []byte("hel"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recordTransfer("u@h:22", false, "/srv/partial", partial, partial, time.Now(), errors.New("no such file"))
+	recordTransfer("", true, local, "/x", local, time.Now(), nil) // disabled
+
+	records, err := config.LoadHistory("u@h:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	failed, ok := records[0], records[1]
+	if failed.OK() || failed.Result != "no such file" || failed.Checksum != "" || failed.Size != 0 {
+		t.Errorf("unexpected failed record %+v", failed)
+	}
+	// sha256("hello")
+	if ok.Checksum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("checksum = %q", ok.Checksum)
+	}
+	if ok.Size != 5 || !ok.Upload {
+		t.Errorf("unexpected ok record %+v", ok)
+	}
+}
+
+func TestRerunTransferBusy(t *testing.T) {
+	m := FileBrowserModel{transferring: true}
+	m, cmd := m.RerunTransfer(config.TransferRecord{Upload: true, Source: "/a", Destination: "/b"})
+	if cmd != nil {
+		t.Error("rerun while transferring should not start a transfer")
+	}
+	if !strings.Contains(m.statusMsg, "already") {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+}
+
+func TestRerunTransferStarts(t *testing.T) {
+	m := FileBrowserModel{}
+	m, cmd := m.RerunTransfer(config.TransferRecord{Upload: false, Source: "/srv/app.log", Destination: "/tmp/dl/app.log"})
+	if cmd == nil || !m.transferring {
+		t.Fatal("rerun should start a transfer")
+	}
+	if m.transferProgress != "app.log" || !strings.HasPrefix(m.statusMsg, "Downloading app.log") {
+		t.Errorf("unexpected state progress=%q status=%q", m.transferProgress, m.statusMsg)
+	}
+	m = FileBrowserModel{}
+	m, _ = m.RerunTransfer(config.TransferRecord{Upload: true, Source: "/build/app.tar.gz", Destination: "/srv/app.tar.gz"})
+	if !strings.HasPrefix(m.statusMsg, "Uploading app.tar.gz") {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+}
@@ -0,0 +1,361 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxDirHistory bounds the back list of each panel.
+const maxDirHistory = 50
+
+// dirHistory is a panel's back/forward directory history.
+type dirHistory struct {
+	back    []string
+	forward []string
+}
+
+// visit records leaving from for a new directory, dropping the forward list.
+func (h *dirHistory) visit(from string) {
+	h.back = append(h.back, from)
+	if len(h.back) > maxDirHistory {
+		h.back = h.back[len(h.back)-maxDirHistory:]
+	}
+	h.forward = nil
+}
+
+// goBack returns the previous directory, moving cur onto the forward list.
+func (h *dirHistory) goBack(cur string) (string, bool) {
+	if len(h.back) == 0 {
+		return "", false
+	}
+	dir := h.back[len(h.back)-1]
+	h.back = h.back[:len(h.back)-1]
+	h.forward = append(h.forward, cur)
+	return dir, true
+}
+
+// goForward undoes goBack.
+func (h *dirHistory) goForward(cur string) (string, bool) {
+	if len(h.forward) == 0 {
+		return "", false
+	}
+	dir := h.forward[len(h.forward)-1]
+	h.forward = h.forward[:len(h.forward)-1]
+	h.back = append(h.back, cur)
+	return dir, true
+}
+
+// gotoDirMsg carries a remote directory resolved for the go-to prompt.
+type gotoDirMsg struct {
+	dir string
+	err error
+}
+
+// completionMsg carries remote directory completions for the go-to prompt.
+// value is the input they were computed for.
+type completionMsg struct {
+	value   string
+	matches []string
+	err     error
+}
+
+// chdirLocal changes the local directory and records it in the history.
+func (m *FileBrowserModel) chdirLocal(dir string) {
+	if dir == m.localDir {
+		return
+	}
+	m.localHist.visit(m.localDir)
+	m.setLocalDir(dir)
+}
+
+// setLocalDir shows dir in the local panel.
+func (m *FileBrowserModel) setLocalDir(dir string) {
+	m.localDir = dir
+	m.localCursor = 0
+	m.localScroll = 0
+	m.localFilter = ""
+	m.refreshLocal()
+}
+
+// chdirRemote changes the remote directory, records it in the history and
+// returns the command that lists it.
+func (m *FileBrowserModel) chdirRemote(dir string) tea.Cmd {
+	if dir == m.remoteDir {
+		return nil
+	}
+	m.remoteHist.visit(m.remoteDir)
+	return m.setRemoteDir(dir)
+}
+
+// setRemoteDir shows dir in the remote panel.
+func (m *FileBrowserModel) setRemoteDir(dir string) tea.Cmd {
+	m.remoteDir = dir
+	m.remoteCursor = 0
+	m.remoteScroll = 0
+	m.remoteFilter = ""
+	m.remoteSpace = nil
+	return tea.Batch(refreshRemoteCmd(m.client, m.remoteDir), remoteSpaceCmd(m.client, m.remoteDir))
+}
+
+// CdTerminalMsg asks to change the terminal's working directory to Dir.
+type CdTerminalMsg struct {
+	Dir string
+}
+
+// FollowShellDir records the working directory the tab's shell reported
+// and, while the remote panel follows the shell, shows it. The returned
+// command lists the directory.
+func (m *FileBrowserModel) FollowShellDir(dir string) tea.Cmd {
+	m.shellDir = dir
+	if !m.followShell {
+		return nil
+	}
+	return m.chdirRemote(dir)
+}
+
+// ToggleFollowShell turns following the shell's working directory on or
+// off. Turning it on shows the directory the shell last reported.
+func (m *FileBrowserModel) ToggleFollowShell() tea.Cmd {
+	m.followShell = !m.followShell
+	if !m.followShell {
+		m.statusMsg = "Remote panel no longer follows the terminal"
+		return nil
+	}
+	m.statusMsg = "Remote panel follows the terminal's directory"
+	if m.shellDir == "" {
+		return nil
+	}
+	return m.chdirRemote(m.shellDir)
+}
+
+// followLabel marks the remote panel header while it follows the shell.
+func (m FileBrowserModel) followLabel() string {
+	if m.followShell && m.shellDir != "" {
+		return " [follow]"
+	}
+	return ""
+}
+
+// remoteParent returns the parent of a remote directory; ok is false at /.
+func remoteParent(dir string) (string, bool) {
+	dir = strings.TrimRight(dir, "/")
+	if dir == "" {
+		return "/", false
+	}
+	parent := path.Dir(dir)
+	if parent == "." {
+		return dir, false
+	}
+	return parent, true
+}
+
+// historyStep moves the focused panel back (or forward) in its history.
+func (m FileBrowserModel) historyStep(back bool) (FileBrowserModel, tea.Cmd) {
+	h, cur := &m.localHist, m.localDir
+	if m.focus == panelRemote {
+		h, cur = &m.remoteHist, m.remoteDir
+	}
+	step := h.goForward
+	if back {
+		step = h.goBack
+	}
+	dir, ok := step(cur)
+	if !ok {
+		return m, nil
+	}
+	if m.focus == panelLocal {
+		m.setLocalDir(dir)
+		return m, nil
+	}
+	return m, m.setRemoteDir(dir)
+}
+
+// startGoto opens the go-to path prompt, prefilled with the focused panel's
+// directory.
+func (m FileBrowserModel) startGoto() (FileBrowserModel, tea.Cmd) {
+	dir := m.localDir
+	if m.focus == panelRemote {
+		dir = m.remoteDir
+	}
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	m.startInput(opGoto, "Go to:")
+	m.inputModel.SetValue(dir)
+	m.inputModel.CursorEnd()
+	return m, nil
+}
+
+// handleGotoKey adds Tab completion to the go-to prompt.
+func (m FileBrowserModel) handleGotoKey(msg tea.KeyMsg) (FileBrowserModel, tea.Cmd, bool) {
+	if msg.Type != tea.KeyTab {
+		m.inputHint = ""
+		return m, nil, false
+	}
+	value := m.inputModel.Value()
+	if value == "~" {
+		m.setInputValue("~/")
+		return m, nil, true
+	}
+	dir, prefix := splitCompletion(value)
+	if m.focus == panelLocal {
+		matches, err := localCompletions(m.resolveLocal(dir), prefix)
+		if err != nil {
+			m.inputHint = err.Error()
+			return m, nil, true
+		}
+		m.applyCompletion(value, matches)
+		return m, nil, true
+	}
+	client := m.client
+	target := m.resolveRemote(dir)
+	return m, func() tea.Msg {
+		matches, err := client.CompleteDir(target, prefix)
+		return completionMsg{value: value, matches: matches, err: err}
+	}, true
+}
+
+// setInputValue replaces the prompt input and moves the cursor to the end.
+func (m *FileBrowserModel) setInputValue(v string) {
+	m.inputModel.SetValue(v)
+	m.inputModel.CursorEnd()
+}
+
+// applyCompletion completes value with matches: a single match is filled in
+// as a directory, several are filled in up to their common prefix and
+// listed.
+func (m *FileBrowserModel) applyCompletion(value string, matches []string) {
+	dir, _ := splitCompletion(value)
+	switch len(matches) {
+	case 0:
+		m.inputHint = "no matches"
+	case 1:
+		m.setInputValue(dir + matches[0] + "/")
+		m.inputHint = ""
+	default:
+		m.setInputValue(dir + commonPrefix(matches))
+		m.inputHint = strings.Join(matches, "  ")
+	}
+}
+
+// splitCompletion splits a prompt value into the directory part (up to and
+// including the last slash) and the name prefix being completed.
+func splitCompletion(value string) (dir, prefix string) {
+	i := strings.LastIndex(value, "/")
+	return value[:i+1], value[i+1:]
+}
+
+// commonPrefix returns the longest common prefix of names.
+func commonPrefix(names []string) string {
+	p := names[0]
+	for _, n := range names[1:] {
+		for !strings.HasPrefix(n, p) {
+			p = p[:len(p)-1]
+		}
+	}
+	return p
+}
+
+// localCompletions lists the subdirectories (or links to them) of dir
+// starting with prefix. Dot directories need a dot prefix.
+func localCompletions(dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// resolveLocal turns a prompt value into a local path: ~ is expanded and
+// relative paths are taken from the local panel's directory.
+func (m FileBrowserModel) resolveLocal(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	if p == "" {
+		return m.localDir
+	}
+	if !filepath.IsAbs(p) {
+		return filepath.Join(m.localDir, p)
+	}
+	return filepath.Clean(p)
+}
+
+// resolveRemote makes a prompt value absolute against the remote panel's
+// directory. Paths starting with ~ are left for the remote shell.
+func (m FileBrowserModel) resolveRemote(p string) string {
+	switch {
+	case p == "":
+		return m.remoteDir
+	case p == "~" || strings.HasPrefix(p, "~/"):
+		return p
+	case strings.HasPrefix(p, "/"):
+		return path.Clean(p)
+	}
+	return path.Join(m.remoteDir, p)
+}
+
+// executeGoto changes the focused panel to the entered directory.
+func (m FileBrowserModel) executeGoto(value string) (FileBrowserModel, tea.Cmd) {
+	if m.focus == panelLocal {
+		dir := m.resolveLocal(value)
+		info, err := os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			err = errors.New("not a directory")
+		}
+		if err != nil {
+			m.statusMsg = "Go to " + value + ": " + err.Error()
+			return m, nil
+		}
+		m.chdirLocal(dir)
+		m.statusMsg = ""
+		return m, nil
+	}
+	client := m.client
+	target := m.resolveRemote(value)
+	m.statusMsg = "Opening " + target + "..."
+	return m, func() tea.Msg {
+		dir, err := client.ResolveDir(target)
+		return gotoDirMsg{dir: dir, err: err}
+	}
+}
+
+// handleNavigateMsg handles the async results of the go-to prompt.
+func (m FileBrowserModel) handleNavigateMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case gotoDirMsg:
+		if msg.err != nil {
+			m.statusMsg = "Go to failed: " + msg.err.Error()
+			return m, sudoPromptCmd(msg.err)
+		}
+		m.statusMsg = ""
+		return m, m.chdirRemote(msg.dir)
+	case completionMsg:
+		if !m.inputActive || m.inputOp != opGoto || m.inputModel.Value() != msg.value {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.inputHint = msg.err.Error()
+			return m, nil
+		}
+		m.applyCompletion(msg.value, msg.matches)
+	}
+	return m, nil
+}
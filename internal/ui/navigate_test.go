@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDirHistory(t *testing.T) {
+	var h dirHistory
+	h.visit("/a")
+	h.visit("/b")
+	if dir, ok := h.goBack("/c"); !ok || dir != "/b" {
+		t.Fatalf("goBack = %q %v, want /b", dir, ok)
+	}
+	if dir, ok := h.goBack("/b"); !ok || dir != "/a" {
+		t.Fatalf("goBack = %q %v, want /a", dir, ok)
+	}
+	if _, ok := h.goBack("/a"); ok {
+		t.Error("goBack past the start should fail")
+	}
+	if dir, ok := h.goForward("/a"); !ok || dir != "/b" {
+		t.Fatalf("goForward = %q %v, want /b", dir, ok)
+	}
+	h.visit("/b")
+	if _, ok := h.goForward("/x"); ok {
+		t.Error("visiting a new directory should drop the forward list")
+	}
+}
+
+func TestDirHistoryBounded(t *testing.T) {
+	var h dirHistory
+	for i := 0; i < maxDirHistory+10; i++ {
+		h.visit("/d")
+	}
+	if len(h.back) != maxDirHistory {
+		t.Errorf("back list has %d entries, want %d", len(h.back), maxDirHistory)
+	}
+}
+
+func TestRemoteParent(t *testing.T) {
+	tests := []struct {
+		dir, want string
+		ok        bool
+	}{
+		{"/srv/app", "/srv", true},
+		{"/srv/", "/", true},
+		{"/", "/", false},
+		{"~", "~", false},
+		{"a/b", "a", true},
+	}
+	for _, tt := range tests {
+		if got, ok := remoteParent(tt.dir); got != tt.want || ok != tt.ok {
+			t.Errorf("remoteParent(%q) = %q %v, want %q %v", tt.dir, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompletionHelpers(t *testing.T) {
+	if dir, prefix := splitCompletion("/opt/app/rel"); dir != "/opt/app/" || prefix != "rel" {
+		t.Errorf("splitCompletion = %q %q", dir, prefix)
+	}
+	if dir, prefix := splitCompletion("rel"); dir != "" || prefix != "rel" {
+		t.Errorf("splitCompletion(rel) = %q %q", dir, prefix)
+	}
+	if got := commonPrefix([]string{"releases", "release-old", "relay"}); got != "rel" {
+		t.Errorf("commonPrefix = %q, want rel", got)
+	}
+}
+
+func TestResolveRemote(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/opt/app")
+	tests := map[string]string{
+		"":             "/opt/app",
+		"releases/../": "/opt/app",
+		"config":       "/opt/app/config",
+		"/etc//nginx/": "/etc/nginx",
+		"~/logs":       "~/logs",
+	}
+	for in, want := range tests {
+		if got := m.resolveRemote(in); got != want {
+			t.Errorf("resolveRemote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// navDir creates dir/releases/{current,old}, dir/run and a file.
+func navDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, d := range []string{"releases/current", "releases/old", "run", ".cache"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFBGotoLocalWithCompletion(t *testing.T) {
+	dir := navDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	if !m.inputActive || m.inputModel.Value() != dir+"/" {
+		t.Fatalf("Ctrl+G should prefill the current dir, got %q", m.inputModel.Value())
+	}
+
+	m.setInputValue(dir + "/re")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if got := m.inputModel.Value(); got != dir+"/releases/" {
+		t.Fatalf("single match should complete to releases/, got %q", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if got := m.inputModel.Value(); got != dir+"/releases/" || m.inputHint != "current  old" {
+		t.Fatalf("several matches should be listed, got %q hint %q", got, m.inputHint)
+	}
+	m.setInputValue(dir + "/releases/c")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.localDir != filepath.Join(dir, "releases", "current") || m.inputActive {
+		t.Fatalf("Enter should go to the completed path, got %q", m.localDir)
+	}
+
+	// Alt+Left returns to where we came from, Alt+Right goes back again.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	if m.localDir != dir {
+		t.Errorf("Alt+Left should go back to %q, got %q", dir, m.localDir)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight, Alt: true})
+	if m.localDir != filepath.Join(dir, "releases", "current") {
+		t.Errorf("Alt+Right should go forward, got %q", m.localDir)
+	}
+}
+
+func TestFBGotoLocalRelativeAndErrors(t *testing.T) {
+	dir := navDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	m, _ = m.executeGoto("releases/old")
+	if m.localDir != filepath.Join(dir, "releases", "old") {
+		t.Errorf("relative goto = %q", m.localDir)
+	}
+	m, _ = m.executeGoto(filepath.Join(dir, "readme"))
+	if !strings.Contains(m.statusMsg, "not a directory") {
+		t.Errorf("goto a file: status %q", m.statusMsg)
+	}
+	m, _ = m.executeGoto("missing")
+	if !strings.Contains(m.statusMsg, "Go to missing") || m.localDir != filepath.Join(dir, "releases", "old") {
+		t.Errorf("goto a missing dir should not move: %q, %q", m.localDir, m.statusMsg)
+	}
+}
+
+func TestLocalCompletionsHidesDotDirs(t *testing.T) {
+	dir := navDir(t)
+	got, err := localCompletions(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "releases,run" {
+		t.Errorf("completions = %v, want releases, run", got)
+	}
+	if got, _ := localCompletions(dir, "."); strings.Join(got, ",") != ".cache" {
+		t.Errorf("dot completions = %v", got)
+	}
+}
+
+func TestFBBackspaceRecordsHistory(t *testing.T) {
+	dir := navDir(t)
+	sub := filepath.Join(dir, "run")
+	m := NewFileBrowserModel(nil, sub, "/")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	if m.localDir != sub {
+		t.Errorf("Alt+Left after Backspace should return to %q, got %q", sub, m.localDir)
+	}
+}
+
+func TestFBRemoteNavigationMessages(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/home/u")
+	m.focus = panelRemote
+	m, cmd := m.Update(gotoDirMsg{dir: "/opt/app"})
+	if m.remoteDir != "/opt/app" || cmd == nil {
+		t.Fatalf("resolved goto should list /opt/app, got %q", m.remoteDir)
+	}
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyLeft, Alt: true})
+	if m.remoteDir != "/home/u" || cmd == nil {
+		t.Errorf("Alt+Left should return to /home/u, got %q", m.remoteDir)
+	}
+
+	// Completions only apply to the input they were requested for.
+	m, _ = m.startGoto()
+	m.setInputValue("/opt/re")
+	m, _ = m.Update(completionMsg{value: "/opt/x", matches: []string{"xyz"}})
+	if m.inputModel.Value() != "/opt/re" {
+		t.Error("stale completion should be ignored")
+	}
+	m, _ = m.Update(completionMsg{value: "/opt/re", matches: []string{"releases"}})
+	if m.inputModel.Value() != "/opt/releases/" {
+		t.Errorf("completion not applied: %q", m.inputModel.Value())
+	}
+}
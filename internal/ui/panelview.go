@@ -0,0 +1,294 @@
+package ui
+
+import (
+	"cmp"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ssh-scp/internal/config"
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Panel sort orders, cycled with Alt+O.
+const (
+	sortName  = "name"
+	sortSize  = "size"
+	sortMTime = "mtime"
+	sortExt   = "ext"
+)
+
+var sortOrder = []string{sortName, sortSize, sortMTime, sortExt}
+
+// PanelViewChangedMsg is sent when the sort or display settings of a panel
+// change, so the app can remember them.
+type PanelViewChangedMsg struct {
+	Local  config.PanelView
+	Remote config.PanelView
+}
+
+// SetPanelViews sets the sort and display settings of both panels.
+func (m *FileBrowserModel) SetPanelViews(local, remote config.PanelView) {
+	m.localView = local
+	m.remoteView = remote
+	m.applyLocalView("")
+	m.applyRemoteView("")
+}
+
+// panelEntry is the part of a local or remote entry that sorting and
+// filtering look at.
+type panelEntry struct {
+	name  string
+	size  int64
+	mtime time.Time
+	dir   bool
+}
+
+// arrangeEntries returns the indices of the entries to show, in display
+// order: hidden files and filter misses are dropped, the rest sorted as v
+// says. Directories stay first in reverse order when DirsFirst is set.
+func arrangeEntries(entries []panelEntry, v config.PanelView, filter string) []int {
+	idx := make([]int, 0, len(entries))
+	for i, e := range entries {
+		if v.HideHidden && strings.HasPrefix(e.name, ".") {
+			continue
+		}
+		if filter != "" && !fuzzyMatch(filter, e.name) {
+			continue
+		}
+		idx = append(idx, i)
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		x, y := entries[a], entries[b]
+		if v.DirsFirst && x.dir != y.dir {
+			if x.dir {
+				return -1
+			}
+			return 1
+		}
+		c := compareEntries(x, y, v.Sort)
+		if v.Reverse {
+			c = -c
+		}
+		return c
+	})
+	return idx
+}
+
+// compareEntries orders two entries by key, falling back to the name.
+func compareEntries(x, y panelEntry, key string) int {
+	switch key {
+	case sortSize:
+		if c := cmp.Compare(x.size, y.size); c != 0 {
+			return c
+		}
+	case sortMTime:
+		if c := x.mtime.Compare(y.mtime); c != 0 {
+			return c
+		}
+	case sortExt:
+		if c := strings.Compare(strings.ToLower(path.Ext(x.name)), strings.ToLower(path.Ext(y.name))); c != 0 {
+			return c
+		}
+	}
+	if c := strings.Compare(strings.ToLower(x.name), strings.ToLower(y.name)); c != 0 {
+		return c
+	}
+	return strings.Compare(x.name, y.name)
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in name in
+// order, ignoring case ("rlog" matches "rotated.log").
+func fuzzyMatch(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	for _, r := range pattern {
+		i := strings.IndexRune(name, r)
+		if i < 0 {
+			return false
+		}
+		name = name[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// applyLocalView rebuilds the visible local list from all entries. The
+// entry named keep stays selected if still visible; otherwise the cursor
+// keeps its position.
+func (m *FileBrowserModel) applyLocalView(keep string) {
+	entries := make([]panelEntry, len(m.localAll))
+	for i, f := range m.localAll {
+		entries[i] = panelEntry{name: f.Name(), size: f.Size(), mtime: f.ModTime(), dir: m.localIsDir(f)}
+	}
+	idx := arrangeEntries(entries, m.localView, m.localFilter)
+	files := make([]os.FileInfo, len(idx))
+	for i, j := range idx {
+		files[i] = m.localAll[j]
+	}
+	m.localFiles = files
+	m.localCursor, m.localScroll = m.keepCursor(len(files), m.localCursor, m.localScroll, func(i int) bool {
+		return files[i].Name() == keep
+	}, keep)
+}
+
+// applyRemoteView is applyLocalView for the remote panel.
+func (m *FileBrowserModel) applyRemoteView(keep string) {
+	entries := make([]panelEntry, len(m.remoteAll))
+	for i, f := range m.remoteAll {
+		entries[i] = panelEntry{name: f.Name, size: f.Size, mtime: f.ModTime, dir: remoteIsDir(f)}
+	}
+	idx := arrangeEntries(entries, m.remoteView, m.remoteFilter)
+	files := make([]sshclient.RemoteFile, len(idx))
+	for i, j := range idx {
+		files[i] = m.remoteAll[j]
+	}
+	m.remoteFiles = files
+	m.remoteCursor, m.remoteScroll = m.keepCursor(len(files), m.remoteCursor, m.remoteScroll, func(i int) bool {
+		return files[i].Name == keep
+	}, keep)
+}
+
+// keepCursor returns the cursor and scroll offset for a rebuilt list of n
+// entries: on the entry matching keep if there is one, else clamped.
+func (m FileBrowserModel) keepCursor(n, cursor, scroll int, match func(int) bool, keep string) (int, int) {
+	if keep != "" {
+		for i := 0; i < n; i++ {
+			if match(i) {
+				cursor = i
+				break
+			}
+		}
+	}
+	if cursor >= n {
+		cursor = 0
+	}
+	vis := m.visibleHeight()
+	if scroll > cursor {
+		scroll = cursor
+	}
+	if cursor >= scroll+vis {
+		scroll = cursor - vis + 1
+	}
+	return cursor, scroll
+}
+
+// focusedView returns the settings of the focused panel.
+func (m *FileBrowserModel) focusedView() *config.PanelView {
+	if m.focus == panelLocal {
+		return &m.localView
+	}
+	return &m.remoteView
+}
+
+// changeView applies fn to the focused panel's settings, rebuilds its list
+// and asks the app to remember the new settings.
+func (m FileBrowserModel) changeView(fn func(v *config.PanelView)) (FileBrowserModel, tea.Cmd) {
+	keep := m.selectedName()
+	fn(m.focusedView())
+	if m.focus == panelLocal {
+		m.applyLocalView(keep)
+	} else {
+		m.applyRemoteView(keep)
+	}
+	m.statusMsg = "Sort: " + viewDescription(*m.focusedView())
+	msg := PanelViewChangedMsg{Local: m.localView, Remote: m.remoteView}
+	return m, func() tea.Msg { return msg }
+}
+
+// cycleSort switches the focused panel to the next sort order.
+func (m FileBrowserModel) cycleSort() (FileBrowserModel, tea.Cmd) {
+	return m.changeView(func(v *config.PanelView) {
+		i := slices.Index(sortOrder, cmp.Or(v.Sort, sortName))
+		v.Sort = sortOrder[(i+1)%len(sortOrder)]
+	})
+}
+
+// viewDescription describes panel settings for the status bar.
+func viewDescription(v config.PanelView) string {
+	s := cmp.Or(v.Sort, sortName)
+	if v.Reverse {
+		s += " (reversed)"
+	}
+	if v.DirsFirst {
+		s += ", directories first"
+	}
+	if v.HideHidden {
+		s += ", dotfiles hidden"
+	} else {
+		s += ", dotfiles shown"
+	}
+	return s
+}
+
+// viewLabel is the compact panel header note for non-default settings and
+// an active filter, e.g. " [size↓ /log]".
+func viewLabel(v config.PanelView, filter string) string {
+	var parts []string
+	if v.Sort != "" && v.Sort != sortName || v.Reverse {
+		arrow := "↑"
+		if v.Reverse {
+			arrow = "↓"
+		}
+		parts = append(parts, cmp.Or(v.Sort, sortName)+arrow)
+	}
+	if v.DirsFirst {
+		parts = append(parts, "dirs")
+	}
+	if v.HideHidden {
+		parts = append(parts, "-dot")
+	}
+	if filter != "" {
+		parts = append(parts, "/"+filter)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parts, " ") + "]"
+}
+
+// startFilter opens the type-to-filter input for the focused panel.
+func (m FileBrowserModel) startFilter() (FileBrowserModel, tea.Cmd) {
+	current := m.localFilter
+	if m.focus == panelRemote {
+		current = m.remoteFilter
+	}
+	m.startInput(opFilter, "Filter:")
+	m.inputModel.SetValue(current)
+	m.inputModel.CursorEnd()
+	return m, nil
+}
+
+// setFilter changes the focused panel's filter and rebuilds its list.
+func (m *FileBrowserModel) setFilter(filter string) {
+	keep := m.selectedName()
+	if m.focus == panelLocal {
+		m.localFilter = filter
+		m.applyLocalView(keep)
+	} else {
+		m.remoteFilter = filter
+		m.applyRemoteView(keep)
+	}
+}
+
+// handleFilterKey updates the filter as the user types. Enter keeps the
+// filter, Esc clears it.
+func (m FileBrowserModel) handleFilterKey(msg tea.KeyMsg) (FileBrowserModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.inputActive = false
+		m.setFilter("")
+		m.statusMsg = ""
+		return m, nil
+	case tea.KeyEnter:
+		m.inputActive = false
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.inputModel, cmd = m.inputModel.Update(msg)
+	m.setFilter(m.inputModel.Value())
+	return m, cmd
+}
@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ssh-scp/internal/config"
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"", "anything", true},
+		{"log", "syslog.1", true},
+		{"rlog", "rotated.log", true},
+		{"SYS", "syslog", true},
+		{"gol", "syslog", false},
+		{"xyz", "syslog", false},
+		{"é", "café.txt", true},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func names(entries []panelEntry, idx []int) string {
+	var out []string
+	for _, i := range idx {
+		out = append(out, entries[i].name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestArrangeEntries(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []panelEntry{
+		{name: "b.txt", size: 30, mtime: t0.Add(2 * time.Hour)},
+		{name: "A.log", size: 10, mtime: t0.Add(3 * time.Hour)},
+		{name: "dir", dir: true, mtime: t0},
+		{name: ".hidden", size: 5, mtime: t0.Add(time.Hour)},
+		{name: "c.gz", size: 20, mtime: t0.Add(4 * time.Hour)},
+	}
+	tests := []struct {
+		view   config.PanelView
+		filter string
+		want   string
+	}{
+		{config.PanelView{}, "", ".hidden,A.log,b.txt,c.gz,dir"},
+		{config.PanelView{Sort: sortSize}, "", "dir,.hidden,A.log,c.gz,b.txt"},
+		{config.PanelView{Sort: sortMTime, Reverse: true}, "", "c.gz,A.log,b.txt,.hidden,dir"},
+		{config.PanelView{Sort: sortExt}, "", "dir,c.gz,.hidden,A.log,b.txt"},
+		{config.PanelView{DirsFirst: true, Reverse: true}, "", "dir,c.gz,b.txt,A.log,.hidden"},
+		{config.PanelView{HideHidden: true}, "", "A.log,b.txt,c.gz,dir"},
+		{config.PanelView{}, "lg", "A.log"},
+		{config.PanelView{}, "zzz", ""},
+	}
+	for _, tt := range tests {
+		if got := names(entries, arrangeEntries(entries, tt.view, tt.filter)); got != tt.want {
+			t.Errorf("arrangeEntries(%+v, %q) = %s, want %s", tt.view, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func viewBrowser(t *testing.T) FileBrowserModel {
+	t.Helper()
+	dir := t.TempDir()
+	for name, size := range map[string]int{"small.txt": 1, "big.bin": 100, ".env": 10, "app.log": 50} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "zdir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m := NewFileBrowserModel(nil, dir, "/srv")
+	m.SetDimensions(120, 30)
+	return m
+}
+
+func localNames(m FileBrowserModel) string {
+	var out []string
+	for _, f := range m.localFiles {
+		out = append(out, f.Name())
+	}
+	return strings.Join(out, ",")
+}
+
+func altKey(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}, Alt: true}
+}
+
+func TestFBSortKeysKeepSelection(t *testing.T) {
+	m := viewBrowser(t)
+	if got := localNames(m); got != ".env,app.log,big.bin,small.txt,zdir" {
+		t.Fatalf("default order = %s", got)
+	}
+	selectLocal(t, &m, "small.txt")
+
+	m, cmd := m.Update(altKey('o'))
+	if m.localView.Sort != sortSize {
+		t.Fatalf("Alt+O should switch to size sort, got %q", m.localView.Sort)
+	}
+	changed, ok := cmd().(PanelViewChangedMsg)
+	if !ok || changed.Local.Sort != sortSize || changed.Remote.Sort != "" {
+		t.Errorf("unexpected change message %+v", changed)
+	}
+	m, _ = m.Update(altKey('r'))
+	m, _ = m.Update(altKey('d'))
+	if got := localNames(m); got != "zdir,big.bin,app.log,.env,small.txt" {
+		t.Errorf("size, reversed, dirs first = %s", got)
+	}
+	if m.localFiles[m.localCursor].Name() != "small.txt" {
+		t.Error("selection should follow the entry across re-sorts")
+	}
+	m, _ = m.Update(altKey('h'))
+	if strings.Contains(localNames(m), ".env") {
+		t.Error("Alt+H should hide dotfiles")
+	}
+	if !strings.Contains(m.View(), "[size↓ dirs -dot]") {
+		t.Error("header should show the panel settings")
+	}
+}
+
+func TestFBSortIsPerPanel(t *testing.T) {
+	m := viewBrowser(t)
+	m.focus = panelRemote
+	m, _ = m.Update(altKey('o'))
+	if m.remoteView.Sort != sortSize || m.localView.Sort != "" {
+		t.Errorf("local=%+v remote=%+v, want only remote changed", m.localView, m.remoteView)
+	}
+}
+
+func TestFBFilterIncremental(t *testing.T) {
+	m := viewBrowser(t)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if !m.InputActive() {
+		t.Fatal("/ should open the filter input")
+	}
+	for _, r := range "lg" {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if got := localNames(m); got != "app.log" {
+		t.Errorf("filtered list = %s, want app.log", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.InputActive() || m.localFilter != "lg" {
+		t.Error("Enter should close the input and keep the filter")
+	}
+	if !strings.Contains(m.View(), "/lg]") {
+		t.Error("header should show the active filter")
+	}
+
+	// Reopening and pressing Esc clears the filter.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if m.inputModel.Value() != "lg" {
+		t.Errorf("filter input should start with the current filter, got %q", m.inputModel.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.localFilter != "" || len(m.localFiles) != 5 {
+		t.Errorf("Esc should clear the filter, got %q with %d files", m.localFilter, len(m.localFiles))
+	}
+}
+
+func TestFBFilterNoMatchesAndDirChange(t *testing.T) {
+	m := viewBrowser(t)
+	m.setFilter("qqq")
+	if len(m.localFiles) != 0 || !strings.Contains(m.View(), "no matches for /qqq") {
+		t.Error("an unmatched filter should show an empty list with a note")
+	}
+	m.setFilter("zd")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if filepath.Base(m.localDir) != "zdir" || m.localFilter != "" {
+		t.Errorf("entering a directory should clear the filter (dir %s, filter %q)", m.localDir, m.localFilter)
+	}
+}
+
+func TestFBRemoteListingUsesView(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m.SetPanelViews(config.PanelView{}, config.PanelView{Sort: sortSize, HideHidden: true})
+	m, _ = m.Update(remoteFilesMsg{files: []sshclient.RemoteFile{
+		{Name: "b", Size: 5}, {Name: ".profile", Size: 1}, {Name: "a", Size: 9},
+	}})
+	if len(m.remoteFiles) != 2 || m.remoteFiles[0].Name != "b" || m.remoteFiles[1].Name != "a" {
+		t.Errorf("remote files = %+v, want b, a", m.remoteFiles)
+	}
+}
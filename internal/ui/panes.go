@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+
+	"ssh-scp/internal/layout"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Footer renders the line below the file panels: the inline input dialog
+// when one is open, otherwise the key hints and status message.
+func (m FileBrowserModel) Footer() string {
+	if m.inputActive {
+		inputLine := messageStyle.Render(
+			fmt.Sprintf(" %s ", m.inputPrompt),
+		) + m.inputModel.View()
+		if m.inputHint != "" {
+			inputLine += "\n" + statusBarStyle.Render(" "+truncate(m.inputHint, max(m.width-2, 10)))
+		}
+		return inputLine
+	}
+
+	hints := statusBarStyle.Render(" ^←/→: panels • ^T: transfer • ^Y: mkdir • ^D: delete • ^R: rename")
+	if m.tail != nil {
+		if n := m.tail.Running(); n > 0 {
+			hints += statusBarStyle.Render(fmt.Sprintf(" | following %d file(s), Alt+W to show", n))
+		}
+	}
+	if m.runner != nil && !m.runnerShown {
+		if n := m.runner.Running(); n > 0 {
+			hints += statusBarStyle.Render(fmt.Sprintf(" | %d command(s) running, ! to show", n))
+		}
+	}
+	if m.statusMsg != "" {
+		hints += statusBarStyle.Render(" | ") + messageStyle.Render(m.statusMsg)
+	}
+	return hints
+}
+
+// RenderPane draws the local panel, remote panel or preview as a layout
+// pane of the given outer size; active highlights its border.
+func (m FileBrowserModel) RenderPane(kind layout.Kind, width, height int, active bool) string {
+	w, h := max(width-2, 1), max(height-2, 1)
+	switch kind {
+	case layout.Local:
+		return m.renderLocalPanel(w, h, active)
+	case layout.Remote:
+		return m.renderRemotePanel(w, h, active)
+	case layout.Preview:
+		if m.preview == nil {
+			return RenderEmptyPane("Preview", "Nothing to preview", width, height, active)
+		}
+		return m.renderPreview(w, h, active)
+	}
+	return ""
+}
+
+// RenderEmptyPane draws a bordered pane with a title and a hint, for a
+// pane with nothing to show yet.
+func RenderEmptyPane(title, hint string, width, height int, active bool) string {
+	style := panelStyle
+	if active {
+		style = activePanelStyle
+	}
+	contentWidth := max(width-4, 1)
+	content := headerStyle.Width(contentWidth).Render(truncate(title, contentWidth)) + "\n" +
+		statusBarStyle.Render(truncate(hint, contentWidth))
+	return style.Width(max(width-2, 1)).Height(max(height-2, 1)).Render(content)
+}
+
+// SetPaneHeights sets the outer heights of the local and remote panels
+// when they are drawn as layout panes, so scrolling keeps the cursor in
+// view; 0 means the panel is not shown.
+func (m *FileBrowserModel) SetPaneHeights(local, remote int) {
+	m.localPane, m.remotePane = local, remote
+}
+
+// FocusedPanel returns the panel that receives file keys: layout.Local or
+// layout.Remote.
+func (m FileBrowserModel) FocusedPanel() layout.Kind {
+	if m.focus == panelRemote {
+		return layout.Remote
+	}
+	return layout.Local
+}
+
+// FocusPanel gives the local or remote panel the focus; other kinds are
+// ignored. The returned command updates the preview.
+func (m *FileBrowserModel) FocusPanel(kind layout.Kind) tea.Cmd {
+	switch kind {
+	case layout.Local:
+		m.focus = panelLocal
+	case layout.Remote:
+		m.focus = panelRemote
+	default:
+		return nil
+	}
+	return m.syncPreview()
+}
+
+// SetPreviewPane keeps the preview loaded while a layout pane shows it.
+func (m *FileBrowserModel) SetPreviewPane(shown bool) tea.Cmd {
+	switch {
+	case shown && m.preview == nil:
+		p := NewPreviewModel()
+		m.preview = &p
+		return m.syncPreview()
+	case !shown:
+		m.preview = nil
+	}
+	return nil
+}
+
+// OverlayActive reports whether a dialog of the browser covers the whole
+// view: properties, bookmarks, search, disk usage, the tail viewer, the
+// command runner or the snippet picker.
+func (m FileBrowserModel) OverlayActive() bool {
+	return m.props != nil || m.bookmarks != nil || m.search != nil || m.du != nil || m.tailShown || m.runnerShown || m.snippets != nil
+}
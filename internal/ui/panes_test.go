@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"ssh-scp/internal/layout"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestRenderPaneSizes(t *testing.T) {
+	m := viewBrowser(t)
+	for _, kind := range []layout.Kind{layout.Local, layout.Remote, layout.Preview} {
+		pane := m.RenderPane(kind, 50, 12, kind == layout.Local)
+		if w, h := lipgloss.Width(pane), lipgloss.Height(pane); w != 50 || h != 12 {
+			t.Errorf("%s pane is %dx%d, want 50x12", kind, w, h)
+		}
+	}
+	if !strings.Contains(m.RenderPane(layout.Local, 50, 12, true), "Local") {
+		t.Error("the local pane should show its header")
+	}
+	if !strings.Contains(m.Footer(), "^T: transfer") {
+		t.Error("the footer should show the key hints")
+	}
+}
+
+func TestPaneFocusAndPreview(t *testing.T) {
+	m := viewBrowser(t)
+	m.FocusPanel(layout.Remote)
+	if m.FocusedPanel() != layout.Remote {
+		t.Error("FocusPanel should focus the remote panel")
+	}
+	m.FocusPanel(layout.Terminal)
+	if m.FocusedPanel() != layout.Remote {
+		t.Error("other kinds should not change the panel focus")
+	}
+
+	m.FocusPanel(layout.Local)
+	if m.SetPreviewPane(true); m.preview == nil {
+		t.Fatal("a preview pane should load the preview")
+	}
+	if m.preview.target.name == "" {
+		t.Error("the preview should follow the selection")
+	}
+	if m.SetPreviewPane(false); m.preview != nil {
+		t.Error("hiding the preview pane should drop the preview")
+	}
+}
+
+func TestPaneHeightsScroll(t *testing.T) {
+	m := viewBrowser(t)
+	m.SetPaneHeights(8, 0) // two rows in the local pane
+	if vis := m.visibleHeight(); vis != 2 {
+		t.Fatalf("visible rows = %d, want 2", vis)
+	}
+	for range 3 {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if m.localScroll != 2 {
+		t.Errorf("scroll = %d, want the cursor kept in the short pane", m.localScroll)
+	}
+}
@@ -0,0 +1,335 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ssh-scp/internal/cast"
+	"ssh-scp/internal/vt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxPlaybackIdle caps pauses between events during playback, so a
+// recording left running overnight does not replay the night.
+const maxPlaybackIdle = 2 * time.Second
+
+// playerSeek is how far ←/→ move during playback.
+const playerSeek = 5 * time.Second
+
+// RecordingFile is a recording in the recording directory.
+type RecordingFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// RecordingsLoadedMsg carries the recordings found in Dir, newest first.
+type RecordingsLoadedMsg struct {
+	Dir   string
+	Files []RecordingFile
+	Err   error
+}
+
+// PlayerLoadedMsg carries a parsed recording to play.
+type PlayerLoadedMsg struct {
+	Path      string
+	Recording *cast.Recording
+	Err       error
+}
+
+// PlayerTickMsg advances playback to the next event.
+type PlayerTickMsg struct {
+	ID int
+}
+
+// PlayerCloseMsg requests closing the player.
+type PlayerCloseMsg struct{}
+
+// LoadRecordingsCmd returns a command that lists the .cast files in dir.
+// A missing directory yields an empty list.
+func LoadRecordingsCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return RecordingsLoadedMsg{Dir: dir}
+		}
+		if err != nil {
+			return RecordingsLoadedMsg{Dir: dir, Err: err}
+		}
+		var files []RecordingFile
+		for _, e := range entries {
+			if e.IsDir() || filepath.Ext(e.Name()) != ".cast" {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, RecordingFile{Path: filepath.Join(dir, e.Name()), Size: info.Size(), ModTime: info.ModTime()})
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].ModTime.After(files[j].ModTime) })
+		return RecordingsLoadedMsg{Dir: dir, Files: files}
+	}
+}
+
+// loadRecordingCmd returns a command that parses the recording at path.
+func loadRecordingCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		rec, err := cast.Load(path)
+		return PlayerLoadedMsg{Path: path, Recording: rec, Err: err}
+	}
+}
+
+// PlayerModel lists the recordings in a directory and replays one through
+// the terminal emulator with its original timing.
+type PlayerModel struct {
+	dir    string
+	files  []RecordingFile
+	cursor int
+	scroll int
+	width  int
+	height int
+	err    string
+
+	// Playback state; rec is nil while the list is shown.
+	path   string
+	rec    *cast.Recording
+	times  []time.Duration // event times with long pauses cut
+	screen *vt.Screen
+	next   int           // next event to apply
+	pos    time.Duration // playback position
+	speed  float64
+	paused bool
+	tickID int
+}
+
+// NewPlayerModel creates a player listing files from dir.
+func NewPlayerModel(dir string, files []RecordingFile) PlayerModel {
+	return PlayerModel{dir: dir, files: files}
+}
+
+// SetDimensions sets the view's display dimensions.
+func (m *PlayerModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Playing reports whether a recording is being played.
+func (m PlayerModel) Playing() bool {
+	return m.rec != nil
+}
+
+func (m PlayerModel) visibleRows() int {
+	return max(m.height-6, 1) // title, header, blank, error, hints
+}
+
+// Update handles key events and playback messages.
+func (m PlayerModel) Update(msg tea.Msg) (PlayerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case PlayerLoadedMsg:
+		if msg.Err != nil {
+			m.err = fmt.Sprintf("%s: %s", filepath.Base(msg.Path), msg.Err)
+			return m, nil
+		}
+		m.err = ""
+		return m, m.start(msg.Path, msg.Recording)
+	case PlayerTickMsg:
+		if msg.ID != m.tickID || m.rec == nil || m.paused {
+			return m, nil
+		}
+		if m.next < len(m.times) {
+			m.seek(m.times[m.next])
+		}
+		return m, m.schedule()
+	case tea.KeyMsg:
+		if m.rec != nil {
+			return m.updatePlaying(msg)
+		}
+		return m.updateList(msg)
+	}
+	return m, nil
+}
+
+func (m PlayerModel) updateList(msg tea.KeyMsg) (PlayerModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		return m, func() tea.Msg { return PlayerCloseMsg{} }
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.files)-1 {
+			m.cursor++
+		}
+	case "enter":
+		if m.cursor < len(m.files) {
+			return m, loadRecordingCmd(m.files[m.cursor].Path)
+		}
+	}
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if vis := m.visibleRows(); m.cursor >= m.scroll+vis {
+		m.scroll = m.cursor - vis + 1
+	}
+	return m, nil
+}
+
+func (m PlayerModel) updatePlaying(msg tea.KeyMsg) (PlayerModel, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		m.stop()
+		return m, func() tea.Msg { return PlayerCloseMsg{} }
+	case "esc":
+		m.stop()
+		return m, nil
+	case " ":
+		m.paused = !m.paused
+	case "+", "=":
+		m.speed = min(m.speed*2, 16)
+	case "-":
+		m.speed = max(m.speed/2, 0.25)
+	case "left", "h":
+		m.seek(max(m.pos-playerSeek, 0))
+	case "right", "l":
+		m.seek(min(m.pos+playerSeek, m.duration()))
+	case "home", "0":
+		m.seek(0)
+	default:
+		return m, nil
+	}
+	m.tickID++
+	return m, m.schedule()
+}
+
+// start begins playing rec from the start.
+func (m *PlayerModel) start(path string, rec *cast.Recording) tea.Cmd {
+	m.path, m.rec = path, rec
+	m.times = make([]time.Duration, len(rec.Events))
+	var prev, at time.Duration
+	for i, ev := range rec.Events {
+		t := time.Duration(ev.Time * float64(time.Second))
+		at += min(max(t-prev, 0), maxPlaybackIdle)
+		prev = t
+		m.times[i] = at
+	}
+	m.speed, m.paused = 1, false
+	m.reset()
+	m.tickID++
+	return m.schedule()
+}
+
+// stop returns to the list.
+func (m *PlayerModel) stop() {
+	m.rec, m.screen, m.times = nil, nil, nil
+	m.tickID++
+}
+
+// reset clears the screen to the recording's initial size.
+func (m *PlayerModel) reset() {
+	m.screen = vt.New(max(m.rec.Header.Width, 1), max(m.rec.Header.Height, 1))
+	m.next, m.pos = 0, 0
+}
+
+// seek moves playback to pos, replaying from the start when going back.
+func (m *PlayerModel) seek(pos time.Duration) {
+	if pos < m.pos {
+		m.reset()
+	}
+	for m.next < len(m.times) && m.times[m.next] <= pos {
+		ev := m.rec.Events[m.next]
+		switch ev.Type {
+		case cast.Output:
+			_, _ = m.screen.Write([]byte(ev.Data))
+		case cast.Resize:
+			if cols, rows, ok := ev.Size(); ok {
+				m.screen.Resize(cols, rows)
+			}
+		}
+		m.next++
+	}
+	m.pos = pos
+}
+
+// schedule returns a tick for the next event, or nil when paused or done.
+func (m PlayerModel) schedule() tea.Cmd {
+	if m.rec == nil || m.paused || m.next >= len(m.times) {
+		return nil
+	}
+	delay := time.Duration(float64(m.times[m.next]-m.pos) / m.speed)
+	id := m.tickID
+	return tea.Tick(delay, func(time.Time) tea.Msg { return PlayerTickMsg{ID: id} })
+}
+
+func (m PlayerModel) duration() time.Duration {
+	if len(m.times) == 0 {
+		return 0
+	}
+	return m.times[len(m.times)-1]
+}
+
+// formatClock renders a playback position as m:ss.
+func formatClock(d time.Duration) string {
+	s := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// View renders the recording list or the playback.
+func (m PlayerModel) View() string {
+	innerWidth := max(m.width-4, 20)
+	var content string
+	if m.rec != nil {
+		content = m.viewPlaying(innerWidth)
+	} else {
+		content = m.viewList(innerWidth)
+	}
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(content)
+}
+
+func (m PlayerModel) viewList(innerWidth int) string {
+	title := messageStyle.Render(fmt.Sprintf("Recordings — %s (%d)", m.dir, len(m.files)))
+	header := headerStyle.Width(innerWidth).Render(fmt.Sprintf("%-16s %8s  %s", "Modified", "Size", "File"))
+	var rows []string
+	if len(m.files) == 0 {
+		rows = append(rows, statusBarStyle.Render("No recordings yet"))
+	}
+	vis := m.visibleRows()
+	for i := m.scroll; i < len(m.files) && i < m.scroll+vis; i++ {
+		f := m.files[i]
+		line := fmt.Sprintf("%-16s %8s  %s", f.ModTime.Local().Format("2006-01-02 15:04"), formatSize(f.Size), filepath.Base(f.Path))
+		line = truncate(line, innerWidth)
+		if i == m.cursor {
+			line = fileSelectedStyle.Width(innerWidth).Render(line)
+		}
+		rows = append(rows, line)
+	}
+	var errLine string
+	if m.err != "" {
+		errLine = historyFailStyle.Render(truncate(m.err, innerWidth))
+	}
+	hints := statusBarStyle.Render("↑/↓: select • Enter: play • Esc: close")
+	return lipgloss.JoinVertical(lipgloss.Left, title, header, strings.Join(rows, "\n"), "", errLine, hints)
+}
+
+func (m PlayerModel) viewPlaying(innerWidth int) string {
+	state := "▶"
+	switch {
+	case m.paused:
+		state = "⏸"
+	case m.next >= len(m.times):
+		state = "■"
+	}
+	title := messageStyle.Render(truncate(fmt.Sprintf("%s %s — %s / %s • %gx",
+		state, filepath.Base(m.path), formatClock(m.pos), formatClock(m.duration()), m.speed), innerWidth))
+	rows := max(m.height-5, 1) // border, title, hints
+	screen := m.screen.Render(innerWidth, rows, false)
+	hints := statusBarStyle.Render(truncate("Space: pause • ←/→: seek 5s • +/-: speed • 0: restart • Esc: list • q: close", innerWidth))
+	return lipgloss.JoinVertical(lipgloss.Left, title, screen, hints)
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ssh-scp/internal/cast"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func sampleRecording() *cast.Recording {
+	return &cast.Recording{
+		Header: cast.Header{Version: 2, Width: 20, Height: 3},
+		Events: []cast.Event{
+			{Time: 0.5, Type: cast.Output, Data: "$ ls\r\n"},
+			{Time: 1, Type: cast.Input, Data: "ignored"},
+			{Time: 1.5, Type: cast.Output, Data: "a.txt"},
+			{Time: 3600, Type: cast.Output, Data: "\r\n$ "}, // an hour idle
+		},
+	}
+}
+
+func TestLoadRecordingsCmd(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"old.cast", "new.cast", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg := LoadRecordingsCmd(dir)().(RecordingsLoadedMsg)
+	if msg.Err != nil || len(msg.Files) != 2 || filepath.Base(msg.Files[0].Path) != "new.cast" {
+		t.Errorf("recordings = %+v, %v", msg.Files, msg.Err)
+	}
+	msg = LoadRecordingsCmd(filepath.Join(dir, "missing"))().(RecordingsLoadedMsg)
+	if msg.Err != nil || len(msg.Files) != 0 {
+		t.Errorf("a missing directory should list nothing, got %+v", msg)
+	}
+}
+
+func TestPlayerModelPlayback(t *testing.T) {
+	m := NewPlayerModel("/rec", []RecordingFile{{Path: "/rec/a.cast"}})
+	m.SetDimensions(60, 12)
+	m, cmd := m.Update(PlayerLoadedMsg{Path: "/rec/a.cast", Recording: sampleRecording()})
+	if !m.Playing() || cmd == nil {
+		t.Fatal("a loaded recording should start playing")
+	}
+	if m.duration() != 1500*time.Millisecond+maxPlaybackIdle {
+		t.Errorf("long pauses should be cut, duration = %v", m.duration())
+	}
+
+	// Ticks apply one event at a time; stale ticks are ignored.
+	m, _ = m.Update(PlayerTickMsg{ID: m.tickID})
+	m, _ = m.Update(PlayerTickMsg{ID: m.tickID - 1})
+	if got := m.screen.String(); got != "$ ls" {
+		t.Errorf("screen after first tick = %q", got)
+	}
+	m, _ = m.Update(PlayerTickMsg{ID: m.tickID})
+	m, _ = m.Update(PlayerTickMsg{ID: m.tickID})
+	if got := m.screen.String(); got != "$ ls\na.txt" {
+		t.Errorf("input events should not be drawn, screen = %q", got)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" ")})
+	if !m.paused {
+		t.Error("space should pause")
+	}
+	if _, cmd := m.Update(PlayerTickMsg{ID: m.tickID}); cmd != nil {
+		t.Error("a paused player should not schedule ticks")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if !strings.HasSuffix(m.screen.String(), "$") || m.next != 4 {
+		t.Errorf("seeking to the end should apply every event, screen = %q", m.screen.String())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if m.pos != 0 || m.screen.String() != "" {
+		t.Errorf("seeking back should replay from the start, pos %v screen %q", m.pos, m.screen.String())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	if m.speed != 2 {
+		t.Errorf("speed = %v", m.speed)
+	}
+	if !strings.Contains(m.View(), "a.cast") {
+		t.Error("view should name the recording")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.Playing() {
+		t.Error("esc should return to the list")
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc}); cmd == nil {
+		t.Error("esc in the list should close the player")
+	} else if _, ok := cmd().(PlayerCloseMsg); !ok {
+		t.Error("esc in the list should send PlayerCloseMsg")
+	}
+}
+
+func TestPlayerModelLoadError(t *testing.T) {
+	m := NewPlayerModel("/rec", nil)
+	m.SetDimensions(60, 12)
+	m, _ = m.Update(PlayerLoadedMsg{Path: "/rec/bad.cast", Err: os.ErrNotExist})
+	if m.Playing() || !strings.Contains(m.View(), "bad.cast") {
+		t.Error("a load error should be shown in the list")
+	}
+}
@@ -0,0 +1,437 @@
+package ui
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image previews
+	_ "image/jpeg" // register JPEG for image previews
+	_ "image/png"  // register PNG for image previews
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	previewSize       = 16 << 10 // bytes of a file read for its preview
+	previewDebounce   = 150 * time.Millisecond
+	maxPreviewCache   = 64
+	maxArchiveEntries = 500
+	previewDateFormat = "2006-01-02 15:04"
+)
+
+// Preview modes.
+type previewKind int
+
+const (
+	previewText previewKind = iota
+	previewHex
+	previewArchive
+	previewImage
+	previewDir
+)
+
+// previewContent is what the preview pane shows below the metadata.
+type previewContent struct {
+	kind      previewKind
+	lines     []string // text lines, archive entries or image details
+	data      []byte   // raw bytes for the hex dump
+	truncated bool     // only the start of the file was read
+	err       error
+}
+
+// previewTarget is the entry the preview pane is showing.
+type previewTarget struct {
+	key    string // identifies the entry and its version, for the cache
+	remote bool
+	path   string
+	name   string
+	size   int64
+	dir    bool
+	meta   []string
+}
+
+// previewTickMsg fires when the cursor has rested long enough to load a
+// preview.
+type previewTickMsg struct{ seq int }
+
+// previewLoadedMsg carries a loaded preview.
+type previewLoadedMsg struct {
+	key     string
+	content previewContent
+}
+
+// PreviewModel is the read-only preview pane. It loads the selected entry
+// once the cursor rests and caches the results.
+type PreviewModel struct {
+	target  previewTarget
+	seq     int
+	content *previewContent // nil while loading
+	cache   map[string]previewContent
+	order   []string // cache keys, oldest first
+}
+
+// NewPreviewModel creates an empty preview pane.
+func NewPreviewModel() PreviewModel {
+	return PreviewModel{cache: map[string]previewContent{}}
+}
+
+// remember caches c under key, dropping the oldest entry when full.
+func (p *PreviewModel) remember(key string, c previewContent) {
+	if _, ok := p.cache[key]; !ok {
+		p.order = append(p.order, key)
+	}
+	p.cache[key] = c
+	if len(p.order) > maxPreviewCache {
+		delete(p.cache, p.order[0])
+		p.order = p.order[1:]
+	}
+}
+
+// togglePreview shows or hides the preview pane.
+func (m FileBrowserModel) togglePreview() (FileBrowserModel, tea.Cmd) {
+	if m.preview != nil {
+		m.preview = nil
+		return m, nil
+	}
+	p := NewPreviewModel()
+	m.preview = &p
+	return m, m.syncPreview()
+}
+
+// previewTarget describes the selected entry of the focused panel; ok is
+// false when the panel is empty.
+func (m FileBrowserModel) previewTarget() (previewTarget, bool) {
+	if m.focus == panelLocal {
+		if len(m.localFiles) == 0 {
+			return previewTarget{}, false
+		}
+		f := m.localFiles[m.localCursor]
+		p := filepath.Join(m.localDir, f.Name())
+		meta := []string{
+			fmt.Sprintf("%s  %s", f.Mode(), formatSize(f.Size())),
+			"Modified " + f.ModTime().Format(previewDateFormat),
+		}
+		if l, ok := m.localLinks[f.Name()]; ok {
+			meta = append(meta, "Link to "+l.target)
+		}
+		return previewTarget{
+			key:  fmt.Sprintf("l:%s:%d:%d", p, f.Size(), f.ModTime().UnixNano()),
+			path: p, name: f.Name(), size: f.Size(), dir: m.localIsDir(f), meta: meta,
+		}, true
+	}
+	if len(m.remoteFiles) == 0 {
+		return previewTarget{}, false
+	}
+	f := m.remoteFiles[m.remoteCursor]
+	p := joinRemotePath(m.remoteDir, f.Name)
+	meta := []string{
+		fmt.Sprintf("%s  %s  %s:%s", f.Mode, formatSize(f.Size), f.Owner, f.Group),
+		"Modified " + f.ModTime.Format(previewDateFormat),
+	}
+	if f.IsLink() {
+		meta = append(meta, "Link to "+f.LinkTarget)
+	}
+	return previewTarget{
+		key:    fmt.Sprintf("r:%s:%d:%d", p, f.Size, f.ModTime.UnixNano()),
+		remote: true, path: p, name: f.Name, size: f.Size, dir: remoteIsDir(f), meta: meta,
+	}, true
+}
+
+// syncPreview points the preview pane at the current selection. Cached
+// and directory previews show at once; others load after a short pause so
+// scrolling through a remote listing does not queue a round trip per row.
+func (m *FileBrowserModel) syncPreview() tea.Cmd {
+	if m.preview == nil {
+		return nil
+	}
+	p := m.preview
+	t, ok := m.previewTarget()
+	if t.key == p.target.key {
+		return nil
+	}
+	p.target = t
+	p.seq++
+	if !ok || t.dir {
+		p.content = &previewContent{kind: previewDir}
+		return nil
+	}
+	if c, ok := p.cache[t.key]; ok {
+		p.content = &c
+		return nil
+	}
+	p.content = nil
+	seq := p.seq
+	return tea.Tick(previewDebounce, func(time.Time) tea.Msg { return previewTickMsg{seq: seq} })
+}
+
+// handlePreviewMsg loads the preview once the cursor has rested and stores
+// loaded previews.
+func (m FileBrowserModel) handlePreviewMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	if m.preview == nil {
+		return m, nil
+	}
+	switch msg := msg.(type) {
+	case previewTickMsg:
+		if msg.seq != m.preview.seq || m.preview.content != nil {
+			return m, nil
+		}
+		t, client := m.preview.target, m.client
+		return m, func() tea.Msg {
+			return previewLoadedMsg{key: t.key, content: loadPreview(client, t)}
+		}
+	case previewLoadedMsg:
+		m.preview.remember(msg.key, msg.content)
+		if msg.key == m.preview.target.key {
+			c := msg.content
+			m.preview.content = &c
+		}
+	}
+	return m, nil
+}
+
+// loadPreview reads the start of a file (or an archive's listing) and
+// decides how to show it.
+func loadPreview(client *sshclient.Client, t previewTarget) previewContent {
+	if sshclient.ArchiveKind(t.name) != "" {
+		var entries []string
+		var err error
+		if t.remote {
+			entries, err = client.ListArchive(t.path, maxArchiveEntries)
+		} else {
+			entries, err = listLocalArchive(t.path, maxArchiveEntries)
+		}
+		return previewContent{kind: previewArchive, lines: entries, truncated: len(entries) >= maxArchiveEntries, err: err}
+	}
+	var head []byte
+	var err error
+	if t.remote {
+		head, err = client.ReadHead(t.path, previewSize)
+	} else {
+		head, err = readLocalHead(t.path, previewSize)
+	}
+	if err != nil {
+		return previewContent{err: err}
+	}
+	return classifyPreview(head, t.size)
+}
+
+// readLocalHead returns at most the first n bytes of a local file.
+func readLocalHead(p string, n int64) (data []byte, retErr error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cErr := f.Close(); cErr != nil {
+			retErr = errors.Join(retErr, cErr)
+		}
+	}()
+	return io.ReadAll(io.LimitReader(f, n))
+}
+
+// classifyPreview turns the start of a file of the given size into an
+// image summary, a hex dump or text.
+func classifyPreview(head []byte, size int64) previewContent {
+	truncated := size > int64(len(head))
+	if cfg, format, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+		return previewContent{kind: previewImage, lines: []string{
+			fmt.Sprintf("%s image, %d×%d pixels", strings.ToUpper(format), cfg.Width, cfg.Height),
+		}}
+	}
+	if isBinary(head, truncated) {
+		return previewContent{kind: previewHex, data: head, truncated: truncated}
+	}
+	text := strings.ReplaceAll(string(head), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	return previewContent{kind: previewText, lines: lines, truncated: truncated}
+}
+
+// isBinary reports whether data looks like a binary file: it has a NUL
+// byte or is not UTF-8. A rune cut off at the end of a truncated read does
+// not count.
+func isBinary(data []byte, truncated bool) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	if truncated {
+		for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+			if r, _ := utf8.DecodeLastRune(data); r != utf8.RuneError {
+				break
+			}
+			data = data[:len(data)-1]
+		}
+	}
+	return !utf8.Valid(data)
+}
+
+// listLocalArchive lists up to limit entries of a local zip or tar archive.
+func listLocalArchive(p string, limit int) ([]string, error) {
+	kind := sshclient.ArchiveKind(p)
+	if kind == "zip" {
+		r, err := zip.OpenReader(p)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = r.Close() }()
+		var lines []string
+		for _, f := range r.File {
+			if len(lines) == limit {
+				break
+			}
+			lines = append(lines, archiveLine(f.Mode(), int64(f.UncompressedSize64), f.Modified, f.Name))
+		}
+		return lines, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var r io.Reader = f
+	switch kind {
+	case "tar.gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		r = gz
+	case "tar.bz2":
+		r = bzip2.NewReader(f)
+	case "tar":
+	default:
+		return nil, fmt.Errorf("%s archives can only be listed on the remote side", kind)
+	}
+	tr := tar.NewReader(r)
+	var lines []string
+	for len(lines) < limit {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, archiveLine(h.FileInfo().Mode(), h.Size, h.ModTime, h.Name))
+	}
+	return lines, nil
+}
+
+// archiveLine formats an archive entry like tar -tv does.
+func archiveLine(mode os.FileMode, size int64, mtime time.Time, name string) string {
+	return fmt.Sprintf("%s %8s %s %s", mode, formatSize(size), mtime.Format(previewDateFormat), name)
+}
+
+// cleanLine makes a line of file content safe to print: tabs are expanded
+// and other control characters (including escape sequences) shown as '.'.
+func cleanLine(s string) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return '.'
+		}
+		return r
+	}, s)
+}
+
+// hexDump renders data as offset, hex bytes and printable characters, with
+// as many bytes per row as fit in width (4 to 16).
+func hexDump(data []byte, width int) []string {
+	perRow := min(max((width-11)/4/4*4, 4), 16)
+	var lines []string
+	for off := 0; off < len(data); off += perRow {
+		row := data[off:min(off+perRow, len(data))]
+		var hex, ascii strings.Builder
+		for i := 0; i < perRow; i++ {
+			if i < len(row) {
+				fmt.Fprintf(&hex, "%02x ", row[i])
+				if row[i] >= 0x20 && row[i] < 0x7f {
+					ascii.WriteByte(row[i])
+				} else {
+					ascii.WriteByte('.')
+				}
+			} else {
+				hex.WriteString("   ")
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%08x  %s %s", off, hex.String(), ascii.String()))
+	}
+	return lines
+}
+
+// renderPreview draws the preview pane; active highlights its border.
+func (m FileBrowserModel) renderPreview(width, height int, active bool) string {
+	p := m.preview
+	contentWidth := max(width-4, 10)
+	t := p.target
+	title := "Preview"
+	if t.name != "" {
+		title += ": " + t.name
+	}
+	header := headerStyle.Width(contentWidth).Render(truncate(title, contentWidth))
+	rows := make([]string, 0, height)
+	for _, line := range t.meta {
+		rows = append(rows, statusBarStyle.Render(truncate(line, contentWidth)))
+	}
+	if len(t.meta) > 0 {
+		rows = append(rows, "")
+	}
+
+	c := p.content
+	var body []string
+	var footer string
+	switch {
+	case t.name == "":
+		body = []string{statusBarStyle.Render("(nothing selected)")}
+	case c == nil:
+		body = []string{statusBarStyle.Render("Loading...")}
+	case c.err != nil:
+		body = []string{historyFailStyle.Render(truncate(c.err.Error(), contentWidth))}
+	case c.kind == previewDir:
+		body = []string{statusBarStyle.Render("Directory — press Enter to open")}
+	case c.kind == previewHex:
+		body = hexDump(c.data, contentWidth)
+	default:
+		for _, l := range c.lines {
+			body = append(body, truncate(cleanLine(l), contentWidth))
+		}
+	}
+	if c != nil && c.truncated {
+		if c.kind == previewArchive {
+			footer = fmt.Sprintf("… first %d entries", len(c.lines))
+		} else {
+			footer = fmt.Sprintf("… first %s of %s", formatSize(previewSize), formatSize(t.size))
+		}
+	}
+
+	room := max(height-4-len(rows), 1)
+	if footer != "" {
+		room = max(room-1, 1)
+	}
+	if len(body) > room {
+		body = body[:room]
+	}
+	rows = append(rows, body...)
+	if footer != "" {
+		rows = append(rows, statusBarStyle.Render(footer))
+	}
+	style := panelStyle
+	if active {
+		style = activePanelStyle
+	}
+	return style.Width(width).Height(height).Render(header + "\n" + strings.Join(rows, "\n"))
+}
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestClassifyPreview(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	c := classifyPreview(img.Bytes(), int64(img.Len()))
+	if c.kind != previewImage || len(c.lines) != 1 || c.lines[0] != "PNG image, 3×2 pixels" {
+		t.Errorf("png preview = %+v", c)
+	}
+
+	c = classifyPreview([]byte("line one\r\nline two\n"), 19)
+	if c.kind != previewText || len(c.lines) != 2 || c.lines[1] != "line two" || c.truncated {
+		t.Errorf("text preview = %+v", c)
+	}
+
+	c = classifyPreview([]byte("ELF\x00\x01\x02"), 1000)
+	if c.kind != previewHex || !c.truncated {
+		t.Errorf("binary preview = %+v", c)
+	}
+
+	// A multi-byte rune cut by the read limit is still text.
+	head := []byte("héllo " + string([]byte("€")[:2]))
+	if c = classifyPreview(head, 100); c.kind != previewText {
+		t.Errorf("truncated UTF-8 should be text, got kind %d", c.kind)
+	}
+	if c = classifyPreview([]byte{0xff, 0xfe, 'a'}, 3); c.kind != previewHex {
+		t.Errorf("invalid UTF-8 should be hex, got kind %d", c.kind)
+	}
+}
+
+func TestHexDump(t *testing.T) {
+	lines := hexDump([]byte("ABCDEFGHIJ\x00"), 80)
+	if len(lines) != 1 {
+		t.Fatalf("lines = %q", lines)
+	}
+	want := "00000000  41 42 43 44 45 46 47 48 49 4a 00                 ABCDEFGHIJ."
+	if lines[0] != want {
+		t.Errorf("hexDump =\n%q\nwant\n%q", lines[0], want)
+	}
+	if lines = hexDump(make([]byte, 16), 30); len(lines) != 4 {
+		t.Errorf("narrow dump should use 4 bytes per row, got %d rows", len(lines))
+	}
+}
+
+func TestCleanLine(t *testing.T) {
+	if got := cleanLine("a\tb\x1b[31mred"); got != "a    b.[31mred" {
+		t.Errorf("cleanLine = %q", got)
+	}
+}
+
+func TestListLocalArchive(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	zipPath := filepath.Join(dir, "a.zip")
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	for _, name := range []string{"one.txt", "sub/two.txt"} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Modified: mtime})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(zipPath, zbuf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := listLocalArchive(zipPath, 10)
+	if err != nil || len(lines) != 2 || !strings.HasSuffix(lines[1], "sub/two.txt") {
+		t.Errorf("zip listing = %q, %v", lines, err)
+	}
+
+	tgzPath := filepath.Join(dir, "b.tar.gz")
+	var tbuf bytes.Buffer
+	gz := gzip.NewWriter(&tbuf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"x", "y", "z"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 1, ModTime: mtime}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("!")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tgzPath, tbuf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err = listLocalArchive(tgzPath, 2)
+	if err != nil || len(lines) != 2 || !strings.HasSuffix(lines[0], " x") {
+		t.Errorf("tar.gz listing = %q, %v", lines, err)
+	}
+
+	if _, err := listLocalArchive(filepath.Join(dir, "c.tar.xz"), 10); err == nil {
+		t.Error("expected an error for tar.xz")
+	}
+}
+
+func TestFBPreviewFollowsCursor(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.bin"), []byte{0, 1, 2, 3}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewFileBrowserModel(nil, dir, "/")
+	m.SetDimensions(120, 30)
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v"), Alt: true})
+	if m.preview == nil || cmd == nil {
+		t.Fatal("Alt+V should show the preview and schedule a load")
+	}
+	if !strings.Contains(m.View(), "Loading...") {
+		t.Error("preview should show loading before the debounce fires")
+	}
+	m, cmd = m.Update(cmd())
+	m, _ = m.Update(cmd())
+	if !strings.Contains(m.View(), "alpha") {
+		t.Errorf("preview should show the text file:\n%s", m.View())
+	}
+
+	// A quick move only loads the entry the cursor settles on.
+	m, first := m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if m.preview.content == nil || m.preview.content.kind != previewText {
+		t.Error("returning to a.txt should use the cache")
+	}
+	if _, cmd = m.Update(first()); cmd != nil {
+		t.Error("a stale debounce tick should not load anything")
+	}
+
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, cmd = m.Update(cmd())
+	m, _ = m.Update(cmd())
+	if !strings.Contains(m.View(), "00000000  00 01 02 03") {
+		t.Errorf("preview should hex dump the binary file:\n%s", m.View())
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v"), Alt: true})
+	if m.preview != nil {
+		t.Error("Alt+V should hide the preview again")
+	}
+}
@@ -0,0 +1,553 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FileProperties describes a local or remote file for the properties dialog.
+type FileProperties struct {
+	Path       string
+	Remote     bool
+	Mode       os.FileMode
+	Owner      string
+	Group      string
+	Size       int64
+	ModTime    time.Time
+	LinkTarget string
+}
+
+// propertiesApplyMsg carries the changes confirmed in the properties dialog.
+// Empty fields are left unchanged.
+type propertiesApplyMsg struct {
+	Props     FileProperties
+	ModeSpec  string
+	Owner     string
+	Group     string
+	Recursive bool
+}
+
+// propertiesCloseMsg is sent when the properties dialog is cancelled.
+type propertiesCloseMsg struct{}
+
+// Properties dialog focus positions.
+const (
+	propMode = iota
+	propOwner
+	propGroup
+	propRecursive
+)
+
+// PropertiesModel shows a file's metadata and edits its mode, owner and
+// group.
+type PropertiesModel struct {
+	props     FileProperties
+	inputs    [3]textinput.Model // mode, owner, group
+	focus     int
+	recursive bool
+	err       string
+	width     int
+	height    int
+}
+
+// NewPropertiesModel creates a properties dialog for p.
+func NewPropertiesModel(p FileProperties) PropertiesModel {
+	m := PropertiesModel{props: p}
+	values := [3]string{modeOctal(p.Mode), p.Owner, p.Group}
+	for i := range m.inputs {
+		ti := textinput.New()
+		ti.CharLimit = 64
+		ti.Width = 24
+		ti.Prompt = ""
+		ti.SetValue(values[i])
+		m.inputs[i] = ti
+	}
+	m.inputs[propMode].Placeholder = "0644 or u+x,go-w"
+	m.inputs[propMode].Focus()
+	return m
+}
+
+// SetDimensions sets the dialog's display dimensions.
+func (m *PropertiesModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// fieldCount returns the number of focusable fields; the recursive option
+// is only offered for directories.
+func (m PropertiesModel) fieldCount() int {
+	if m.props.Mode.IsDir() {
+		return 4
+	}
+	return 3
+}
+
+func (m *PropertiesModel) setFocus(f int) {
+	n := m.fieldCount()
+	m.focus = (f + n) % n
+	for i := range m.inputs {
+		if i == m.focus {
+			m.inputs[i].Focus()
+		} else {
+			m.inputs[i].Blur()
+		}
+	}
+}
+
+// Update handles key events for the properties dialog.
+func (m PropertiesModel) Update(msg tea.Msg) (PropertiesModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return propertiesCloseMsg{} }
+	case "tab", "down":
+		m.setFocus(m.focus + 1)
+		return m, nil
+	case "shift+tab", "up":
+		m.setFocus(m.focus - 1)
+		return m, nil
+	case "enter":
+		return m.submit()
+	case " ":
+		if m.focus == propRecursive {
+			m.recursive = !m.recursive
+			return m, nil
+		}
+	}
+	if m.focus == propRecursive {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	m.err = ""
+	return m, cmd
+}
+
+// submit validates the edited fields and requests applying the changes.
+func (m PropertiesModel) submit() (PropertiesModel, tea.Cmd) {
+	req := propertiesApplyMsg{Props: m.props, Recursive: m.recursive && m.props.Mode.IsDir()}
+	if spec := strings.TrimSpace(m.inputs[propMode].Value()); spec != modeOctal(m.props.Mode) {
+		if _, err := applyModeSpec(m.props.Mode, spec); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+		req.ModeSpec = spec
+	}
+	if owner := strings.TrimSpace(m.inputs[propOwner].Value()); owner != m.props.Owner {
+		req.Owner = owner
+	}
+	if group := strings.TrimSpace(m.inputs[propGroup].Value()); group != m.props.Group {
+		req.Group = group
+	}
+	if req.ModeSpec == "" && req.Owner == "" && req.Group == "" {
+		return m, func() tea.Msg { return propertiesCloseMsg{} }
+	}
+	return m, func() tea.Msg { return req }
+}
+
+var propLabelStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#7D56F4")).
+	Width(11)
+
+// View renders the properties dialog.
+func (m PropertiesModel) View() string {
+	p := m.props
+	where := "local"
+	if p.Remote {
+		where = "remote"
+	}
+	kind := fileTypeName(p.Mode)
+	row := func(label, value string) string {
+		return propLabelStyle.Render(label) + value
+	}
+	lines := []string{
+		messageStyle.Render(fmt.Sprintf("Properties (%s) — %s", where, p.Path)),
+		"",
+		row("Type", kind),
+		row("Mode", fmt.Sprintf("%s (%s)", modeString(p.Mode), modeOctal(p.Mode))),
+		row("Owner", p.Owner+":"+p.Group),
+		row("Size", fmt.Sprintf("%s (%d bytes)", formatSize(p.Size), p.Size)),
+		row("Modified", p.ModTime.Format("2006-01-02 15:04:05")),
+	}
+	if p.Mode&os.ModeSymlink != 0 {
+		lines = append(lines, row("Target", p.LinkTarget))
+	}
+	lines = append(lines, "")
+
+	labels := [3]string{"New mode", "Owner", "Group"}
+	for i, in := range m.inputs {
+		marker := "  "
+		if m.focus == i {
+			marker = "> "
+		}
+		lines = append(lines, marker+row(labels[i], in.View()))
+	}
+	if p.Mode.IsDir() {
+		marker := "  "
+		if m.focus == propRecursive {
+			marker = "> "
+		}
+		box := "[ ]"
+		if m.recursive {
+			box = "[x]"
+		}
+		lines = append(lines, marker+box+" Apply recursively")
+	}
+	lines = append(lines, "")
+	if m.err != "" {
+		lines = append(lines, historyFailStyle.Render(m.err))
+	}
+	lines = append(lines, statusBarStyle.Render("Tab: next field • Space: toggle • Enter: apply • Esc: cancel"))
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// fileTypeName describes the file type encoded in mode.
+func fileTypeName(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symbolic link"
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "character device"
+	case mode&os.ModeDevice != 0:
+		return "block device"
+	}
+	return "regular file"
+}
+
+// modeBits returns the permission and special bits of mode in chmod's octal
+// layout (e.g. 04755).
+func modeBits(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+// withModeBits returns mode with its permission and special bits replaced by
+// the chmod-style octal bits.
+func withModeBits(mode os.FileMode, bits uint32) os.FileMode {
+	mode &^= os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	mode |= os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// modeOctal formats mode as a four-digit octal number.
+func modeOctal(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", modeBits(mode))
+}
+
+// modeString formats mode like the first column of ls -l.
+func modeString(mode os.FileMode) string {
+	b := []byte("----------")
+	switch {
+	case mode.IsDir():
+		b[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		b[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		b[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		b[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	special := func(pos int, set bool, on, off byte) {
+		if !set {
+			return
+		}
+		if b[pos] == 'x' {
+			b[pos] = on
+		} else {
+			b[pos] = off
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's', 'S')
+	special(6, mode&os.ModeSetgid != 0, 's', 'S')
+	special(9, mode&os.ModeSticky != 0, 't', 'T')
+	return string(b)
+}
+
+// applyModeSpec applies a chmod mode to cur and returns the result. spec is
+// either octal ("755", "4755") or a comma-separated list of symbolic
+// clauses such as "u+x", "go-w" or "a=rX". Unlike chmod, an empty "who" is
+// treated as "a" without consulting the umask.
+func applyModeSpec(cur os.FileMode, spec string) (os.FileMode, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return cur, errors.New("mode is empty")
+	}
+	if spec[0] >= '0' && spec[0] <= '7' {
+		v, err := strconv.ParseUint(spec, 8, 32)
+		if err != nil || v > 07777 {
+			return cur, fmt.Errorf("invalid octal mode %q", spec)
+		}
+		return withModeBits(cur, uint32(v)), nil
+	}
+
+	bits := modeBits(cur)
+	for _, clause := range strings.Split(spec, ",") {
+		i := 0
+		var who, special uint32
+		for ; i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0; i++ {
+			switch clause[i] {
+			case 'u':
+				who, special = who|0700, special|04000
+			case 'g':
+				who, special = who|0070, special|02000
+			case 'o':
+				who, special = who|0007, special|01000
+			case 'a':
+				who, special = 0777, 07000
+			}
+		}
+		if who == 0 {
+			who, special = 0777, 07000
+		}
+		if i == len(clause) {
+			return cur, fmt.Errorf("invalid mode clause %q: missing +, - or =", clause)
+		}
+		for i < len(clause) {
+			op := clause[i]
+			if op != '+' && op != '-' && op != '=' {
+				return cur, fmt.Errorf("invalid mode clause %q", clause)
+			}
+			i++
+			var perm uint32
+			for ; i < len(clause) && strings.IndexByte("+-=", clause[i]) < 0; i++ {
+				switch clause[i] {
+				case 'r':
+					perm |= 0444 & who
+				case 'w':
+					perm |= 0222 & who
+				case 'x':
+					perm |= 0111 & who
+				case 'X':
+					if cur.IsDir() || bits&0111 != 0 {
+						perm |= 0111 & who
+					}
+				case 's':
+					perm |= special & 06000
+				case 't':
+					perm |= special & 01000
+				default:
+					return cur, fmt.Errorf("invalid permission %q in %q", clause[i], clause)
+				}
+			}
+			switch op {
+			case '+':
+				bits |= perm
+			case '-':
+				bits &^= perm
+			case '=':
+				bits = bits&^(who|special) | perm
+			}
+		}
+	}
+	return withModeBits(cur, bits), nil
+}
+
+// localFileProperties reads the properties of a local file without
+// following symbolic links.
+func localFileProperties(p string) (FileProperties, error) {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return FileProperties{}, err
+	}
+	props := FileProperties{
+		Path:    p,
+		Mode:    info.Mode(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		props.Owner = strconv.FormatUint(uint64(st.Uid), 10)
+		if u, err := user.LookupId(props.Owner); err == nil {
+			props.Owner = u.Username
+		}
+		props.Group = strconv.FormatUint(uint64(st.Gid), 10)
+		if g, err := user.LookupGroupId(props.Group); err == nil {
+			props.Group = g.Name
+		}
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		props.LinkTarget, _ = os.Readlink(p)
+	}
+	return props, nil
+}
+
+// remoteFileProperties converts a remote listing entry to FileProperties.
+func remoteFileProperties(dir string, f sshclient.RemoteFile) FileProperties {
+	mode := f.Mode
+	if f.IsDir {
+		mode |= os.ModeDir
+	}
+	return FileProperties{
+		Path:       joinRemotePath(dir, f.Name),
+		Remote:     true,
+		Mode:       mode,
+		Owner:      f.Owner,
+		Group:      f.Group,
+		Size:       f.Size,
+		ModTime:    f.ModTime,
+		LinkTarget: f.LinkTarget,
+	}
+}
+
+// lookupID resolves a user or group name (or numeric ID) to a numeric ID.
+// An empty name returns -1, which os.Lchown leaves unchanged.
+func lookupID(name string, group bool) (int, error) {
+	if name == "" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	var id string
+	if group {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return -1, err
+		}
+		id = g.Gid
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return -1, err
+		}
+		id = u.Uid
+	}
+	return strconv.Atoi(id)
+}
+
+// applyLocalProperties changes the mode and ownership of a local file, and
+// of everything below it when req.Recursive is set. Symbolic links are
+// chowned themselves and never chmodded, since chmod would follow them.
+func applyLocalProperties(req propertiesApplyMsg) error {
+	uid, err := lookupID(req.Owner, false)
+	if err != nil {
+		return err
+	}
+	gid, err := lookupID(req.Group, true)
+	if err != nil {
+		return err
+	}
+	apply := func(p string) error {
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		if req.ModeSpec != "" && info.Mode()&os.ModeSymlink == 0 {
+			mode, err := applyModeSpec(info.Mode(), req.ModeSpec)
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(p, mode); err != nil {
+				return err
+			}
+		}
+		if uid != -1 || gid != -1 {
+			return os.Lchown(p, uid, gid)
+		}
+		return nil
+	}
+	if !req.Recursive {
+		return apply(req.Props.Path)
+	}
+	return filepath.WalkDir(req.Props.Path, func(p string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return apply(p)
+	})
+}
+
+// applyPropertiesCmd applies the changes from the properties dialog on the
+// side the file lives on.
+func applyPropertiesCmd(client *sshclient.Client, req propertiesApplyMsg) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if req.Props.Remote {
+			if req.ModeSpec != "" {
+				err = client.Chmod(req.Props.Path, req.ModeSpec, req.Recursive)
+			}
+			if err == nil {
+				err = client.Chown(req.Props.Path, req.Owner, req.Group, req.Recursive)
+			}
+		} else {
+			err = applyLocalProperties(req)
+		}
+		return FileOpDoneMsg{Op: opProperties, Err: err}
+	}
+}
+
+// openProperties opens the properties dialog for the selected file.
+func (m FileBrowserModel) openProperties() (FileBrowserModel, tea.Cmd) {
+	var props FileProperties
+	switch {
+	case m.focus == panelLocal && len(m.localFiles) > 0:
+		p, err := localFileProperties(filepath.Join(m.localDir, m.localFiles[m.localCursor].Name()))
+		if err != nil {
+			m.statusMsg = "Error: " + err.Error()
+			return m, nil
+		}
+		props = p
+	case m.focus == panelRemote && len(m.remoteFiles) > 0:
+		props = remoteFileProperties(m.remoteDir, m.remoteFiles[m.remoteCursor])
+	default:
+		return m, nil
+	}
+	if path.Base(props.Path) == ".." {
+		return m, nil
+	}
+	pm := NewPropertiesModel(props)
+	m.props = &pm
+	return m, textinput.Blink
+}
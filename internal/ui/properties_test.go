@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestApplyModeSpec(t *testing.T) {
+	tests := []struct {
+		cur  os.FileMode
+		spec string
+		want os.FileMode
+	}{
+		{0644, "755", 0755},
+		{0644, "4755", os.ModeSetuid | 0755},
+		{0755, "0640", 0640},
+		{0644, "u+x", 0744},
+		{0777, "go-w", 0755},
+		{0600, "a+r", 0644},
+		{0777, "o=", 0770},
+		{0644, "u=rwx,g=rx,o=", 0750},
+		{0640, "a+X", 0640},
+		{os.ModeDir | 0640, "a+X", os.ModeDir | 0751},
+		{0755, "u+s,g+s", os.ModeSetuid | os.ModeSetgid | 0755},
+		{os.ModeDir | 0777, "+t", os.ModeDir | os.ModeSticky | 0777},
+		{0644, "u+x-w", 0544},
+		{os.ModeSetuid | 0755, "u=rwx", 0755},
+	}
+	for _, tt := range tests {
+		got, err := applyModeSpec(tt.cur, tt.spec)
+		if err != nil {
+			t.Errorf("applyModeSpec(%v, %q): %v", tt.cur, tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("applyModeSpec(%v, %q) = %v, want %v", tt.cur, tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestApplyModeSpecInvalid(t *testing.T) {
+	for _, spec := range []string{"", "9", "17777", "u", "u+q", "z+x", "u+x,"} {
+		if _, err := applyModeSpec(0644, spec); err == nil {
+			t.Errorf("applyModeSpec(%q) should fail", spec)
+		}
+	}
+}
+
+func TestModeStringAndOctal(t *testing.T) {
+	tests := []struct {
+		mode  os.FileMode
+		str   string
+		octal string
+	}{
+		{0644, "-rw-r--r--", "0644"},
+		{os.ModeDir | 0755, "drwxr-xr-x", "0755"},
+		{os.ModeSymlink | 0777, "lrwxrwxrwx", "0777"},
+		{os.ModeSetuid | 0755, "-rwsr-xr-x", "4755"},
+		{os.ModeSetgid | 0644, "-rw-r-Sr--", "2644"},
+		{os.ModeDir | os.ModeSticky | 0777, "drwxrwxrwt", "1777"},
+		{os.ModeNamedPipe | 0600, "prw-------", "0600"},
+	}
+	for _, tt := range tests {
+		if got := modeString(tt.mode); got != tt.str {
+			t.Errorf("modeString(%v) = %q, want %q", tt.mode, got, tt.str)
+		}
+		if got := modeOctal(tt.mode); got != tt.octal {
+			t.Errorf("modeOctal(%v) = %q, want %q", tt.mode, got, tt.octal)
+		}
+	}
+}
+
+func TestLocalFileProperties(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("a.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := localFileProperties(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Remote || p.Size != 5 || p.Mode != 0640 || p.Owner == "" || p.Group == "" {
+		t.Errorf("unexpected properties %+v", p)
+	}
+	p, err = localFileProperties(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Mode&os.ModeSymlink == 0 || p.LinkTarget != "a.txt" {
+		t.Errorf("symlink properties = %+v", p)
+	}
+}
+
+func TestApplyLocalPropertiesRecursive(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(sub, "f")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f", filepath.Join(sub, "l")); err != nil {
+		t.Fatal(err)
+	}
+
+	props, err := localFileProperties(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := propertiesApplyMsg{Props: props, ModeSpec: "go-rx", Recursive: true}
+	if err := applyLocalProperties(req); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]os.FileMode{sub: 0700, file: 0600} {
+		info, err := os.Lstat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s mode = %v, want %v", p, info.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestApplyLocalPropertiesUnknownOwner(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	req := propertiesApplyMsg{Props: FileProperties{Path: file}, Owner: "no-such-user-xyz"}
+	if err := applyLocalProperties(req); err == nil {
+		t.Error("unknown owner should fail")
+	}
+}
+
+func TestPropertiesModelSubmit(t *testing.T) {
+	m := NewPropertiesModel(FileProperties{Path: "/srv/app", Remote: true, Mode: os.ModeDir | 0755, Owner: "root", Group: "root"})
+
+	// Unchanged fields close the dialog without applying anything.
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := cmd().(propertiesCloseMsg); !ok {
+		t.Fatal("Enter without changes should close the dialog")
+	}
+
+	m.inputs[propMode].SetValue("u+w,o-rx")
+	m.inputs[propGroup].SetValue("www-data")
+	m.setFocus(propRecursive)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if !m.recursive {
+		t.Fatal("Space on the recursive option should toggle it")
+	}
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	req, ok := cmd().(propertiesApplyMsg)
+	if !ok {
+		t.Fatal("Enter with changes should request applying them")
+	}
+	if req.ModeSpec != "u+w,o-rx" || req.Owner != "" || req.Group != "www-data" || !req.Recursive {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestPropertiesModelInvalidMode(t *testing.T) {
+	m := NewPropertiesModel(FileProperties{Path: "/f", Mode: 0644})
+	m.inputs[propMode].SetValue("u+q")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || m.err == "" {
+		t.Error("invalid mode should show an error and not submit")
+	}
+	if !strings.Contains(m.View(), "invalid permission") {
+		t.Error("error should be rendered")
+	}
+}
+
+func TestPropertiesModelFocusSkipsRecursiveForFiles(t *testing.T) {
+	m := NewPropertiesModel(FileProperties{Path: "/f", Mode: 0644})
+	for i := 0; i < 3; i++ {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	if m.focus != propMode {
+		t.Errorf("focus = %d, want wrap-around to mode", m.focus)
+	}
+	if strings.Contains(m.View(), "recursively") {
+		t.Error("recursive option should only be shown for directories")
+	}
+}
+
+func TestFBAltPOpensRemoteProperties(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m.SetDimensions(100, 30)
+	m.focus = panelRemote
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "www", IsDir: true, Mode: 0755, Owner: "root", Group: "staff"},
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p"), Alt: true})
+	if m.props == nil || !m.InputActive() {
+		t.Fatal("Alt+P should open the properties dialog")
+	}
+	if !m.props.props.Mode.IsDir() || m.props.props.Path != "/srv/www" {
+		t.Errorf("unexpected properties %+v", m.props.props)
+	}
+	view := m.View()
+	if !strings.Contains(view, "drwxr-xr-x") || !strings.Contains(view, "root:staff") {
+		t.Errorf("view missing mode or owner:\n%s", view)
+	}
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m, _ = m.Update(cmd())
+	if m.props != nil || m.InputActive() {
+		t.Error("Esc should close the properties dialog")
+	}
+}
+
+func TestFBPropertiesAppliedStatus(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m, _ = m.Update(FileOpDoneMsg{Op: opProperties})
+	if m.statusMsg != "Properties updated" {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+}
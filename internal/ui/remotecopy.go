@@ -0,0 +1,275 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RemoteCopyRequestMsg asks the app to copy a remote file to another tab.
+type RemoteCopyRequestMsg struct {
+	Path string
+	Mode os.FileMode
+	Size int64
+}
+
+// CopyTarget is a tab a remote file can be copied to.
+type CopyTarget struct {
+	Index int    // tab index
+	Title string // tab title
+	Dir   string // remote directory the file is copied into
+}
+
+// CopyTargetPickedMsg is sent when the user chooses a destination tab.
+type CopyTargetPickedMsg struct {
+	Request RemoteCopyRequestMsg
+	Target  CopyTarget
+	Direct  bool // run scp on the source host instead of streaming
+}
+
+// CopyTargetCloseMsg is sent when the destination picker is cancelled.
+type CopyTargetCloseMsg struct{}
+
+// RemoteCopyDest describes the destination of a cross-tab copy.
+type RemoteCopyDest struct {
+	Client *sshclient.Client
+	Label  string // shown in the status bar
+	Dir    string
+	User   string // used for direct copies
+	Host   string
+	Port   string
+}
+
+// RemoteCopyDoneMsg is sent when a cross-tab copy finishes.
+type RemoteCopyDoneMsg struct {
+	Source *sshclient.Client // client of the tab the file was copied from
+	Dest   *sshclient.Client
+	Err    error
+	Detail string
+}
+
+// pendingRemoteCopy is a cross-tab copy waiting for its destination to be
+// checked, or for the user to confirm replacing the file there.
+type pendingRemoteCopy struct {
+	req     RemoteCopyRequestMsg
+	dest    RemoteCopyDest
+	direct  bool
+	dstPath string
+}
+
+// RemoteCopyCheckedMsg reports whether the destination of a copy exists. It
+// is routed to every tab's browser; only the browser that started the copy
+// takes it.
+type RemoteCopyCheckedMsg struct {
+	copy   *pendingRemoteCopy
+	exists bool
+	err    error
+}
+
+// RemoteCopyTickMsg refreshes the progress of a running cross-tab copy. It
+// is routed to every tab's browser, since the user may switch tabs during
+// the copy; only the browser running the copy takes it.
+type RemoteCopyTickMsg struct {
+	copied *atomic.Int64
+}
+
+// remoteCopyTick schedules the next progress refresh of the copy counting
+// its bytes in copied.
+func remoteCopyTick(copied *atomic.Int64) tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg { return RemoteCopyTickMsg{copied: copied} })
+}
+
+// requestRemoteCopy asks the app to copy the selected remote file to
+// another tab.
+func (m FileBrowserModel) requestRemoteCopy() (FileBrowserModel, tea.Cmd) {
+	if m.transferring {
+		return m, nil
+	}
+	if m.focus != panelRemote || len(m.remoteFiles) == 0 {
+		m.statusMsg = "Select a remote file to copy to another tab"
+		return m, nil
+	}
+	f := m.remoteFiles[m.remoteCursor]
+	if remoteIsDir(f) || f.LinkBroken {
+		m.statusMsg = "Only files can be copied between tabs"
+		return m, nil
+	}
+	req := RemoteCopyRequestMsg{Path: joinRemotePath(m.remoteDir, f.Name), Mode: f.Mode, Size: f.Size}
+	if f.IsLink() {
+		// The link is followed; its own mode and size say nothing about the
+		// target.
+		req.Mode, req.Size = 0o644, 0
+	}
+	return m, func() tea.Msg { return req }
+}
+
+// StartRemoteCopy copies a remote file of this tab to another tab's host.
+// The destination is checked first through dest.Client; an existing file is
+// only replaced once the user confirms. The file is streamed through this
+// machine unless direct is set, in which case scp runs on the source host
+// and streaming is the fallback.
+func (m FileBrowserModel) StartRemoteCopy(req RemoteCopyRequestMsg, dest RemoteCopyDest, direct bool) (FileBrowserModel, tea.Cmd) {
+	if m.transferring || m.pendingCopy != nil {
+		m.statusMsg = "A transfer is already in progress"
+		return m, nil
+	}
+	p := &pendingRemoteCopy{req: req, dest: dest, direct: direct, dstPath: joinRemotePath(dest.Dir, path.Base(req.Path))}
+	m.pendingCopy = p
+	m.statusMsg = fmt.Sprintf("Checking %s on %s...", p.dstPath, dest.Label)
+	return m, func() tea.Msg {
+		exists, err := dest.Client.Exists(p.dstPath)
+		return RemoteCopyCheckedMsg{copy: p, exists: exists, err: err}
+	}
+}
+
+// remoteCopyChecked continues a copy once its destination was checked.
+func (m FileBrowserModel) remoteCopyChecked(msg RemoteCopyCheckedMsg) (FileBrowserModel, tea.Cmd) {
+	p := msg.copy
+	switch {
+	case msg.err != nil:
+		m.pendingCopy = nil
+		m.statusMsg = fmt.Sprintf("Cannot check %s on %s: %v", p.dstPath, p.dest.Label, msg.err)
+		return m, nil
+	case msg.exists:
+		m.startInput(opReplaceCopy, fmt.Sprintf("'%s' exists on %s. Replace it? (y/yes to confirm):", p.dstPath, p.dest.Label))
+		return m, nil
+	}
+	return m.beginRemoteCopy()
+}
+
+// RemoteCopyCheckedForTest returns the result of the destination check of
+// the pending copy, for testing purposes.
+func (m FileBrowserModel) RemoteCopyCheckedForTest(exists bool) RemoteCopyCheckedMsg {
+	return RemoteCopyCheckedMsg{copy: m.pendingCopy, exists: exists}
+}
+
+// beginRemoteCopy runs the pending copy.
+func (m FileBrowserModel) beginRemoteCopy() (FileBrowserModel, tea.Cmd) {
+	p := m.pendingCopy
+	m.pendingCopy = nil
+	req, dest, dstPath := p.req, p.dest, p.dstPath
+	name := path.Base(req.Path)
+	copied := &atomic.Int64{}
+	m.transferring = true
+	m.transferProgress = name
+	m.copyBytes = copied
+	m.copyTotal = req.Size
+	m.copyLabel = dest.Label
+	m.statusMsg = fmt.Sprintf("Copying %s to %s...", name, dest.Label)
+
+	src := m.client
+	direct := p.direct
+	copyCmd := func() tea.Msg {
+		if direct {
+			err := src.DirectCopy(req.Path, dest.User, dest.Host, dest.Port, dstPath)
+			if err == nil {
+				return RemoteCopyDoneMsg{Source: src, Dest: dest.Client, Detail: "direct scp"}
+			}
+			// The hosts cannot reach each other, or the source is in sudo
+			// mode; stream instead.
+			streamErr := src.CopyToClient(req.Path, dest.Client, dstPath, req.Mode, copied.Store)
+			detail := "streamed; direct scp failed: " + err.Error()
+			return RemoteCopyDoneMsg{Source: src, Dest: dest.Client, Err: streamErr, Detail: detail}
+		}
+		err := src.CopyToClient(req.Path, dest.Client, dstPath, req.Mode, copied.Store)
+		return RemoteCopyDoneMsg{Source: src, Dest: dest.Client, Err: err, Detail: "to " + dest.Label}
+	}
+	return m, tea.Batch(copyCmd, remoteCopyTick(copied))
+}
+
+// copyStatus renders the progress of a running cross-tab copy.
+func (m FileBrowserModel) copyStatus() string {
+	done := m.copyBytes.Load()
+	s := fmt.Sprintf("Copying %s to %s: %s", m.transferProgress, m.copyLabel, formatSize(done))
+	if m.copyTotal > 0 {
+		s += fmt.Sprintf(" of %s (%d%%)", formatSize(m.copyTotal), done*100/m.copyTotal)
+	}
+	return s + m.rateLimitSuffix()
+}
+
+// CopyTargetModel lets the user choose the destination tab for a copy.
+type CopyTargetModel struct {
+	request RemoteCopyRequestMsg
+	targets []CopyTarget
+	cursor  int
+	direct  bool
+	width   int
+	height  int
+}
+
+// NewCopyTargetModel creates a destination picker for req.
+func NewCopyTargetModel(req RemoteCopyRequestMsg, targets []CopyTarget) CopyTargetModel {
+	return CopyTargetModel{request: req, targets: targets}
+}
+
+// SetDimensions sets the picker's display dimensions.
+func (m *CopyTargetModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Update handles key events for the destination picker.
+func (m CopyTargetModel) Update(msg tea.Msg) (CopyTargetModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return CopyTargetCloseMsg{} }
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.targets)-1 {
+			m.cursor++
+		}
+	case "d":
+		m.direct = !m.direct
+	case "enter":
+		if len(m.targets) == 0 {
+			return m, nil
+		}
+		picked := CopyTargetPickedMsg{Request: m.request, Target: m.targets[m.cursor], Direct: m.direct}
+		return m, func() tea.Msg { return picked }
+	}
+	return m, nil
+}
+
+// View renders the destination picker.
+func (m CopyTargetModel) View() string {
+	innerWidth := m.width - 4
+	if innerWidth < 20 {
+		innerWidth = 20
+	}
+	var rows []string
+	for i, t := range m.targets {
+		line := truncate(fmt.Sprintf("%s  →  %s", t.Title, t.Dir), innerWidth)
+		if i == m.cursor {
+			line = fileSelectedStyle.Width(innerWidth).Render(line)
+		}
+		rows = append(rows, line)
+	}
+	mode := "stream through this machine"
+	if m.direct {
+		mode = "direct scp between the hosts (falls back to streaming)"
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		messageStyle.Render("Copy "+m.request.Path+" to:"),
+		"",
+		strings.Join(rows, "\n"),
+		"",
+		statusBarStyle.Render("Mode: "+mode),
+		statusBarStyle.Render("↑/↓: select • d: toggle direct • Enter: copy • Esc: cancel"),
+	)
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(content)
+}
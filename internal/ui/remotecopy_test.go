@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func remoteCopyBrowser(t *testing.T) FileBrowserModel {
+	t.Helper()
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m.focus = panelRemote
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "app.tar.gz", Size: 2048, Mode: 0o640},
+		{Name: "logs", IsDir: true},
+	}
+	return m
+}
+
+func TestFBAltCRequestsRemoteCopy(t *testing.T) {
+	m := remoteCopyBrowser(t)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c"), Alt: true})
+	if cmd == nil {
+		t.Fatal("Alt+C on a remote file should return a command")
+	}
+	req, ok := cmd().(RemoteCopyRequestMsg)
+	if !ok || req.Path != "/srv/app.tar.gz" || req.Size != 2048 || req.Mode != 0o640 {
+		t.Errorf("unexpected request %#v", req)
+	}
+}
+
+func TestFBAltCRejectsDirsAndLocal(t *testing.T) {
+	m := remoteCopyBrowser(t)
+	m.remoteCursor = 1
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c"), Alt: true})
+	if cmd != nil || !strings.Contains(m.statusMsg, "Only files") {
+		t.Errorf("directory copy: cmd=%v status=%q", cmd != nil, m.statusMsg)
+	}
+	m.focus = panelLocal
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c"), Alt: true})
+	if cmd != nil || !strings.Contains(m.statusMsg, "remote file") {
+		t.Errorf("local panel: cmd=%v status=%q", cmd != nil, m.statusMsg)
+	}
+}
+
+func TestStartRemoteCopyProgress(t *testing.T) {
+	m := remoteCopyBrowser(t)
+	req := RemoteCopyRequestMsg{Path: "/srv/app.tar.gz", Size: 2048}
+	m, cmd := m.StartRemoteCopy(req, RemoteCopyDest{Label: "prod", Dir: "/opt"}, false)
+	if cmd == nil || m.transferring || m.pendingCopy == nil {
+		t.Fatal("StartRemoteCopy should check the destination first")
+	}
+	if _, cmd := m.Update(RemoteCopyCheckedMsg{copy: &pendingRemoteCopy{}}); cmd != nil {
+		t.Error("the check of another tab's copy should be ignored")
+	}
+	m, cmd = m.Update(RemoteCopyCheckedMsg{copy: m.pendingCopy})
+	if cmd == nil || !m.transferring || m.copyBytes == nil || m.pendingCopy != nil {
+		t.Fatal("StartRemoteCopy should begin a transfer")
+	}
+	if !strings.Contains(m.statusMsg, "Copying app.tar.gz to prod") {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+
+	m.copyBytes.Store(1024)
+	copied := m.copyBytes
+	if _, cmd := m.Update(RemoteCopyTickMsg{copied: &atomic.Int64{}}); cmd != nil {
+		t.Error("a tick of another tab's copy should be ignored")
+	}
+	m, cmd = m.Update(RemoteCopyTickMsg{copied: copied})
+	if cmd == nil {
+		t.Error("tick should reschedule while copying")
+	}
+	if !strings.Contains(m.statusMsg, "(50%)") {
+		t.Errorf("statusMsg = %q, want 50%%", m.statusMsg)
+	}
+
+	m, _ = m.Update(TransferDoneMsg{Detail: "to prod"})
+	if m.copyBytes != nil || m.transferring {
+		t.Error("copy state should be cleared when done")
+	}
+	if _, cmd = m.Update(RemoteCopyTickMsg{copied: copied}); cmd != nil {
+		t.Error("tick should stop after the copy finished")
+	}
+}
+
+func TestStartRemoteCopyWhileTransferring(t *testing.T) {
+	m := remoteCopyBrowser(t)
+	m.transferring = true
+	m, cmd := m.StartRemoteCopy(RemoteCopyRequestMsg{Path: "/srv/x"}, RemoteCopyDest{}, false)
+	if cmd != nil || !strings.Contains(m.statusMsg, "already in progress") {
+		t.Errorf("cmd=%v status=%q", cmd != nil, m.statusMsg)
+	}
+}
+
+func TestStartRemoteCopyAsksBeforeReplacing(t *testing.T) {
+	m := remoteCopyBrowser(t)
+	req := RemoteCopyRequestMsg{Path: "/srv/app.tar.gz", Size: 2048}
+	m, _ = m.StartRemoteCopy(req, RemoteCopyDest{Label: "prod", Dir: "/opt"}, false)
+	if _, cmd := m.StartRemoteCopy(req, RemoteCopyDest{Label: "prod", Dir: "/opt"}, false); cmd != nil {
+		t.Error("a second copy should not start while the first is checked")
+	}
+	m, cmd := m.Update(RemoteCopyCheckedMsg{copy: m.pendingCopy, exists: true})
+	if cmd != nil || m.transferring || m.inputOp != opReplaceCopy {
+		t.Fatal("an existing destination should be confirmed first")
+	}
+	if !strings.Contains(m.inputPrompt, "'/opt/app.tar.gz' exists on prod") {
+		t.Errorf("prompt = %q", m.inputPrompt)
+	}
+
+	m, _ = answer(m, "n")
+	if m.transferring || m.pendingCopy != nil || m.statusMsg != "Copy cancelled" {
+		t.Errorf("declining should cancel the copy, status %q", m.statusMsg)
+	}
+
+	m, _ = m.StartRemoteCopy(req, RemoteCopyDest{Label: "prod", Dir: "/opt"}, false)
+	m, _ = m.Update(RemoteCopyCheckedMsg{copy: m.pendingCopy, exists: true})
+	m, cmd = answer(m, "yes")
+	if cmd == nil || !m.transferring {
+		t.Error("confirming should start the copy")
+	}
+}
+
+func TestStartRemoteCopyCheckFails(t *testing.T) {
+	m := remoteCopyBrowser(t)
+	m, _ = m.StartRemoteCopy(RemoteCopyRequestMsg{Path: "/srv/x"}, RemoteCopyDest{Label: "prod", Dir: "/opt"}, false)
+	m, cmd := m.Update(RemoteCopyCheckedMsg{copy: m.pendingCopy, err: os.ErrPermission})
+	if cmd != nil || m.pendingCopy != nil || !strings.Contains(m.statusMsg, "Cannot check /opt/x on prod") {
+		t.Errorf("status %q", m.statusMsg)
+	}
+}
+
+func TestCopyStatusUnknownSize(t *testing.T) {
+	m := FileBrowserModel{transferProgress: "f", copyLabel: "b", copyBytes: &atomic.Int64{}}
+	if s := m.copyStatus(); strings.Contains(s, "%") {
+		t.Errorf("copyStatus without total = %q", s)
+	}
+}
+
+func TestCopyTargetModel(t *testing.T) {
+	req := RemoteCopyRequestMsg{Path: "/srv/app.tar.gz"}
+	m := NewCopyTargetModel(req, []CopyTarget{
+		{Index: 1, Title: "staging", Dir: "/srv"},
+		{Index: 2, Title: "prod", Dir: "/opt"},
+	})
+	m.SetDimensions(80, 20)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if !strings.Contains(m.View(), "direct scp") {
+		t.Error("view should show direct mode")
+	}
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter should pick the target")
+	}
+	picked, ok := cmd().(CopyTargetPickedMsg)
+	if !ok || picked.Target.Index != 2 || !picked.Direct || picked.Request.Path != req.Path {
+		t.Errorf("unexpected pick %#v", picked)
+	}
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, ok := cmd().(CopyTargetCloseMsg); !ok {
+		t.Error("Esc should close the picker")
+	}
+}
+
+func TestRefreshIfStale(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	if m.RefreshIfStale() != nil {
+		t.Error("fresh browser should not refresh")
+	}
+	m.MarkRemoteStale()
+	if m.RefreshIfStale() == nil {
+		t.Error("stale browser should refresh")
+	}
+	if m.RefreshIfStale() != nil {
+		t.Error("refresh should clear the stale flag")
+	}
+}
@@ -0,0 +1,625 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ssh-scp/internal/config"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	maxRunnerLines = 10000 // output kept per command
+	maxRunnerRuns  = 20    // finished commands kept in the runner
+)
+
+// execFunc runs a command in a remote directory, calling line for each
+// line of output, and returns its exit code.
+type execFunc func(ctx context.Context, dir, cmd string, line func(text string, stderr bool)) (int, error)
+
+// runnerLine is a line of command output.
+type runnerLine struct {
+	text   string
+	stderr bool
+}
+
+// RunnerOutputMsg delivers output of a command started in the command
+// runner. It is routed to every tab's browser, since the command may have
+// been started in a tab that is no longer shown.
+type RunnerOutputMsg struct {
+	run   *commandRun
+	lines []runnerLine
+	done  bool
+	code  int
+	err   error
+}
+
+// runnerHideMsg hides the runner; running commands keep running.
+type runnerHideMsg struct{}
+
+// runnerCloseMsg is sent when the runner is closed and all commands
+// stopped.
+type runnerCloseMsg struct{}
+
+// runnerSaveMsg asks to write a command's output to a local file.
+type runnerSaveMsg struct {
+	path string
+	text string
+}
+
+// commandRun is one command and its output.
+type commandRun struct {
+	cmd       string
+	dir       string
+	lines     []runnerLine
+	offset    int // lines scrolled up from the bottom
+	started   time.Time
+	elapsed   time.Duration
+	done      bool
+	code      int
+	err       error
+	cancelled bool
+	cancel    context.CancelFunc // interrupts the command
+	gone      chan struct{}      // closed when the run is dropped
+	next      tea.Cmd
+}
+
+// add appends received lines, dropping the oldest beyond maxRunnerLines.
+func (r *commandRun) add(lines []runnerLine) {
+	r.lines = append(r.lines, lines...)
+	if n := len(r.lines) - maxRunnerLines; n > 0 {
+		r.lines = append(r.lines[:0:0], r.lines[n:]...)
+	}
+	if r.offset > 0 {
+		// Keep the scrolled-back view where it is.
+		r.offset += len(lines)
+	}
+}
+
+// interrupt asks a running command to stop.
+func (r *commandRun) interrupt() {
+	if !r.done && !r.cancelled {
+		r.cancelled = true
+		r.cancel()
+	}
+}
+
+// drop stops the command and stops delivering its output.
+func (r *commandRun) drop() {
+	r.interrupt()
+	select {
+	case <-r.gone:
+	default:
+		close(r.gone)
+	}
+}
+
+// state describes how the command is doing.
+func (r *commandRun) state() string {
+	switch {
+	case !r.done && r.cancelled:
+		return "interrupting…"
+	case !r.done:
+		return "running " + formatDuration(time.Since(r.started).Round(time.Second))
+	case r.code < 0 && r.err != nil:
+		return "failed: " + r.err.Error()
+	case r.cancelled && r.code < 0:
+		return "cancelled after " + formatDuration(r.elapsed)
+	case r.cancelled:
+		return fmt.Sprintf("cancelled, exit %d after %s", r.code, formatDuration(r.elapsed))
+	}
+	return fmt.Sprintf("exit %d after %s", r.code, formatDuration(r.elapsed))
+}
+
+// text returns the output as plain text.
+func (r *commandRun) text() string {
+	var b strings.Builder
+	for _, l := range r.lines {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Prompts of the command runner.
+const (
+	runnerInputNone = iota
+	runnerInputCommand
+	runnerInputSave
+)
+
+// RunnerModel runs one-off commands on the remote host, each in its own
+// session, and shows their output. Previous commands stay in the runner
+// until dropped, and the commands typed are remembered per host.
+type RunnerModel struct {
+	exec      execFunc
+	hostKey   string
+	dir       string // remote directory new commands run in
+	runs      []*commandRun
+	active    int
+	history   []string // commands typed, newest first
+	histPos   int      // position while browsing the history; -1 = not browsing
+	draft     string   // what was typed before browsing the history
+	inputMode int
+	input     textinput.Model
+	status    string
+	width     int
+	height    int
+}
+
+// NewRunnerModel creates a runner that runs commands with exec and keeps
+// their history under hostKey ("" keeps none).
+func NewRunnerModel(exec execFunc, hostKey string) RunnerModel {
+	m := RunnerModel{exec: exec, hostKey: hostKey, histPos: -1}
+	if hostKey != "" {
+		history, err := config.LoadCommandHistory(hostKey)
+		if err != nil {
+			log.Printf("[Runner] load command history: %v", err)
+		}
+		m.history = history
+	}
+	return m
+}
+
+// SetDimensions sets the runner's display dimensions.
+func (m *RunnerModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Prompt opens the command prompt for a command run in dir.
+func (m *RunnerModel) Prompt(dir string) tea.Cmd {
+	m.dir = dir
+	return m.startInput(runnerInputCommand, "")
+}
+
+// Run starts cmd in dir and shows its output.
+func (m *RunnerModel) Run(dir, cmd string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &commandRun{cmd: cmd, dir: dir, started: time.Now(), cancel: cancel, gone: make(chan struct{})}
+	m.runs = append(m.runs, r)
+	m.trimRuns()
+	m.active = len(m.runs) - 1
+	m.remember(cmd)
+
+	ch := make(chan runnerLine, 256)
+	result := make(chan RunnerOutputMsg, 1)
+	exec := m.exec
+	go func() {
+		code, err := exec(ctx, dir, cmd, func(text string, stderr bool) {
+			select {
+			case ch <- runnerLine{text: text, stderr: stderr}:
+			case <-r.gone:
+			}
+		})
+		cancel()
+		result <- RunnerOutputMsg{run: r, done: true, code: code, err: err}
+		close(ch)
+	}()
+	r.next = waitRunner(r, ch, result)
+	return r.next
+}
+
+// waitRunner returns a command delivering the next output of a command.
+func waitRunner(r *commandRun, ch <-chan runnerLine, result <-chan RunnerOutputMsg) tea.Cmd {
+	return func() tea.Msg {
+		l, ok := <-ch
+		if !ok {
+			return <-result
+		}
+		lines := []runnerLine{l}
+		for len(lines) < 500 {
+			select {
+			case l, ok := <-ch:
+				if !ok {
+					msg := <-result
+					msg.lines = lines
+					return msg
+				}
+				lines = append(lines, l)
+			default:
+				return RunnerOutputMsg{run: r, lines: lines}
+			}
+		}
+		return RunnerOutputMsg{run: r, lines: lines}
+	}
+}
+
+// trimRuns drops the oldest finished commands beyond maxRunnerRuns.
+func (m *RunnerModel) trimRuns() {
+	for i := 0; len(m.runs) > maxRunnerRuns && i < len(m.runs); {
+		if !m.runs[i].done {
+			i++
+			continue
+		}
+		m.runs = append(m.runs[:i:i], m.runs[i+1:]...)
+	}
+}
+
+// remember puts cmd at the front of the command history.
+func (m *RunnerModel) remember(cmd string) {
+	history := []string{cmd}
+	for _, c := range m.history {
+		if c != cmd {
+			history = append(history, c)
+		}
+	}
+	m.history = history
+	if m.hostKey == "" {
+		return
+	}
+	if err := config.AppendCommandHistory(m.hostKey, cmd); err != nil {
+		log.Printf("[Runner] save command history: %v", err)
+	}
+}
+
+// Owns reports whether msg belongs to a command of this runner.
+func (m RunnerModel) Owns(msg RunnerOutputMsg) bool {
+	for _, r := range m.runs {
+		if r == msg.run {
+			return true
+		}
+	}
+	return false
+}
+
+// Running returns the number of commands still running.
+func (m RunnerModel) Running() int {
+	n := 0
+	for _, r := range m.runs {
+		if !r.done {
+			n++
+		}
+	}
+	return n
+}
+
+// Stop ends all running commands.
+func (m *RunnerModel) Stop() {
+	for _, r := range m.runs {
+		r.drop()
+	}
+}
+
+func (m RunnerModel) current() *commandRun {
+	if len(m.runs) == 0 {
+		return nil
+	}
+	return m.runs[m.active]
+}
+
+func (m RunnerModel) visibleRows() int {
+	return max(m.height-7, 1) // command tabs, status, prompt/hints, borders
+}
+
+// Update handles command output and keys.
+func (m RunnerModel) Update(msg tea.Msg) (RunnerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case RunnerOutputMsg:
+		r := msg.run
+		if !m.Owns(msg) {
+			return m, nil
+		}
+		r.add(msg.lines)
+		if !msg.done {
+			return m, r.next
+		}
+		r.done, r.next = true, nil
+		r.code, r.elapsed = msg.code, time.Since(r.started)
+		if !errors.Is(msg.err, context.Canceled) {
+			r.err = msg.err
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.inputMode != runnerInputNone {
+			return m.handleInputKey(msg)
+		}
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m RunnerModel) handleKey(msg tea.KeyMsg) (RunnerModel, tea.Cmd) {
+	m.status = ""
+	r := m.current()
+	switch msg.String() {
+	case "esc":
+		return m, func() tea.Msg { return runnerHideMsg{} }
+	case "q":
+		m.Stop()
+		return m, func() tea.Msg { return runnerCloseMsg{} }
+	case "!", "i":
+		return m, m.startInput(runnerInputCommand, "")
+	}
+	if r == nil {
+		return m, nil
+	}
+	switch msg.String() {
+	case "ctrl+c", "x":
+		r.interrupt()
+	case "r":
+		return m, m.Run(r.dir, r.cmd)
+	case "d":
+		r.drop()
+		m.runs = append(m.runs[:m.active:m.active], m.runs[m.active+1:]...)
+		m.active = max(min(m.active, len(m.runs)-1), 0)
+		return m, nil
+	case "s":
+		return m, m.startInput(runnerInputSave, outputFileName(r.cmd, r.started))
+	default:
+		m.handleViewKey(msg)
+	}
+	return m, nil
+}
+
+// handleViewKey scrolls the output and switches between commands; these
+// keys also work while typing a command.
+func (m *RunnerModel) handleViewKey(msg tea.KeyMsg) bool {
+	r := m.current()
+	if r == nil {
+		return false
+	}
+	switch msg.String() {
+	case "tab", "]":
+		m.active = (m.active + 1) % len(m.runs)
+	case "shift+tab", "[":
+		m.active = (m.active + len(m.runs) - 1) % len(m.runs)
+	case "up", "k":
+		r.offset++
+	case "down", "j":
+		r.offset--
+	case "pgup":
+		r.offset += m.visibleRows()
+	case "pgdown":
+		r.offset -= m.visibleRows()
+	case "home", "g":
+		r.offset = len(r.lines)
+	case "end", "G":
+		r.offset = 0
+	default:
+		return false
+	}
+	r = m.current()
+	r.offset = max(min(r.offset, len(r.lines)-m.visibleRows()), 0)
+	return true
+}
+
+func (m *RunnerModel) startInput(mode int, value string) tea.Cmd {
+	ti := textinput.New()
+	ti.CharLimit = 1024
+	ti.Width = max(m.width-len(m.dir)-12, 20)
+	ti.SetValue(value)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.inputMode = mode
+	m.histPos = -1
+	return textinput.Blink
+}
+
+// handleInputKey edits the command or the file name to save to.
+func (m RunnerModel) handleInputKey(msg tea.KeyMsg) (RunnerModel, tea.Cmd) {
+	if m.inputMode == runnerInputSave {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.inputMode = runnerInputNone
+			return m, nil
+		case tea.KeyEnter:
+			m.inputMode = runnerInputNone
+			save := runnerSaveMsg{path: strings.TrimSpace(m.input.Value()), text: m.current().text()}
+			return m, func() tea.Msg { return save }
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.inputMode = runnerInputNone
+		if len(m.runs) == 0 {
+			return m, func() tea.Msg { return runnerHideMsg{} }
+		}
+		return m, nil
+	case "enter":
+		cmd := strings.TrimSpace(m.input.Value())
+		if cmd == "" {
+			m.inputMode = runnerInputNone
+			return m, nil
+		}
+		m.input.SetValue("")
+		m.histPos = -1
+		return m, m.Run(m.dir, cmd)
+	case "ctrl+c":
+		if r := m.current(); r != nil && !r.done {
+			r.interrupt()
+		} else {
+			m.input.SetValue("")
+		}
+		return m, nil
+	case "up", "down":
+		m.browseHistory(msg.String() == "up")
+		return m, nil
+	}
+	if m.handleViewKey(msg) {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// browseHistory puts the previous (older) or next command of the history
+// into the prompt.
+func (m *RunnerModel) browseHistory(older bool) {
+	pos := m.histPos
+	if older {
+		pos++
+	} else {
+		pos--
+	}
+	switch {
+	case pos >= len(m.history):
+		return
+	case pos < 0:
+		if m.histPos >= 0 {
+			m.input.SetValue(m.draft)
+		}
+		m.histPos = -1
+	default:
+		if m.histPos < 0 {
+			m.draft = m.input.Value()
+		}
+		m.histPos = pos
+		m.input.SetValue(m.history[pos])
+	}
+	m.input.CursorEnd()
+}
+
+// outputFileName suggests a file name for the output of cmd.
+func outputFileName(cmd string, started time.Time) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(cmd), " ")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return -1
+	}, filepath.Base(name))
+	if name == "" {
+		name = "output"
+	}
+	return name + "-" + started.Format("20060102-150405") + ".txt"
+}
+
+// View renders the runner.
+func (m RunnerModel) View() string {
+	width := max(m.width-4, 10)
+
+	var tabs []string
+	for i, r := range m.runs {
+		mark := "…"
+		switch {
+		case r.done && r.code == 0:
+			mark = "✓"
+		case r.done:
+			mark = "✗"
+		}
+		name := " " + mark + " " + truncate(r.cmd, 20) + " "
+		if i == m.active {
+			name = fileSelectedStyle.Render(name)
+		}
+		tabs = append(tabs, name)
+	}
+	lines := []string{strings.Join(tabs, " ")}
+
+	vis := m.visibleRows()
+	if r := m.current(); r != nil {
+		info := "$ " + r.cmd + " — in " + r.dir + " — " + r.state()
+		if r.offset > 0 {
+			info += fmt.Sprintf(" — scrolled back %d lines", r.offset)
+		}
+		style := statusBarStyle
+		switch {
+		case r.done && r.code == 0 && !r.cancelled:
+			style = historyOKStyle
+		case r.done:
+			style = historyFailStyle
+		}
+		lines = append(lines, style.Render(truncate(info, width)))
+
+		end := len(r.lines) - r.offset
+		for _, l := range r.lines[max(end-vis, 0):max(end, 0)] {
+			text := truncate(cleanLine(lastSegment(l.text)), width)
+			if l.stderr {
+				text = historyFailStyle.Render(text)
+			}
+			lines = append(lines, text)
+		}
+	} else {
+		lines = append(lines, statusBarStyle.Render(truncate("Type a command to run in "+m.dir, width)))
+	}
+	for i := len(lines); i < vis+2; i++ {
+		lines = append(lines, "")
+	}
+
+	switch {
+	case m.inputMode == runnerInputCommand:
+		lines = append(lines, messageStyle.Render(truncatePath(m.dir, 30)+" $ ")+m.input.View())
+	case m.inputMode == runnerInputSave:
+		lines = append(lines, messageStyle.Render("Save output to: ")+m.input.View())
+	case m.status != "":
+		lines = append(lines, messageStyle.Render(truncate(m.status, width)))
+	default:
+		lines = append(lines, statusBarStyle.Render("!: new command • r: run again • ^C: interrupt • s: save • d: drop • Tab: next • ↑↓ PgUp/PgDn G: scroll • Esc: hide • q: stop all"))
+	}
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// lastSegment returns what a terminal would show of a line rewritten with
+// carriage returns, such as a progress bar.
+func lastSegment(s string) string {
+	s = strings.TrimRight(s, "\r")
+	if i := strings.LastIndexByte(s, '\r'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// openRunner shows the command runner with the prompt open for a command
+// in the remote panel's directory.
+func (m FileBrowserModel) openRunner() (FileBrowserModel, tea.Cmd) {
+	m.ensureRunner()
+	m.runnerShown = true
+	return m, m.runner.Prompt(m.remoteDir)
+}
+
+// ensureRunner creates the command runner on first use.
+func (m *FileBrowserModel) ensureRunner() {
+	if m.runner == nil {
+		client := m.client
+		rm := NewRunnerModel(client.Exec, m.historyHost)
+		m.runner = &rm
+	}
+}
+
+// handleRunnerMsg routes messages of the command runner.
+func (m FileBrowserModel) handleRunnerMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case runnerHideMsg:
+		m.runnerShown = false
+	case runnerCloseMsg:
+		if m.runner != nil {
+			m.runner.Stop()
+		}
+		m.runner = nil
+		m.runnerShown = false
+	case runnerSaveMsg:
+		if m.runner == nil {
+			return m, nil
+		}
+		p := m.resolveLocal(msg.path)
+		if err := os.WriteFile(p, []byte(msg.text), 0o644); err != nil {
+			m.runner.status = "Save failed: " + err.Error()
+			return m, nil
+		}
+		m.runner.status = "Output saved to " + p
+		m.refreshLocal()
+	case RunnerOutputMsg:
+		if m.runner == nil {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		*m.runner, cmd = m.runner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
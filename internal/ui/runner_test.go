@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ssh-scp/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeExec prints out on stdout and errOut on stderr, then exits with code.
+func fakeExec(out, errOut []string, code int) execFunc {
+	return func(ctx context.Context, dir, cmd string, line func(string, bool)) (int, error) {
+		for _, l := range out {
+			line(l, false)
+		}
+		for _, l := range errOut {
+			line(l, true)
+		}
+		return code, nil
+	}
+}
+
+// runToEnd feeds the runner its output messages until the command ends.
+func runToEnd(t *testing.T, m RunnerModel, cmd tea.Cmd) RunnerModel {
+	t.Helper()
+	for cmd != nil {
+		msg, ok := cmd().(RunnerOutputMsg)
+		if !ok {
+			t.Fatalf("got %T, want command output", msg)
+		}
+		m, cmd = m.Update(msg)
+	}
+	return m
+}
+
+func typeText(m RunnerModel, s string) RunnerModel {
+	for _, r := range s {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+func TestRunnerRunsCommand(t *testing.T) {
+	var gotDir, gotCmd string
+	exec := func(ctx context.Context, dir, cmd string, line func(string, bool)) (int, error) {
+		gotDir, gotCmd = dir, cmd
+		return fakeExec([]string{"Filesystem Size", "/dev/sda1 20G"}, []string{"df: warning"}, 3)(ctx, dir, cmd, line)
+	}
+	m := NewRunnerModel(exec, "")
+	m.SetDimensions(100, 20)
+	m.Prompt("/srv")
+	m = typeText(m, "df -h")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = runToEnd(t, m, cmd)
+
+	if gotDir != "/srv" || gotCmd != "df -h" {
+		t.Errorf("ran %q in %q", gotCmd, gotDir)
+	}
+	r := m.current()
+	if len(r.lines) != 3 || !r.lines[2].stderr || r.lines[0].stderr {
+		t.Fatalf("output = %+v, want two stdout lines and one stderr line", r.lines)
+	}
+	if !r.done || r.code != 3 || !strings.HasPrefix(r.state(), "exit 3") {
+		t.Errorf("state = %q, want exit 3", r.state())
+	}
+	view := m.View()
+	for _, want := range []string{"$ df -h — in /srv", "/dev/sda1 20G", "df: warning", "✗ df -h"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q", want)
+		}
+	}
+	if m.inputMode != runnerInputCommand || m.input.Value() != "" {
+		t.Error("the prompt should stay open and empty for the next command")
+	}
+}
+
+func TestRunnerInterrupt(t *testing.T) {
+	started := make(chan struct{})
+	exec := func(ctx context.Context, dir, cmd string, line func(string, bool)) (int, error) {
+		line("working", false)
+		close(started)
+		<-ctx.Done()
+		return 130, ctx.Err()
+	}
+	m := NewRunnerModel(exec, "")
+	cmd := m.Run("/", "sleep 100")
+	<-started
+	msg := cmd().(RunnerOutputMsg)
+	m, cmd = m.Update(msg)
+	if m.Running() != 1 {
+		t.Fatal("the command should still be running")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if got := m.current().state(); got != "interrupting…" {
+		t.Errorf("state = %q while interrupting", got)
+	}
+	m = runToEnd(t, m, cmd)
+	r := m.current()
+	if r.code != 130 || r.err != nil || !strings.HasPrefix(r.state(), "cancelled, exit 130") {
+		t.Errorf("state = %q, err %v; want cancelled with the exit code", r.state(), r.err)
+	}
+}
+
+func TestRunnerDropStopsCommand(t *testing.T) {
+	finished := make(chan struct{})
+	exec := func(ctx context.Context, dir, cmd string, line func(string, bool)) (int, error) {
+		defer close(finished)
+		for range 10 * maxRunnerLines {
+			line("spam", false)
+		}
+		return 0, nil
+	}
+	m := NewRunnerModel(exec, "")
+	m.Run("/", "yes")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dropping a command should stop waiting for its output to be read")
+	}
+	if len(m.runs) != 0 {
+		t.Errorf("%d runs left after dropping the only one", len(m.runs))
+	}
+}
+
+func TestRunnerHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := config.AppendCommandHistory("u@h:22", "uptime"); err != nil {
+		t.Fatal(err)
+	}
+	m := NewRunnerModel(fakeExec(nil, nil, 0), "u@h:22")
+	m.Prompt("/")
+	m = typeText(m, "df -h")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = runToEnd(t, m, cmd)
+
+	m = typeText(m, "ls")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got := m.input.Value(); got != "df -h" {
+		t.Errorf("Up = %q, want the last command", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got := m.input.Value(); got != "uptime" {
+		t.Errorf("Up past the end = %q, want the oldest command", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := m.input.Value(); got != "ls" {
+		t.Errorf("Down back to the start = %q, want what was typed", got)
+	}
+
+	saved, _ := config.LoadCommandHistory("u@h:22")
+	if strings.Join(saved, ",") != "df -h,uptime" {
+		t.Errorf("saved history = %q", saved)
+	}
+}
+
+func TestRunnerKeepsRunsAndReruns(t *testing.T) {
+	m := NewRunnerModel(fakeExec([]string{"ok"}, nil, 0), "")
+	m = runToEnd(t, m, m.Run("/a", "one"))
+	m = runToEnd(t, m, m.Run("/b", "two"))
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	if m.current().cmd != "one" {
+		t.Fatalf("[ should show the previous command, got %q", m.current().cmd)
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = runToEnd(t, m, cmd)
+	if len(m.runs) != 3 || m.current().cmd != "one" || m.current().dir != "/a" {
+		t.Errorf("r should run the command again in its directory, runs %d", len(m.runs))
+	}
+
+	for range maxRunnerRuns + 5 {
+		m = runToEnd(t, m, m.Run("/", "true"))
+	}
+	if len(m.runs) != maxRunnerRuns {
+		t.Errorf("%d runs kept, want %d", len(m.runs), maxRunnerRuns)
+	}
+}
+
+func TestRunnerSaveOutput(t *testing.T) {
+	local := t.TempDir()
+	b := NewFileBrowserModel(nil, local, "/srv")
+	rm := NewRunnerModel(fakeExec([]string{"line 1", "line 2"}, []string{"oops"}, 0), "")
+	b.runner = &rm
+	b, _ = b.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	if !b.runnerShown || b.runner.dir != "/srv" || !b.OverlayActive() {
+		t.Fatal("! should show the runner for the remote directory")
+	}
+	*b.runner = typeText(*b.runner, "cat log")
+	var cmd tea.Cmd
+	b, cmd = b.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for cmd != nil {
+		b, cmd = b.Update(cmd())
+	}
+	b, _ = b.Update(tea.KeyMsg{Type: tea.KeyEsc}) // leave the prompt
+	b, _ = b.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if !strings.HasPrefix(b.runner.input.Value(), "cat-") {
+		t.Errorf("suggested file name = %q", b.runner.input.Value())
+	}
+	b.runner.input.SetValue("out.txt")
+	b, cmd = b.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	b, _ = b.Update(cmd())
+
+	data, err := os.ReadFile(filepath.Join(local, "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "line 1\nline 2\noops\n" {
+		t.Errorf("saved %q", data)
+	}
+	if !strings.Contains(b.runner.status, "out.txt") {
+		t.Errorf("status = %q", b.runner.status)
+	}
+
+	b, cmd = b.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	b, _ = b.Update(cmd())
+	if b.runnerShown || b.runner == nil {
+		t.Error("Esc should hide the runner and keep its commands")
+	}
+}
+
+func TestOutputFileName(t *testing.T) {
+	at := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	for cmd, want := range map[string]string{
+		"systemctl status nginx": "systemctl-20240305-140709.txt",
+		"/usr/bin/df -h":         "df-20240305-140709.txt",
+		"  | grep x":             "output-20240305-140709.txt",
+	} {
+		if got := outputFileName(cmd, at); got != want {
+			t.Errorf("outputFileName(%q) = %q, want %q", cmd, got, want)
+		}
+	}
+}
+
+func TestLastSegment(t *testing.T) {
+	if got := lastSegment("10%\r50%\r100%\r"); got != "100%" {
+		t.Errorf("lastSegment = %q", got)
+	}
+}
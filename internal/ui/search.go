@@ -0,0 +1,571 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSearchResults stops a search once this many entries were found.
+const maxSearchResults = 1000
+
+// searchFunc runs a search and reports each result to found.
+type searchFunc func(ctx context.Context, spec sshclient.SearchSpec, found func(sshclient.SearchResult)) error
+
+// SearchBatchMsg delivers results of a search, identified by the channel
+// they were read from. It is routed to every tab's browser, since the
+// search may run in a tab that is no longer shown.
+type SearchBatchMsg struct {
+	from    <-chan sshclient.SearchResult
+	results []sshclient.SearchResult
+	done    bool
+	err     error
+}
+
+// searchOpenMsg requests showing a search result; edit opens a file in the
+// editor instead of revealing it in the panel.
+type searchOpenMsg struct {
+	result sshclient.SearchResult
+	edit   bool
+}
+
+// searchCloseMsg is sent when the search dialog is closed.
+type searchCloseMsg struct{}
+
+// Search form fields; searchResults is the result list.
+const (
+	searchName = iota
+	searchContent
+	searchMinSize
+	searchMaxSize
+	searchModified
+	searchResults
+)
+
+// SearchModel is the search dialog: a form for the filters and a list the
+// results stream into.
+type SearchModel struct {
+	remote  bool
+	dir     string
+	search  searchFunc
+	inputs  [5]textinput.Model
+	focus   int
+	results []sshclient.SearchResult
+	cursor  int
+	scroll  int
+	running bool
+	cancel  context.CancelFunc
+	from    <-chan sshclient.SearchResult // results of the running search
+	next    tea.Cmd                       // reads the next batch of the running search
+	status  string
+	width   int
+	height  int
+}
+
+// NewSearchModel creates a search dialog for dir, using search to run it.
+func NewSearchModel(remote bool, dir string, search searchFunc) SearchModel {
+	m := SearchModel{remote: remote, dir: dir, search: search}
+	placeholders := [5]string{"*.conf", "text inside files", "e.g. 10K", "e.g. 5M", "e.g. 30m, 2h, 7d"}
+	for i := range m.inputs {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.CharLimit = 256
+		ti.Width = 30
+		ti.Placeholder = placeholders[i]
+		m.inputs[i] = ti
+	}
+	m.inputs[searchName].Focus()
+	return m
+}
+
+// SetDimensions sets the dialog's display dimensions.
+func (m *SearchModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m SearchModel) visibleRows() int {
+	v := m.height - 13 // title, form, status, hints, borders
+	if v < 1 {
+		v = 1
+	}
+	return v
+}
+
+func (m *SearchModel) setFocus(f int) {
+	m.focus = (f + searchResults + 1) % (searchResults + 1)
+	for i := range m.inputs {
+		if i == m.focus {
+			m.inputs[i].Focus()
+		} else {
+			m.inputs[i].Blur()
+		}
+	}
+}
+
+// Stop cancels the running search, if any.
+func (m *SearchModel) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+// Update handles keys and streamed results.
+func (m SearchModel) Update(msg tea.Msg) (SearchModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case SearchBatchMsg:
+		return m.handleBatch(msg)
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m SearchModel) handleBatch(msg SearchBatchMsg) (SearchModel, tea.Cmd) {
+	if msg.from != m.from || !m.running {
+		return m, nil
+	}
+	m.results = append(m.results, msg.results...)
+	if len(m.results) >= maxSearchResults {
+		m.results = m.results[:maxSearchResults]
+		m.Stop()
+		m.running = false
+		m.status = fmt.Sprintf("Stopped at %d results — narrow the search", maxSearchResults)
+		return m, nil
+	}
+	if !msg.done {
+		m.status = fmt.Sprintf("Searching… %d found", len(m.results))
+		return m, m.next
+	}
+	m.running = false
+	m.cancel = nil
+	m.next = nil
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		m.status = fmt.Sprintf("Cancelled: %d found", len(m.results))
+	case msg.err != nil:
+		m.status = "Search failed: " + msg.err.Error()
+	default:
+		m.status = fmt.Sprintf("Done: %d found", len(m.results))
+	}
+	return m, nil
+}
+
+func (m SearchModel) handleKey(msg tea.KeyMsg) (SearchModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		if m.running {
+			m.Stop()
+			return m, nil
+		}
+		return m, func() tea.Msg { return searchCloseMsg{} }
+	case "tab":
+		m.setFocus(m.focus + 1)
+		return m, nil
+	case "shift+tab":
+		m.setFocus(m.focus - 1)
+		return m, nil
+	}
+
+	if m.focus != searchResults {
+		if msg.Type == tea.KeyEnter {
+			return m.start()
+		}
+		var cmd tea.Cmd
+		m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+			if m.cursor < m.scroll {
+				m.scroll = m.cursor
+			}
+		}
+	case "down", "j":
+		if m.cursor < len(m.results)-1 {
+			m.cursor++
+			if vis := m.visibleRows(); m.cursor >= m.scroll+vis {
+				m.scroll = m.cursor - vis + 1
+			}
+		}
+	case "enter", "e":
+		if len(m.results) > 0 {
+			r := m.results[m.cursor]
+			edit := msg.String() == "e" && !r.IsDir
+			m.Stop()
+			return m, func() tea.Msg { return searchOpenMsg{result: r, edit: edit} }
+		}
+	}
+	return m, nil
+}
+
+// spec builds the search spec from the form.
+func (m SearchModel) spec() (sshclient.SearchSpec, error) {
+	var s sshclient.SearchSpec
+	var err error
+	s.Name = strings.TrimSpace(m.inputs[searchName].Value())
+	s.Content = m.inputs[searchContent].Value()
+	if s.MinSize, err = parseSize(m.inputs[searchMinSize].Value()); err != nil {
+		return s, fmt.Errorf("min size: %w", err)
+	}
+	if s.MaxSize, err = parseSize(m.inputs[searchMaxSize].Value()); err != nil {
+		return s, fmt.Errorf("max size: %w", err)
+	}
+	if s.ModifiedWithin, err = parseAge(m.inputs[searchModified].Value()); err != nil {
+		return s, fmt.Errorf("modified within: %w", err)
+	}
+	if s.Name != "" {
+		if _, err := path.Match(s.Name, ""); err != nil {
+			return s, fmt.Errorf("name: bad pattern %q", s.Name)
+		}
+	}
+	return s, nil
+}
+
+// start runs a new search, cancelling the previous one.
+func (m SearchModel) start() (SearchModel, tea.Cmd) {
+	spec, err := m.spec()
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.running = true
+	m.results = nil
+	m.cursor, m.scroll = 0, 0
+	m.status = "Searching…"
+	m.setFocus(searchResults)
+
+	ch := make(chan sshclient.SearchResult, 256)
+	errCh := make(chan error, 1)
+	search := m.search
+	go func() {
+		errCh <- search(ctx, spec, func(r sshclient.SearchResult) {
+			select {
+			case ch <- r:
+			case <-ctx.Done():
+			}
+		})
+		close(ch)
+	}()
+	m.from = ch
+	m.next = waitSearch(ch, errCh)
+	return m, m.next
+}
+
+// waitSearch returns a command delivering the next batch of results.
+func waitSearch(ch <-chan sshclient.SearchResult, errCh <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		r, ok := <-ch
+		if !ok {
+			return SearchBatchMsg{from: ch, done: true, err: <-errCh}
+		}
+		batch := []sshclient.SearchResult{r}
+		for len(batch) < 200 {
+			select {
+			case r, ok := <-ch:
+				if !ok {
+					return SearchBatchMsg{from: ch, results: batch, done: true, err: <-errCh}
+				}
+				batch = append(batch, r)
+			default:
+				return SearchBatchMsg{from: ch, results: batch}
+			}
+		}
+		return SearchBatchMsg{from: ch, results: batch}
+	}
+}
+
+// View renders the search dialog.
+func (m SearchModel) View() string {
+	side := "local"
+	if m.remote {
+		side = "remote"
+	}
+	lines := []string{messageStyle.Render(fmt.Sprintf("Search (%s) in %s", side, m.dir)), ""}
+	labels := [5]string{"Name", "Content", "Min size", "Max size", "Modified"}
+	for i, in := range m.inputs {
+		marker := "  "
+		if m.focus == i {
+			marker = "> "
+		}
+		lines = append(lines, marker+propLabelStyle.Render(labels[i])+in.View())
+	}
+	lines = append(lines, "", statusBarStyle.Render(m.status))
+
+	vis := m.visibleRows()
+	for i := m.scroll; i < len(m.results) && i < m.scroll+vis; i++ {
+		r := m.results[i]
+		name := relPath(m.dir, r.Path, m.remote)
+		if r.IsDir {
+			name = dirStyle.Render(name + "/")
+		}
+		if i == m.cursor && m.focus == searchResults {
+			name = fileSelectedStyle.Render(name)
+		}
+		lines = append(lines, "  "+name)
+	}
+	hint := "Enter: search • Tab: next field / results • Esc: close"
+	if m.focus == searchResults {
+		hint = "Enter: show in panel • e: edit • Tab: form • Esc: cancel search / close"
+	}
+	lines = append(lines, "", statusBarStyle.Render(hint))
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// relPath shows p relative to dir when it lies below it.
+func relPath(dir, p string, remote bool) string {
+	if remote {
+		if rel := strings.TrimPrefix(p, strings.TrimRight(dir, "/")+"/"); rel != p {
+			return rel
+		}
+		return p
+	}
+	if rel, err := filepath.Rel(dir, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return p
+}
+
+// parseSize parses sizes like "512", "10K", "5M" or "1G" (powers of 1024).
+// An empty string means no limit.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	in := s
+	if len(s) > 1 {
+		s = strings.TrimSuffix(s, "B")
+	}
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", in)
+	}
+	return n * mult, nil
+}
+
+// parseAge parses durations like "30m", "2h" or "7d". An empty string means
+// no limit.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// localSearch walks dir like the remote find/grep search. Unreadable
+// entries are skipped.
+func localSearch(ctx context.Context, dir string, spec sshclient.SearchSpec, found func(sshclient.SearchResult)) error {
+	now := time.Now()
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || p == dir {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if spec.Content != "" && !info.Mode().IsRegular() {
+			return nil
+		}
+		if !spec.Matches(d.Name(), info.Size(), info.ModTime(), now) {
+			return nil
+		}
+		if spec.Content != "" {
+			ok, err := fileContains(p, spec.Content)
+			if err != nil || !ok {
+				return nil
+			}
+		}
+		found(sshclient.SearchResult{Path: p, IsDir: d.IsDir()})
+		return nil
+	})
+}
+
+// fileContains reports whether the file at p contains text. Binary files
+// (a NUL byte in the first block) never match, as with grep -I.
+func fileContains(p, text string) (found bool, retErr error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if cErr := f.Close(); cErr != nil {
+			retErr = errors.Join(retErr, cErr)
+		}
+	}()
+	needle := []byte(text)
+	buf := make([]byte, 64<<10)
+	var carry []byte
+	first := true
+	for {
+		n, err := f.Read(buf)
+		chunk := buf[:n]
+		if first && n > 0 {
+			if bytes.IndexByte(chunk, 0) >= 0 {
+				return false, nil
+			}
+			first = false
+		}
+		data := append(carry, chunk...)
+		if bytes.Contains(data, needle) {
+			return true, nil
+		}
+		// Keep enough of the tail to catch a match across reads.
+		if keep := len(needle) - 1; len(data) > keep {
+			carry = append(carry[:0], data[len(data)-keep:]...)
+		} else {
+			carry = append(carry[:0], data...)
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// openSearch opens the search dialog for the focused panel's directory.
+func (m FileBrowserModel) openSearch() (FileBrowserModel, tea.Cmd) {
+	var sm SearchModel
+	if m.focus == panelLocal {
+		dir := m.localDir
+		sm = NewSearchModel(false, dir, func(ctx context.Context, spec sshclient.SearchSpec, found func(sshclient.SearchResult)) error {
+			return localSearch(ctx, dir, spec, found)
+		})
+	} else {
+		client, dir := m.client, m.remoteDir
+		sm = NewSearchModel(true, dir, func(ctx context.Context, spec sshclient.SearchSpec, found func(sshclient.SearchResult)) error {
+			return client.Search(ctx, dir, spec, found)
+		})
+	}
+	m.search = &sm
+	return m, textinput.Blink
+}
+
+// handleSearchMsg routes search results and actions.
+func (m FileBrowserModel) handleSearchMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case SearchBatchMsg:
+		if m.search == nil {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		*m.search, cmd = m.search.Update(msg)
+		return m, cmd
+	case searchCloseMsg:
+		if m.search != nil {
+			m.search.Stop()
+		}
+		m.search = nil
+	case searchOpenMsg:
+		m.search = nil
+		return m.openSearchResult(msg)
+	}
+	return m, nil
+}
+
+// openSearchResult shows a search result in the focused panel: directories
+// are opened, files selected in their directory. With edit set the file is
+// then opened in the editor.
+func (m FileBrowserModel) openSearchResult(msg searchOpenMsg) (FileBrowserModel, tea.Cmd) {
+	r := msg.result
+	if m.focus == panelLocal {
+		if r.IsDir {
+			m.chdirLocal(r.Path)
+			return m, nil
+		}
+		m.chdirLocal(filepath.Dir(r.Path))
+		m.applyLocalView(filepath.Base(r.Path))
+		if msg.edit && len(m.localFiles) > 0 {
+			return m.editSelected()
+		}
+		return m, nil
+	}
+	if r.IsDir {
+		return m, m.chdirRemote(r.Path)
+	}
+	// The file is selected (and opened) once its directory is listed.
+	dir, _ := remoteParent(r.Path)
+	m.remoteSelect = path.Base(r.Path)
+	m.remoteEdit = msg.edit
+	if dir == m.remoteDir {
+		return m, refreshRemoteCmd(m.client, dir)
+	}
+	return m, m.chdirRemote(dir)
+}
+
+// editSelected opens the selected file of the focused panel in the editor,
+// unless it is a directory or too large.
+func (m FileBrowserModel) editSelected() (FileBrowserModel, tea.Cmd) {
+	var size int64
+	var p string
+	remote := m.focus == panelRemote
+	if remote {
+		f := m.remoteFiles[m.remoteCursor]
+		if remoteIsDir(f) {
+			return m, nil
+		}
+		size, p = f.Size, joinRemotePath(m.remoteDir, f.Name)
+	} else {
+		f := m.localFiles[m.localCursor]
+		if m.localIsDir(f) {
+			return m, nil
+		}
+		size, p = f.Size(), filepath.Join(m.localDir, f.Name())
+	}
+	if size > MaxEditableSize {
+		m.statusMsg = "File too large to edit (max 1 MB)"
+		return m, nil
+	}
+	return m, func() tea.Msg { return OpenEditorMsg{Path: p, IsRemote: remote} }
+}
@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"", 0, true},
+		{"512", 512, true},
+		{"10K", 10 << 10, true},
+		{"5m", 5 << 20, true},
+		{"1G", 1 << 30, true},
+		{"2KB", 2 << 10, true},
+		{"abc", 0, false},
+		{"-1", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, true},
+		{"30m", 30 * time.Minute, true},
+		{"2h", 2 * time.Hour, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+// searchDir creates a small tree for the local search tests.
+func searchDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.conf":          "listen 8080\n",
+		"logs/error.log":    "fatal: disk full\n",
+		"logs/access.log":   "GET /\n",
+		"logs/old/app.conf": "listen 80\n",
+		"bin/tool":          "listen\x00binary",
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "logs/old/app.conf"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func runLocalSearch(t *testing.T, dir string, spec sshclient.SearchSpec) []string {
+	t.Helper()
+	var got []string
+	err := localSearch(context.Background(), dir, spec, func(r sshclient.SearchResult) {
+		rel, _ := filepath.Rel(dir, r.Path)
+		if r.IsDir {
+			rel += "/"
+		}
+		got = append(got, rel)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	return got
+}
+
+func TestLocalSearch(t *testing.T) {
+	dir := searchDir(t)
+	tests := []struct {
+		spec sshclient.SearchSpec
+		want []string
+	}{
+		{sshclient.SearchSpec{Name: "*.conf"}, []string{"app.conf", "logs/old/app.conf"}},
+		{sshclient.SearchSpec{Name: "log*"}, []string{"logs/"}},
+		{sshclient.SearchSpec{Content: "listen"}, []string{"app.conf", "logs/old/app.conf"}},
+		{sshclient.SearchSpec{Name: "*.log", Content: "fatal"}, []string{"logs/error.log"}},
+		{sshclient.SearchSpec{Name: "*.conf", ModifiedWithin: time.Hour}, []string{"app.conf"}},
+		{sshclient.SearchSpec{Name: "*.log", MinSize: 10}, []string{"logs/error.log"}},
+	}
+	for _, tt := range tests {
+		if got := runLocalSearch(t, dir, tt.spec); !slices.Equal(got, tt.want) {
+			t.Errorf("search %+v = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestLocalSearchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := localSearch(ctx, searchDir(t), sshclient.SearchSpec{}, func(sshclient.SearchResult) {
+		t.Error("cancelled search should not report results")
+	})
+	if err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestFileContainsAcrossReads(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "big.txt")
+	data := strings.Repeat("x", 64<<10-3) + "needle" + strings.Repeat("y", 100)
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := fileContains(p, "needle"); err != nil || !ok {
+		t.Errorf("fileContains across a read boundary = %v, %v", ok, err)
+	}
+	if ok, _ := fileContains(p, "missing"); ok {
+		t.Error("fileContains should not match absent text")
+	}
+}
+
+// runSearch feeds search messages back into m until the search is finished.
+func runSearch(t *testing.T, m FileBrowserModel, cmd tea.Cmd) (FileBrowserModel, tea.Cmd) {
+	t.Helper()
+	for cmd != nil {
+		msg := cmd()
+		if _, ok := msg.(SearchBatchMsg); !ok {
+			return m, func() tea.Msg { return msg }
+		}
+		m, cmd = m.Update(msg)
+	}
+	return m, nil
+}
+
+func TestFBSearchRevealLocal(t *testing.T) {
+	dir := searchDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	m.SetDimensions(100, 30)
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f"), Alt: true})
+	if m.search == nil || !m.InputActive() {
+		t.Fatal("Alt+F should open the search dialog")
+	}
+	m.search.inputs[searchName].SetValue("error*")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = runSearch(t, m, cmd)
+	if m.search.running || len(m.search.results) != 1 {
+		t.Fatalf("results = %+v, running = %v", m.search.results, m.search.running)
+	}
+	if !strings.Contains(m.View(), "logs/error.log") || !strings.Contains(m.View(), "Done: 1 found") {
+		t.Errorf("view should list the result:\n%s", m.View())
+	}
+
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = m.Update(cmd())
+	if m.search != nil {
+		t.Error("opening a result should close the dialog")
+	}
+	if m.localDir != filepath.Join(dir, "logs") || m.selectedName() != "error.log" {
+		t.Errorf("panel at %s on %q, want logs/error.log", m.localDir, m.selectedName())
+	}
+}
+
+func TestFBSearchEditLocal(t *testing.T) {
+	dir := searchDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	m.SetDimensions(100, 30)
+	m, _ = m.openSearch()
+	m.search.inputs[searchContent].SetValue("fatal")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = runSearch(t, m, cmd)
+
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m, cmd = m.Update(cmd())
+	if cmd == nil {
+		t.Fatal("e should open the editor")
+	}
+	open, ok := cmd().(OpenEditorMsg)
+	if !ok || open.Path != filepath.Join(dir, "logs", "error.log") || open.IsRemote {
+		t.Errorf("got %#v", open)
+	}
+}
+
+func TestSearchModelCancelAndLimit(t *testing.T) {
+	release := make(chan struct{})
+	sm := NewSearchModel(true, "/srv", func(ctx context.Context, _ sshclient.SearchSpec, found func(sshclient.SearchResult)) error {
+		found(sshclient.SearchResult{Path: "/srv/a"})
+		<-release
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	sm.SetDimensions(80, 30)
+	sm, cmd := sm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	sm, _ = sm.Update(cmd())
+	if !sm.running || len(sm.results) != 1 {
+		t.Fatalf("expected a running search with one result, got %+v", sm.results)
+	}
+	next := sm.next
+
+	// Esc stops the search but keeps the dialog open.
+	sm, cmd = sm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd != nil {
+		t.Error("Esc during a search should not close the dialog")
+	}
+	close(release)
+	sm, _ = sm.Update(next())
+	if sm.running || !strings.Contains(sm.status, "Cancelled") {
+		t.Errorf("status = %q, running = %v", sm.status, sm.running)
+	}
+	if _, cmd = sm.Update(tea.KeyMsg{Type: tea.KeyEsc}); cmd == nil {
+		t.Fatal("Esc should close an idle dialog")
+	} else if _, ok := cmd().(searchCloseMsg); !ok {
+		t.Error("expected searchCloseMsg")
+	}
+
+	// A flood of results is cut at the limit.
+	ch := make(chan sshclient.SearchResult)
+	sm.running, sm.from, sm.results = true, ch, nil
+	many := make([]sshclient.SearchResult, maxSearchResults+5)
+	sm, _ = sm.Update(SearchBatchMsg{from: ch, results: many})
+	if len(sm.results) != maxSearchResults || sm.running {
+		t.Errorf("results = %d, running = %v", len(sm.results), sm.running)
+	}
+}
+
+func TestSearchBatchesGoToTheirOwnSearch(t *testing.T) {
+	// Two tabs search at once; a tab's results never reach the other.
+	newSearch := func(found string) (SearchModel, tea.Cmd) {
+		sm := NewSearchModel(true, "/srv", func(ctx context.Context, _ sshclient.SearchSpec, fn func(sshclient.SearchResult)) error {
+			fn(sshclient.SearchResult{Path: found})
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		sm, cmd := sm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		t.Cleanup(sm.Stop)
+		return sm, cmd
+	}
+	a, cmdA := newSearch("/srv/a")
+	b, _ := newSearch("/srv/b")
+
+	msg := cmdA()
+	a, next := a.Update(msg)
+	b, _ = b.Update(msg)
+	if len(a.results) != 1 || a.results[0].Path != "/srv/a" || next == nil {
+		t.Errorf("owner results = %+v, next = %v", a.results, next != nil)
+	}
+	if len(b.results) != 0 {
+		t.Errorf("other search got %+v", b.results)
+	}
+}
+
+func TestSearchModelRejectsBadInput(t *testing.T) {
+	sm := NewSearchModel(false, "/", nil)
+	sm.inputs[searchMinSize].SetValue("lots")
+	sm, cmd := sm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || sm.running || !strings.Contains(sm.status, "min size") {
+		t.Errorf("status = %q, running = %v", sm.status, sm.running)
+	}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	sshclient "ssh-scp/internal/ssh"
+)
+
+// cwdHook is typed into the shell when shell integration is on. It makes
+// bash and zsh report the working directory with OSC 7 before every
+// prompt, and once right away. The leading space keeps it out of the
+// history where HISTCONTROL=ignorespace or HIST_IGNORE_SPACE is set.
+const cwdHook = ` __sshscp_osc7() { printf '\033]7;file://%s%s\033\\' "${HOSTNAME:-$HOST}" "$PWD"; }; ` +
+	`if [ -n "$ZSH_VERSION" ]; then precmd_functions+=(__sshscp_osc7); ` +
+	`else PROMPT_COMMAND="__sshscp_osc7${PROMPT_COMMAND:+;$PROMPT_COMMAND}"; fi; __sshscp_osc7` + "\r"
+
+// SetHostname sets the remote host's name. Working directories reported
+// by a shell on another host, such as a nested ssh session, are ignored.
+func (m *TerminalModel) SetHostname(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hostname = name
+}
+
+// SetShellIntegration makes StartSession install a prompt hook that
+// reports the shell's working directory.
+func (m *TerminalModel) SetShellIntegration(on bool) {
+	m.cwdHook = on
+}
+
+// sameHost reports whether an OSC 7 host names the remote host. Empty
+// hosts and localhost are accepted, and short names match the full name.
+// The caller holds m.mu.
+func (m *TerminalModel) sameHost(host string) bool {
+	if host == "" || m.hostname == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	short := func(h string) string {
+		name, _, _ := strings.Cut(h, ".")
+		return name
+	}
+	return strings.EqualFold(host, m.hostname) || strings.EqualFold(short(host), short(m.hostname))
+}
+
+// Cwd returns the shell's working directory as last reported, or "".
+func (m *TerminalModel) Cwd() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cwd
+}
+
+// TakeCwd returns the shell's working directory if it changed since the
+// last call.
+func (m *TerminalModel) TakeCwd() (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := m.cwdChanged
+	m.cwdChanged = false
+	return m.cwd, changed
+}
+
+// Cd makes the shell change to dir by typing a cd command, after clearing
+// any half-typed command line. It refuses while a full-screen program
+// runs, since the keys would go to that program.
+func (m *TerminalModel) Cd(dir string) error {
+	m.mu.Lock()
+	alt := m.term().AltScreen()
+	m.mu.Unlock()
+	if alt {
+		return errors.New("a full-screen program is running in the terminal")
+	}
+	m.notice = ""
+	return m.Write([]byte("\x15 cd -- " + sshclient.ShellQuote(dir) + "\r"))
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"io"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTerminalTakeCwd(t *testing.T) {
+	m := NewTerminalModel(nil)
+	m.SetHostname("web1.example.com")
+	if _, ok := m.TakeCwd(); ok {
+		t.Fatal("no directory has been reported yet")
+	}
+	m.AppendOutput([]byte("\x1b]7;file://web1/srv/my%20app\x07$ "))
+	if dir, ok := m.TakeCwd(); !ok || dir != "/srv/my app" {
+		t.Fatalf("TakeCwd = %q %v, want /srv/my app", dir, ok)
+	}
+	if _, ok := m.TakeCwd(); ok {
+		t.Error("an unchanged directory should not be reported twice")
+	}
+	m.AppendOutput([]byte("\x1b]7;file://web1/srv/my%20app\x07$ "))
+	if _, ok := m.TakeCwd(); ok {
+		t.Error("repeating the same directory is not a change")
+	}
+	// A nested ssh session on another host does not move the panel.
+	m.AppendOutput([]byte("\x1b]7;file://db2/var/lib\x07"))
+	if _, ok := m.TakeCwd(); ok || m.Cwd() != "/srv/my app" {
+		t.Errorf("a directory on another host should be ignored, cwd %q", m.Cwd())
+	}
+}
+
+func TestTerminalSameHost(t *testing.T) {
+	m := NewTerminalModel(nil)
+	m.SetHostname("web1.example.com")
+	for host, want := range map[string]bool{
+		"":                 true,
+		"localhost":        true,
+		"web1":             true,
+		"WEB1.example.com": true,
+		"web1.other.net":   true,
+		"web2":             false,
+	} {
+		if got := m.sameHost(host); got != want {
+			t.Errorf("sameHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestTerminalCd(t *testing.T) {
+	r, w := io.Pipe()
+	defer func() { _ = r.Close() }()
+	m := NewTerminalModel(nil)
+	m.SetStdinForTest(w)
+	got := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		n, _ := r.Read(buf)
+		got <- string(buf[:n])
+	}()
+	if err := m.Cd("/srv/it's here"); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-got; s != "\x15 cd -- '/srv/it'\\''s here'\r" {
+		t.Errorf("Cd typed %q", s)
+	}
+
+	m.AppendOutput([]byte("\x1b[?1049h"))
+	if err := m.Cd("/tmp"); err == nil {
+		t.Error("Cd should refuse while a full-screen program runs")
+	}
+}
+
+func TestFollowShellDir(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/home/u")
+	if cmd := m.FollowShellDir("/srv"); cmd == nil || m.remoteDir != "/srv" {
+		t.Fatalf("following should list the shell's directory, remote dir %q", m.remoteDir)
+	}
+	if m.followLabel() == "" {
+		t.Error("the remote header should show that it follows the shell")
+	}
+	if m.FollowShellDir("/srv") != nil {
+		t.Error("the same directory should not be listed again")
+	}
+
+	m.ToggleFollowShell()
+	if m.FollowShellDir("/var/log") != nil || m.remoteDir != "/srv" || m.followLabel() != "" {
+		t.Errorf("with following off the panel should stay put, remote dir %q", m.remoteDir)
+	}
+	if cmd := m.ToggleFollowShell(); cmd == nil || m.remoteDir != "/var/log" {
+		t.Errorf("turning following on should catch up with the shell, remote dir %q", m.remoteDir)
+	}
+}
+
+func TestCdTerminalKey(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv/app")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g"), Alt: true})
+	if cmd == nil {
+		t.Fatal("Alt+G should ask to cd the terminal")
+	}
+	if msg, ok := cmd().(CdTerminalMsg); !ok || msg.Dir != "/srv/app" {
+		t.Errorf("Alt+G sent %#v, want the remote directory", msg)
+	}
+}
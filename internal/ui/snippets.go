@@ -0,0 +1,385 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ssh-scp/internal/config"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SnippetTerminalMsg asks the app to paste a command into the tab's
+// terminal.
+type SnippetTerminalMsg struct {
+	Command string
+}
+
+// snippetRunMsg asks to run a command in the command runner.
+type snippetRunMsg struct{ command string }
+
+// snippetsCloseMsg is sent when the snippet picker is closed.
+type snippetsCloseMsg struct{}
+
+// Modes of the snippet picker.
+const (
+	snippetModeList = iota
+	snippetModeParams
+	snippetModeName
+	snippetModeCommand
+	snippetModeDelete
+)
+
+// SnippetsModel lists the host's and the global command snippets. Picking
+// one asks for its parameters and sends the command to the terminal or the
+// command runner. Snippets can also be added, edited and deleted.
+type SnippetsModel struct {
+	hostKey  string
+	snippets []config.Snippet
+	cursor   int
+	mode     int
+	input    textinput.Model
+	status   string
+	width    int
+	height   int
+
+	// Filling in the parameters of the snippet under the cursor.
+	toRunner bool
+	params   []config.SnippetParam
+	values   map[string]string
+	param    int
+
+	// Adding or editing a snippet.
+	editHost bool   // stored for the host rather than globally
+	editFrom string // name of the snippet being edited; "" when adding
+	editName string
+}
+
+// NewSnippetsModel loads the snippets of hostKey and the global ones.
+func NewSnippetsModel(hostKey string) SnippetsModel {
+	m := SnippetsModel{hostKey: hostKey}
+	m.reload()
+	return m
+}
+
+// SetDimensions sets the view's display dimensions.
+func (m *SnippetsModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m *SnippetsModel) reload() {
+	snippets, err := config.LoadSnippets(m.hostKey)
+	if err != nil {
+		log.Printf("[Snippets] load: %v", err)
+		m.status = "Some snippets could not be loaded: " + err.Error()
+	}
+	m.snippets = snippets
+	m.cursor = max(min(m.cursor, len(m.snippets)-1), 0)
+}
+
+func (m SnippetsModel) current() *config.Snippet {
+	if m.cursor >= len(m.snippets) {
+		return nil
+	}
+	return &m.snippets[m.cursor]
+}
+
+// scope returns the host key a snippet is stored under.
+func (m SnippetsModel) scope(host bool) string {
+	if host {
+		return m.hostKey
+	}
+	return ""
+}
+
+func (m *SnippetsModel) startInput(mode int, value string) tea.Cmd {
+	ti := textinput.New()
+	ti.CharLimit = 1024
+	ti.Width = max(m.width-30, 20)
+	ti.SetValue(value)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.mode = mode
+	return textinput.Blink
+}
+
+// Update handles key events for the picker.
+func (m SnippetsModel) Update(msg tea.Msg) (SnippetsModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch m.mode {
+	case snippetModeParams:
+		return m.handleParamKey(key)
+	case snippetModeName, snippetModeCommand:
+		return m.handleEditKey(key)
+	case snippetModeDelete:
+		if key.String() == "y" || key.String() == "Y" {
+			s := m.current()
+			if err := config.DeleteSnippet(m.scope(s.Host), s.Name); err != nil {
+				m.status = "Delete failed: " + err.Error()
+			} else {
+				m.status = "Deleted " + s.Name
+				m.reload()
+			}
+		} else {
+			m.status = ""
+		}
+		m.mode = snippetModeList
+		return m, nil
+	}
+
+	m.status = ""
+	switch key.String() {
+	case "esc", "q":
+		return m, func() tea.Msg { return snippetsCloseMsg{} }
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = max(min(m.cursor+1, len(m.snippets)-1), 0)
+	case "enter", "!":
+		if m.current() != nil {
+			return m.pick(key.String() == "!")
+		}
+	case "a":
+		if m.hostKey == "" {
+			return m.startEdit(false, config.Snippet{})
+		}
+		return m.startEdit(true, config.Snippet{})
+	case "A":
+		return m.startEdit(false, config.Snippet{})
+	case "e":
+		if s := m.current(); s != nil {
+			return m.startEdit(s.Host, *s)
+		}
+	case "d":
+		if s := m.current(); s != nil {
+			m.mode = snippetModeDelete
+			m.status = fmt.Sprintf("Delete snippet %q? (y/n)", s.Name)
+		}
+	}
+	return m, nil
+}
+
+// pick starts filling in the parameters of the snippet under the cursor,
+// or sends it straight away when it has none.
+func (m SnippetsModel) pick(toRunner bool) (SnippetsModel, tea.Cmd) {
+	m.toRunner = toRunner
+	m.params = m.current().Params()
+	m.values = map[string]string{}
+	m.param = 0
+	if len(m.params) == 0 {
+		return m, m.send()
+	}
+	return m, m.startInput(snippetModeParams, m.params[0].Default)
+}
+
+// send closes the picker and sends the expanded snippet.
+func (m SnippetsModel) send() tea.Cmd {
+	cmd := m.current().Expand(m.values)
+	closeCmd := func() tea.Msg { return snippetsCloseMsg{} }
+	if m.toRunner {
+		return tea.Batch(closeCmd, func() tea.Msg { return snippetRunMsg{command: cmd} })
+	}
+	return tea.Batch(closeCmd, func() tea.Msg { return SnippetTerminalMsg{Command: cmd} })
+}
+
+func (m SnippetsModel) handleParamKey(key tea.KeyMsg) (SnippetsModel, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.mode = snippetModeList
+		return m, nil
+	case "enter", "tab":
+		m.values[m.params[m.param].Name] = m.input.Value()
+		if m.param == len(m.params)-1 {
+			if key.String() == "tab" {
+				return m, nil
+			}
+			m.mode = snippetModeList
+			return m, m.send()
+		}
+		m.param++
+		return m, m.startInput(snippetModeParams, m.paramValue(m.param))
+	case "shift+tab":
+		if m.param > 0 {
+			m.values[m.params[m.param].Name] = m.input.Value()
+			m.param--
+			return m, m.startInput(snippetModeParams, m.paramValue(m.param))
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(key)
+	return m, cmd
+}
+
+// paramValue returns what was typed for parameter i, or its default.
+func (m SnippetsModel) paramValue(i int) string {
+	if v, ok := m.values[m.params[i].Name]; ok {
+		return v
+	}
+	return m.params[i].Default
+}
+
+// preview returns the command as it would be sent with the values typed
+// so far.
+func (m SnippetsModel) preview() string {
+	values := map[string]string{}
+	for k, v := range m.values {
+		values[k] = v
+	}
+	values[m.params[m.param].Name] = m.input.Value()
+	return m.current().Expand(values)
+}
+
+func (m SnippetsModel) startEdit(host bool, s config.Snippet) (SnippetsModel, tea.Cmd) {
+	m.editHost = host
+	m.editFrom = s.Name
+	m.editName = ""
+	return m, m.startInput(snippetModeName, s.Name)
+}
+
+func (m SnippetsModel) handleEditKey(key tea.KeyMsg) (SnippetsModel, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.mode = snippetModeList
+		return m, nil
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		if value == "" {
+			return m, nil
+		}
+		if m.mode == snippetModeName {
+			m.editName = value
+			command := ""
+			for _, s := range m.snippets {
+				if s.Name == m.editFrom && s.Host == m.editHost {
+					command = s.Command
+				}
+			}
+			return m, m.startInput(snippetModeCommand, command)
+		}
+		m.mode = snippetModeList
+		m.save(config.Snippet{Name: m.editName, Command: value})
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(key)
+	return m, cmd
+}
+
+// save stores an added or edited snippet, keeping the description of the
+// one it replaces.
+func (m *SnippetsModel) save(s config.Snippet) {
+	scope := m.scope(m.editHost)
+	for _, old := range m.snippets {
+		if old.Name == m.editFrom && old.Host == m.editHost {
+			s.Description = old.Description
+		}
+	}
+	if err := config.SaveSnippet(scope, s); err != nil {
+		m.status = "Save failed: " + err.Error()
+		return
+	}
+	if m.editFrom != "" && m.editFrom != s.Name {
+		if err := config.DeleteSnippet(scope, m.editFrom); err != nil {
+			log.Printf("[Snippets] remove renamed snippet: %v", err)
+		}
+	}
+	m.reload()
+	for i, c := range m.snippets {
+		if c.Name == s.Name {
+			m.cursor = i
+		}
+	}
+	m.status = "Saved " + s.Name
+}
+
+// View renders the picker.
+func (m SnippetsModel) View() string {
+	width := max(m.width-4, 20)
+	lines := []string{messageStyle.Render("Snippets"), ""}
+	if len(m.snippets) == 0 {
+		lines = append(lines, statusBarStyle.Render("No snippets yet — press a to add one, e.g. journalctl -u {{unit}} --since '{{since:1 hour ago}}'"))
+	}
+	nameWidth := 12
+	for _, s := range m.snippets {
+		nameWidth = max(nameWidth, min(len(s.Name), 30))
+	}
+	vis := max(m.height-9, 1)
+	start := max(min(m.cursor-vis/2, len(m.snippets)-vis), 0)
+	for i := start; i < len(m.snippets) && i < start+vis; i++ {
+		s := m.snippets[i]
+		scope := "global"
+		if s.Host {
+			scope = "host"
+		}
+		text := s.Command
+		if s.Description != "" {
+			text = s.Description + " — " + s.Command
+		}
+		line := truncate(fmt.Sprintf("%-*s  %-6s  %s", nameWidth, truncate(s.Name, nameWidth), scope, text), width)
+		if i == m.cursor {
+			line = fileSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	for i := len(lines); i < vis+2; i++ {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "")
+
+	target := "terminal"
+	if m.toRunner {
+		target = "command runner"
+	}
+	switch m.mode {
+	case snippetModeParams:
+		p := m.params[m.param]
+		lines = append(lines,
+			statusBarStyle.Render(truncate(fmt.Sprintf("To the %s: %s", target, m.preview()), width)),
+			messageStyle.Render(fmt.Sprintf("%s (%d/%d): ", p.Name, m.param+1, len(m.params)))+m.input.View())
+	case snippetModeName:
+		where := "global"
+		if m.editHost {
+			where = "for this host"
+		}
+		lines = append(lines, statusBarStyle.Render("New "+where+" snippet"), messageStyle.Render("Name: ")+m.input.View())
+	case snippetModeCommand:
+		lines = append(lines, statusBarStyle.Render("Parameters are written {{name}} or {{name:default}}"),
+			messageStyle.Render("Command: ")+m.input.View())
+	default:
+		status := m.status
+		if status == "" {
+			status = "Enter: paste into the terminal • !: run in the command runner"
+		}
+		lines = append(lines, messageStyle.Render(truncate(status, width)),
+			statusBarStyle.Render("a: add for this host • A: add global • e: edit • d: delete • Esc: close"))
+	}
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// OpenSnippets shows the snippet picker for the tab's host.
+func (m *FileBrowserModel) OpenSnippets() {
+	sm := NewSnippetsModel(m.historyHost)
+	m.snippets = &sm
+}
+
+// handleSnippetMsg applies the actions of the snippet picker.
+func (m FileBrowserModel) handleSnippetMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case snippetsCloseMsg:
+		m.snippets = nil
+	case snippetRunMsg:
+		m.ensureRunner()
+		m.runnerShown = true
+		m.runner.dir = m.remoteDir
+		return m, m.runner.Run(m.remoteDir, msg.command)
+	}
+	return m, nil
+}
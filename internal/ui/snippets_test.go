@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"ssh-scp/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeSnippets(m SnippetsModel, s string) SnippetsModel {
+	for _, r := range s {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+// sentSnippet returns the message a picked snippet sends besides closing
+// the picker.
+func sentSnippet(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("picking a snippet should send it")
+	}
+	var sent tea.Msg
+	closed := false
+	for _, c := range cmd().(tea.BatchMsg) {
+		switch msg := c().(type) {
+		case snippetsCloseMsg:
+			closed = true
+		default:
+			sent = msg
+		}
+	}
+	if !closed {
+		t.Error("picking a snippet should close the picker")
+	}
+	return sent
+}
+
+func TestSnippetParameterPrompts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := config.Snippet{Name: "logs", Command: "journalctl -u {{unit}} --since '{{since:1 hour ago}}' | grep {{unit}}"}
+	if err := config.SaveSnippet("", s); err != nil {
+		t.Fatal(err)
+	}
+	m := NewSnippetsModel("u@h:22")
+	m.SetDimensions(120, 30)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != snippetModeParams || m.input.Value() != "" {
+		t.Fatalf("Enter should ask for the first parameter, mode %d", m.mode)
+	}
+	m = typeSnippets(m, "nginx")
+	if !strings.Contains(m.View(), "journalctl -u nginx --since '1 hour ago' | grep nginx") {
+		t.Error("the prompt should preview the expanded command")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.input.Value() != "1 hour ago" {
+		t.Errorf("the second parameter should start at its default, got %q", m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if m.input.Value() != "nginx" {
+		t.Errorf("Shift+Tab should go back to the typed value, got %q", m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg, ok := sentSnippet(t, cmd).(SnippetTerminalMsg)
+	if !ok || msg.Command != "journalctl -u nginx --since '1 hour ago' | grep nginx" {
+		t.Errorf("sent %+v, want the expanded command for the terminal", msg)
+	}
+}
+
+func TestSnippetAddEditDelete(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := config.SaveSnippet("", config.Snippet{Name: "disk", Command: "df -h"}); err != nil {
+		t.Fatal(err)
+	}
+	m := NewSnippetsModel("u@h:22")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = typeSnippets(m, "disk")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = typeSnippets(m, "df -h /srv")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.snippets) != 1 || !m.snippets[0].Host || m.snippets[0].Command != "df -h /srv" {
+		t.Fatalf("a host snippet should hide the global one of the same name, got %+v", m.snippets)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if m.input.Value() != "disk" {
+		t.Fatalf("e should start with the snippet's name, got %q", m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = typeSnippets(m, "k-srv")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.input.Value() != "df -h /srv" {
+		t.Fatalf("editing should start with the snippet's command, got %q", m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.snippets) != 2 || m.snippets[0].Name != "disk-srv" || m.snippets[1].Host {
+		t.Fatalf("renaming should replace the host snippet and show the global one again, got %+v", m.snippets)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if len(m.snippets) != 2 {
+		t.Fatal("n should keep the snippet")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if len(m.snippets) != 1 || m.snippets[0].Name != "disk" {
+		t.Errorf("y should delete the snippet, got %+v", m.snippets)
+	}
+}
+
+func TestSnippetToRunner(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := config.SaveSnippet("", config.Snippet{Name: "ls", Command: "ls {{dir:.}}"}); err != nil {
+		t.Fatal(err)
+	}
+	b := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	rm := NewRunnerModel(fakeExec([]string{"app"}, nil, 0), "")
+	b.runner = &rm
+	b.OpenSnippets()
+	if !b.OverlayActive() || !b.InputActive() {
+		t.Fatal("the snippet picker should cover the browser")
+	}
+	b, _ = b.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	b, cmd := b.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for _, c := range cmd().(tea.BatchMsg) {
+		b, _ = b.Update(c())
+	}
+	if b.snippets != nil || !b.runnerShown || b.runner.current().cmd != "ls ." || b.runner.current().dir != "/srv" {
+		t.Error("! should run the expanded snippet in the command runner")
+	}
+	b.runner.Stop()
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"errors"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SudoPasswordNeededMsg asks the app to prompt for the sudo password of the
+// active tab.
+type SudoPasswordNeededMsg struct{}
+
+// SudoDoneMsg reports the result of turning sudo mode on.
+type SudoDoneMsg struct {
+	Err error
+}
+
+// SudoLoginCmd returns a command that verifies and caches a sudo password.
+func SudoLoginCmd(client *sshclient.Client, password string, ttl time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		return SudoDoneMsg{Err: client.SudoLogin(password, ttl)}
+	}
+}
+
+// sudoPromptCmd returns a command asking for the sudo password if err means
+// that the cached password is missing or has expired, and nil otherwise.
+func sudoPromptCmd(err error) tea.Cmd {
+	if !errors.Is(err, sshclient.ErrSudoPassword) {
+		return nil
+	}
+	return func() tea.Msg { return SudoPasswordNeededMsg{} }
+}
+
+// toggleSudo turns sudo mode off, or starts turning it on. Turning it on may
+// need the sudo password, which the app then prompts for.
+func (m FileBrowserModel) toggleSudo() (FileBrowserModel, tea.Cmd) {
+	if m.client == nil {
+		return m, nil
+	}
+	if m.client.SudoEnabled() {
+		m.client.DisableSudo()
+		m.statusMsg = "sudo mode off"
+		return m, refreshRemoteCmd(m.client, m.remoteDir)
+	}
+	m.statusMsg = "Enabling sudo mode..."
+	client := m.client
+	return m, func() tea.Msg {
+		err := client.EnableSudo()
+		if errors.Is(err, sshclient.ErrSudoPassword) {
+			return SudoPasswordNeededMsg{}
+		}
+		return SudoDoneMsg{Err: err}
+	}
+}
+
+// sudoLabel returns the remote panel marker for sudo mode.
+func (m FileBrowserModel) sudoLabel() string {
+	if m.client != nil && m.client.SudoEnabled() {
+		return " [sudo]"
+	}
+	return ""
+}
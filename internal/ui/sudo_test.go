@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	sshclient "ssh-scp/internal/ssh"
+)
+
+func TestSudoPromptCmd(t *testing.T) {
+	if sudoPromptCmd(errors.New("permission denied")) != nil {
+		t.Error("other errors should not prompt")
+	}
+	cmd := sudoPromptCmd(fmt.Errorf("write: %w", sshclient.ErrSudoPassword))
+	if cmd == nil {
+		t.Fatal("wrapped ErrSudoPassword should prompt")
+	}
+	if _, ok := cmd().(SudoPasswordNeededMsg); !ok {
+		t.Error("expected SudoPasswordNeededMsg")
+	}
+}
+
+func TestFBFileOpSudoPasswordPrompts(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/etc")
+	_, cmd := m.Update(FileOpDoneMsg{Op: opDelete, Err: sshclient.ErrSudoPassword})
+	if cmd == nil {
+		t.Fatal("expired sudo password should trigger a prompt")
+	}
+	if _, ok := cmd().(SudoPasswordNeededMsg); !ok {
+		t.Error("expected SudoPasswordNeededMsg")
+	}
+}
+
+func TestFBTransferSudoPasswordPrompts(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/etc")
+	m.transferring = true
+	_, cmd := m.Update(TransferDoneMsg{Err: sshclient.ErrSudoPassword})
+	if cmd == nil {
+		t.Fatal("expired sudo password should trigger a prompt")
+	}
+}
+
+func TestFBSudoDone(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/etc")
+	m, _ = m.Update(SudoDoneMsg{Err: errors.New("sudo: incorrect password")})
+	if !strings.Contains(m.statusMsg, "incorrect password") {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+	m, cmd := m.Update(SudoDoneMsg{})
+	if cmd == nil || !strings.Contains(m.statusMsg, "sudo mode on") {
+		t.Errorf("success should refresh the listing, status=%q", m.statusMsg)
+	}
+}
+
+func TestFBAltSWithoutClient(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/etc")
+	if _, cmd := m.toggleSudo(); cmd != nil {
+		t.Error("toggleSudo without a client should do nothing")
+	}
+	if m.sudoLabel() != "" {
+		t.Error("no sudo label without a client")
+	}
+}
@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// localLink describes where a local symlink points.
+type localLink struct {
+	target string
+	dir    bool // target is a directory
+	broken bool // target does not exist
+}
+
+var (
+	linkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#56F4B0"))
+
+	brokenLinkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F45656"))
+)
+
+// readLocalLinks resolves the symlinks among files in dir.
+func readLocalLinks(dir string, files []os.FileInfo) map[string]localLink {
+	links := make(map[string]localLink)
+	for _, f := range files {
+		if f.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		p := filepath.Join(dir, f.Name())
+		l := localLink{}
+		l.target, _ = os.Readlink(p)
+		if info, err := os.Stat(p); err != nil {
+			l.broken = true
+		} else {
+			l.dir = info.IsDir()
+		}
+		links[f.Name()] = l
+	}
+	return links
+}
+
+// localIsDir reports whether a local entry is a directory or a symlink to
+// one, i.e. whether Enter navigates into it.
+func (m FileBrowserModel) localIsDir(f os.FileInfo) bool {
+	return f.IsDir() || m.localLinks[f.Name()].dir
+}
+
+// remoteIsDir reports whether a remote entry is a directory or a symlink to
+// one.
+func remoteIsDir(f sshclient.RemoteFile) bool {
+	return f.IsDir || f.LinkDir
+}
+
+// renderLinkRow renders a symlink as "name -> target", flagging broken
+// links.
+func renderLinkRow(name, target string, dir, broken bool, width int) string {
+	if dir {
+		name += "/"
+	}
+	label := "↪ " + name + " -> " + target
+	switch {
+	case broken:
+		return brokenLinkStyle.Render(truncate(label+" (broken)", width))
+	case dir:
+		return linkStyle.Bold(true).Render(truncate(label, width))
+	}
+	return linkStyle.Render(truncate(label, width))
+}
+
+// selectedLink returns the target of the selected entry and whether it is
+// broken; ok is false when the selection is not a symlink.
+func (m FileBrowserModel) selectedLink() (target string, broken, ok bool) {
+	if m.focus == panelLocal {
+		if len(m.localFiles) == 0 {
+			return "", false, false
+		}
+		l, ok := m.localLinks[m.localFiles[m.localCursor].Name()]
+		return l.target, l.broken, ok
+	}
+	if len(m.remoteFiles) == 0 {
+		return "", false, false
+	}
+	f := m.remoteFiles[m.remoteCursor]
+	return f.LinkTarget, f.LinkBroken, f.IsLink()
+}
+
+// linkTargetPath returns the path of the selected symlink's target,
+// resolved against the link's directory. Only the link itself is resolved,
+// not links in the target.
+func (m FileBrowserModel) linkTargetPath() string {
+	target, _, _ := m.selectedLink()
+	if m.focus == panelLocal {
+		if filepath.IsAbs(target) {
+			return filepath.Clean(target)
+		}
+		return filepath.Join(m.localDir, target)
+	}
+	if path.IsAbs(target) {
+		return path.Clean(target)
+	}
+	return path.Join(m.remoteDir, target)
+}
+
+// askLinkChoice asks whether op acts on the selected symlink or on its
+// target. Transfers remember compress for when the answer comes.
+func (m FileBrowserModel) askLinkChoice(op fileOpKind, compress bool) FileBrowserModel {
+	name := m.selectedName()
+	target, _, _ := m.selectedLink()
+	m.linkOp = op
+	m.linkCompress = compress
+	m.linkTarget = false
+	var prompt string
+	switch op {
+	case opDelete:
+		prompt = fmt.Sprintf("Delete link '%s' or its target '%s'? (l/t):", name, target)
+	case opRename:
+		prompt = fmt.Sprintf("Rename link '%s' or its target '%s'? (l/t):", name, target)
+	default:
+		prompt = fmt.Sprintf("Transfer '%s' as a link or copy its target '%s'? (l/t):", name, target)
+	}
+	m.startInput(opLinkChoice, prompt)
+	return m
+}
+
+// answerLinkChoice continues the operation asked about by askLinkChoice:
+// "l" acts on the link, "t" on its target.
+func (m FileBrowserModel) answerLinkChoice(answer string) (FileBrowserModel, tea.Cmd) {
+	switch strings.ToLower(answer) {
+	case "l", "link":
+		m.linkTarget = false
+	case "t", "target":
+		m.linkTarget = true
+	default:
+		m.statusMsg = "Cancelled"
+		return m, nil
+	}
+	name := m.selectedName()
+	switch m.linkOp {
+	case opDelete:
+		if m.linkTarget {
+			m.startInput(opDelete, fmt.Sprintf("Delete '%s', the target of '%s'? (y/yes to confirm):", m.linkTargetPath(), name))
+		} else {
+			m.startInput(opDelete, fmt.Sprintf("Delete link '%s' (target is kept)? (y/yes to confirm):", name))
+		}
+		return m, nil
+	case opRename:
+		if m.linkTarget {
+			m.startInput(opRename, "Rename target '"+m.linkTargetPath()+"' to:")
+		} else {
+			m.startInput(opRename, "Rename link '"+name+"' to:")
+		}
+		return m, nil
+	}
+	if m.transferring {
+		return m, nil
+	}
+	if !m.linkTarget {
+		target, _, _ := m.selectedLink()
+		return m.copyLink(target)
+	}
+	if m.focus == panelLocal && m.localLinks[name].dir || m.focus == panelRemote && m.remoteFiles[m.remoteCursor].LinkDir {
+		m.statusMsg = "Directories are not transferred; choose l to recreate the link"
+		return m, nil
+	}
+	return m.transferEntry(m.linkCompress)
+}
+
+// startSymlink opens the input for creating a symlink in the focused panel,
+// prefilled to point at the selected entry.
+func (m FileBrowserModel) startSymlink() (FileBrowserModel, tea.Cmd) {
+	m.startInput(opSymlink, "New symlink (name -> target):")
+	if name := m.selectedName(); name != "" && name != ".." {
+		m.inputModel.SetValue(" -> " + name)
+		m.inputModel.CursorStart()
+	}
+	return m, nil
+}
+
+// parseLinkSpec splits "name -> target" as entered in the symlink prompt.
+func parseLinkSpec(spec string) (name, target string, err error) {
+	name, target, found := strings.Cut(spec, "->")
+	name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+	if !found || name == "" || target == "" {
+		return "", "", errors.New("expected: name -> target")
+	}
+	if strings.Contains(name, "/") {
+		return "", "", errors.New("link name must not contain '/'")
+	}
+	return name, target, nil
+}
+
+// executeSymlink creates a symlink locally or remotely.
+func (m FileBrowserModel) executeSymlink(spec string) (FileBrowserModel, tea.Cmd) {
+	name, target, err := parseLinkSpec(spec)
+	if err != nil {
+		m.statusMsg = "Invalid symlink: " + err.Error()
+		return m, nil
+	}
+	if m.focus == panelLocal {
+		linkPath := filepath.Join(m.localDir, name)
+		m.statusMsg = "Creating symlink..."
+		return m, func() tea.Msg {
+			return FileOpDoneMsg{Op: opSymlink, Err: os.Symlink(target, linkPath)}
+		}
+	}
+	linkPath := joinRemotePath(m.remoteDir, name)
+	client := m.client
+	m.statusMsg = "Creating remote symlink..."
+	return m, func() tea.Msg {
+		return FileOpDoneMsg{Op: opSymlink, Err: client.Symlink(target, linkPath)}
+	}
+}
+
+// copyLink recreates a symlink on the other side instead of transferring
+// its target. Transfers use it when asked to and for broken links, which
+// cannot be followed.
+func (m FileBrowserModel) copyLink(target string) (FileBrowserModel, tea.Cmd) {
+	name := m.selectedName()
+	m.transferring = true
+	m.transferProgress = name
+	client := m.client
+	detail := fmt.Sprintf("symlink -> %s recreated", target)
+	if m.focus == panelLocal {
+		remotePath := joinRemotePath(m.remoteDir, name)
+		m.statusMsg = "Creating remote symlink " + name + "..."
+		return m, func() tea.Msg {
+			return TransferDoneMsg{Err: client.Symlink(target, remotePath), Detail: detail}
+		}
+	}
+	localPath := filepath.Join(m.localDir, name)
+	m.statusMsg = "Creating local symlink " + name + "..."
+	return m, func() tea.Msg {
+		return TransferDoneMsg{Err: os.Symlink(target, localPath), Detail: detail}
+	}
+}
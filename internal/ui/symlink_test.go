@@ -0,0 +1,326 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// symlinkDir creates a directory with a subdirectory, a file and links to
+// both plus a broken link.
+func symlinkDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"dirlink": "sub", "filelink": "file.txt", "dangling": "nowhere"} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// selectLocal moves the local cursor to the named entry.
+func selectLocal(t *testing.T, m *FileBrowserModel, name string) {
+	t.Helper()
+	for i, f := range m.localFiles {
+		if f.Name() == name {
+			m.localCursor = i
+			return
+		}
+	}
+	t.Fatalf("%s not in local listing", name)
+}
+
+func TestReadLocalLinks(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/")
+	if len(m.localLinks) != 3 {
+		t.Fatalf("localLinks = %v, want 3 links", m.localLinks)
+	}
+	if l := m.localLinks["dirlink"]; !l.dir || l.broken || l.target != "sub" {
+		t.Errorf("dirlink = %+v", l)
+	}
+	if l := m.localLinks["filelink"]; l.dir || l.broken {
+		t.Errorf("filelink = %+v", l)
+	}
+	if l := m.localLinks["dangling"]; !l.broken {
+		t.Errorf("dangling = %+v, want broken", l)
+	}
+}
+
+func TestFBEnterFollowsLocalDirLink(t *testing.T) {
+	dir := symlinkDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	selectLocal(t, &m, "dirlink")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.localDir != filepath.Join(dir, "dirlink") {
+		t.Errorf("localDir = %q, want the link path", m.localDir)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.localDir != dir {
+		t.Errorf("Backspace should return to %q, got %q", dir, m.localDir)
+	}
+}
+
+func TestFBEnterFollowsRemoteDirLink(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m.focus = panelRemote
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "current", Mode: os.ModeSymlink | 0o777, LinkTarget: "releases/42", LinkDir: true},
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.remoteDir != "/srv/current" || cmd == nil {
+		t.Errorf("remoteDir = %q, want /srv/current with a refresh", m.remoteDir)
+	}
+}
+
+func TestFBRendersLinks(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/srv")
+	m.SetDimensions(160, 30)
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "www", Mode: os.ModeSymlink | 0o777, LinkTarget: "/var/www", LinkDir: true},
+		{Name: "old", Mode: os.ModeSymlink | 0o777, LinkTarget: "gone", LinkBroken: true},
+	}
+	view := m.View()
+	for _, want := range []string{"dirlink/ -> sub", "filelink -> file.txt", "dangling -> nowhere (broken)", "www/ -> /var/www", "old -> gone (broken)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+}
+
+// answer types value into the open input and presses Enter.
+func answer(m FileBrowserModel, value string) (FileBrowserModel, tea.Cmd) {
+	m.inputModel.SetValue(value)
+	return m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestFBDeleteLinkPrompt(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/")
+	selectLocal(t, &m, "dirlink")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	if m.inputOp != opLinkChoice || !strings.Contains(m.inputPrompt, "Delete link 'dirlink' or its target 'sub'") {
+		t.Fatalf("prompt = %q", m.inputPrompt)
+	}
+	m, _ = answer(m, "l")
+	if m.inputOp != opDelete || !strings.Contains(m.inputPrompt, "Delete link 'dirlink' (target is kept)") {
+		t.Errorf("prompt = %q", m.inputPrompt)
+	}
+}
+
+func TestFBDeleteLinkChoiceCancelled(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/")
+	selectLocal(t, &m, "filelink")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	m, cmd := answer(m, "x")
+	if m.inputActive || cmd != nil || m.statusMsg != "Cancelled" {
+		t.Errorf("an unknown answer should cancel, got %q", m.statusMsg)
+	}
+}
+
+func TestFBDeleteBrokenLinkAsksNoChoice(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/")
+	selectLocal(t, &m, "dangling")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	if m.inputOp != opDelete {
+		t.Errorf("a broken link has no target to delete, prompt = %q", m.inputPrompt)
+	}
+}
+
+func TestFBDeleteLinkTarget(t *testing.T) {
+	dir := symlinkDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	selectLocal(t, &m, "filelink")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	m, _ = answer(m, "t")
+	if !strings.Contains(m.inputPrompt, filepath.Join(dir, "file.txt")) {
+		t.Errorf("prompt = %q, want the target path", m.inputPrompt)
+	}
+	m, cmd := answer(m, "y")
+	if msg := cmd().(FileOpDoneMsg); msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file.txt")); !os.IsNotExist(err) {
+		t.Error("target should be removed")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "filelink")); err != nil {
+		t.Error("link should be kept")
+	}
+}
+
+func TestFBRenameLinkTarget(t *testing.T) {
+	dir := symlinkDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	selectLocal(t, &m, "filelink")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m, _ = answer(m, "target")
+	if m.inputOp != opRename || !strings.Contains(m.inputPrompt, "Rename target") {
+		t.Fatalf("prompt = %q", m.inputPrompt)
+	}
+	m, cmd := answer(m, "renamed.txt")
+	if msg := cmd().(FileOpDoneMsg); msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "renamed.txt")); err != nil {
+		t.Error("target should be renamed")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "filelink")); err != nil {
+		t.Error("link should keep its name")
+	}
+}
+
+func TestFBRenameRemoteLink(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/srv")
+	m.focus = panelRemote
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "current", Mode: os.ModeSymlink | 0o777, LinkTarget: "releases/42", LinkDir: true},
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	m, _ = answer(m, "t")
+	if got := m.linkTargetPath(); got != "/srv/releases/42" {
+		t.Errorf("linkTargetPath = %q", got)
+	}
+	if m.inputPrompt != "Rename target '/srv/releases/42' to:" {
+		t.Errorf("prompt = %q", m.inputPrompt)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.linkTarget {
+		t.Error("a new rename should not keep the earlier answer")
+	}
+	m, _ = answer(m, "l")
+	if m.inputPrompt != "Rename link 'current' to:" {
+		t.Errorf("prompt = %q", m.inputPrompt)
+	}
+	m.remoteFiles[0].LinkTarget = "/opt/app/../app2"
+	if got := m.linkTargetPath(); got != "/opt/app2" {
+		t.Errorf("absolute linkTargetPath = %q", got)
+	}
+}
+
+func TestFBDeleteLinkKeepsTarget(t *testing.T) {
+	dir := symlinkDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	selectLocal(t, &m, "dirlink")
+	_, cmd := m.executeDelete()
+	if msg := cmd().(FileOpDoneMsg); msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "dirlink")); !os.IsNotExist(err) {
+		t.Error("link should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); err != nil {
+		t.Error("link target should be kept")
+	}
+}
+
+func TestParseLinkSpec(t *testing.T) {
+	name, target, err := parseLinkSpec(" latest ->  releases/v2 ")
+	if err != nil || name != "latest" || target != "releases/v2" {
+		t.Errorf("got %q %q %v", name, target, err)
+	}
+	for _, bad := range []string{"latest", "-> x", "x ->", "a/b -> c"} {
+		if _, _, err := parseLinkSpec(bad); err == nil {
+			t.Errorf("parseLinkSpec(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFBCreateLocalSymlink(t *testing.T) {
+	dir := symlinkDir(t)
+	m := NewFileBrowserModel(nil, dir, "/")
+	selectLocal(t, &m, "file.txt")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l"), Alt: true})
+	if !m.inputActive || m.inputModel.Value() != " -> file.txt" {
+		t.Fatalf("Alt+L should prefill the target, got %q", m.inputModel.Value())
+	}
+	m.inputModel.SetValue("alias -> file.txt")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg := cmd().(FileOpDoneMsg)
+	if msg.Err != nil || msg.Op != opSymlink {
+		t.Fatalf("unexpected result %+v", msg)
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "alias")); err != nil || target != "file.txt" {
+		t.Errorf("alias -> %q (%v)", target, err)
+	}
+	m, _ = m.Update(msg)
+	if m.statusMsg != "Symlink created" {
+		t.Errorf("statusMsg = %q", m.statusMsg)
+	}
+}
+
+func TestFBTransferBrokenRemoteLinkRecreatesIt(t *testing.T) {
+	local := t.TempDir()
+	m := NewFileBrowserModel(nil, local, "/srv")
+	m.focus = panelRemote
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "old", Mode: os.ModeSymlink | 0o777, LinkTarget: "gone", LinkBroken: true},
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if !m.transferring || cmd == nil {
+		t.Fatal("Ctrl+T on a broken link should start a transfer")
+	}
+	done := cmd().(TransferDoneMsg)
+	if done.Err != nil || !strings.Contains(done.Detail, "symlink") {
+		t.Errorf("unexpected result %+v", done)
+	}
+	if target, err := os.Readlink(filepath.Join(local, "old")); err != nil || target != "gone" {
+		t.Errorf("local link -> %q (%v)", target, err)
+	}
+}
+
+func TestFBTransferDirLinkTarget(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/")
+	selectLocal(t, &m, "dirlink")
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if m.inputOp != opLinkChoice || cmd != nil {
+		t.Fatalf("Ctrl+T on a link should ask, prompt = %q", m.inputPrompt)
+	}
+	m, cmd = answer(m, "t")
+	if m.transferring || cmd != nil {
+		t.Error("links to directories should not be followed")
+	}
+}
+
+func TestFBTransferRemoteLinkAsLink(t *testing.T) {
+	local := t.TempDir()
+	m := NewFileBrowserModel(nil, local, "/srv")
+	m.focus = panelRemote
+	m.remoteFiles = []sshclient.RemoteFile{
+		{Name: "current", Mode: os.ModeSymlink | 0o777, LinkTarget: "releases/42", LinkDir: true},
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	m, cmd := answer(m, "l")
+	if !m.transferring || cmd == nil {
+		t.Fatal("l should recreate the link")
+	}
+	if done := cmd().(TransferDoneMsg); done.Err != nil {
+		t.Fatal(done.Err)
+	}
+	if target, err := os.Readlink(filepath.Join(local, "current")); err != nil || target != "releases/42" {
+		t.Errorf("local link -> %q (%v)", target, err)
+	}
+}
+
+func TestFBTransferLinkTargetKeepsCompression(t *testing.T) {
+	m := NewFileBrowserModel(nil, symlinkDir(t), "/srv")
+	selectLocal(t, &m, "filelink")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t"), Alt: true})
+	if !m.linkCompress {
+		t.Error("Alt+T should remember compression for the answer")
+	}
+	m, cmd := answer(m, "t")
+	if !m.transferring || cmd == nil || !strings.Contains(m.statusMsg, "Uploading filelink") {
+		t.Errorf("t should upload the target's content, status %q", m.statusMsg)
+	}
+}
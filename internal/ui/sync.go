@@ -0,0 +1,684 @@
+package ui
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// OpenSyncMsg requests the directory sync dialog for the two panel directories.
+type OpenSyncMsg struct {
+	LocalDir  string
+	RemoteDir string
+}
+
+// SyncScanDoneMsg carries the differences found between the two trees.
+// Skipped lists the remote directories that could not be read; they are
+// left out of the comparison.
+type SyncScanDoneMsg struct {
+	Items   []SyncItem
+	Skipped []string
+	Err     error
+}
+
+// SyncApplyDoneMsg reports the result of applying a sync plan.
+type SyncApplyDoneMsg struct {
+	Applied int
+	Errs    []error
+}
+
+// SyncCloseMsg requests closing the sync dialog.
+type SyncCloseMsg struct{}
+
+// SyncDirection selects which way changes are copied.
+type SyncDirection int
+
+const (
+	SyncPush   SyncDirection = iota // local → remote
+	SyncPull                        // remote → local
+	SyncTwoWay                      // newer side wins
+)
+
+func (d SyncDirection) String() string {
+	switch d {
+	case SyncPush:
+		return "local → remote"
+	case SyncPull:
+		return "remote → local"
+	default:
+		return "two-way (newer wins)"
+	}
+}
+
+// SyncCompare selects how files present on both sides are compared.
+type SyncCompare int
+
+const (
+	CompareSizeTime SyncCompare = iota // size and modification time
+	CompareChecksum                    // SHA-256 of the content
+)
+
+func (c SyncCompare) String() string {
+	if c == CompareChecksum {
+		return "checksum"
+	}
+	return "size + mtime"
+}
+
+// SyncDiff classifies how a path differs between the two trees.
+type SyncDiff int
+
+const (
+	DiffOnlyLocal  SyncDiff = iota // exists only in the local tree
+	DiffOnlyRemote                 // exists only in the remote tree
+	DiffChanged                    // exists on both sides with different content
+)
+
+// SyncAction is the operation planned for a SyncItem.
+type SyncAction int
+
+const (
+	ActionSkip SyncAction = iota
+	ActionUpload
+	ActionDownload
+	ActionMkdirRemote
+	ActionMkdirLocal
+	ActionDeleteRemote
+	ActionDeleteLocal
+)
+
+func (a SyncAction) String() string {
+	switch a {
+	case ActionUpload:
+		return "upload"
+	case ActionDownload:
+		return "download"
+	case ActionMkdirRemote:
+		return "mkdir remote"
+	case ActionMkdirLocal:
+		return "mkdir local"
+	case ActionDeleteRemote:
+		return "delete remote"
+	case ActionDeleteLocal:
+		return "delete local"
+	default:
+		return "skip"
+	}
+}
+
+// SyncItem is one difference between the trees and the action planned for it.
+type SyncItem struct {
+	Path    string // slash-separated, relative to the sync roots
+	Diff    SyncDiff
+	IsDir   bool
+	Local   *sshclient.TreeEntry
+	Remote  *sshclient.TreeEntry
+	Action  SyncAction
+	Enabled bool
+}
+
+// mtimeTolerance absorbs timestamp granularity differences between file
+// systems when comparing by modification time.
+const mtimeTolerance = 2 * time.Second
+
+// localTree recursively lists root in the same form as Client.ListTree.
+func localTree(root string) ([]sshclient.TreeEntry, error) {
+	var entries []sshclient.TreeEntry
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		if !d.Type().IsRegular() && !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, sshclient.TreeEntry{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   d.IsDir(),
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// compareTrees returns the paths that differ between the local and remote
+// trees, sorted by path. With checksum comparison, files of equal size are
+// compared by the digests in localSums/remoteSums; otherwise by mtime.
+func compareTrees(local, remote []sshclient.TreeEntry, cmp SyncCompare, localSums, remoteSums map[string]string) []SyncItem {
+	remoteByPath := make(map[string]*sshclient.TreeEntry, len(remote))
+	for i := range remote {
+		remoteByPath[remote[i].Path] = &remote[i]
+	}
+	seen := make(map[string]bool, len(local))
+
+	var items []SyncItem
+	for i := range local {
+		l := &local[i]
+		seen[l.Path] = true
+		r, ok := remoteByPath[l.Path]
+		if !ok {
+			items = append(items, SyncItem{Path: l.Path, Diff: DiffOnlyLocal, IsDir: l.IsDir, Local: l})
+			continue
+		}
+		if l.IsDir || r.IsDir {
+			// A directory on both sides needs nothing; a file/directory
+			// clash can't be synced automatically and is left alone.
+			continue
+		}
+		if filesDiffer(l, r, cmp, localSums, remoteSums) {
+			items = append(items, SyncItem{Path: l.Path, Diff: DiffChanged, Local: l, Remote: r})
+		}
+	}
+	for i := range remote {
+		r := &remote[i]
+		if !seen[r.Path] {
+			items = append(items, SyncItem{Path: r.Path, Diff: DiffOnlyRemote, IsDir: r.IsDir, Remote: r})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items
+}
+
+// filesDiffer reports whether two files present on both sides differ.
+func filesDiffer(l, r *sshclient.TreeEntry, cmp SyncCompare, localSums, remoteSums map[string]string) bool {
+	if l.Size != r.Size {
+		return true
+	}
+	if cmp == CompareChecksum {
+		ls, lok := localSums[l.Path]
+		rs, rok := remoteSums[r.Path]
+		return !lok || !rok || ls != rs
+	}
+	d := l.ModTime.Sub(r.ModTime)
+	return d > mtimeTolerance || d < -mtimeTolerance
+}
+
+// planSync assigns an action to every item for the given direction.
+// Extraneous files on the destination are only deleted when deleteExtra is
+// set; two-way sync never deletes since it can't tell a deletion from a new
+// file. Children of a deleted directory are dropped from the plan.
+func planSync(items []SyncItem, dir SyncDirection, deleteExtra bool) []SyncItem {
+	planned := make([]SyncItem, 0, len(items))
+	var deletedDirs []string
+	for _, it := range items {
+		if underAny(it.Path, deletedDirs) {
+			continue
+		}
+		it.Action = ActionSkip
+		switch it.Diff {
+		case DiffOnlyLocal:
+			switch {
+			case dir == SyncPull && deleteExtra:
+				it.Action = ActionDeleteLocal
+			case dir != SyncPull && it.IsDir:
+				it.Action = ActionMkdirRemote
+			case dir != SyncPull:
+				it.Action = ActionUpload
+			}
+		case DiffOnlyRemote:
+			switch {
+			case dir == SyncPush && deleteExtra:
+				it.Action = ActionDeleteRemote
+			case dir != SyncPush && it.IsDir:
+				it.Action = ActionMkdirLocal
+			case dir != SyncPush:
+				it.Action = ActionDownload
+			}
+		case DiffChanged:
+			switch dir {
+			case SyncPush:
+				it.Action = ActionUpload
+			case SyncPull:
+				it.Action = ActionDownload
+			default:
+				if it.Local.ModTime.After(it.Remote.ModTime.Add(mtimeTolerance)) {
+					it.Action = ActionUpload
+				} else if it.Remote.ModTime.After(it.Local.ModTime.Add(mtimeTolerance)) {
+					it.Action = ActionDownload
+				}
+				// Same mtime but different content: a conflict, skipped.
+			}
+		}
+		if it.IsDir && (it.Action == ActionDeleteLocal || it.Action == ActionDeleteRemote) {
+			deletedDirs = append(deletedDirs, it.Path)
+		}
+		it.Enabled = it.Action != ActionSkip
+		planned = append(planned, it)
+	}
+	return planned
+}
+
+// underAny reports whether p lies below one of the given directories.
+func underAny(p string, dirs []string) bool {
+	for _, d := range dirs {
+		if strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutUnder returns the entries that do not lie below one of dirs.
+func withoutUnder(entries []sshclient.TreeEntry, dirs []string) []sshclient.TreeEntry {
+	var kept []sshclient.TreeEntry
+	for _, e := range entries {
+		if !underAny(e.Path, dirs) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// scanSyncCmd lists both trees and compares them in the background.
+func scanSyncCmd(client *sshclient.Client, localRoot, remoteRoot string, cmp SyncCompare) tea.Cmd {
+	return func() tea.Msg {
+		local, err := localTree(localRoot)
+		if err != nil {
+			return SyncScanDoneMsg{Err: fmt.Errorf("scan local: %w", err)}
+		}
+		remote, skipped, err := client.ListTree(remoteRoot)
+		if err != nil {
+			return SyncScanDoneMsg{Err: fmt.Errorf("scan remote: %w", err)}
+		}
+		if len(skipped) > 0 {
+			// Their contents are unknown, so nothing below them may be
+			// uploaded, downloaded or deleted.
+			local = withoutUnder(local, skipped)
+		}
+
+		var localSums, remoteSums map[string]string
+		if cmp == CompareChecksum {
+			// Only same-size files on both sides need hashing.
+			remoteSize := map[string]int64{}
+			for _, r := range remote {
+				if !r.IsDir {
+					remoteSize[r.Path] = r.Size
+				}
+			}
+			var candidates []string
+			for _, l := range local {
+				if size, ok := remoteSize[l.Path]; ok && !l.IsDir && size == l.Size {
+					candidates = append(candidates, l.Path)
+				}
+			}
+			localSums = map[string]string{}
+			for _, p := range candidates {
+				if sum, err := fileSHA256(filepath.Join(localRoot, filepath.FromSlash(p))); err == nil {
+					localSums[p] = sum
+				}
+			}
+			remoteSums, err = client.Checksums(remoteRoot, candidates)
+			if err != nil {
+				return SyncScanDoneMsg{Err: err}
+			}
+		}
+		return SyncScanDoneMsg{Items: compareTrees(local, remote, cmp, localSums, remoteSums), Skipped: skipped}
+	}
+}
+
+// applySyncCmd executes the enabled items of a plan in the background.
+// Directories are created first (parents before children), then files are
+// copied, then deletions run.
+func applySyncCmd(client *sshclient.Client, localRoot, remoteRoot string, items []SyncItem) tea.Cmd {
+	var enabled []SyncItem
+	for _, it := range items {
+		if it.Enabled && it.Action != ActionSkip {
+			enabled = append(enabled, it)
+		}
+	}
+	return func() tea.Msg {
+		phase := func(a SyncAction) int {
+			switch a {
+			case ActionMkdirLocal, ActionMkdirRemote:
+				return 0
+			case ActionUpload, ActionDownload:
+				return 1
+			}
+			return 2
+		}
+		sort.SliceStable(enabled, func(i, j int) bool {
+			return phase(enabled[i].Action) < phase(enabled[j].Action)
+		})
+
+		var errs []error
+		applied := 0
+		for _, it := range enabled {
+			localPath := filepath.Join(localRoot, filepath.FromSlash(it.Path))
+			remotePath := joinRemotePath(remoteRoot, it.Path)
+			var err error
+			switch it.Action {
+			case ActionMkdirRemote:
+				err = client.MkDir(remotePath)
+			case ActionMkdirLocal:
+				err = os.MkdirAll(localPath, 0o755)
+			case ActionUpload:
+				if err = client.MkDir(path.Dir(remotePath)); err == nil {
+					err = client.UploadFile(localPath, remotePath)
+				}
+				if err == nil && it.Local != nil {
+					// Keep mtimes aligned so the next comparison sees no change.
+					if tErr := client.SetModTime(remotePath, it.Local.ModTime); tErr != nil {
+						log.Printf("[Sync] set remote mtime %s: %v", remotePath, tErr)
+					}
+				}
+			case ActionDownload:
+				if err = os.MkdirAll(filepath.Dir(localPath), 0o755); err == nil {
+					err = client.DownloadFile(remotePath, filepath.Dir(localPath))
+				}
+				if err == nil && it.Remote != nil {
+					if tErr := os.Chtimes(localPath, it.Remote.ModTime, it.Remote.ModTime); tErr != nil {
+						log.Printf("[Sync] set local mtime %s: %v", localPath, tErr)
+					}
+				}
+			case ActionDeleteRemote:
+				err = client.Remove(remotePath)
+			case ActionDeleteLocal:
+				err = os.RemoveAll(localPath)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", it.Action, it.Path, err))
+				continue
+			}
+			applied++
+		}
+		return SyncApplyDoneMsg{Applied: applied, Errs: errs}
+	}
+}
+
+// syncState tracks which step of the sync dialog is shown.
+type syncState int
+
+const (
+	syncOptions syncState = iota
+	syncScanning
+	syncReview
+	syncApplying
+	syncDone
+)
+
+// SyncModel is the dialog that compares the local and remote panel
+// directories and applies the differences.
+type SyncModel struct {
+	client      *sshclient.Client
+	localRoot   string
+	remoteRoot  string
+	direction   SyncDirection
+	compare     SyncCompare
+	deleteExtra bool
+
+	state   syncState
+	scanned []SyncItem // differences as found by the scan
+	items   []SyncItem // scanned items with the current plan applied
+	cursor  int
+	scroll  int
+	width   int
+	height  int
+	status  string
+}
+
+// NewSyncModel creates a sync dialog for the given directories.
+func NewSyncModel(client *sshclient.Client, localRoot, remoteRoot string) SyncModel {
+	return SyncModel{client: client, localRoot: localRoot, remoteRoot: remoteRoot}
+}
+
+// SetDimensions sets the dialog's display dimensions.
+func (m *SyncModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m SyncModel) visibleRows() int {
+	v := m.height - 9
+	if v < 1 {
+		v = 1
+	}
+	return v
+}
+
+// counts returns the number of enabled items per action.
+func (m SyncModel) counts() map[SyncAction]int {
+	c := map[SyncAction]int{}
+	for _, it := range m.items {
+		if it.Enabled {
+			c[it.Action]++
+		}
+	}
+	return c
+}
+
+// Update handles key events and scan/apply results.
+func (m SyncModel) Update(msg tea.Msg) (SyncModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case SyncScanDoneMsg:
+		if msg.Err != nil {
+			m.state = syncOptions
+			m.status = "Scan failed: " + msg.Err.Error()
+			return m, nil
+		}
+		m.scanned = msg.Items
+		m.items = planSync(msg.Items, m.direction, m.deleteExtra)
+		m.cursor, m.scroll = 0, 0
+		m.state = syncReview
+		m.status = ""
+		if len(msg.Skipped) > 0 {
+			m.status = fmt.Sprintf("Skipped %d unreadable remote dir(s): %s", len(msg.Skipped), strings.Join(msg.Skipped, ", "))
+		}
+		return m, nil
+
+	case SyncApplyDoneMsg:
+		m.state = syncDone
+		m.status = fmt.Sprintf("Applied %d change(s)", msg.Applied)
+		if len(msg.Errs) > 0 {
+			m.status += fmt.Sprintf(", %d failed: %v", len(msg.Errs), msg.Errs[0])
+		}
+		log.Printf("[Sync] %s", m.status)
+		return m, nil
+
+	case tea.KeyMsg:
+		switch m.state {
+		case syncOptions:
+			return m.updateOptions(msg)
+		case syncReview:
+			return m.updateReview(msg)
+		case syncDone:
+			if msg.Type == tea.KeyEnter || msg.Type == tea.KeyEsc {
+				return m, func() tea.Msg { return SyncCloseMsg{} }
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m SyncModel) updateOptions(msg tea.KeyMsg) (SyncModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return m, func() tea.Msg { return SyncCloseMsg{} }
+	case "m":
+		m.direction = (m.direction + 1) % 3
+	case "c":
+		m.compare = (m.compare + 1) % 2
+	case "x":
+		m.deleteExtra = !m.deleteExtra
+	case "enter":
+		m.state = syncScanning
+		m.status = "Comparing directories..."
+		return m, scanSyncCmd(m.client, m.localRoot, m.remoteRoot, m.compare)
+	}
+	return m, nil
+}
+
+func (m SyncModel) updateReview(msg tea.KeyMsg) (SyncModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = syncOptions
+		return m, nil
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	case " ":
+		if m.cursor < len(m.items) && m.items[m.cursor].Action != ActionSkip {
+			m.items[m.cursor].Enabled = !m.items[m.cursor].Enabled
+		}
+	case "a":
+		// Toggle all: enable everything unless everything is already enabled.
+		all := true
+		for _, it := range m.items {
+			if it.Action != ActionSkip && !it.Enabled {
+				all = false
+				break
+			}
+		}
+		for i := range m.items {
+			m.items[i].Enabled = !all && m.items[i].Action != ActionSkip
+		}
+	case "enter":
+		if len(m.counts()) == 0 {
+			m.status = "Nothing to apply"
+			return m, nil
+		}
+		m.state = syncApplying
+		m.status = "Applying changes..."
+		return m, applySyncCmd(m.client, m.localRoot, m.remoteRoot, m.items)
+	}
+	vis := m.visibleRows()
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if m.cursor >= m.scroll+vis {
+		m.scroll = m.cursor - vis + 1
+	}
+	return m, nil
+}
+
+var (
+	syncNewStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B"))
+	syncChangeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#F1FA8C"))
+	syncDeleteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555"))
+	syncSkipStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#555555"))
+)
+
+// diffLabel describes an item's difference from the point of view of the
+// sync direction, e.g. a file only on the remote is "extra" when pushing.
+func diffLabel(it SyncItem, dir SyncDirection) string {
+	switch it.Diff {
+	case DiffChanged:
+		return "changed"
+	case DiffOnlyLocal:
+		if dir == SyncPull {
+			return "extra local"
+		}
+		return "new local"
+	default:
+		if dir == SyncPush {
+			return "extra remote"
+		}
+		return "new remote"
+	}
+}
+
+// View renders the dialog.
+func (m SyncModel) View() string {
+	innerWidth := m.width - 4
+	if innerWidth < 30 {
+		innerWidth = 30
+	}
+	title := messageStyle.Render("Sync directories")
+	roots := fmt.Sprintf("Local:  %s\nRemote: %s",
+		truncatePath(m.localRoot, innerWidth-8), truncatePath(m.remoteRoot, innerWidth-8))
+
+	onOff := "no"
+	if m.deleteExtra {
+		onOff = "yes"
+	}
+	options := fmt.Sprintf("[m] Direction: %s   [c] Compare: %s   [x] Delete extraneous: %s",
+		m.direction, m.compare, onOff)
+
+	var body, hints string
+	switch m.state {
+	case syncOptions, syncScanning:
+		body = statusBarStyle.Render("Choose options, then press Enter to compare.")
+		hints = "m/c/x: options • Enter: compare • Esc: close"
+	case syncReview, syncApplying:
+		body = m.renderItems(innerWidth)
+		c := m.counts()
+		options = fmt.Sprintf("%d upload • %d download • %d mkdir • %d delete",
+			c[ActionUpload], c[ActionDownload], c[ActionMkdirLocal]+c[ActionMkdirRemote],
+			c[ActionDeleteLocal]+c[ActionDeleteRemote])
+		hints = "Space: toggle • a: toggle all • Enter: apply • Esc: back"
+	case syncDone:
+		hints = "Enter/Esc: close"
+	}
+
+	var status string
+	if m.status != "" {
+		status = messageStyle.Render(m.status)
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title, roots, "", options, "", body, status, statusBarStyle.Render(hints))
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(content)
+}
+
+func (m SyncModel) renderItems(width int) string {
+	if len(m.items) == 0 {
+		return statusBarStyle.Render("Directories are in sync")
+	}
+	var rows []string
+	vis := m.visibleRows()
+	for i := m.scroll; i < len(m.items) && i < m.scroll+vis; i++ {
+		it := m.items[i]
+		check := "[ ]"
+		if it.Enabled {
+			check = "[x]"
+		}
+		name := it.Path
+		if it.IsDir {
+			name += "/"
+		}
+		line := fmt.Sprintf("%s %-13s %-13s %s", check, diffLabel(it, m.direction), it.Action, name)
+		line = truncate(line, width)
+		style := syncNewStyle
+		switch {
+		case it.Action == ActionSkip || !it.Enabled:
+			style = syncSkipStyle
+		case it.Action == ActionDeleteLocal || it.Action == ActionDeleteRemote:
+			style = syncDeleteStyle
+		case it.Diff == DiffChanged:
+			style = syncChangeStyle
+		}
+		if i == m.cursor {
+			line = fileSelectedStyle.Width(width).Render(line)
+		} else {
+			line = style.Render(line)
+		}
+		rows = append(rows, line)
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,300 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var syncT0 = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func syncTrees() (local, remote []sshclient.TreeEntry) {
+	local = []sshclient.TreeEntry{
+		{Path: "a.txt", Size: 10, ModTime: syncT0},
+		{Path: "b.txt", Size: 10, ModTime: syncT0.Add(time.Hour)},
+		{Path: "same.txt", Size: 5, ModTime: syncT0},
+		{Path: "newdir", IsDir: true},
+		{Path: "newdir/c.txt", Size: 1, ModTime: syncT0},
+	}
+	remote = []sshclient.TreeEntry{
+		{Path: "a.txt", Size: 11, ModTime: syncT0},
+		{Path: "b.txt", Size: 10, ModTime: syncT0},
+		{Path: "same.txt", Size: 5, ModTime: syncT0.Add(time.Second)},
+		{Path: "old", IsDir: true},
+		{Path: "old/d.txt", Size: 1, ModTime: syncT0},
+	}
+	return local, remote
+}
+
+func TestCompareTreesSizeTime(t *testing.T) {
+	local, remote := syncTrees()
+	items := compareTrees(local, remote, CompareSizeTime, nil, nil)
+	got := map[string]SyncDiff{}
+	for _, it := range items {
+		got[it.Path] = it.Diff
+	}
+	want := map[string]SyncDiff{
+		"a.txt":        DiffChanged,
+		"b.txt":        DiffChanged,
+		"newdir":       DiffOnlyLocal,
+		"newdir/c.txt": DiffOnlyLocal,
+		"old":          DiffOnlyRemote,
+		"old/d.txt":    DiffOnlyRemote,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("items = %+v", items)
+	}
+	for p, d := range want {
+		if got[p] != d {
+			t.Errorf("%s: diff = %d, want %d", p, got[p], d)
+		}
+	}
+	if items[0].Path != "a.txt" {
+		t.Errorf("items should be sorted by path, first = %q", items[0].Path)
+	}
+}
+
+func TestCompareTreesChecksum(t *testing.T) {
+	local, remote := syncTrees()
+	localSums := map[string]string{"b.txt": "x", "same.txt": "y"}
+	remoteSums := map[string]string{"b.txt": "x", "same.txt": "z"}
+	items := compareTrees(local, remote, CompareChecksum, localSums, remoteSums)
+	changed := map[string]bool{}
+	for _, it := range items {
+		if it.Diff == DiffChanged {
+			changed[it.Path] = true
+		}
+	}
+	if changed["b.txt"] {
+		t.Error("b.txt has equal checksums and should not be changed")
+	}
+	if !changed["same.txt"] || !changed["a.txt"] {
+		t.Errorf("expected same.txt and a.txt changed, got %v", changed)
+	}
+}
+
+func planFor(items []SyncItem, path string) SyncItem {
+	for _, it := range items {
+		if it.Path == path {
+			return it
+		}
+	}
+	return SyncItem{Path: "<missing>"}
+}
+
+func TestPlanSyncPush(t *testing.T) {
+	local, remote := syncTrees()
+	items := compareTrees(local, remote, CompareSizeTime, nil, nil)
+
+	plan := planSync(items, SyncPush, false)
+	if a := planFor(plan, "a.txt").Action; a != ActionUpload {
+		t.Errorf("changed file push action = %s", a)
+	}
+	if a := planFor(plan, "newdir").Action; a != ActionMkdirRemote {
+		t.Errorf("new dir push action = %s", a)
+	}
+	if it := planFor(plan, "old/d.txt"); it.Action != ActionSkip || it.Enabled {
+		t.Errorf("extra remote file without delete = %s enabled=%v", it.Action, it.Enabled)
+	}
+
+	plan = planSync(items, SyncPush, true)
+	if a := planFor(plan, "old").Action; a != ActionDeleteRemote {
+		t.Errorf("extra remote dir with delete = %s", a)
+	}
+	if planFor(plan, "old/d.txt").Path != "<missing>" {
+		t.Error("children of a deleted directory should be dropped")
+	}
+}
+
+func TestPlanSyncPull(t *testing.T) {
+	local, remote := syncTrees()
+	items := compareTrees(local, remote, CompareSizeTime, nil, nil)
+	plan := planSync(items, SyncPull, true)
+	if a := planFor(plan, "a.txt").Action; a != ActionDownload {
+		t.Errorf("changed file pull action = %s", a)
+	}
+	if a := planFor(plan, "old").Action; a != ActionMkdirLocal {
+		t.Errorf("remote-only dir pull action = %s", a)
+	}
+	if a := planFor(plan, "newdir").Action; a != ActionDeleteLocal {
+		t.Errorf("extra local dir with delete = %s", a)
+	}
+}
+
+func TestPlanSyncTwoWay(t *testing.T) {
+	local, remote := syncTrees()
+	items := compareTrees(local, remote, CompareSizeTime, nil, nil)
+	plan := planSync(items, SyncTwoWay, true)
+	if a := planFor(plan, "b.txt").Action; a != ActionUpload {
+		t.Errorf("newer local file should upload, got %s", a)
+	}
+	if it := planFor(plan, "a.txt"); it.Action != ActionSkip {
+		t.Errorf("same mtime, different size is a conflict; got %s", it.Action)
+	}
+	if a := planFor(plan, "old/d.txt").Action; a != ActionDownload {
+		t.Errorf("two-way should never delete, got %s", a)
+	}
+}
+
+func TestLocalTree(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "f.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := localTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if entries[1].Path != "sub/f.txt" || entries[1].Size != 3 {
+		t.Errorf("file entry = %+v", entries[1])
+	}
+}
+
+func TestApplySyncLocalActions(t *testing.T) {
+	dir := t.TempDir()
+	extra := filepath.Join(dir, "extra")
+	if err := os.MkdirAll(extra, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	items := []SyncItem{
+		{Path: "extra", IsDir: true, Action: ActionDeleteLocal, Enabled: true},
+		{Path: "made/deep", IsDir: true, Action: ActionMkdirLocal, Enabled: true},
+		{Path: "skipped", IsDir: true, Action: ActionMkdirLocal, Enabled: false},
+	}
+	msg := applySyncCmd(nil, dir, "/remote", items)().(SyncApplyDoneMsg)
+	if msg.Applied != 2 || len(msg.Errs) != 0 {
+		t.Fatalf("applied=%d errs=%v", msg.Applied, msg.Errs)
+	}
+	if _, err := os.Stat(extra); !os.IsNotExist(err) {
+		t.Error("extra dir should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "made", "deep")); err != nil {
+		t.Error("made/deep should be created")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skipped")); !os.IsNotExist(err) {
+		t.Error("disabled items must not be applied")
+	}
+}
+
+func TestSyncModelFlow(t *testing.T) {
+	m := NewSyncModel(nil, "/local", "/remote")
+	m.SetDimensions(100, 30)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if m.direction != SyncPull || m.compare != CompareChecksum || !m.deleteExtra {
+		t.Fatalf("options not toggled: %+v", m)
+	}
+	if !strings.Contains(m.View(), "remote → local") {
+		t.Error("view should show the direction")
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || m.state != syncScanning {
+		t.Fatal("Enter should start scanning")
+	}
+
+	local, remote := syncTrees()
+	m, _ = m.Update(SyncScanDoneMsg{Items: compareTrees(local, remote, CompareSizeTime, nil, nil)})
+	if m.state != syncReview || len(m.items) == 0 {
+		t.Fatal("scan result should show the review list")
+	}
+	first := m.items[0].Enabled
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	if m.items[0].Enabled == first {
+		t.Error("Space should toggle the item")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if len(m.counts()) != 0 {
+		t.Errorf("toggling all twice should disable everything, counts=%v", m.counts())
+	}
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || m.status != "Nothing to apply" {
+		t.Error("Enter with nothing enabled should not apply")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || m.state != syncApplying {
+		t.Fatal("Enter should apply the plan")
+	}
+	m, _ = m.Update(SyncApplyDoneMsg{Applied: 3})
+	if m.state != syncDone || !strings.Contains(m.status, "Applied 3") {
+		t.Errorf("state=%d status=%q", m.state, m.status)
+	}
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter after completion should close")
+	}
+	if _, ok := cmd().(SyncCloseMsg); !ok {
+		t.Error("expected SyncCloseMsg")
+	}
+}
+
+func TestSyncModelScanError(t *testing.T) {
+	m := NewSyncModel(nil, "/l", "/r")
+	m.state = syncScanning
+	m, _ = m.Update(SyncScanDoneMsg{Err: os.ErrPermission})
+	if m.state != syncOptions || !strings.Contains(m.status, "Scan failed") {
+		t.Errorf("state=%d status=%q", m.state, m.status)
+	}
+}
+
+func TestSyncModelReportsSkipped(t *testing.T) {
+	m := NewSyncModel(nil, "/l", "/r")
+	m.state = syncScanning
+	m, _ = m.Update(SyncScanDoneMsg{Skipped: []string{"var/private"}})
+	if m.state != syncReview || m.status != "Skipped 1 unreadable remote dir(s): var/private" {
+		t.Errorf("state=%d status=%q", m.state, m.status)
+	}
+}
+
+func TestWithoutUnder(t *testing.T) {
+	entries := []sshclient.TreeEntry{{Path: "private", IsDir: true}, {Path: "private/a"}, {Path: "private2"}, {Path: "b"}}
+	got := withoutUnder(entries, []string{"private"})
+	if len(got) != 3 || got[0].Path != "private" || got[1].Path != "private2" || got[2].Path != "b" {
+		t.Errorf("withoutUnder = %+v, want everything but private/a", got)
+	}
+}
+
+func TestSyncModelInSyncView(t *testing.T) {
+	m := NewSyncModel(nil, "/l", "/r")
+	m.SetDimensions(80, 24)
+	m, _ = m.Update(SyncScanDoneMsg{})
+	if !strings.Contains(m.View(), "in sync") {
+		t.Error("empty diff should report directories in sync")
+	}
+}
+
+func TestDiffLabel(t *testing.T) {
+	it := SyncItem{Diff: DiffOnlyRemote}
+	if got := diffLabel(it, SyncPush); got != "extra remote" {
+		t.Errorf("push label = %q", got)
+	}
+	if got := diffLabel(it, SyncPull); got != "new remote" {
+		t.Errorf("pull label = %q", got)
+	}
+}
+
+func TestFBCtrlSOpensSync(t *testing.T) {
+	m := FileBrowserModel{localDir: "/l", remoteDir: "/r"}
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if cmd == nil {
+		t.Fatal("Ctrl+S should return a command")
+	}
+	msg, ok := cmd().(OpenSyncMsg)
+	if !ok || msg.LocalDir != "/l" || msg.RemoteDir != "/r" {
+		t.Errorf("unexpected msg %#v", msg)
+	}
+}
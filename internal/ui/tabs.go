@@ -11,6 +11,8 @@ import (
 type Tab struct {
 	Title     string
 	Connected bool
+	Recording bool // the terminal session is being recorded
+	Broadcast bool // the terminal receives broadcast keystrokes
 }
 
 var (
@@ -39,6 +41,12 @@ func RenderTabBar(tabs []Tab, active int, width int) string {
 		} else {
 			label = "○ " + label
 		}
+		if tab.Recording {
+			label += " [REC]"
+		}
+		if tab.Broadcast {
+			label += " [BC]"
+		}
 		if i == active {
 			parts = append(parts, tabActiveStyle.Render(label))
 		} else {
@@ -83,6 +83,18 @@ func TestRenderTabBarDisconnectedIcon(t *testing.T) {
 	}
 }
 
+func TestRenderTabBarRecording(t *testing.T) {
+	tabs := []Tab{{Title: "a", Connected: true, Recording: true}, {Title: "b", Connected: true}}
+	bar := RenderTabBar(tabs, 1, 80)
+	if strings.Count(bar, "[REC]") != 1 {
+		t.Errorf("only the recorded tab should be marked: %q", bar)
+	}
+	tabs[1].Broadcast = true
+	if bar := RenderTabBar(tabs, 0, 80); strings.Count(bar, "[BC]") != 1 {
+		t.Errorf("the broadcast tab should be marked: %q", bar)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Tab struct
 // ---------------------------------------------------------------------------
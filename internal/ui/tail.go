@@ -0,0 +1,459 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	maxTailLines     = 5000 // scrollback kept per followed file
+	tailInitialLines = 200  // lines shown from the end of a file on start
+)
+
+// tailFunc follows a file, calling line for each line until ctx is done.
+type tailFunc func(ctx context.Context, p string, n int, line func(string)) error
+
+// TailLinesMsg delivers lines of a followed file. It is routed to every
+// tab's browser, since the file may be followed in a tab that is no longer
+// shown.
+type TailLinesMsg struct {
+	file  *tailFile
+	lines []string
+	done  bool
+	err   error
+}
+
+// tailHideMsg hides the viewer; the files keep being followed.
+type tailHideMsg struct{}
+
+// tailCloseMsg is sent when the viewer is closed and all follows stopped.
+type tailCloseMsg struct{}
+
+// Prompts of the tail viewer.
+const (
+	tailInputNone = iota
+	tailInputHighlight
+	tailInputFilter
+)
+
+// tailFile is one followed file and its scrollback.
+type tailFile struct {
+	path      string
+	lines     []string
+	total     int // lines received, including those dropped from lines
+	paused    bool
+	frozen    int // total when paused; later lines are held back
+	offset    int // lines scrolled up from the bottom
+	highlight *regexp.Regexp
+	filter    *regexp.Regexp
+	cancel    context.CancelFunc
+	next      tea.Cmd
+	done      bool
+	err       error
+}
+
+// add appends received lines, dropping the oldest beyond maxTailLines.
+func (f *tailFile) add(lines []string) {
+	f.lines = append(f.lines, lines...)
+	f.total += len(lines)
+	if n := len(f.lines) - maxTailLines; n > 0 {
+		f.lines = append(f.lines[:0:0], f.lines[n:]...)
+	}
+	if f.offset > 0 && !f.paused {
+		// Keep the scrolled-back view where it is.
+		for _, l := range lines {
+			if f.filter == nil || f.filter.MatchString(l) {
+				f.offset++
+			}
+		}
+	}
+}
+
+// shown returns the lines to display: all received (or, while paused, up
+// to the pause) that pass the filter.
+func (f *tailFile) shown() []string {
+	lines := f.lines
+	if f.paused {
+		end := f.frozen - (f.total - len(f.lines))
+		lines = lines[:max(min(end, len(lines)), 0)]
+	}
+	if f.filter == nil {
+		return lines
+	}
+	var out []string
+	for _, l := range lines {
+		if f.filter.MatchString(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// stop ends the follow session.
+func (f *tailFile) stop() {
+	if f.cancel != nil {
+		f.cancel()
+		f.cancel = nil
+	}
+}
+
+// TailModel is the live log viewer: one or more remote files followed with
+// tail -F, each in its own session.
+type TailModel struct {
+	tail      tailFunc
+	files     []*tailFile
+	active    int
+	inputMode int
+	input     textinput.Model
+	status    string
+	width     int
+	height    int
+}
+
+// NewTailModel creates an empty viewer that follows files with tail.
+func NewTailModel(tail tailFunc) TailModel {
+	return TailModel{tail: tail}
+}
+
+// SetDimensions sets the viewer's display dimensions.
+func (m *TailModel) SetDimensions(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Follow shows p, starting to follow it unless it already is.
+func (m *TailModel) Follow(p string) tea.Cmd {
+	for i, f := range m.files {
+		if f.path == p {
+			m.active = i
+			return nil
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &tailFile{path: p, cancel: cancel}
+	m.files = append(m.files, f)
+	m.active = len(m.files) - 1
+
+	ch := make(chan string, 256)
+	errCh := make(chan error, 1)
+	tail := m.tail
+	go func() {
+		errCh <- tail(ctx, p, tailInitialLines, func(line string) {
+			select {
+			case ch <- line:
+			case <-ctx.Done():
+			}
+		})
+		close(ch)
+	}()
+	f.next = waitTail(f, ch, errCh)
+	return f.next
+}
+
+// waitTail returns a command delivering the next lines of a followed file.
+func waitTail(f *tailFile, ch <-chan string, errCh <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		l, ok := <-ch
+		if !ok {
+			return TailLinesMsg{file: f, done: true, err: <-errCh}
+		}
+		lines := []string{l}
+		for len(lines) < 500 {
+			select {
+			case l, ok := <-ch:
+				if !ok {
+					return TailLinesMsg{file: f, lines: lines, done: true, err: <-errCh}
+				}
+				lines = append(lines, l)
+			default:
+				return TailLinesMsg{file: f, lines: lines}
+			}
+		}
+		return TailLinesMsg{file: f, lines: lines}
+	}
+}
+
+// Running returns the number of files being followed.
+func (m TailModel) Running() int {
+	n := 0
+	for _, f := range m.files {
+		if !f.done {
+			n++
+		}
+	}
+	return n
+}
+
+// Stop ends all follow sessions.
+func (m *TailModel) Stop() {
+	for _, f := range m.files {
+		f.stop()
+	}
+}
+
+func (m TailModel) current() *tailFile {
+	if len(m.files) == 0 {
+		return nil
+	}
+	return m.files[m.active]
+}
+
+func (m TailModel) visibleRows() int {
+	return max(m.height-7, 1) // file tabs, status, prompt/hints, borders
+}
+
+// Update handles streamed lines and keys.
+func (m TailModel) Update(msg tea.Msg) (TailModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case TailLinesMsg:
+		for _, f := range m.files {
+			if f != msg.file {
+				continue
+			}
+			f.add(msg.lines)
+			if !msg.done {
+				return m, f.next
+			}
+			f.done, f.cancel, f.next = true, nil, nil
+			if !errors.Is(msg.err, context.Canceled) {
+				f.err = msg.err
+			}
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.inputMode != tailInputNone {
+			return m.handleInputKey(msg)
+		}
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m TailModel) handleKey(msg tea.KeyMsg) (TailModel, tea.Cmd) {
+	f := m.current()
+	if f == nil {
+		return m, func() tea.Msg { return tailCloseMsg{} }
+	}
+	m.status = ""
+	switch msg.String() {
+	case "esc":
+		return m, func() tea.Msg { return tailHideMsg{} }
+	case "q":
+		m.Stop()
+		return m, func() tea.Msg { return tailCloseMsg{} }
+	case "x":
+		f.stop()
+		m.files = append(m.files[:m.active:m.active], m.files[m.active+1:]...)
+		if len(m.files) == 0 {
+			return m, func() tea.Msg { return tailCloseMsg{} }
+		}
+		m.active = min(m.active, len(m.files)-1)
+	case "tab", "]":
+		m.active = (m.active + 1) % len(m.files)
+	case "shift+tab", "[":
+		m.active = (m.active + len(m.files) - 1) % len(m.files)
+	case " ", "p":
+		f.paused = !f.paused
+		f.frozen = f.total
+	case "up", "k":
+		f.offset++
+	case "down", "j":
+		f.offset--
+	case "pgup":
+		f.offset += m.visibleRows()
+	case "pgdown":
+		f.offset -= m.visibleRows()
+	case "home", "g":
+		f.offset = len(f.shown())
+	case "end", "G":
+		f.offset = 0
+	case "/":
+		return m.startInput(tailInputHighlight, f.highlight)
+	case "f":
+		return m.startInput(tailInputFilter, f.filter)
+	}
+	f.offset = max(min(f.offset, len(f.shown())-m.visibleRows()), 0)
+	return m, nil
+}
+
+func (m TailModel) startInput(mode int, current *regexp.Regexp) (TailModel, tea.Cmd) {
+	ti := textinput.New()
+	ti.CharLimit = 256
+	ti.Width = 40
+	if current != nil {
+		ti.SetValue(current.String())
+		ti.CursorEnd()
+	}
+	ti.Focus()
+	m.input = ti
+	m.inputMode = mode
+	return m, textinput.Blink
+}
+
+// handleInputKey edits the highlight or filter regex. Enter applies it (an
+// empty one clears it), Esc cancels.
+func (m TailModel) handleInputKey(msg tea.KeyMsg) (TailModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.inputMode = tailInputNone
+		return m, nil
+	case tea.KeyEnter:
+		var re *regexp.Regexp
+		if v := m.input.Value(); v != "" {
+			var err error
+			if re, err = regexp.Compile(v); err != nil {
+				m.status = "Invalid regex: " + err.Error()
+				return m, nil
+			}
+		}
+		f := m.current()
+		if m.inputMode == tailInputHighlight {
+			f.highlight = re
+		} else {
+			f.filter = re
+			f.offset = 0
+		}
+		m.inputMode = tailInputNone
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// View renders the viewer.
+func (m TailModel) View() string {
+	f := m.current()
+	if f == nil {
+		return ""
+	}
+	width := max(m.width-4, 10)
+
+	var tabs []string
+	for i, t := range m.files {
+		name := " " + path.Base(t.path) + " "
+		if i == m.active {
+			name = fileSelectedStyle.Render(name)
+		}
+		tabs = append(tabs, name)
+	}
+	lines := []string{strings.Join(tabs, " ")}
+
+	state := "following"
+	switch {
+	case f.done && f.err != nil:
+		state = "ended: " + f.err.Error()
+	case f.done:
+		state = "ended"
+	case f.paused:
+		state = fmt.Sprintf("PAUSED (+%d new)", f.total-f.frozen)
+	case f.offset > 0:
+		state = fmt.Sprintf("scrolled back %d lines", f.offset)
+	}
+	info := f.path + " — " + state
+	if f.filter != nil {
+		info += " — filter /" + f.filter.String() + "/"
+	}
+	lines = append(lines, statusBarStyle.Render(truncate(info, width)))
+
+	shown := f.shown()
+	vis := m.visibleRows()
+	end := len(shown) - f.offset
+	for _, l := range shown[max(end-vis, 0):max(end, 0)] {
+		lines = append(lines, highlightMatches(truncate(cleanLine(l), width), f.highlight))
+	}
+	for i := len(lines); i < vis+2; i++ {
+		lines = append(lines, "")
+	}
+
+	switch {
+	case m.inputMode == tailInputHighlight:
+		lines = append(lines, messageStyle.Render("Highlight regex: ")+m.input.View())
+	case m.inputMode == tailInputFilter:
+		lines = append(lines, messageStyle.Render("Filter regex: ")+m.input.View())
+	case m.status != "":
+		lines = append(lines, historyFailStyle.Render(m.status))
+	default:
+		lines = append(lines, statusBarStyle.Render("Space: pause • /: highlight • f: filter • ↑↓ PgUp/PgDn G: scroll • Tab: next file • x: stop file • Esc: hide • q: stop all"))
+	}
+	return historyBoxStyle.Width(m.width - 2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+var tailMatchStyle = lipgloss.NewStyle().
+	Background(lipgloss.Color("#F4D03F")).
+	Foreground(lipgloss.Color("#000000"))
+
+// highlightMatches marks the matches of re in line.
+func highlightMatches(line string, re *regexp.Regexp) string {
+	if re == nil {
+		return line
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		b.WriteString(line[last:loc[0]])
+		b.WriteString(tailMatchStyle.Render(line[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// followSelected follows the selected remote file in the tail viewer, or
+// shows the viewer when the selection is not a file.
+func (m FileBrowserModel) followSelected() (FileBrowserModel, tea.Cmd) {
+	var file string
+	if m.focus == panelRemote && len(m.remoteFiles) > 0 {
+		if f := m.remoteFiles[m.remoteCursor]; !remoteIsDir(f) {
+			file = joinRemotePath(m.remoteDir, f.Name)
+		}
+	}
+	if file == "" {
+		if m.tail != nil {
+			m.tailShown = true
+			return m, nil
+		}
+		m.statusMsg = "Select a remote file to follow"
+		return m, nil
+	}
+	if m.tail == nil {
+		client := m.client
+		tm := NewTailModel(client.Tail)
+		m.tail = &tm
+	}
+	m.tailShown = true
+	return m, m.tail.Follow(file)
+}
+
+// handleTailMsg routes messages of the tail viewer.
+func (m FileBrowserModel) handleTailMsg(msg tea.Msg) (FileBrowserModel, tea.Cmd) {
+	switch msg.(type) {
+	case tailHideMsg:
+		m.tailShown = false
+	case tailCloseMsg:
+		if m.tail != nil {
+			m.tail.Stop()
+		}
+		m.tail = nil
+		m.tailShown = false
+	case TailLinesMsg:
+		if m.tail == nil {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		*m.tail, cmd = m.tail.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
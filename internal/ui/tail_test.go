@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeTail follows nothing: it sends the lines given for a path, then
+// waits to be cancelled. stopped records the paths whose session ended.
+type fakeTail struct {
+	lines   map[string][]string
+	stopped chan string
+}
+
+func (f fakeTail) tail(ctx context.Context, p string, _ int, line func(string)) error {
+	for _, l := range f.lines[p] {
+		line(l)
+	}
+	<-ctx.Done()
+	f.stopped <- p
+	return ctx.Err()
+}
+
+func TestTailFileScrollbackAndPause(t *testing.T) {
+	f := &tailFile{}
+	for i := 0; i < maxTailLines+10; i++ {
+		f.add([]string{fmt.Sprint(i)})
+	}
+	if len(f.lines) != maxTailLines || f.lines[0] != "10" || f.total != maxTailLines+10 {
+		t.Fatalf("scrollback = %d lines from %q, total %d", len(f.lines), f.lines[0], f.total)
+	}
+
+	f.paused, f.frozen = true, f.total
+	f.add([]string{"new"})
+	if shown := f.shown(); shown[len(shown)-1] == "new" {
+		t.Error("lines arriving while paused should be held back")
+	}
+	f.paused = false
+	if shown := f.shown(); shown[len(shown)-1] != "new" {
+		t.Error("resuming should show held-back lines")
+	}
+
+	f.filter = regexp.MustCompile(`^499\d$`)
+	if shown := f.shown(); len(shown) != 10 || shown[0] != "4990" {
+		t.Errorf("filtered = %q", shown)
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	re := regexp.MustCompile(`err\w*`)
+	got := highlightMatches("an error and errors", re)
+	want := "an " + tailMatchStyle.Render("error") + " and " + tailMatchStyle.Render("errors")
+	if got != want {
+		t.Errorf("highlightMatches = %q, want %q", got, want)
+	}
+	if highlightMatches("plain", nil) != "plain" {
+		t.Error("no regex should leave the line alone")
+	}
+}
+
+func TestTailModelFollowFilesAndStop(t *testing.T) {
+	ft := fakeTail{
+		lines:   map[string][]string{"/var/log/app.log": {"GET / 200", "GET /x 500"}, "/var/log/db.log": {"ready"}},
+		stopped: make(chan string, 2),
+	}
+	m := NewTailModel(ft.tail)
+	m.SetDimensions(100, 20)
+
+	cmd := m.Follow("/var/log/app.log")
+	m, _ = m.Update(cmd())
+	if got := m.files[0].lines; len(got) != 2 {
+		t.Fatalf("lines = %q", got)
+	}
+	cmd = m.Follow("/var/log/db.log")
+	m, _ = m.Update(cmd())
+	if m.active != 1 || m.Running() != 2 {
+		t.Fatalf("active = %d, running = %d", m.active, m.Running())
+	}
+	if m.Follow("/var/log/app.log") != nil || m.active != 0 {
+		t.Error("following a file again should just switch to it")
+	}
+
+	// Filter the first file.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m.input.SetValue(" 500$")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	view := m.View()
+	if strings.Contains(view, "GET / 200") || !strings.Contains(view, "GET /x 500") {
+		t.Errorf("filter not applied:\n%s", view)
+	}
+
+	// An invalid regex is reported and the prompt stays open.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	m.input.SetValue("(")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.inputMode != tailInputHighlight || !strings.Contains(m.status, "Invalid regex") {
+		t.Errorf("mode = %d, status = %q", m.inputMode, m.status)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	// x stops only the current file.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if got := <-ft.stopped; got != "/var/log/app.log" || len(m.files) != 1 {
+		t.Errorf("stopped %q, %d files left", got, len(m.files))
+	}
+
+	// q stops everything.
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if _, ok := cmd().(tailCloseMsg); !ok {
+		t.Error("q should close the viewer")
+	}
+	if got := <-ft.stopped; got != "/var/log/db.log" {
+		t.Errorf("stopped %q", got)
+	}
+}
+
+func TestFBTailHideAndClose(t *testing.T) {
+	m := NewFileBrowserModel(nil, t.TempDir(), "/var/log")
+	m.SetDimensions(100, 30)
+	m.focus = panelRemote
+	m, _ = m.Update(remoteFilesMsg{files: []sshclient.RemoteFile{{Name: "syslog"}}})
+
+	ft := fakeTail{lines: map[string][]string{"/var/log/syslog": {"boot"}}, stopped: make(chan string, 1)}
+	tm := NewTailModel(ft.tail)
+	m.tail = &tm
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w"), Alt: true})
+	if !m.tailShown || !m.InputActive() {
+		t.Fatal("Alt+W should show the viewer")
+	}
+	m, _ = m.Update(cmd())
+	if !strings.Contains(m.View(), "boot") {
+		t.Errorf("viewer should show the file:\n%s", m.View())
+	}
+
+	// Esc hides the viewer but keeps following.
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m, _ = m.Update(cmd())
+	if m.tailShown || m.tail == nil || !strings.Contains(m.View(), "following 1 file(s)") {
+		t.Errorf("hidden viewer should keep following:\n%s", m.View())
+	}
+
+	// Closing the tab stops the session.
+	m.Close()
+	if got := <-ft.stopped; got != "/var/log/syslog" {
+		t.Errorf("stopped %q", got)
+	}
+}
+
+func TestTailLinesGoToTheirOwnBrowser(t *testing.T) {
+	// Two tabs follow a file each; a tab's lines keep arriving while
+	// another tab is shown, and never reach the other tab's viewer.
+	newBrowser := func(lines ...string) (FileBrowserModel, tea.Cmd) {
+		m := NewFileBrowserModel(nil, t.TempDir(), "/var/log")
+		ft := fakeTail{lines: map[string][]string{"/var/log/syslog": lines}, stopped: make(chan string, 1)}
+		tm := NewTailModel(ft.tail)
+		m.tail = &tm
+		t.Cleanup(tm.Stop)
+		return m, tm.Follow("/var/log/syslog")
+	}
+	a, cmdA := newBrowser("from a")
+	b, _ := newBrowser("from b")
+
+	msg := cmdA()
+	a, next := a.Update(msg)
+	b, _ = b.Update(msg)
+	if got := a.tail.files[0].lines; len(got) != 1 || got[0] != "from a" {
+		t.Errorf("owner lines = %q", got)
+	}
+	if got := b.tail.files[0].lines; len(got) != 0 {
+		t.Errorf("other tab got %q", got)
+	}
+	if next == nil {
+		t.Error("the owner should wait for more lines")
+	}
+}
@@ -1,47 +1,82 @@
 package ui
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"os"
+	"path/filepath"
 	"sync"
 
+	"ssh-scp/internal/cast"
 	sshclient "ssh-scp/internal/ssh"
+	"ssh-scp/internal/vt"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/crypto/ssh"
 )
 
-// TerminalOutputMsg carries new output from the SSH session.
+// Default screen size until the pane has been laid out.
+const (
+	defaultTermCols = 80
+	defaultTermRows = 24
+)
+
+// TerminalOutputMsg carries new output from the SSH session of Term.
 type TerminalOutputMsg struct {
+	Term *TerminalModel
 	Data []byte
 }
 
-// TerminalModel manages an interactive SSH terminal.
+// TerminalModel manages an interactive SSH terminal. Output is fed through
+// a VT100/xterm emulator so full-screen programs render as they would in a
+// real terminal.
 type TerminalModel struct {
 	client  *sshclient.Client
 	session *ssh.Session
 	stdin   io.WriteCloser
-	buf     bytes.Buffer
+	screen  *vt.Screen
 	mu      sync.Mutex
-	width   int
-	height  int
+	width   int // PTY columns
+	height  int // PTY rows
 	active  bool
 	err     string
 	program *tea.Program
+
+	scrollback int       // scrollback lines kept by the screen
+	copy       *copyMode // non-nil while copy mode is active
+	notice     string    // shown in the pane after leaving copy mode
+
+	recorder    *cast.Writer // non-nil while the session is being recorded
+	recordPath  string
+	recordInput bool
+
+	broadcast bool // receives keystrokes broadcast from other tabs
+
+	// Working directory reported by the shell with OSC 7.
+	hostname   string // remote host name; reports from other hosts are ignored
+	cwdHook    bool   // type cwdHook into the shell when it starts
+	cwd        string
+	cwdChanged bool
 }
 
 // terminalWriter implements io.Writer and sends output as tea messages.
+// Without a program the output goes straight to the screen.
 type terminalWriter struct {
 	program *tea.Program
+	term    *TerminalModel
 }
 
 func (tw *terminalWriter) Write(p []byte) (int, error) {
 	data := make([]byte, len(p))
 	copy(data, p)
-	tw.program.Send(TerminalOutputMsg{Data: data})
+	if tw.program == nil {
+		tw.term.AppendOutput(data)
+		return len(p), nil
+	}
+	tw.program.Send(TerminalOutputMsg{Term: tw.term, Data: data})
 	return len(p), nil
 }
 
@@ -63,102 +98,292 @@ func (m *TerminalModel) SetStdinForTest(w io.WriteCloser) {
 	m.stdin = w
 }
 
-// StartSession starts the SSH terminal session.
+// StartSession starts the SSH terminal session. It blocks on the network
+// and is meant to run inside a tea.Cmd.
 func (m *TerminalModel) StartSession() error {
 	session, err := m.client.NewSession()
 	if err != nil {
 		return err
 	}
-	m.session = session
 
 	stdinPipe, err := session.StdinPipe()
 	if err != nil {
 		closeErr := session.Close()
 		return errors.Join(err, closeErr)
 	}
-	m.stdin = stdinPipe
-
-	var tw io.Writer
-	if m.program != nil {
-		tw = &terminalWriter{program: m.program}
-	} else {
-		tw = &m.buf
-	}
 
-	if err := m.client.StartTerminal(session, nil, tw, tw); err != nil {
+	// The PTY starts at the pane's size, so the shell and the first
+	// program draw for the right geometry.
+	m.mu.Lock()
+	width, height := m.width, m.height
+	m.mu.Unlock()
+	tw := &terminalWriter{program: m.program, term: m}
+	if err := m.client.StartTerminal(session, width, height, nil, tw, tw); err != nil {
 		closeErr := session.Close()
 		return errors.Join(err, closeErr)
 	}
 
+	m.mu.Lock()
+	m.session = session
+	m.stdin = stdinPipe
+	resized := m.width != width || m.height != height
+	width, height = m.width, m.height
+	m.mu.Unlock()
+	if resized && width > 0 && height > 0 {
+		if err := m.client.ResizePty(session, width, height); err != nil {
+			log.Printf("[Terminal] resize pty: %v", err)
+		}
+	}
+	if m.cwdHook {
+		if err := m.Write([]byte(cwdHook)); err != nil {
+			log.Printf("[Terminal] shell integration: %v", err)
+		}
+	}
+
 	go func() {
+		msg := "\r\n[Session closed]\r\n"
 		if err := session.Wait(); err != nil {
-			if m.program != nil {
-				m.program.Send(TerminalOutputMsg{Data: []byte(fmt.Sprintf("\r\n[Session exited: %s]\r\n", err))})
-			}
-			return
-		}
-		if m.program != nil {
-			m.program.Send(TerminalOutputMsg{Data: []byte("\r\n[Session closed]\r\n")})
+			msg = fmt.Sprintf("\r\n[Session exited: %s]\r\n", err)
 		}
+		_, _ = tw.Write([]byte(msg))
 	}()
-
-	m.err = ""
 	return nil
 }
 
 // Write sends data to the SSH session stdin.
 func (m *TerminalModel) Write(data []byte) error {
-	if m.stdin == nil {
+	m.mu.Lock()
+	stdin := m.stdin
+	m.mu.Unlock()
+	if stdin == nil {
 		return nil
 	}
-	_, err := m.stdin.Write(data)
+	_, err := stdin.Write(data)
 	return err
 }
 
-// Resize resizes the terminal PTY.
+// SendKey translates a key press into the bytes the PTY expects and sends
+// them to the session.
+func (m *TerminalModel) SendKey(msg tea.KeyMsg) error {
+	m.mu.Lock()
+	m.notice = ""
+	s := m.term()
+	data := keyToBytes(msg, s.AppCursorKeys(), s.BracketedPaste())
+	rec := m.recorder
+	if !m.recordInput {
+		rec = nil
+	}
+	m.mu.Unlock()
+	if len(data) == 0 {
+		return nil
+	}
+	if rec != nil {
+		rec.Input(data)
+	}
+	return m.Write(data)
+}
+
+// Resize resizes the screen and the terminal PTY to width columns and
+// height rows.
 func (m *TerminalModel) Resize(width, height int) {
+	m.mu.Lock()
 	m.width = width
 	m.height = height
-	if m.session != nil {
-		if err := m.client.ResizePty(m.session, width, height); err != nil {
+	if m.screen != nil {
+		m.screen.Resize(width, height)
+	}
+	if m.recorder != nil {
+		m.recorder.Resize(width, height)
+	}
+	session := m.session
+	m.mu.Unlock()
+	if session != nil {
+		if err := m.client.ResizePty(session, width, height); err != nil {
 			m.err = fmt.Sprintf("resize pty: %s", err)
 		}
 	}
 }
 
+// termType returns the terminal type of the session's PTY.
+func (m *TerminalModel) termType() string {
+	if m.client == nil {
+		return sshclient.DefaultTerm
+	}
+	return m.client.Term()
+}
+
+// SetDimensions sizes the terminal for a pane of the given outer size, as
+// drawn by RenderTerminal. The PTY is only resized when the inner area
+// changes.
+func (m *TerminalModel) SetDimensions(width, height int) {
+	cols, rows := max(width-4, 1), max(height-2, 1)
+	if cols != m.width || rows != m.height {
+		m.Resize(cols, rows)
+	}
+}
+
 // Close closes the terminal session and returns any errors encountered.
+// A running recording is finished.
 func (m *TerminalModel) Close() error {
+	m.mu.Lock()
+	stdin, session := m.stdin, m.session
+	m.mu.Unlock()
 	var errs []error
-	if m.stdin != nil {
-		if err := m.stdin.Close(); err != nil {
+	if m.Recording() != "" {
+		if _, err := m.StopRecording(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if stdin != nil {
+		if err := stdin.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("close stdin: %w", err))
 		}
 	}
-	if m.session != nil {
-		if err := m.session.Close(); err != nil {
+	if session != nil {
+		if err := session.Close(); err != nil && !errors.Is(err, io.EOF) {
 			errs = append(errs, fmt.Errorf("close session: %w", err))
 		}
 	}
 	return errors.Join(errs...)
 }
 
-// AppendOutput appends terminal output to the buffer.
+// term returns the emulated screen, creating it on first use. The caller
+// holds m.mu.
+func (m *TerminalModel) term() *vt.Screen {
+	if m.screen == nil {
+		cols, rows := m.width, m.height
+		if cols <= 0 || rows <= 0 {
+			cols, rows = defaultTermCols, defaultTermRows
+		}
+		m.screen = vt.New(cols, rows)
+		m.screen.SetScrollback(m.scrollback)
+	}
+	return m.screen
+}
+
+// SetScrollback sets how many lines that scroll off the top of the screen
+// are kept for copy mode. Zero or less keeps none.
+func (m *TerminalModel) SetScrollback(lines int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scrollback = max(lines, 0)
+	if m.screen != nil {
+		m.screen.SetScrollback(m.scrollback)
+	}
+}
+
+// EnterCopyMode starts copy mode on a snapshot of the scrollback and the
+// screen, with the cursor one page up from the bottom.
+func (m *TerminalModel) EnterCopyMode() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.term()
+	cols, rows := s.Size()
+	lines := s.Lines()
+	m.copy = newCopyMode(lines, len(lines)-1, 0, cols, rows)
+	m.copy.page(-m.copy.rows)
+	m.notice = ""
+}
+
+// CopyMode reports whether copy mode is active.
+func (m *TerminalModel) CopyMode() bool {
+	return m.copy != nil
+}
+
+// UpdateCopyMode handles a key press in copy mode. Yanked text is copied to
+// the system clipboard when copy mode ends.
+func (m *TerminalModel) UpdateCopyMode(msg tea.KeyMsg) tea.Cmd {
+	if m.copy == nil {
+		return nil
+	}
+	done, cmd := m.copy.update(msg)
+	if !done {
+		return cmd
+	}
+	text := m.copy.copied
+	m.copy = nil
+	if text == "" {
+		return nil
+	}
+	m.notice = fmt.Sprintf("Copied %d bytes to clipboard", len(text))
+	return copyToClipboard(text)
+}
+
+// AppendOutput feeds terminal output to the screen. Answers to device
+// queries in the output are written back to the session.
 func (m *TerminalModel) AppendOutput(data []byte) {
+	m.mu.Lock()
+	s := m.term()
+	_, _ = s.Write(data)
+	replies := s.Replies()
+	if m.recorder != nil {
+		m.recorder.Output(data)
+	}
+	if host, dir := s.Cwd(); dir != "" && dir != m.cwd && m.sameHost(host) {
+		m.cwd, m.cwdChanged = dir, true
+	}
+	m.mu.Unlock()
+	if len(replies) > 0 {
+		if err := m.Write(replies); err != nil {
+			log.Printf("[Terminal] write reply: %v", err)
+		}
+	}
+}
+
+// StartRecording records the session's output, and its keystrokes when
+// input is set, to a new asciinema file at path.
+func (m *TerminalModel) StartRecording(path, title string, input bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.buf.Write(data)
-	if m.buf.Len() > 100*1024 {
-		b := m.buf.Bytes()
-		m.buf.Reset()
-		m.buf.Write(b[len(b)-50*1024:])
+	if m.recorder != nil {
+		return fmt.Errorf("already recording to %s", m.recordPath)
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	cols, rows := m.term().Size()
+	rec, err := cast.Create(path, cast.Header{
+		Width:  cols,
+		Height: rows,
+		Title:  title,
+		Env:    map[string]string{"TERM": m.termType()},
+	})
+	if err != nil {
+		return err
+	}
+	m.recorder, m.recordPath, m.recordInput = rec, path, input
+	m.notice = "Recording to " + path
+	return nil
 }
 
-// BufferedOutput returns the current terminal output.
+// StopRecording finishes the recording and returns its path.
+func (m *TerminalModel) StopRecording() (string, error) {
+	m.mu.Lock()
+	rec, path := m.recorder, m.recordPath
+	m.recorder, m.recordPath = nil, ""
+	m.mu.Unlock()
+	if rec == nil {
+		return "", nil
+	}
+	if err := rec.Close(); err != nil {
+		return path, fmt.Errorf("recording %s: %w", path, err)
+	}
+	m.notice = "Saved recording " + path
+	return path, nil
+}
+
+// Recording returns the path of the running recording, or "".
+func (m *TerminalModel) Recording() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.recordPath
+}
+
+// BufferedOutput returns the text on the screen, without trailing blanks.
 func (m *TerminalModel) BufferedOutput() string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.buf.String()
+	return m.term().String()
 }
 
 var (
@@ -169,67 +394,136 @@ var (
 
 	activeTerminalStyle = terminalStyle.
 				BorderForeground(lipgloss.Color("#7D56F4"))
+
+	broadcastTerminalStyle = terminalStyle.
+				BorderForeground(lipgloss.Color("#FF9500")).
+				BorderStyle(lipgloss.DoubleBorder())
 )
 
+// SetBroadcast marks the terminal as a member of the active broadcast
+// group, which draws its pane with an orange double border.
+func (m *TerminalModel) SetBroadcast(on bool) {
+	m.broadcast = on
+}
+
+// Broadcast reports whether the terminal is in the broadcast group.
+func (m *TerminalModel) Broadcast() bool {
+	return m.broadcast
+}
+
 // SetError records an error message to display in the terminal view.
 func (m *TerminalModel) SetError(msg string) {
 	m.err = msg
 }
 
-// RenderTerminal returns the terminal view string.
+// RenderTerminal returns the terminal pane, width by height cells including
+// its border. The cursor is only drawn in the active pane.
 func (m *TerminalModel) RenderTerminal(active bool, width, height int) string {
 	style := terminalStyle
-	if active {
+	switch {
+	case m.broadcast:
+		style = broadcastTerminalStyle
+	case active:
 		style = activeTerminalStyle
 	}
+	style = style.Width(max(width-2, 0)).Height(max(height-2, 0))
 
 	if m.err != "" {
 		errView := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FF5555")).
 			Bold(true).
 			Render("Error: " + m.err)
-		return style.Width(width).Height(height).Render(errView)
+		return style.Render(errView)
 	}
 
-	output := m.BufferedOutput()
-	lines := splitOutputLines(output, height-4)
-	content := joinLines(lines)
+	cols, rows := max(width-4, 1), max(height-2, 1)
+	if m.copy != nil {
+		m.copy.cols, m.copy.rows = cols, max(rows-1, 1)
+		m.copy.scroll()
+		return style.Render(m.copy.view())
+	}
 
-	return style.Width(width).Height(height).Render(content)
+	m.mu.Lock()
+	if m.notice != "" {
+		rows--
+	}
+	content := m.term().Render(cols, max(rows, 1), active)
+	m.mu.Unlock()
+	if m.notice != "" {
+		content += "\n" + messageStyle.Render(truncate(m.notice, max(cols, 1)))
+	}
+	return style.Render(content)
 }
 
-func splitOutputLines(s string, n int) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			lines = append(lines, s[start:i])
-			start = i + 1
+// keyToBytes maps a key press to the byte sequence an xterm sends for it.
+// appCursor selects application cursor keys (DECCKM); bracketed wraps
+// pasted text in bracketed-paste markers.
+func keyToBytes(msg tea.KeyMsg, appCursor, bracketed bool) []byte {
+	var seq string
+	switch msg.Type {
+	case tea.KeyRunes:
+		seq = string(msg.Runes)
+		if msg.Paste && bracketed {
+			return []byte("\x1b[200~" + seq + "\x1b[201~")
+		}
+	case tea.KeySpace:
+		seq = " "
+	case tea.KeyUp, tea.KeyDown, tea.KeyRight, tea.KeyLeft, tea.KeyHome, tea.KeyEnd:
+		final := cursorKeyFinal[msg.Type]
+		if appCursor {
+			seq = "\x1bO" + final
+		} else {
+			seq = "\x1b[" + final
+		}
+	case tea.KeyShiftTab:
+		seq = "\x1b[Z"
+	case tea.KeyInsert:
+		seq = "\x1b[2~"
+	case tea.KeyDelete:
+		seq = "\x1b[3~"
+	case tea.KeyPgUp:
+		seq = "\x1b[5~"
+	case tea.KeyPgDown:
+		seq = "\x1b[6~"
+	case tea.KeyCtrlPgUp:
+		seq = "\x1b[5;5~"
+	case tea.KeyCtrlPgDown:
+		seq = "\x1b[6;5~"
+	default:
+		if mod, ok := modifiedCursorKeys[msg.Type]; ok {
+			seq = "\x1b[1;" + mod
+		} else if f, ok := functionKeys[msg.Type]; ok {
+			seq = f
+		} else if msg.Type >= 0 && msg.Type <= 0x1f || msg.Type == 0x7f {
+			seq = string(rune(msg.Type))
+		} else {
+			return nil
 		}
 	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
-	}
-	if n > 0 && len(lines) > n {
-		lines = lines[len(lines)-n:]
+	if msg.Alt {
+		seq = "\x1b" + seq
 	}
-	return lines
+	return []byte(seq)
 }
 
-func joinLines(lines []string) string {
-	if len(lines) == 0 {
-		return ""
-	}
-	total := 0
-	for _, l := range lines {
-		total += len(l) + 1
-	}
-	b := make([]byte, 0, total)
-	for i, l := range lines {
-		b = append(b, l...)
-		if i < len(lines)-1 {
-			b = append(b, '\n')
-		}
-	}
-	return string(b)
+var cursorKeyFinal = map[tea.KeyType]string{
+	tea.KeyUp: "A", tea.KeyDown: "B", tea.KeyRight: "C", tea.KeyLeft: "D",
+	tea.KeyHome: "H", tea.KeyEnd: "F",
+}
+
+// modifiedCursorKeys holds the modifier parameter and final byte of
+// shifted and control cursor keys (CSI 1 ; mod final).
+var modifiedCursorKeys = map[tea.KeyType]string{
+	tea.KeyShiftUp: "2A", tea.KeyShiftDown: "2B", tea.KeyShiftRight: "2C", tea.KeyShiftLeft: "2D",
+	tea.KeyShiftHome: "2H", tea.KeyShiftEnd: "2F",
+	tea.KeyCtrlUp: "5A", tea.KeyCtrlDown: "5B", tea.KeyCtrlRight: "5C", tea.KeyCtrlLeft: "5D",
+	tea.KeyCtrlHome: "5H", tea.KeyCtrlEnd: "5F",
+	tea.KeyCtrlShiftUp: "6A", tea.KeyCtrlShiftDown: "6B", tea.KeyCtrlShiftRight: "6C", tea.KeyCtrlShiftLeft: "6D",
+	tea.KeyCtrlShiftHome: "6H", tea.KeyCtrlShiftEnd: "6F",
+}
+
+var functionKeys = map[tea.KeyType]string{
+	tea.KeyF1: "\x1bOP", tea.KeyF2: "\x1bOQ", tea.KeyF3: "\x1bOR", tea.KeyF4: "\x1bOS",
+	tea.KeyF5: "\x1b[15~", tea.KeyF6: "\x1b[17~", tea.KeyF7: "\x1b[18~", tea.KeyF8: "\x1b[19~",
+	tea.KeyF9: "\x1b[20~", tea.KeyF10: "\x1b[21~", tea.KeyF11: "\x1b[23~", tea.KeyF12: "\x1b[24~",
 }
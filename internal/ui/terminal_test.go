@@ -2,84 +2,15 @@ package ui
 
 import (
 	"io"
+	"path/filepath"
 	"strings"
 	"testing"
-)
-
-// ---------------------------------------------------------------------------
-// splitOutputLines
-// ---------------------------------------------------------------------------
 
-func TestSplitOutputLinesBasic(t *testing.T) {
-	lines := splitOutputLines("line1\nline2\nline3", 10)
-	if len(lines) != 3 {
-		t.Fatalf("expected 3, got %d", len(lines))
-	}
-}
+	"ssh-scp/internal/cast"
 
-func TestSplitOutputLinesTruncates(t *testing.T) {
-	input := "a\nb\nc\nd\ne"
-	lines := splitOutputLines(input, 3)
-	if len(lines) != 3 {
-		t.Fatalf("expected 3 (truncated), got %d", len(lines))
-	}
-	if lines[0] != "c" {
-		t.Errorf("first visible line = %q, want %q", lines[0], "c")
-	}
-}
-
-func TestSplitOutputLinesZeroN(t *testing.T) {
-	lines := splitOutputLines("a\nb\nc", 0)
-	if len(lines) != 3 {
-		t.Errorf("n=0 should return all, got %d", len(lines))
-	}
-}
-
-func TestSplitOutputLinesEmpty(t *testing.T) {
-	lines := splitOutputLines("", 10)
-	if len(lines) != 0 {
-		t.Errorf("expected 0, got %d", len(lines))
-	}
-}
-
-func TestSplitOutputLinesSingle(t *testing.T) {
-	lines := splitOutputLines("hello", 1)
-	if len(lines) != 1 || lines[0] != "hello" {
-		t.Errorf("expected [hello], got %v", lines)
-	}
-}
-
-func TestSplitOutputLinesNoNewline(t *testing.T) {
-	lines := splitOutputLines("hello world", 10)
-	if len(lines) != 1 || lines[0] != "hello world" {
-		t.Errorf("expected [hello world], got %v", lines)
-	}
-}
-
-// ---------------------------------------------------------------------------
-// joinLines
-// ---------------------------------------------------------------------------
-
-func TestJoinLinesMultiple(t *testing.T) {
-	got := joinLines([]string{"a", "b", "c"})
-	if got != "a\nb\nc" {
-		t.Errorf("joinLines = %q, want %q", got, "a\nb\nc")
-	}
-}
-
-func TestJoinLinesEmpty(t *testing.T) {
-	got := joinLines(nil)
-	if got != "" {
-		t.Errorf("joinLines nil = %q, want empty", got)
-	}
-}
-
-func TestJoinLinesSingle(t *testing.T) {
-	got := joinLines([]string{"hello"})
-	if got != "hello" {
-		t.Errorf("joinLines single = %q, want %q", got, "hello")
-	}
-}
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
 
 // ---------------------------------------------------------------------------
 // TerminalModel - AppendOutput & BufferedOutput
@@ -95,22 +26,64 @@ func TestTerminalModelAppendOutput(t *testing.T) {
 	}
 }
 
-func TestTerminalModelBufferCap(t *testing.T) {
+func TestTerminalModelKeepsOnlyScreen(t *testing.T) {
 	m := &TerminalModel{}
-	// Write > 100KB to trigger trim
-	bigData := make([]byte, 80*1024)
+	// 160KB of output without newlines wraps and scrolls off the screen.
+	bigData := make([]byte, 160*1024)
 	for i := range bigData {
 		bigData[i] = 'A'
 	}
 	m.AppendOutput(bigData)
-	m.AppendOutput(bigData) // ~160KB total, should trigger trim to ~50KB
 
-	output := m.BufferedOutput()
-	if len(output) > 100*1024 {
-		t.Errorf("buffer should be capped, got %d bytes", len(output))
+	lines := strings.Split(m.BufferedOutput(), "\n")
+	if len(lines) != defaultTermRows {
+		t.Errorf("screen should hold %d rows, got %d", defaultTermRows, len(lines))
+	}
+	if len(lines[0]) != defaultTermCols {
+		t.Errorf("rows should be %d columns wide, got %d", defaultTermCols, len(lines[0]))
+	}
+}
+
+func TestTerminalModelEscapeSequences(t *testing.T) {
+	m := &TerminalModel{}
+	m.AppendOutput([]byte("old prompt\r\n\x1b[2J\x1b[H\x1b[1;32mclean\x1b[0m\x1b[5;3Hx"))
+	if got := m.BufferedOutput(); got != "clean\n\n\n\n  x" {
+		t.Errorf("BufferedOutput = %q", got)
 	}
-	if len(output) < 50*1024 {
-		t.Errorf("buffer should retain ~50KB, got %d bytes", len(output))
+	if strings.Contains(m.RenderTerminal(false, 80, 24), "[1;32m") {
+		t.Error("escape sequences should be interpreted, not shown")
+	}
+}
+
+func TestTerminalModelAnswersQueries(t *testing.T) {
+	r, w := io.Pipe()
+	defer func() { _ = r.Close() }()
+	m := &TerminalModel{stdin: w}
+	got := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 32)
+		n, _ := r.Read(buf)
+		got <- string(buf[:n])
+	}()
+	m.AppendOutput([]byte("ab\x1b[6n"))
+	if reply := <-got; reply != "\x1b[1;3R" {
+		t.Errorf("cursor position reply = %q", reply)
+	}
+}
+
+func TestTerminalModelSetDimensions(t *testing.T) {
+	m := NewTerminalModel(nil)
+	m.SetDimensions(100, 12)
+	if m.width != 96 || m.height != 10 {
+		t.Errorf("PTY size = %dx%d, want 96x10", m.width, m.height)
+	}
+	m.AppendOutput([]byte("x"))
+	if w, h := m.screen.Size(); w != 96 || h != 10 {
+		t.Errorf("screen size = %dx%d, want 96x10", w, h)
+	}
+	view := m.RenderTerminal(true, 100, 12)
+	if lipgloss.Width(view) != 100 || lipgloss.Height(view) != 12 {
+		t.Errorf("pane is %dx%d, want 100x12", lipgloss.Width(view), lipgloss.Height(view))
 	}
 }
 
@@ -347,3 +320,102 @@ func TestTerminalModelRenderActiveError(t *testing.T) {
 		t.Error("active error view should contain error text")
 	}
 }
+
+// ---------------------------------------------------------------------------
+// keyToBytes & SendKey
+// ---------------------------------------------------------------------------
+
+func TestKeyToBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       tea.KeyMsg
+		appCursor bool
+		want      string
+	}{
+		{"runes", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ls")}, false, "ls"},
+		{"alt rune", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true}, false, "\x1bb"},
+		{"enter", tea.KeyMsg{Type: tea.KeyEnter}, false, "\r"},
+		{"backspace", tea.KeyMsg{Type: tea.KeyBackspace}, false, "\x7f"},
+		{"ctrl+c", tea.KeyMsg{Type: tea.KeyCtrlC}, false, "\x03"},
+		{"esc", tea.KeyMsg{Type: tea.KeyEsc}, false, "\x1b"},
+		{"space", tea.KeyMsg{Type: tea.KeySpace}, false, " "},
+		{"up", tea.KeyMsg{Type: tea.KeyUp}, false, "\x1b[A"},
+		{"up app", tea.KeyMsg{Type: tea.KeyUp}, true, "\x1bOA"},
+		{"ctrl+left", tea.KeyMsg{Type: tea.KeyCtrlLeft}, false, "\x1b[1;5D"},
+		{"delete", tea.KeyMsg{Type: tea.KeyDelete}, false, "\x1b[3~"},
+		{"f1", tea.KeyMsg{Type: tea.KeyF1}, false, "\x1bOP"},
+		{"f10", tea.KeyMsg{Type: tea.KeyF10}, false, "\x1b[21~"},
+		{"shift+tab", tea.KeyMsg{Type: tea.KeyShiftTab}, false, "\x1b[Z"},
+	}
+	for _, tt := range tests {
+		if got := string(keyToBytes(tt.key, tt.appCursor, false)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	paste := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a b"), Paste: true}
+	if got := string(keyToBytes(paste, false, true)); got != "\x1b[200~a b\x1b[201~" {
+		t.Errorf("bracketed paste = %q", got)
+	}
+}
+
+func TestTerminalModelSendKeyFollowsCursorMode(t *testing.T) {
+	r, w := io.Pipe()
+	defer func() { _ = r.Close() }()
+	m := &TerminalModel{stdin: w}
+	got := make(chan string, 2)
+	go func() {
+		buf := make([]byte, 16)
+		for i := 0; i < 2; i++ {
+			n, _ := r.Read(buf)
+			got <- string(buf[:n])
+		}
+	}()
+	_ = m.SendKey(tea.KeyMsg{Type: tea.KeyDown})
+	if s := <-got; s != "\x1b[B" {
+		t.Errorf("normal mode down = %q", s)
+	}
+	m.AppendOutput([]byte("\x1b[?1h"))
+	_ = m.SendKey(tea.KeyMsg{Type: tea.KeyDown})
+	if s := <-got; s != "\x1bOB" {
+		t.Errorf("application mode down = %q", s)
+	}
+}
+
+func TestTerminalModelRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec", "s.cast")
+	m := &TerminalModel{}
+	m.SetDimensions(44, 12)
+	if err := m.StartRecording(path, "u@h", true); err != nil {
+		t.Fatal(err)
+	}
+	if m.Recording() != path {
+		t.Errorf("Recording() = %q", m.Recording())
+	}
+	if err := m.StartRecording(path, "u@h", true); err == nil {
+		t.Error("starting a second recording should fail")
+	}
+	m.AppendOutput([]byte("$ "))
+	_ = m.SendKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	m.SetDimensions(54, 12)
+	if got, err := m.StopRecording(); err != nil || got != path {
+		t.Fatalf("StopRecording = %q, %v", got, err)
+	}
+	if m.Recording() != "" {
+		t.Error("recording should have stopped")
+	}
+
+	rec, err := cast.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header.Width != 40 || rec.Header.Height != 10 || rec.Header.Title != "u@h" {
+		t.Errorf("header = %+v", rec.Header)
+	}
+	var types []string
+	for _, ev := range rec.Events {
+		types = append(types, ev.Type+":"+ev.Data)
+	}
+	if got := strings.Join(types, ","); got != "o:$ ,i:l,r:50x10" {
+		t.Errorf("events = %s", got)
+	}
+}
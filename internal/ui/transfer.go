@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"ssh-scp/internal/config"
+	sshclient "ssh-scp/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SetHistoryHost sets the host key under which completed transfers are
+// recorded in the transfer history. An empty key disables recording.
+func (m *FileBrowserModel) SetHistoryHost(hostKey string) {
+	m.historyHost = hostKey
+}
+
+// SetCompress sets whether transfers are compressed by default.
+func (m *FileBrowserModel) SetCompress(on bool) {
+	m.compress = on
+}
+
+// transferSelected uploads or downloads the selected file, depending on the
+// focused panel. Directories are ignored. For a symlink it asks whether to
+// recreate the link on the other side or copy its target's content; broken
+// links can only be recreated.
+func (m FileBrowserModel) transferSelected(compress bool) (FileBrowserModel, tea.Cmd) {
+	if m.transferring {
+		return m, nil
+	}
+	if target, broken, isLink := m.selectedLink(); isLink && broken {
+		return m.copyLink(target)
+	} else if isLink {
+		return m.askLinkChoice(opTransfer, compress), nil
+	}
+	return m.transferEntry(compress)
+}
+
+// transferEntry uploads or downloads the selected file, following it if it
+// is a symlink. Directories and links to them are ignored.
+func (m FileBrowserModel) transferEntry(compress bool) (FileBrowserModel, tea.Cmd) {
+	if m.focus == panelLocal && len(m.localFiles) > 0 {
+		f := m.localFiles[m.localCursor]
+		if !m.localIsDir(f) {
+			localPath := filepath.Join(m.localDir, f.Name())
+			remotePath := joinRemotePath(m.remoteDir, f.Name())
+			return m.startUpload(localPath, remotePath, compress)
+		}
+	} else if m.focus == panelRemote && len(m.remoteFiles) > 0 {
+		f := m.remoteFiles[m.remoteCursor]
+		if !remoteIsDir(f) {
+			remotePath := joinRemotePath(m.remoteDir, f.Name)
+			return m.startDownload(remotePath, m.localDir, compress)
+		}
+	}
+	return m, nil
+}
+
+// deltaMinSize is the file size from which uploads try a delta transfer
+// first. Below it, hashing blocks on both sides costs more than it saves.
+const deltaMinSize = 8 << 20
+
+// startUpload begins an async SCP upload of localPath to remotePath. When
+// compress is set the file is piped through gzip/zstd; otherwise large files
+// are sent as a delta against the existing remote file, if any.
+func (m FileBrowserModel) startUpload(localPath, remotePath string, compress bool) (FileBrowserModel, tea.Cmd) {
+	name := filepath.Base(localPath)
+	m.transferring = true
+	m.transferProgress = name
+	m.statusMsg = "Uploading " + name + "..." + compressSuffix(compress) + m.rateLimitSuffix()
+	client := m.client
+	host := m.historyHost
+	delta := false
+	if info, err := os.Stat(localPath); err == nil && info.Size() >= deltaMinSize && !compress {
+		delta = true
+	}
+	return m, func() tea.Msg {
+		start := time.Now()
+		var err error
+		var detail string
+		switch {
+		case compress:
+			var comp sshclient.Compression
+			comp, err = client.UploadCompressed(localPath, remotePath)
+			detail = compressionDetail(comp)
+		case delta:
+			var res sshclient.DeltaResult
+			res, err = client.DeltaUpload(localPath, remotePath)
+			if err == nil && !res.Full {
+				detail = fmt.Sprintf("delta: %d/%d blocks sent", res.Changed, res.Blocks)
+			}
+		default:
+			err = client.UploadFile(localPath, remotePath)
+		}
+		recordTransfer(host, true, localPath, remotePath, localPath, start, err)
+		return TransferDoneMsg{Err: err, Detail: detail}
+	}
+}
+
+// startDownload begins an async SCP download of remotePath into localDir,
+// optionally compressed on the remote side.
+func (m FileBrowserModel) startDownload(remotePath, localDir string, compress bool) (FileBrowserModel, tea.Cmd) {
+	name := path.Base(remotePath)
+	m.transferring = true
+	m.transferProgress = name
+	m.statusMsg = "Downloading " + name + "..." + compressSuffix(compress) + m.rateLimitSuffix()
+	client := m.client
+	host := m.historyHost
+	return m, func() tea.Msg {
+		start := time.Now()
+		var err error
+		var detail string
+		if compress {
+			var comp sshclient.Compression
+			comp, err = client.DownloadCompressed(remotePath, localDir)
+			detail = compressionDetail(comp)
+		} else {
+			err = client.DownloadFile(remotePath, localDir)
+		}
+		localPath := filepath.Join(localDir, name)
+		recordTransfer(host, false, remotePath, localPath, localPath, start, err)
+		return TransferDoneMsg{Err: err, Detail: detail}
+	}
+}
+
+// compressSuffix returns the status bar note for a compressed transfer.
+func compressSuffix(compress bool) string {
+	if compress {
+		return " (compressed)"
+	}
+	return ""
+}
+
+// compressionDetail describes the compressor used for a transfer.
+func compressionDetail(comp sshclient.Compression) string {
+	if comp == sshclient.CompressNone {
+		return "uncompressed: no common compressor"
+	}
+	return string(comp) + " compressed"
+}
+
+// recordTransfer appends a transfer to the host's history. localPath is the
+// local side of the transfer, used for the size and checksum of a successful
+// one. It runs inside
+// the transfer command, so the extra disk I/O never blocks the UI.
+func recordTransfer(hostKey string, upload bool, src, dst, localPath string, start time.Time, err error) {
+	if hostKey == "" {
+		return
+	}
+	rec := config.TransferRecord{
+		Time:        start,
+		Upload:      upload,
+		Source:      src,
+		Destination: dst,
+		Duration:    time.Since(start),
+		Result:      "ok",
+	}
+	if err != nil {
+		// The local file of a failed download is truncated, so its size
+		// says nothing about the transfer.
+		rec.Result = err.Error()
+	} else {
+		if info, statErr := os.Stat(localPath); statErr == nil {
+			rec.Size = info.Size()
+		}
+		if sum, sumErr := fileSHA256(localPath); sumErr == nil {
+			rec.Checksum = sum
+		}
+	}
+	if hErr := config.AppendHistory(hostKey, rec); hErr != nil {
+		log.Printf("[FileBrowser] failed to record transfer history: %v", hErr)
+	}
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 digest of a local file.
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RerunTransfer repeats a transfer from the history. Downloads are placed in
+// the directory of the originally downloaded file.
+func (m FileBrowserModel) RerunTransfer(rec config.TransferRecord) (FileBrowserModel, tea.Cmd) {
+	if m.transferring {
+		m.statusMsg = "A transfer is already in progress"
+		return m, nil
+	}
+	if rec.Upload {
+		return m.startUpload(rec.Source, rec.Destination, m.compress)
+	}
+	return m.startDownload(rec.Source, filepath.Dir(rec.Destination), m.compress)
+}
@@ -0,0 +1,580 @@
+package vt
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parser states, after the DEC ANSI parser state machine.
+const (
+	stateGround = iota
+	stateEscape
+	stateEscapeInter
+	stateCSI
+	stateOSC
+	stateString // DCS, SOS, PM and APC payloads are skipped
+)
+
+const (
+	maxParams = 32
+	maxOSC    = 4096
+)
+
+// parser holds the state carried between calls to Write.
+type parser struct {
+	state   int
+	params  []int
+	param   int  // value of the parameter being read, -1 if empty
+	private byte // '?', '>', '=' or '<' leading a CSI sequence
+	inter   byte // intermediate byte of a CSI or ESC sequence
+	osc     []byte
+	strEsc  bool // ESC seen inside an OSC or string; ST may follow
+	utf8    [utf8.UTFMax]byte
+	utf8n   int
+}
+
+// Write feeds PTY output to the screen. It never fails.
+func (s *Screen) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.step(b)
+	}
+	return len(p), nil
+}
+
+func (s *Screen) step(b byte) {
+	ps := &s.parser
+	if ps.utf8n > 0 {
+		if b&0xc0 == 0x80 {
+			ps.utf8[ps.utf8n] = b
+			ps.utf8n++
+			if utf8.FullRune(ps.utf8[:ps.utf8n]) {
+				r, _ := utf8.DecodeRune(ps.utf8[:ps.utf8n])
+				ps.utf8n = 0
+				s.put(r)
+			}
+			return
+		}
+		ps.utf8n = 0
+		s.put(utf8.RuneError)
+	}
+
+	switch ps.state {
+	case stateOSC, stateString:
+		s.stepString(b)
+		return
+	}
+
+	if b < 0x20 {
+		if b == 0x1b {
+			ps.state = stateEscape
+			ps.inter = 0
+			return
+		}
+		if b == 0x18 || b == 0x1a { // CAN, SUB abort a sequence
+			ps.state = stateGround
+			return
+		}
+		s.execute(b)
+		return
+	}
+	if b == 0x7f {
+		return
+	}
+
+	switch ps.state {
+	case stateGround:
+		if b < 0x80 {
+			s.put(rune(b))
+			return
+		}
+		if utf8.RuneStart(b) && b >= 0xc0 {
+			ps.utf8[0] = b
+			ps.utf8n = 1
+			return
+		}
+		s.put(utf8.RuneError)
+
+	case stateEscape:
+		switch {
+		case b == '[':
+			ps.state = stateCSI
+			ps.params = ps.params[:0]
+			ps.param = -1
+			ps.private, ps.inter = 0, 0
+		case b == ']':
+			ps.state = stateOSC
+			ps.osc = ps.osc[:0]
+			ps.strEsc = false
+		case b == 'P' || b == 'X' || b == '^' || b == '_':
+			ps.state = stateString
+			ps.strEsc = false
+		case b >= 0x20 && b <= 0x2f:
+			ps.inter = b
+			ps.state = stateEscapeInter
+		default:
+			ps.state = stateGround
+			s.escDispatch(b)
+		}
+
+	case stateEscapeInter:
+		if b >= 0x20 && b <= 0x2f {
+			return
+		}
+		ps.state = stateGround
+		s.escInterDispatch(ps.inter, b)
+
+	case stateCSI:
+		switch {
+		case b >= '0' && b <= '9':
+			if ps.param < 0 {
+				ps.param = 0
+			}
+			if ps.param < 100000 {
+				ps.param = ps.param*10 + int(b-'0')
+			}
+		case b == ';' || b == ':':
+			if len(ps.params) < maxParams {
+				ps.params = append(ps.params, ps.param)
+			}
+			ps.param = -1
+		case b >= '<' && b <= '?':
+			ps.private = b
+		case b >= 0x20 && b <= 0x2f:
+			ps.inter = b
+		case b >= 0x40 && b <= 0x7e:
+			if (ps.param >= 0 || len(ps.params) > 0) && len(ps.params) < maxParams {
+				ps.params = append(ps.params, ps.param)
+			}
+			ps.state = stateGround
+			s.csiDispatch(b)
+		default:
+			ps.state = stateGround
+		}
+	}
+}
+
+// stepString consumes the payload of an OSC or an ignored string. Both end
+// at ST (ESC \); BEL also ends them, as xterm allows.
+func (s *Screen) stepString(b byte) {
+	ps := &s.parser
+	if ps.strEsc {
+		ps.strEsc = false
+		if ps.state == stateOSC {
+			s.oscDispatch(ps.osc)
+		}
+		ps.state = stateGround
+		if b != '\\' {
+			s.step(0x1b)
+			s.step(b)
+		}
+		return
+	}
+	switch b {
+	case 0x1b:
+		ps.strEsc = true
+	case 0x07:
+		if ps.state == stateOSC {
+			s.oscDispatch(ps.osc)
+		}
+		ps.state = stateGround
+	case 0x18, 0x1a:
+		ps.state = stateGround
+	default:
+		if ps.state == stateOSC && len(ps.osc) < maxOSC {
+			ps.osc = append(ps.osc, b)
+		}
+	}
+}
+
+// execute runs a C0 control character.
+func (s *Screen) execute(b byte) {
+	switch b {
+	case 0x08: // BS
+		s.moveTo(s.cur.x-1, s.cur.y)
+	case 0x09: // HT
+		s.tab(1)
+	case 0x0a, 0x0b, 0x0c: // LF, VT, FF
+		s.pendingWrap = false
+		s.lineFeed()
+	case 0x0d: // CR
+		s.moveTo(0, s.cur.y)
+	case 0x0e: // SO
+		s.cur.shift = 1
+	case 0x0f: // SI
+		s.cur.shift = 0
+	}
+}
+
+// escDispatch runs an ESC sequence without intermediates.
+func (s *Screen) escDispatch(b byte) {
+	switch b {
+	case '7': // DECSC
+		s.saveCursor()
+	case '8': // DECRC
+		s.restoreCursor()
+	case 'D': // IND
+		s.pendingWrap = false
+		s.lineFeed()
+	case 'E': // NEL
+		s.moveTo(0, s.cur.y)
+		s.lineFeed()
+	case 'M': // RI
+		s.pendingWrap = false
+		s.reverseIndex()
+	case 'H': // HTS
+		s.tabs[s.cur.x] = true
+	case 'c': // RIS
+		s.reset(s.width, s.height)
+	case '=': // DECKPAM
+		s.appKeypad = true
+	case '>': // DECKPNM
+		s.appKeypad = false
+	}
+}
+
+// escInterDispatch runs an ESC sequence with an intermediate byte.
+func (s *Screen) escInterDispatch(inter, b byte) {
+	switch inter {
+	case '(', ')': // designate G0 / G1
+		g := 0
+		if inter == ')' {
+			g = 1
+		}
+		s.cur.charset[g] = b == '0'
+	case '#':
+		if b == '8' { // DECALN
+			for y := range s.buf.lines {
+				for x := range s.buf.lines[y] {
+					s.buf.lines[y][x] = Cell{Ch: 'E'}
+				}
+			}
+			s.top, s.bottom = 0, s.height-1
+			s.moveTo(0, 0)
+		}
+	}
+}
+
+// param returns parameter i, or def when it is missing or zero.
+func (s *Screen) param(i, def int) int {
+	if i < len(s.parser.params) && s.parser.params[i] > 0 {
+		return s.parser.params[i]
+	}
+	return def
+}
+
+// csiDispatch runs a complete CSI sequence.
+func (s *Screen) csiDispatch(final byte) {
+	ps := &s.parser
+	if ps.private != 0 {
+		s.csiPrivate(final)
+		return
+	}
+	if ps.inter != 0 {
+		if ps.inter == '!' && final == 'p' { // DECSTR
+			s.softReset()
+		}
+		return
+	}
+	n := s.param(0, 1)
+	switch final {
+	case '@': // ICH
+		s.insertChars(n)
+	case 'A': // CUU
+		top := 0
+		if s.cur.y >= s.top {
+			top = s.top
+		}
+		s.moveTo(s.cur.x, max(s.cur.y-n, top))
+	case 'B', 'e': // CUD, VPR
+		bottom := s.height - 1
+		if s.cur.y <= s.bottom {
+			bottom = s.bottom
+		}
+		s.moveTo(s.cur.x, min(s.cur.y+n, bottom))
+	case 'C', 'a': // CUF, HPR
+		s.moveTo(s.cur.x+n, s.cur.y)
+	case 'D': // CUB
+		s.moveTo(s.cur.x-n, s.cur.y)
+	case 'E': // CNL
+		s.moveTo(0, min(s.cur.y+n, s.bottom))
+	case 'F': // CPL
+		s.moveTo(0, max(s.cur.y-n, s.top))
+	case 'G', '`': // CHA, HPA
+		s.moveTo(n-1, s.cur.y)
+	case 'H', 'f': // CUP, HVP
+		s.cursorPosition(s.param(0, 1), s.param(1, 1))
+	case 'I': // CHT
+		s.tab(n)
+	case 'J': // ED
+		s.eraseDisplay(s.param(0, 0))
+	case 'K': // EL
+		s.eraseLine(s.param(0, 0))
+	case 'L': // IL
+		s.insertLines(n)
+	case 'M': // DL
+		s.deleteLines(n)
+	case 'P': // DCH
+		s.deleteChars(n)
+	case 'S': // SU
+		s.scrollUp(n)
+	case 'T': // SD
+		if len(ps.params) <= 1 {
+			s.scrollDown(n)
+		}
+	case 'X': // ECH
+		s.eraseCells(s.cur.y, s.cur.x, s.cur.x+n)
+	case 'Z': // CBT
+		s.backTab(n)
+	case 'b': // REP
+		if s.lastRune != 0 {
+			for i := 0; i < min(n, s.width*s.height); i++ {
+				s.put(s.lastRune)
+			}
+		}
+	case 'c': // DA
+		if s.param(0, 0) == 0 {
+			s.replies = append(s.replies, "\x1b[?62;22c"...)
+		}
+	case 'd': // VPA
+		s.cursorPosition(n, s.cur.x+1)
+	case 'g': // TBC
+		switch s.param(0, 0) {
+		case 0:
+			s.tabs[s.cur.x] = false
+		case 3:
+			clear(s.tabs)
+		}
+	case 'h', 'l': // SM, RM
+		for _, p := range ps.params {
+			if p == 4 {
+				s.insert = final == 'h'
+			}
+		}
+	case 'm':
+		s.sgr()
+	case 'n': // DSR
+		switch s.param(0, 0) {
+		case 5:
+			s.replies = append(s.replies, "\x1b[0n"...)
+		case 6:
+			y := s.cur.y
+			if s.cur.origin {
+				y -= s.top
+			}
+			s.replies = fmt.Appendf(s.replies, "\x1b[%d;%dR", y+1, s.cur.x+1)
+		}
+	case 'r': // DECSTBM
+		top, bottom := s.param(0, 1)-1, s.param(1, s.height)-1
+		bottom = min(bottom, s.height-1)
+		if top < bottom {
+			s.top, s.bottom = top, bottom
+			s.cursorPosition(1, 1)
+		}
+	case 's': // SCOSC
+		s.saveCursor()
+	case 'u': // SCORC
+		s.restoreCursor()
+	}
+}
+
+// csiPrivate runs CSI sequences with a private marker, mostly DEC modes.
+func (s *Screen) csiPrivate(final byte) {
+	ps := &s.parser
+	switch {
+	case ps.private == '>' && final == 'c': // secondary DA
+		s.replies = append(s.replies, "\x1b[>1;10;0c"...)
+	case ps.private == '?' && (final == 'h' || final == 'l'):
+		on := final == 'h'
+		for _, p := range ps.params {
+			s.setMode(p, on)
+		}
+	}
+}
+
+// setMode sets a DEC private mode.
+func (s *Screen) setMode(mode int, on bool) {
+	switch mode {
+	case 1: // DECCKM
+		s.appCursor = on
+	case 6: // DECOM
+		s.cur.origin = on
+		s.cursorPosition(1, 1)
+	case 7: // DECAWM
+		s.autowrap = on
+		s.pendingWrap = false
+	case 25: // DECTCEM
+		s.cursorVisible = on
+	case 47, 1047:
+		s.setAltScreen(on)
+	case 1048:
+		if on {
+			s.saveCursor()
+		} else {
+			s.restoreCursor()
+		}
+	case 1049:
+		if on {
+			s.saveCursor()
+			s.setAltScreen(true)
+		} else {
+			s.setAltScreen(false)
+			s.restoreCursor()
+		}
+	case 2004:
+		s.bracketed = on
+	}
+}
+
+// cursorPosition implements CUP with 1-based row and column, relative to
+// the scroll region in origin mode.
+func (s *Screen) cursorPosition(row, col int) {
+	y := row - 1
+	if s.cur.origin {
+		y = min(y+s.top, s.bottom)
+	}
+	s.moveTo(col-1, y)
+}
+
+// softReset implements DECSTR.
+func (s *Screen) softReset() {
+	s.cursorVisible, s.autowrap, s.insert = true, true, false
+	s.appCursor, s.appKeypad = false, false
+	s.cur.origin, s.cur.attr = false, Attr{}
+	s.cur.charset, s.cur.shift = [2]bool{}, 0
+	s.top, s.bottom = 0, s.height-1
+	s.buf.saved = cursor{}
+}
+
+// sgr applies Select Graphic Rendition parameters to the pen.
+func (s *Screen) sgr() {
+	params := s.parser.params
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	a := &s.cur.attr
+	for i := 0; i < len(params); i++ {
+		p := max(params[i], 0)
+		switch {
+		case p == 0:
+			*a = Attr{}
+		case p == 1:
+			a.Flags |= Bold
+		case p == 2:
+			a.Flags |= Faint
+		case p == 3:
+			a.Flags |= Italic
+		case p == 4 || p == 21:
+			a.Flags |= Underline
+		case p == 5 || p == 6:
+			a.Flags |= Blink
+		case p == 7:
+			a.Flags |= Reverse
+		case p == 8:
+			a.Flags |= Hidden
+		case p == 9:
+			a.Flags |= Strike
+		case p == 22:
+			a.Flags &^= Bold | Faint
+		case p == 23:
+			a.Flags &^= Italic
+		case p == 24:
+			a.Flags &^= Underline
+		case p == 25:
+			a.Flags &^= Blink
+		case p == 27:
+			a.Flags &^= Reverse
+		case p == 28:
+			a.Flags &^= Hidden
+		case p == 29:
+			a.Flags &^= Strike
+		case p >= 30 && p <= 37:
+			a.FG = IndexedColor(uint8(p - 30))
+		case p == 39:
+			a.FG = DefaultColor
+		case p >= 40 && p <= 47:
+			a.BG = IndexedColor(uint8(p - 40))
+		case p == 49:
+			a.BG = DefaultColor
+		case p >= 90 && p <= 97:
+			a.FG = IndexedColor(uint8(p - 90 + 8))
+		case p >= 100 && p <= 107:
+			a.BG = IndexedColor(uint8(p - 100 + 8))
+		case p == 38 || p == 48:
+			c, used := extendedColor(params[i+1:])
+			i += used
+			if used == 0 {
+				return
+			}
+			if p == 38 {
+				a.FG = c
+			} else {
+				a.BG = c
+			}
+		}
+	}
+}
+
+// extendedColor parses the arguments of SGR 38/48: 5;n or 2;r;g;b. It
+// returns the color and the number of parameters consumed.
+func extendedColor(args []int) (Color, int) {
+	if len(args) == 0 {
+		return DefaultColor, 0
+	}
+	switch args[0] {
+	case 5:
+		if len(args) >= 2 {
+			return IndexedColor(uint8(min(max(args[1], 0), 255))), 2
+		}
+	case 2:
+		if len(args) >= 4 {
+			c := func(v int) uint8 { return uint8(min(max(v, 0), 255)) }
+			return RGBColor(c(args[1]), c(args[2]), c(args[3])), 4
+		}
+	}
+	return DefaultColor, 0
+}
+
+// oscDispatch handles an Operating System Command.
+func (s *Screen) oscDispatch(data []byte) {
+	cmd, arg := data, []byte(nil)
+	for i, b := range data {
+		if b == ';' {
+			cmd, arg = data[:i], data[i+1:]
+			break
+		}
+	}
+	n, err := strconv.Atoi(string(cmd))
+	if err != nil {
+		return
+	}
+	switch n {
+	case 0, 2:
+		s.title = string(arg)
+	case 7:
+		if host, dir, ok := parseFileURL(string(arg)); ok {
+			s.cwdHost, s.cwd = host, dir
+		}
+	}
+}
+
+// parseFileURL splits an OSC 7 working directory report, file://host/path,
+// into its host and path. Shells often send the path unescaped, so it is
+// only unescaped when it decodes cleanly.
+func parseFileURL(u string) (host, dir string, ok bool) {
+	rest, ok := strings.CutPrefix(u, "file://")
+	if !ok {
+		return "", "", false
+	}
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		return "", "", false
+	}
+	host, dir = rest[:i], rest[i:]
+	if d, err := url.PathUnescape(dir); err == nil {
+		dir = d
+	}
+	return host, dir, true
+}
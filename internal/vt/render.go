@@ -0,0 +1,102 @@
+package vt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Render returns the top-left cols×rows region of the screen with SGR
+// escapes for colors and attributes, one line per row. Areas outside the
+// screen are blank. When cursor is true and the cursor is visible, its
+// cell is drawn in reverse video.
+func (s *Screen) Render(cols, rows int, cursor bool) string {
+	var b strings.Builder
+	for y := 0; y < rows; y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		cursorX := -1
+		if cursor && s.cursorVisible && y == s.cur.y {
+			cursorX = s.cur.x
+		}
+		var line []Cell
+		if y < s.height {
+			line = s.buf.lines[y]
+		}
+		s.renderLine(&b, line, cols, cursorX)
+	}
+	return b.String()
+}
+
+func (s *Screen) renderLine(b *strings.Builder, line []Cell, cols, cursorX int) {
+	var pen Attr
+	for x := 0; x < cols; x++ {
+		c := Cell{Ch: ' '}
+		if x < len(line) {
+			c = line[x]
+		}
+		if c.Ch == 0 {
+			continue // right half of a wide character
+		}
+		if x+1 == cols && x+1 < len(line) && line[x+1].Ch == 0 {
+			c.Ch = ' ' // a wide character cut by the right edge
+		}
+		a := c.Attr
+		if x == cursorX {
+			a.Flags ^= Reverse
+		}
+		if a != pen {
+			b.WriteString(sgrSequence(a))
+			pen = a
+		}
+		b.WriteRune(c.Ch)
+	}
+	if pen != (Attr{}) {
+		b.WriteString("\x1b[0m")
+	}
+}
+
+// sgrSequence returns the escape sequence that selects a from the default
+// rendition.
+func sgrSequence(a Attr) string {
+	var b strings.Builder
+	b.WriteString("\x1b[0")
+	flags := [...]struct {
+		flag uint8
+		code string
+	}{
+		{Bold, "1"}, {Faint, "2"}, {Italic, "3"}, {Underline, "4"},
+		{Blink, "5"}, {Reverse, "7"}, {Hidden, "8"}, {Strike, "9"},
+	}
+	for _, f := range flags {
+		if a.Flags&f.flag != 0 {
+			b.WriteByte(';')
+			b.WriteString(f.code)
+		}
+	}
+	writeColor(&b, a.FG, 30, 90, "38")
+	writeColor(&b, a.BG, 40, 100, "48")
+	b.WriteByte('m')
+	return b.String()
+}
+
+func writeColor(b *strings.Builder, c Color, base, bright int, ext string) {
+	switch c &^ 0xffffff {
+	case colorIndexed:
+		i := int(c & 0xff)
+		b.WriteByte(';')
+		switch {
+		case i < 8:
+			b.WriteString(strconv.Itoa(base + i))
+		case i < 16:
+			b.WriteString(strconv.Itoa(bright + i - 8))
+		default:
+			b.WriteString(ext + ";5;" + strconv.Itoa(i))
+		}
+	case colorRGB:
+		b.WriteString(";" + ext + ";2;" +
+			strconv.Itoa(int(c>>16&0xff)) + ";" +
+			strconv.Itoa(int(c>>8&0xff)) + ";" +
+			strconv.Itoa(int(c&0xff)))
+	}
+}
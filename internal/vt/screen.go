@@ -0,0 +1,586 @@
+// Package vt emulates the subset of a VT100/xterm terminal that interactive
+// shells and full-screen programs rely on. A Screen consumes the byte stream
+// a PTY produces and keeps the result as a grid of styled cells.
+package vt
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Color is a cell color: the terminal default, one of the 256 indexed
+// colors, or a 24-bit RGB value.
+type Color uint32
+
+const (
+	// DefaultColor is the terminal's own foreground or background.
+	DefaultColor Color = 0
+
+	colorIndexed Color = 1 << 24
+	colorRGB     Color = 2 << 24
+)
+
+// IndexedColor returns palette color i (0–15 are the ANSI colors).
+func IndexedColor(i uint8) Color { return colorIndexed | Color(i) }
+
+// RGBColor returns a 24-bit color.
+func RGBColor(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// Attribute flags.
+const (
+	Bold uint8 = 1 << iota
+	Faint
+	Italic
+	Underline
+	Blink
+	Reverse
+	Hidden
+	Strike
+)
+
+// Attr is the rendition of a cell.
+type Attr struct {
+	FG, BG Color
+	Flags  uint8
+}
+
+// Cell is one character position. The right half of a wide character is
+// stored as a cell with Ch == 0.
+type Cell struct {
+	Ch   rune
+	Attr Attr
+}
+
+// cursor is the state saved and restored by DECSC/DECRC.
+type cursor struct {
+	x, y    int
+	attr    Attr
+	origin  bool
+	charset [2]bool // G0/G1 designated as DEC special graphics
+	shift   int     // 0 = G0 invoked into GL, 1 = G1
+}
+
+// buffer is one of the two screens, each with its own saved cursor.
+type buffer struct {
+	lines [][]Cell
+	saved cursor
+}
+
+// Screen is a terminal screen. It is not safe for concurrent use.
+type Screen struct {
+	width, height int
+
+	primary, alternate buffer
+	buf                *buffer
+
+	cur         cursor
+	pendingWrap bool // the last column was written; wrap before the next rune
+	top, bottom int  // scroll region, inclusive
+	tabs        []bool
+	lastRune    rune
+
+	autowrap      bool
+	insert        bool
+	cursorVisible bool
+	appCursor     bool
+	appKeypad     bool
+	bracketed     bool
+
+	title   string
+	replies []byte
+
+	cwdHost, cwd string // working directory reported with OSC 7
+
+	parser     parser
+	scrollback scrollback
+
+	// OnScroll, when set, receives each line scrolled off the top of the
+	// primary screen.
+	OnScroll func(line []Cell)
+}
+
+// New returns a blank screen of the given size.
+func New(width, height int) *Screen {
+	s := &Screen{}
+	s.reset(max(width, 1), max(height, 1))
+	return s
+}
+
+func (s *Screen) reset(width, height int) {
+	s.width, s.height = width, height
+	s.primary = buffer{lines: blankLines(width, height, Attr{})}
+	s.alternate = buffer{lines: blankLines(width, height, Attr{})}
+	s.buf = &s.primary
+	s.cur = cursor{}
+	s.pendingWrap = false
+	s.top, s.bottom = 0, height-1
+	s.resetTabs()
+	s.autowrap, s.insert, s.cursorVisible = true, false, true
+	s.appCursor, s.appKeypad, s.bracketed = false, false, false
+	s.title = ""
+	s.parser = parser{}
+}
+
+func blankLine(width int, a Attr) []Cell {
+	line := make([]Cell, width)
+	for i := range line {
+		line[i] = Cell{Ch: ' ', Attr: Attr{BG: a.BG}}
+	}
+	return line
+}
+
+func blankLines(width, height int, a Attr) [][]Cell {
+	lines := make([][]Cell, height)
+	for i := range lines {
+		lines[i] = blankLine(width, a)
+	}
+	return lines
+}
+
+func (s *Screen) resetTabs() {
+	s.tabs = make([]bool, s.width)
+	for i := 8; i < s.width; i += 8 {
+		s.tabs[i] = true
+	}
+}
+
+// Size returns the screen's width and height in cells.
+func (s *Screen) Size() (width, height int) { return s.width, s.height }
+
+// Cursor returns the cursor position (0-based column and row).
+func (s *Screen) Cursor() (x, y int) { return s.cur.x, s.cur.y }
+
+// CursorVisible reports whether the program has the cursor shown (DECTCEM).
+func (s *Screen) CursorVisible() bool { return s.cursorVisible }
+
+// AppCursorKeys reports whether arrow keys should be sent in application
+// mode (DECCKM), as ESC O A rather than ESC [ A.
+func (s *Screen) AppCursorKeys() bool { return s.appCursor }
+
+// BracketedPaste reports whether pasted text should be wrapped in
+// ESC [ 200 ~ … ESC [ 201 ~.
+func (s *Screen) BracketedPaste() bool { return s.bracketed }
+
+// AltScreen reports whether the alternate screen is shown.
+func (s *Screen) AltScreen() bool { return s.buf == &s.alternate }
+
+// Title returns the window title last set with OSC 0 or 2.
+func (s *Screen) Title() string { return s.title }
+
+// Cwd returns the host and working directory last reported by the shell
+// with OSC 7, or empty strings.
+func (s *Screen) Cwd() (host, dir string) { return s.cwdHost, s.cwd }
+
+// Replies returns and clears the answers to device queries (DA, DSR) that
+// should be written back to the PTY.
+func (s *Screen) Replies() []byte {
+	r := s.replies
+	s.replies = nil
+	return r
+}
+
+// Cell returns the cell at column x, row y.
+func (s *Screen) Cell(x, y int) Cell {
+	if x < 0 || y < 0 || x >= s.width || y >= s.height {
+		return Cell{Ch: ' '}
+	}
+	return s.buf.lines[y][x]
+}
+
+// Line returns row y as plain text without trailing blanks.
+func (s *Screen) Line(y int) string {
+	if y < 0 || y >= s.height {
+		return ""
+	}
+	return cellsText(s.buf.lines[y])
+}
+
+func cellsText(cells []Cell) string {
+	var b strings.Builder
+	for _, c := range cells {
+		if c.Ch != 0 {
+			b.WriteRune(c.Ch)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// String returns the screen as plain text, one line per row, without
+// trailing blank rows.
+func (s *Screen) String() string {
+	lines := make([]string, s.height)
+	for y := range lines {
+		lines[y] = s.Line(y)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetScrollback sets how many lines scrolled off the primary screen are
+// kept (0 keeps none). The newest lines are kept when the limit shrinks.
+func (s *Screen) SetScrollback(limit int) {
+	s.scrollback.setLimit(limit)
+}
+
+// Lines returns the screen as plain text, one entry per row, preceded by
+// the scrollback when the primary screen is shown. Blank rows below the
+// cursor are left out.
+func (s *Screen) Lines() []string {
+	var lines []string
+	if !s.AltScreen() {
+		lines = make([]string, 0, s.scrollback.len()+s.height)
+		for i := 0; i < s.scrollback.len(); i++ {
+			lines = append(lines, cellsText(s.scrollback.line(i)))
+		}
+	}
+	last := s.cur.y
+	for y := s.height - 1; y > last; y-- {
+		if s.Line(y) != "" {
+			last = y
+			break
+		}
+	}
+	for y := 0; y <= last; y++ {
+		lines = append(lines, s.Line(y))
+	}
+	return lines
+}
+
+// ScrollbackLen returns the number of lines in the scrollback.
+func (s *Screen) ScrollbackLen() int { return s.scrollback.len() }
+
+// Resize changes the screen size. Rows are dropped from the top when the
+// screen gets shorter so the cursor line stays visible.
+func (s *Screen) Resize(width, height int) {
+	width, height = max(width, 1), max(height, 1)
+	if width == s.width && height == s.height {
+		return
+	}
+	for _, b := range []*buffer{&s.primary, &s.alternate} {
+		drop := 0
+		if b == s.buf && s.cur.y >= height {
+			drop = s.cur.y - height + 1
+		}
+		if b == &s.primary {
+			for _, line := range b.lines[:drop] {
+				s.scrolledOff(line)
+			}
+		}
+		lines := b.lines[drop:]
+		if len(lines) > height {
+			lines = lines[:height]
+		}
+		for len(lines) < height {
+			lines = append(lines, blankLine(width, Attr{}))
+		}
+		for y, line := range lines {
+			switch {
+			case len(line) > width:
+				line = line[:width]
+				if line[width-1].Ch != 0 && runewidth.RuneWidth(line[width-1].Ch) == 2 {
+					line[width-1] = Cell{Ch: ' ', Attr: line[width-1].Attr}
+				}
+			case len(line) < width:
+				line = append(line, blankLine(width-len(line), Attr{})...)
+			}
+			lines[y] = line
+		}
+		b.lines = lines
+		if b == s.buf {
+			s.cur.y -= drop
+		}
+	}
+	s.width, s.height = width, height
+	s.top, s.bottom = 0, height-1
+	s.resetTabs()
+	s.cur.x = min(s.cur.x, width-1)
+	s.cur.y = min(max(s.cur.y, 0), height-1)
+	s.pendingWrap = false
+}
+
+// blank is an erased cell: a space carrying the current background.
+func (s *Screen) blank() Cell {
+	return Cell{Ch: ' ', Attr: Attr{BG: s.cur.attr.BG}}
+}
+
+// moveTo places the cursor, clamped to the screen, and clears a pending wrap.
+func (s *Screen) moveTo(x, y int) {
+	s.cur.x = min(max(x, 0), s.width-1)
+	s.cur.y = min(max(y, 0), s.height-1)
+	s.pendingWrap = false
+}
+
+// put writes a printable rune at the cursor and advances it.
+func (s *Screen) put(r rune) {
+	if s.cur.charset[s.cur.shift] && r >= 0x5f && r <= 0x7e {
+		r = decGraphics[r-0x5f]
+	}
+	w := runewidth.RuneWidth(r)
+	if w == 0 {
+		return // combining marks are not tracked
+	}
+	if s.pendingWrap && s.autowrap {
+		s.cur.x = 0
+		s.lineFeed()
+	}
+	s.pendingWrap = false
+	if w == 2 && s.cur.x == s.width-1 {
+		if !s.autowrap || s.width < 2 {
+			return
+		}
+		s.buf.lines[s.cur.y][s.cur.x] = s.blank()
+		s.cur.x = 0
+		s.lineFeed()
+	}
+	line := s.buf.lines[s.cur.y]
+	if s.insert {
+		copy(line[s.cur.x+w:], line[s.cur.x:])
+	}
+	s.splitWide(line, s.cur.x)
+	if w == 2 {
+		s.splitWide(line, s.cur.x+1)
+	}
+	line[s.cur.x] = Cell{Ch: r, Attr: s.cur.attr}
+	if w == 2 {
+		line[s.cur.x+1] = Cell{Ch: 0, Attr: s.cur.attr}
+	}
+	s.lastRune = r
+	if s.cur.x+w >= s.width {
+		s.cur.x = s.width - 1
+		s.pendingWrap = s.autowrap
+		if !s.autowrap && w == 2 {
+			s.cur.x = s.width - 2
+		}
+		return
+	}
+	s.cur.x += w
+}
+
+// splitWide blanks the other half of a wide character that is about to be
+// partly overwritten at column x.
+func (s *Screen) splitWide(line []Cell, x int) {
+	if x >= len(line) {
+		return
+	}
+	if line[x].Ch == 0 && x > 0 {
+		line[x-1] = Cell{Ch: ' ', Attr: line[x-1].Attr}
+	}
+	if x+1 < len(line) && line[x+1].Ch == 0 {
+		line[x+1] = Cell{Ch: ' ', Attr: line[x+1].Attr}
+	}
+}
+
+// lineFeed moves down a line, scrolling the region at its bottom margin.
+func (s *Screen) lineFeed() {
+	if s.cur.y == s.bottom {
+		s.scrollUp(1)
+	} else if s.cur.y < s.height-1 {
+		s.cur.y++
+	}
+}
+
+// reverseIndex moves up a line, scrolling the region at its top margin.
+func (s *Screen) reverseIndex() {
+	if s.cur.y == s.top {
+		s.scrollDown(1)
+	} else if s.cur.y > 0 {
+		s.cur.y--
+	}
+}
+
+// scrolledOff keeps a line that left the top of the primary screen.
+func (s *Screen) scrolledOff(line []Cell) {
+	s.scrollback.push(line)
+	if s.OnScroll != nil {
+		s.OnScroll(line)
+	}
+}
+
+// scrollUp scrolls the scroll region up by n lines. Lines leaving the top
+// of the primary screen go to the scrollback.
+func (s *Screen) scrollUp(n int) {
+	s.scrollRegionUp(n, s.top == 0 && s.buf == &s.primary)
+}
+
+func (s *Screen) scrollRegionUp(n int, keep bool) {
+	n = min(n, s.bottom-s.top+1)
+	lines := s.buf.lines
+	if keep {
+		for _, line := range lines[:n] {
+			s.scrolledOff(line)
+		}
+	}
+	copy(lines[s.top:], lines[s.top+n:s.bottom+1])
+	for y := s.bottom - n + 1; y <= s.bottom; y++ {
+		lines[y] = blankLine(s.width, s.cur.attr)
+	}
+}
+
+// scrollDown scrolls the scroll region down by n lines.
+func (s *Screen) scrollDown(n int) {
+	n = min(n, s.bottom-s.top+1)
+	lines := s.buf.lines
+	copy(lines[s.top+n:s.bottom+1], lines[s.top:])
+	for y := s.top; y < s.top+n; y++ {
+		lines[y] = blankLine(s.width, s.cur.attr)
+	}
+}
+
+// tab moves to the next tab stop (n times), or the last column.
+func (s *Screen) tab(n int) {
+	x := s.cur.x
+	for ; n > 0 && x < s.width-1; n-- {
+		for x++; x < s.width-1 && !s.tabs[x]; x++ {
+		}
+	}
+	s.moveTo(x, s.cur.y)
+}
+
+// backTab moves to the previous tab stop (n times), or the first column.
+func (s *Screen) backTab(n int) {
+	x := s.cur.x
+	for ; n > 0 && x > 0; n-- {
+		for x--; x > 0 && !s.tabs[x]; x-- {
+		}
+	}
+	s.moveTo(x, s.cur.y)
+}
+
+// eraseCells blanks columns [from, to) of row y.
+func (s *Screen) eraseCells(y, from, to int) {
+	line := s.buf.lines[y]
+	from, to = max(from, 0), min(to, s.width)
+	if from >= to {
+		return
+	}
+	s.splitWide(line, from)
+	if to < s.width {
+		s.splitWide(line, to)
+	}
+	b := s.blank()
+	for x := from; x < to; x++ {
+		line[x] = b
+	}
+}
+
+// eraseDisplay implements ED.
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseCells(s.cur.y, s.cur.x, s.width)
+		for y := s.cur.y + 1; y < s.height; y++ {
+			s.eraseCells(y, 0, s.width)
+		}
+	case 1:
+		for y := 0; y < s.cur.y; y++ {
+			s.eraseCells(y, 0, s.width)
+		}
+		s.eraseCells(s.cur.y, 0, s.cur.x+1)
+	case 2:
+		for y := 0; y < s.height; y++ {
+			s.eraseCells(y, 0, s.width)
+		}
+	case 3:
+		s.scrollback.clear()
+	}
+}
+
+// eraseLine implements EL.
+func (s *Screen) eraseLine(mode int) {
+	switch mode {
+	case 0:
+		s.eraseCells(s.cur.y, s.cur.x, s.width)
+	case 1:
+		s.eraseCells(s.cur.y, 0, s.cur.x+1)
+	case 2:
+		s.eraseCells(s.cur.y, 0, s.width)
+	}
+}
+
+// insertLines implements IL: blank lines pushed in at the cursor row.
+func (s *Screen) insertLines(n int) {
+	if s.cur.y < s.top || s.cur.y > s.bottom {
+		return
+	}
+	top := s.top
+	s.top = s.cur.y
+	s.scrollDown(n)
+	s.top = top
+	s.moveTo(0, s.cur.y)
+}
+
+// deleteLines implements DL: lines removed at the cursor row.
+func (s *Screen) deleteLines(n int) {
+	if s.cur.y < s.top || s.cur.y > s.bottom {
+		return
+	}
+	top := s.top
+	s.top = s.cur.y
+	s.scrollRegionUp(n, false)
+	s.top = top
+	s.moveTo(0, s.cur.y)
+}
+
+// insertChars implements ICH.
+func (s *Screen) insertChars(n int) {
+	line := s.buf.lines[s.cur.y]
+	n = min(n, s.width-s.cur.x)
+	s.splitWide(line, s.cur.x)
+	copy(line[s.cur.x+n:], line[s.cur.x:])
+	b := s.blank()
+	for x := s.cur.x; x < s.cur.x+n; x++ {
+		line[x] = b
+	}
+	s.pendingWrap = false
+}
+
+// deleteChars implements DCH.
+func (s *Screen) deleteChars(n int) {
+	line := s.buf.lines[s.cur.y]
+	n = min(n, s.width-s.cur.x)
+	s.splitWide(line, s.cur.x)
+	s.splitWide(line, s.cur.x+n)
+	copy(line[s.cur.x:], line[s.cur.x+n:])
+	b := s.blank()
+	for x := s.width - n; x < s.width; x++ {
+		line[x] = b
+	}
+	s.pendingWrap = false
+}
+
+// saveCursor implements DECSC for the current screen.
+func (s *Screen) saveCursor() {
+	s.buf.saved = s.cur
+}
+
+// restoreCursor implements DECRC for the current screen.
+func (s *Screen) restoreCursor() {
+	s.cur = s.buf.saved
+	s.moveTo(s.cur.x, s.cur.y)
+}
+
+// setAltScreen switches between the primary and alternate screens. The
+// alternate screen is cleared when entered.
+func (s *Screen) setAltScreen(on bool) {
+	if on == s.AltScreen() {
+		return
+	}
+	if on {
+		s.buf = &s.alternate
+		s.alternate.lines = blankLines(s.width, s.height, Attr{})
+	} else {
+		s.buf = &s.primary
+	}
+	s.pendingWrap = false
+}
+
+// decGraphics maps 0x5f–0x7e to the DEC special graphics (line drawing) set.
+var decGraphics = [...]rune{
+	' ', '◆', '▒', '␉', '␌', '␍', '␊', '°', '±', '␤', '␋', '┘', '┐', '┌', '└', '┼',
+	'⎺', '⎻', '─', '⎼', '⎽', '├', '┤', '┴', '┬', '│', '≤', '≥', 'π', '≠', '£', '·',
+}
@@ -0,0 +1,252 @@
+package vt
+
+import (
+	"strings"
+	"testing"
+)
+
+func write(s *Screen, data string) {
+	_, _ = s.Write([]byte(data))
+}
+
+func TestWrapAndScroll(t *testing.T) {
+	s := New(5, 3)
+	var scrolled []string
+	s.OnScroll = func(line []Cell) { scrolled = append(scrolled, cellsText(line)) }
+
+	write(s, "abcdefg\r\nxy\r\nz\r\nlast")
+	if got := s.String(); got != "xy\nz\nlast" {
+		t.Errorf("screen =\n%s", got)
+	}
+	if strings.Join(scrolled, "|") != "abcde|fg" {
+		t.Errorf("scrolled = %q", scrolled)
+	}
+
+	// Writing the last column defers the wrap until the next character.
+	s = New(5, 2)
+	write(s, "abcde")
+	if x, y := s.Cursor(); x != 4 || y != 0 {
+		t.Errorf("cursor after filling the row = %d,%d", x, y)
+	}
+	write(s, "\r\n")
+	if s.Line(1) != "" || s.Line(0) != "abcde" {
+		t.Errorf("CR LF after a full row should not add a blank line:\n%s", s.String())
+	}
+}
+
+func TestCursorAddressingAndErase(t *testing.T) {
+	s := New(10, 4)
+	write(s, "0123456789\r\nabcdefghij\r\nABCDEFGHIJ")
+	write(s, "\x1b[2;3H\x1b[K")        // row 2, col 3; erase to end of line
+	write(s, "\x1b[1;5H\x1b[2P")       // delete two chars at col 5 of row 1
+	write(s, "\x1b[3;2H\x1b[3@XYZ")    // insert three blanks, then overwrite them
+	write(s, "\x1b[4;1H*\x1b[A\x1b[C") // print, up, right
+	want := "01236789\nab\nAXYZBCDEFG\n*"
+	if got := s.String(); got != want {
+		t.Errorf("screen =\n%q\nwant\n%q", got, want)
+	}
+	if x, y := s.Cursor(); x != 2 || y != 2 {
+		t.Errorf("cursor = %d,%d", x, y)
+	}
+	write(s, "\x1b[2J")
+	if s.String() != "" {
+		t.Errorf("ED 2 should clear the screen:\n%s", s.String())
+	}
+}
+
+func TestScrollRegion(t *testing.T) {
+	s := New(4, 5)
+	write(s, "1\r\n2\r\n3\r\n4\r\n5")
+	write(s, "\x1b[2;4r") // region rows 2–4; cursor homes
+	if x, y := s.Cursor(); x != 0 || y != 0 {
+		t.Errorf("DECSTBM should home the cursor, got %d,%d", x, y)
+	}
+	write(s, "\x1b[4;1H\n") // LF at the bottom margin scrolls the region only
+	if got := s.String(); got != "1\n3\n4\n\n5" {
+		t.Errorf("after LF =\n%q", got)
+	}
+	write(s, "\x1b[2;1H\x1bM") // RI at the top margin
+	if got := s.String(); got != "1\n\n3\n4\n5" {
+		t.Errorf("after RI =\n%q", got)
+	}
+	write(s, "\x1b[3;1H\x1b[L") // IL inside the region
+	if got := s.String(); got != "1\n\n\n3\n5" {
+		t.Errorf("after IL =\n%q", got)
+	}
+	write(s, "\x1b[2;1H\x1b[2M") // DL
+	if got := s.String(); got != "1\n3\n\n\n5" {
+		t.Errorf("after DL =\n%q", got)
+	}
+}
+
+func TestAltScreen(t *testing.T) {
+	s := New(10, 3)
+	write(s, "$ vim\r\n")
+	write(s, "\x1b[?1049h\x1b[H\x1b[2Jeditor")
+	if !s.AltScreen() || s.String() != "editor" {
+		t.Fatalf("alt screen = %q", s.String())
+	}
+	write(s, "\x1b[?1049l")
+	if s.AltScreen() || s.String() != "$ vim" {
+		t.Errorf("primary screen = %q", s.String())
+	}
+	if x, y := s.Cursor(); x != 0 || y != 1 {
+		t.Errorf("cursor should be restored, got %d,%d", x, y)
+	}
+}
+
+func TestSGR(t *testing.T) {
+	s := New(10, 1)
+	write(s, "\x1b[1;31mA\x1b[38;5;200;48;2;1;2;3mB\x1b[22;39;49mC\x1b[0;7mD")
+	tests := []struct {
+		x    int
+		want Attr
+	}{
+		{0, Attr{FG: IndexedColor(1), Flags: Bold}},
+		{1, Attr{FG: IndexedColor(200), BG: RGBColor(1, 2, 3), Flags: Bold}},
+		{2, Attr{}},
+		{3, Attr{Flags: Reverse}},
+	}
+	for _, tt := range tests {
+		if got := s.Cell(tt.x, 0).Attr; got != tt.want {
+			t.Errorf("cell %d attr = %+v, want %+v", tt.x, got, tt.want)
+		}
+	}
+	got := s.Render(5, 1, false)
+	want := "\x1b[0;1;31mA\x1b[0;1;38;5;200;48;2;1;2;3mB\x1b[0mC\x1b[0;7mD\x1b[0m "
+	if got != want {
+		t.Errorf("Render =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRenderCursorAndCrop(t *testing.T) {
+	s := New(4, 2)
+	write(s, "ab")
+	if got := s.Render(3, 3, true); got != "ab\x1b[0;7m \x1b[0m\n   \n   " {
+		t.Errorf("Render = %q", got)
+	}
+	write(s, "\x1b[?25l")
+	if got := s.Render(3, 1, true); got != "ab " {
+		t.Errorf("hidden cursor Render = %q", got)
+	}
+}
+
+func TestUTF8AndWideRunes(t *testing.T) {
+	s := New(6, 2)
+	euro := []byte("€")
+	_, _ = s.Write(euro[:1])
+	_, _ = s.Write(euro[1:])
+	write(s, "世界x")
+	if got := s.Line(0); got != "€世界x" {
+		t.Errorf("line = %q", got)
+	}
+	if s.Cell(1, 0).Ch != '世' || s.Cell(2, 0).Ch != 0 {
+		t.Error("a wide rune should take two cells")
+	}
+	// A wide rune that does not fit in the last column wraps.
+	s = New(3, 2)
+	write(s, "ab世")
+	if s.Line(0) != "ab" || s.Line(1) != "世" {
+		t.Errorf("screen = %q", s.String())
+	}
+	// Overwriting half of a wide rune blanks the other half.
+	write(s, "\r\x1b[1Cz")
+	if s.Line(1) != " z" {
+		t.Errorf("line = %q", s.Line(1))
+	}
+}
+
+func TestRepliesTitleAndCharset(t *testing.T) {
+	s := New(20, 5)
+	write(s, "\x1b[3;7H\x1b[6n\x1b[c")
+	if got := string(s.Replies()); got != "\x1b[3;7R\x1b[?62;22c" {
+		t.Errorf("replies = %q", got)
+	}
+	if s.Replies() != nil {
+		t.Error("replies should be drained")
+	}
+
+	write(s, "\x1b]0;user@host: ~\x1b")
+	write(s, "\\\x1b]2;second\x07")
+	if s.Title() != "second" {
+		t.Errorf("title = %q", s.Title())
+	}
+	write(s, "\x1bP1$r0m\x1b\\") // DCS payloads are skipped
+	write(s, "\r\x1b(0lqk\x1b(B")
+	if got := s.Line(2); got != "┌─┐" {
+		t.Errorf("line drawing = %q", got)
+	}
+	write(s, "\x1b[?1h")
+	if !s.AppCursorKeys() {
+		t.Error("DECCKM should enable application cursor keys")
+	}
+}
+
+func TestWorkingDirectory(t *testing.T) {
+	s := New(20, 5)
+	if host, dir := s.Cwd(); host != "" || dir != "" {
+		t.Errorf("a new screen should have no directory, got %q %q", host, dir)
+	}
+	write(s, "\x1b]7;file://web1/srv/my%20app\x1b\\")
+	if host, dir := s.Cwd(); host != "web1" || dir != "/srv/my app" {
+		t.Errorf("cwd = %q %q", host, dir)
+	}
+	write(s, "\x1b]7;file:///tmp/100%\x07") // unescaped, does not decode
+	if host, dir := s.Cwd(); host != "" || dir != "/tmp/100%" {
+		t.Errorf("cwd = %q %q", host, dir)
+	}
+	write(s, "\x1b]7;http://example.com/\x07")
+	if _, dir := s.Cwd(); dir != "/tmp/100%" {
+		t.Errorf("other URLs should be ignored, cwd = %q", dir)
+	}
+}
+
+func TestResize(t *testing.T) {
+	s := New(6, 4)
+	var scrolled []string
+	s.OnScroll = func(line []Cell) { scrolled = append(scrolled, cellsText(line)) }
+	write(s, "one\r\ntwo\r\nthree\r\nfour")
+	s.Resize(3, 2)
+	if got := s.String(); got != "thr\nfou" {
+		t.Errorf("after shrink =\n%q", got)
+	}
+	if x, y := s.Cursor(); x != 2 || y != 1 {
+		t.Errorf("cursor = %d,%d", x, y)
+	}
+	if strings.Join(scrolled, "|") != "one|two" {
+		t.Errorf("rows dropped on resize should go to scrollback, got %q", scrolled)
+	}
+	s.Resize(8, 3)
+	if w, h := s.Size(); w != 8 || h != 3 || s.String() != "thr\nfou" {
+		t.Errorf("after grow %dx%d =\n%q", w, h, s.String())
+	}
+}
+
+func TestScrollback(t *testing.T) {
+	s := New(5, 2)
+	s.SetScrollback(3)
+	write(s, "1\r\n2\r\n3\r\n4\r\n5\r\n6")
+	if got := strings.Join(s.Lines(), ","); got != "2,3,4,5,6" {
+		t.Errorf("lines = %q, want the last 3 scrollback lines and the screen", got)
+	}
+	s.SetScrollback(2)
+	if got := strings.Join(s.Lines(), ","); got != "3,4,5,6" {
+		t.Errorf("after shrinking = %q", got)
+	}
+	write(s, "\x1b[?1049h\x1b[Hfull")
+	if got := strings.Join(s.Lines(), ","); got != "full" {
+		t.Errorf("alt screen lines = %q", got)
+	}
+	write(s, "\x1b[?1049l\x1b[3J")
+	if s.ScrollbackLen() != 0 {
+		t.Errorf("ED 3 should clear the scrollback, %d lines left", s.ScrollbackLen())
+	}
+
+	// Scrolling inside a region does not feed the scrollback.
+	s = New(5, 3)
+	s.SetScrollback(10)
+	write(s, "\x1b[2;3r\x1b[3;1Ha\nb\nc")
+	if s.ScrollbackLen() != 0 {
+		t.Errorf("region scroll kept %d lines", s.ScrollbackLen())
+	}
+}
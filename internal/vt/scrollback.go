@@ -0,0 +1,41 @@
+package vt
+
+// scrollback is a ring of the most recent lines scrolled off the screen.
+type scrollback struct {
+	lines [][]Cell
+	start int // index of the oldest line once the ring is full
+	limit int
+}
+
+func (r *scrollback) push(line []Cell) {
+	if r.limit <= 0 {
+		return
+	}
+	if len(r.lines) < r.limit {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % r.limit
+}
+
+func (r *scrollback) len() int { return len(r.lines) }
+
+// line returns line i, counting from the oldest.
+func (r *scrollback) line(i int) []Cell {
+	return r.lines[(r.start+i)%len(r.lines)]
+}
+
+func (r *scrollback) setLimit(limit int) {
+	limit = max(limit, 0)
+	keep := min(len(r.lines), limit)
+	lines := make([][]Cell, keep, limit)
+	for i := range lines {
+		lines[i] = r.line(len(r.lines) - keep + i)
+	}
+	r.lines, r.start, r.limit = lines, 0, limit
+}
+
+func (r *scrollback) clear() {
+	r.lines, r.start = r.lines[:0], 0
+}